@@ -0,0 +1,17 @@
+package api
+
+import (
+	"web-task/blog/internal/controller"
+	"web-task/blog/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAdminRouter 注册管理员路由，均需管理员身份
+func SetupAdminRouter(router *gin.RouterGroup, uc *controller.UserController, ic *controller.ImportHandler) {
+	adminRouter := router.Group("/admin", middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	{
+		adminRouter.POST("/users/:username/unlock", uc.Unlock)               // 解除登录锁定
+		adminRouter.POST("/import", middleware.TransferTimeout(), ic.Import) // 批量导入文章
+	}
+}
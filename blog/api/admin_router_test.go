@@ -0,0 +1,76 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/logic"
+)
+
+func wxrExport(author string) []byte {
+	return []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<item>
+		<title>Imported post</title>
+		<dc:creator>` + author + `</dc:creator>
+		<content:encoded><![CDATA[<p>Hello from WordPress</p>]]></content:encoded>
+		<wp:post_id>42</wp:post_id>
+		<wp:post_date_gmt>2020-01-02 03:04:05</wp:post_date_gmt>
+		<wp:status>publish</wp:status>
+		<wp:post_type>post</wp:post_type>
+		<category domain="post_tag" nicename="go"><![CDATA[go]]></category>
+	</item>
+</channel>
+</rss>`)
+}
+
+func TestAdminImport(t *testing.T) {
+	s := apitest.New(t)
+	admin := s.CreateAdmin("admin")
+	user := s.CreateUser("alice")
+	wxr := wxrExport("alice")
+
+	cases := []struct {
+		name     string
+		token    string
+		fields   map[string]string
+		filename string
+		file     []byte
+		status   int
+	}{
+		{"without token", "", nil, "export.xml", wxr, http.StatusUnauthorized},
+		{"non-admin", user.Token, nil, "export.xml", wxr, http.StatusForbidden},
+		{"without file", admin.Token, nil, "", nil, http.StatusBadRequest},
+		{"unsupported file", admin.Token, nil, "export.txt", wxr, http.StatusUnsupportedMediaType},
+		{"invalid author map", admin.Token, map[string]string{"author_map": "[1]"}, "export.xml", wxr, http.StatusBadRequest},
+		{"invalid dry run", admin.Token, map[string]string{"dry_run": "maybe"}, "export.xml", wxr, http.StatusBadRequest},
+		{"invalid wxr", admin.Token, nil, "export.xml", []byte("<rss><channel>"), http.StatusBadRequest},
+		{"invalid zip", admin.Token, nil, "site.zip", []byte("not a zip"), http.StatusBadRequest},
+		{"unknown author", admin.Token, nil, "export.xml", wxrExport("nobody"), http.StatusUnprocessableEntity},
+		{"dry run", admin.Token, map[string]string{"dry_run": "true"}, "export.xml", wxr, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := s.Serve(s.Multipart(http.MethodPost, "/api/v1/admin/import", tc.token, tc.fields, tc.filename, tc.file))
+			apitest.ExpectStatus(t, w, tc.status)
+		})
+	}
+
+	for _, want := range []struct {
+		name    string
+		created int
+		same    int
+	}{{"import", 1, 0}, {"reimport", 0, 1}} {
+		w := s.Serve(s.Multipart(http.MethodPost, "/api/v1/admin/import", admin.Token, nil, "export.xml", wxr))
+		apitest.ExpectStatus(t, w, http.StatusOK)
+		report := apitest.DecodeData[logic.ImportReport](t, w)
+		if report.Created != want.created || report.Unchanged != want.same {
+			t.Errorf("%s report = %+v, want %d created, %d unchanged", want.name, report, want.created, want.same)
+		}
+	}
+}
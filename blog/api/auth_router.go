@@ -0,0 +1,25 @@
+package api
+
+import (
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/controller"
+	"web-task/blog/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 定义第三方登录路由
+func SetupAuthRouter(router *gin.RouterGroup, ic *controller.IdentityController, limiter *middleware.RateLimiter) {
+	authRouter := router.Group("/auth")
+	{
+		loginLimit := limiter.Limit(consts.RateLimitLogin)
+		authRouter.GET("/providers", ic.Providers)                     // 已启用的提供方
+		authRouter.GET("/:provider/login", loginLimit, ic.Login)       // 跳转到提供方授权
+		authRouter.GET("/:provider/callback", loginLimit, ic.Callback) // 授权回调
+
+		me := router.Group("/users/me", middleware.AuthMiddleware())
+		me.GET("/identities", ic.ListIdentities)          // 已绑定的第三方身份
+		me.POST("/identities/:provider", ic.LinkIdentity) // 发起绑定
+		me.DELETE("/identities/:id", ic.UnlinkIdentity)   // 解除绑定
+	}
+}
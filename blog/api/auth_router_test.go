@@ -0,0 +1,230 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/oidc/oidctest"
+)
+
+const callbackPath = "/api/v1/auth/" + apitest.OIDCProvider + "/callback"
+
+// oauthFlow 发起的一次授权：提供方授权地址与下发给浏览器的 state Cookie
+type oauthFlow struct {
+	authURL string
+	cookie  *http.Cookie
+}
+
+// startFlow 从发起授权的响应中取出授权地址与 state Cookie
+func startFlow(t *testing.T, w *httptest.ResponseRecorder, authURL string) oauthFlow {
+	t.Helper()
+
+	for _, c := range w.Result().Cookies() {
+		if c.Name == consts.OAuthStateCookie {
+			if !c.HttpOnly || c.SameSite != http.SameSiteLaxMode || c.Path != consts.OAuthStateCookiePath || c.MaxAge <= 0 {
+				t.Fatalf("state cookie = %+v, want an HttpOnly SameSite=Lax cookie on %s", c, consts.OAuthStateCookiePath)
+			}
+			return oauthFlow{authURL: authURL, cookie: c}
+		}
+	}
+	t.Fatalf("no %s cookie set when starting authorization", consts.OAuthStateCookie)
+	return oauthFlow{}
+}
+
+// loginFlow 发起第三方登录
+func loginFlow(t *testing.T, s *apitest.Server) oauthFlow {
+	t.Helper()
+
+	w := s.Do(http.MethodGet, "/api/v1/auth/"+apitest.OIDCProvider+"/login", "", nil)
+	apitest.ExpectStatus(t, w, http.StatusFound)
+	return startFlow(t, w, w.Header().Get("Location"))
+}
+
+// linkFlow 为已登录用户发起绑定
+func linkFlow(t *testing.T, s *apitest.Server, token string) oauthFlow {
+	t.Helper()
+
+	w := s.Do(http.MethodPost, "/api/v1/users/me/identities/"+apitest.OIDCProvider, token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	return startFlow(t, w, apitest.DecodeData[struct {
+		AuthorizationURL string `json:"authorization_url"`
+	}](t, w).AuthorizationURL)
+}
+
+// callback 让模拟提供方同意授权，返回回调地址（含 code 与 state）
+func (f oauthFlow) callback(t *testing.T, s *apitest.Server) string {
+	t.Helper()
+
+	code, state, err := s.OIDC.Authorize(f.authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	return callbackPath + "?" + url.Values{"code": {code}, "state": {state}}.Encode()
+}
+
+// finish 在发起授权的浏览器中（带上 state Cookie）访问回调地址
+func (f oauthFlow) finish(s *apitest.Server, callback string) *httptest.ResponseRecorder {
+	req := s.NewRequest(http.MethodGet, callback, "", nil)
+	req.AddCookie(f.cookie)
+	return s.Serve(req)
+}
+
+// login 完成一次第三方登录
+func (f oauthFlow) login(t *testing.T, s *apitest.Server) *httptest.ResponseRecorder {
+	t.Helper()
+	return f.finish(s, f.callback(t, s))
+}
+
+type oidcLogin struct {
+	Token   string `json:"token"`
+	NewUser bool   `json:"new_user"`
+}
+
+type identity struct {
+	ID       uint   `json:"id"`
+	Provider string `json:"provider"`
+}
+
+func TestOIDCLogin(t *testing.T) {
+	s := apitest.New(t)
+	s.Register("alice")
+
+	w := s.Do(http.MethodGet, "/api/v1/auth/providers", "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if names := apitest.DecodeData[[]string](t, w); len(names) != 1 || names[0] != apitest.OIDCProvider {
+		t.Fatalf("providers = %v", names)
+	}
+
+	first := loginFlow(t, s)
+	if !strings.HasPrefix(first.authURL, s.OIDC.Issuer()) {
+		t.Fatalf("login redirects to %q, want the mock provider", first.authURL)
+	}
+
+	// 本地 alice 的邮箱未验证，不能自动关联同邮箱的第三方身份
+	conflict := first.callback(t, s)
+
+	s.OIDC.SetUser(oidctest.User{Subject: "2002", Email: "carol@example.com", Username: "carol", Name: "Carol"})
+	carol := loginFlow(t, s)
+	callback := carol.callback(t, s)
+
+	// 提供方在换取令牌时签发 ID Token，nonce 被篡改时回调失败，授权码随 state 一起作废
+	nonce := "forged"
+	s.OIDC.OverrideNonce = &nonce
+	apitest.ExpectStatus(t, loginFlow(t, s).login(t, s), http.StatusBadRequest)
+	s.OIDC.OverrideNonce = nil
+
+	runCases(t, s, []routeCase{
+		{"login unknown provider", http.MethodGet, "/api/v1/auth/unknown/login", "", nil, http.StatusNotFound},
+		{"callback unknown provider", http.MethodGet, "/api/v1/auth/unknown/callback?code=x&state=y", "", nil, http.StatusNotFound},
+		{"callback provider error", http.MethodGet, callbackPath + "?error=access_denied", "", nil, http.StatusBadRequest},
+		{"callback missing state", http.MethodGet, callbackPath + "?code=x", "", nil, http.StatusBadRequest},
+		{"callback unknown state", http.MethodGet, callbackPath + "?code=x&state=y", "", nil, http.StatusBadRequest},
+		// 回调地址被发给没有发起授权的浏览器（登录 CSRF）
+		{"callback without state cookie", http.MethodGet, callback, "", nil, http.StatusBadRequest},
+	})
+	apitest.ExpectStatus(t, first.finish(s, callback), http.StatusBadRequest)
+	apitest.ExpectStatus(t, first.finish(s, conflict), http.StatusConflict)
+
+	// 被拒绝的回调不消耗 state，发起授权的浏览器仍可完成登录
+	w = carol.finish(s, callback)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	login := apitest.DecodeData[oidcLogin](t, w)
+	if !login.NewUser || login.Token == "" {
+		t.Fatalf("login = %+v, want a new user with a token", login)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == consts.OAuthStateCookie && c.MaxAge >= 0 {
+			t.Fatalf("state cookie = %+v after callback, want it cleared", c)
+		}
+	}
+
+	w = s.Do(http.MethodGet, "/api/v1/users/me/identities", login.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	identities := apitest.DecodeData[[]identity](t, w)
+	if len(identities) != 1 || identities[0].Provider != apitest.OIDCProvider {
+		t.Fatalf("identities = %+v", identities)
+	}
+
+	apitest.ExpectStatus(t, carol.finish(s, callback), http.StatusBadRequest)
+	runCases(t, s, []routeCase{
+		{"identities without token", http.MethodGet, "/api/v1/users/me/identities", "", nil, http.StatusUnauthorized},
+		{"unlink invalid id", http.MethodDelete, "/api/v1/users/me/identities/abc", login.Token, nil, http.StatusBadRequest},
+		{"unlink not found", http.MethodDelete, "/api/v1/users/me/identities/999", login.Token, nil, http.StatusNotFound},
+		// 提供方未确认邮箱，新账号没有已验证邮箱，不能解除唯一的登录方式
+		{"unlink last login method", http.MethodDelete, fmt.Sprintf("/api/v1/users/me/identities/%d", identities[0].ID), login.Token, nil, http.StatusConflict},
+	})
+
+	// 再次登录使用已有账号
+	w = loginFlow(t, s).login(t, s)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if again := apitest.DecodeData[oidcLogin](t, w); again.NewUser {
+		t.Error("second login created another user")
+	}
+}
+
+func TestOIDCLinkAndUnlink(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	s.VerifyEmail(alice)
+	mallory := s.CreateUser("mallory")
+
+	s.OIDC.SetUser(oidctest.User{Subject: "2002", Email: "carol@example.com", EmailVerified: true, Username: "carol"})
+	w := loginFlow(t, s).login(t, s)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+
+	// carol 的身份已属于另一个账号
+	taken := linkFlow(t, s, alice.Token)
+	takenCallback := taken.callback(t, s)
+
+	// mallory 发起绑定后把回调地址发给 alice，alice 的浏览器没有对应的 state Cookie，
+	// alice 的第三方身份不能被绑定到 mallory 的账号
+	s.OIDC.SetUser(oidctest.User{Subject: "1001", Email: "alice@example.com", EmailVerified: true, Username: "alice"})
+	hijack := linkFlow(t, s, mallory.Token).callback(t, s)
+	link := linkFlow(t, s, alice.Token)
+	linkCallback := link.callback(t, s)
+
+	runCases(t, s, []routeCase{
+		{"link without token", http.MethodPost, "/api/v1/users/me/identities/" + apitest.OIDCProvider, "", nil, http.StatusUnauthorized},
+		{"link unknown provider", http.MethodPost, "/api/v1/users/me/identities/unknown", alice.Token, nil, http.StatusNotFound},
+		{"link without state cookie", http.MethodGet, linkCallback, "", nil, http.StatusBadRequest},
+	})
+	apitest.ExpectStatus(t, link.finish(s, hijack), http.StatusBadRequest)
+	apitest.ExpectStatus(t, taken.finish(s, takenCallback), http.StatusConflict)
+	apitest.ExpectStatus(t, link.finish(s, linkCallback), http.StatusOK)
+
+	w = loginFlow(t, s).login(t, s)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	login := apitest.DecodeData[oidcLogin](t, w)
+	me := s.Do(http.MethodGet, "/api/v1/users/me", login.Token, nil)
+	apitest.ExpectStatus(t, me, http.StatusOK)
+	if got := apitest.DecodeData[struct {
+		Username string `json:"username"`
+	}](t, me).Username; got != "alice" {
+		t.Fatalf("linked login signed in as %q, want alice", got)
+	}
+
+	w = s.Do(http.MethodGet, "/api/v1/users/me/identities", mallory.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if identities := apitest.DecodeData[[]identity](t, w); len(identities) != 0 {
+		t.Fatalf("mallory's identities = %+v, want none", identities)
+	}
+
+	w = s.Do(http.MethodGet, "/api/v1/users/me/identities", alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	identities := apitest.DecodeData[[]identity](t, w)
+	if len(identities) != 1 {
+		t.Fatalf("identities = %+v", identities)
+	}
+
+	unlink := fmt.Sprintf("/api/v1/users/me/identities/%d", identities[0].ID)
+	runCases(t, s, []routeCase{
+		{"unlink", http.MethodDelete, unlink, alice.Token, nil, http.StatusOK},
+		{"unlink again", http.MethodDelete, unlink, alice.Token, nil, http.StatusNotFound},
+	})
+}
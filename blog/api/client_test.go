@@ -0,0 +1,198 @@
+package api_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-task/blog/client"
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/consts"
+)
+
+// newClient 通过真实的 HTTP 服务访问测试实例
+func newClient(t *testing.T, handler http.Handler, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	opts = append([]client.Option{client.WithRetry(client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})}, opts...)
+	c, err := client.New(srv.URL, opts...)
+	if err != nil {
+		t.Fatalf("client.New: %v", err)
+	}
+	return c
+}
+
+func TestClientPostsAndComments(t *testing.T) {
+	s := apitest.New(t)
+	ctx := context.Background()
+
+	anon := newClient(t, s.Engine)
+	if _, err := anon.Register(ctx, client.RegisterRequest{Username: "alice", Email: "alice@example.com", Password: apitest.Password}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := anon.Register(ctx, client.RegisterRequest{Username: "alice", Email: "alice@example.com", Password: apitest.Password}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("duplicate Register error = %v, want ErrBadRequest", err)
+	}
+
+	// 配置账号密码后第一次调用需要登录的接口时自动登录
+	alice := newClient(t, s.Engine, client.WithCredentials("alice", apitest.Password))
+	post, err := alice.CreatePost(ctx, client.CreatePostRequest{Title: "Hello", Content: "World"})
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	if alice.Token() == "" {
+		t.Fatal("client did not keep the login token")
+	}
+
+	list, err := anon.ListPosts(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("ListPosts: %v", err)
+	}
+	if list.Total != 1 || len(list.Posts) != 1 || list.Posts[0].ID != post.ID || list.Posts[0].User.Username != "alice" {
+		t.Fatalf("ListPosts = %+v, want the created post", list)
+	}
+	got, err := anon.GetPost(ctx, post.ID)
+	if err != nil || got.Title != "Hello" {
+		t.Fatalf("GetPost = %+v, %v", got, err)
+	}
+
+	comment, err := alice.CreateComment(ctx, post.ID, client.CreateCommentRequest{Content: "first"})
+	if err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	if _, err := alice.UpdateComment(ctx, comment.ID, client.UpdateCommentRequest{Content: "edited"}); err != nil {
+		t.Fatalf("UpdateComment: %v", err)
+	}
+	if err := alice.DeleteComment(ctx, comment.ID, false); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	if err := alice.RestoreComment(ctx, comment.ID, false); err != nil {
+		t.Fatalf("RestoreComment: %v", err)
+	}
+	if got, err := anon.GetComment(ctx, comment.ID); err != nil || got.Content != "edited" {
+		t.Fatalf("GetComment = %+v, %v", got, err)
+	}
+
+	me, err := alice.Me(ctx)
+	if err != nil || me.Username != "alice" || me.Email != "alice@example.com" {
+		t.Fatalf("Me = %+v, %v", me, err)
+	}
+
+	// 各种错误响应都解析为 *Error，并可按状态码分类判断
+	_, err = anon.GetPost(ctx, 9999)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrNotFound) || apiErr.Message != "文章不存在" {
+		t.Fatalf("GetPost missing error = %#v, want ErrNotFound with the server message", err)
+	}
+	bob := newClient(t, s.Engine, client.WithCredentials(s.CreateUser("bob").Username, apitest.Password))
+	if err := bob.DeletePost(ctx, post.ID); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("DeletePost by another user error = %v, want ErrForbidden", err)
+	}
+	if _, err := anon.Me(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("anonymous Me error = %v, want ErrUnauthorized", err)
+	}
+
+	pat, err := alice.CreateAccessToken(ctx, client.CreateAccessTokenRequest{Name: "reader", Scopes: []string{consts.ScopePostsRead}})
+	if err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+	reader := newClient(t, s.Engine, client.WithToken(pat.Token))
+	_, err = reader.CreatePost(ctx, client.CreatePostRequest{Title: "t", Content: "c"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || len(apiErr.RequiredScopes) == 0 {
+		t.Fatalf("CreatePost with read-only token error = %#v, want 403 with required scopes", err)
+	}
+}
+
+func TestClientTokenRefresh(t *testing.T) {
+	s := apitest.New(t)
+	ctx := context.Background()
+	s.CreateUser("alice")
+
+	// 服务端拒绝令牌后用账号密码重新登录并重发请求
+	c := newClient(t, s.Engine, client.WithToken("revoked"), client.WithCredentials("alice", apitest.Password))
+	if _, err := c.Me(ctx); err != nil {
+		t.Fatalf("Me with a rejected token: %v", err)
+	}
+	if c.Token() == "revoked" {
+		t.Fatal("client kept the rejected token")
+	}
+
+	// 已过期的令牌在发送前就重新登录
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"exp":1}`))
+	expired := "e30." + payload + ".sig"
+	c.SetToken(expired)
+	if _, err := c.Me(ctx); err != nil {
+		t.Fatalf("Me with an expired token: %v", err)
+	}
+	if c.Token() == expired {
+		t.Fatal("client kept the expired token")
+	}
+
+	wrong := newClient(t, s.Engine, client.WithCredentials("alice", "wrong-password"))
+	if _, err := wrong.Me(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("Me with wrong credentials error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	s := apitest.New(t)
+	ctx := context.Background()
+	alice := s.CreateUser("alice")
+
+	// 每个请求的前两次尝试返回 503
+	var attempts atomic.Int32
+	flaky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1)%3 != 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"code":503,"msg":"draining"}`))
+			return
+		}
+		s.Engine.ServeHTTP(w, r)
+	})
+	c := newClient(t, flaky, client.WithToken(alice.Token))
+
+	if _, err := c.ListPosts(ctx, 0, 0); err != nil {
+		t.Fatalf("ListPosts: %v", err)
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Fatalf("ListPosts attempts = %d, want 3", n)
+	}
+
+	// 非幂等请求不重试
+	attempts.Store(0)
+	_, err := c.CreatePost(ctx, client.CreatePostRequest{Title: "t", Content: "c"})
+	if !errors.Is(err, client.ErrServer) || attempts.Load() != 1 {
+		t.Fatalf("CreatePost error = %v after %d attempts, want ErrServer after 1", err, attempts.Load())
+	}
+
+	// 取消的 context 不再重试
+	attempts.Store(0)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.ListPosts(canceled, 0, 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ListPosts with canceled context error = %v, want context.Canceled", err)
+	}
+}
+
+// 客户端只能依赖标准库与 dto 包，不能把 gin、gorm 等服务端依赖带给调用方
+func TestClientDependencies(t *testing.T) {
+	out, err := exec.Command("go", "list", "-deps", "-f", "{{if not .Standard}}{{.ImportPath}}{{end}}", "web-task/blog/client").Output()
+	if err != nil {
+		t.Skipf("go list: %v", err)
+	}
+	for _, dep := range strings.Fields(string(out)) {
+		if dep != "web-task/blog/client" && dep != "web-task/blog/dto" {
+			t.Errorf("client depends on %s", dep)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package api_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialLive 以子协议携带令牌加入文章的评论直播间，返回握手响应的状态码
+func dialLive(t *testing.T, srv *httptest.Server, postID any, token string) (*websocket.Conn, int) {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + fmt.Sprintf("/api/v1/posts/%v/live", postID)
+	dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	if token != "" {
+		dialer.Subprotocols = []string{consts.LiveSubprotocol, token}
+	}
+	conn, resp, err := dialer.Dial(url, nil)
+	if err != nil {
+		if resp == nil {
+			t.Fatalf("dial %s: %v", url, err)
+		}
+		return nil, resp.StatusCode
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, resp.StatusCode
+}
+
+// nextCommentEvent 读取下一条评论事件
+func nextCommentEvent(t *testing.T, conn *websocket.Conn) logic.CommentEvent {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var ev logic.CommentEvent
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read event: %v", err)
+	}
+	if err := json.Unmarshal(data, &ev); err != nil {
+		t.Fatalf("decode event %s: %v", data, err)
+	}
+	return ev
+}
+
+func TestLiveComments(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	post := s.CreatePost(alice, "Hello", "World")
+	other := s.CreatePost(alice, "Other", "content")
+	srv := httptest.NewServer(s.Engine)
+	t.Cleanup(srv.Close)
+	live := fmt.Sprintf("/api/v1/posts/%d/live", post.ID)
+
+	runCases(t, s, []routeCase{
+		{"without token", http.MethodGet, live, "", nil, http.StatusUnauthorized},
+		{"without upgrade", http.MethodGet, live, bob.Token, nil, http.StatusUpgradeRequired},
+		{"invalid post id", http.MethodGet, "/api/v1/posts/abc/live", bob.Token, nil, http.StatusBadRequest},
+	})
+	for _, tc := range []struct {
+		name   string
+		postID any
+		token  string
+		status int
+	}{
+		{"dial without token", post.ID, "", http.StatusUnauthorized},
+		{"dial with invalid token", post.ID, "not-a-token", http.StatusUnauthorized},
+		{"dial unknown post", 999, bob.Token, http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, status := dialLive(t, srv, tc.postID, tc.token); status != tc.status {
+				t.Fatalf("status = %d, want %d", status, tc.status)
+			}
+		})
+	}
+
+	conn, status := dialLive(t, srv, post.ID, bob.Token)
+	if status != http.StatusSwitchingProtocols || conn.Subprotocol() != consts.LiveSubprotocol {
+		t.Fatalf("handshake = %d %q, want 101 with the bearer subprotocol", status, conn.Subprotocol())
+	}
+
+	// 只收到本文章下评论的创建、修改与删除
+	s.CreateComment(bob, other.ID, "elsewhere")
+	comment := s.CreateComment(bob, post.ID, "first")
+	path := fmt.Sprintf("/api/v1/comments/%d", comment.ID)
+	apitest.ExpectStatus(t, s.Do(http.MethodPut, path, bob.Token, map[string]string{"content": "edited"}), http.StatusOK)
+	apitest.ExpectStatus(t, s.Do(http.MethodDelete, path, bob.Token, nil), http.StatusNoContent)
+
+	for _, want := range []struct{ typ, content string }{
+		{consts.LiveEventCommentCreated, "first"},
+		{consts.LiveEventCommentUpdated, "edited"},
+		{consts.LiveEventCommentDeleted, "edited"},
+	} {
+		ev := nextCommentEvent(t, conn)
+		if ev.Type != want.typ || ev.Comment == nil || ev.Comment.ID != comment.ID || ev.Comment.PostID != post.ID || ev.Comment.Content != want.content {
+			t.Fatalf("event = %+v, want %s of comment %d with %q", ev, want.typ, comment.ID, want.content)
+		}
+	}
+
+	// 服务退出时连接以 1001 (going away) 关闭
+	s.Hub.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+		t.Fatalf("read after shutdown = %v, want close 1001", err)
+	}
+}
@@ -2,12 +2,13 @@ package api
 
 import (
 	"web-task/blog/internal/controller"
+	"web-task/blog/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupCommentRouter 注册评论相关路由
-func SetupCommentRouter(router *gin.RouterGroup, cc *controller.CommentHandler) {
+func SetupCommentRouter(router *gin.RouterGroup, cc *controller.CommentHandler, limiter *middleware.RateLimiter) {
 	// 直接调用处理器自带的路由注册方法
-	cc.RegisterRoutes(router)
+	cc.RegisterRoutes(router, limiter)
 }
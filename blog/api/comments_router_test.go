@@ -0,0 +1,65 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/consts"
+)
+
+func TestComments(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	post := s.CreatePost(alice, "Hello", "World")
+	comment := s.CreateComment(bob, post.ID, "Nice post")
+	create := fmt.Sprintf("/api/v1/posts/%d/comments", post.ID)
+	path := fmt.Sprintf("/api/v1/comments/%d", comment.ID)
+
+	runCases(t, s, []routeCase{
+		{"create without token", http.MethodPost, create, "", map[string]string{"content": "c"}, http.StatusUnauthorized},
+		{"create invalid post id", http.MethodPost, "/api/v1/posts/abc/comments", bob.Token, map[string]string{"content": "c"}, http.StatusBadRequest},
+		{"create invalid json", http.MethodPost, create, bob.Token, "{", http.StatusBadRequest},
+		{"create empty content", http.MethodPost, create, bob.Token, map[string]string{"content": ""}, http.StatusBadRequest},
+		{"get", http.MethodGet, path, "", nil, http.StatusOK},
+		{"get invalid id", http.MethodGet, "/api/v1/comments/abc", "", nil, http.StatusBadRequest},
+		{"get not found", http.MethodGet, "/api/v1/comments/999", "", nil, http.StatusNotFound},
+		{"update without token", http.MethodPut, path, "", map[string]string{"content": "x"}, http.StatusUnauthorized},
+		{"update invalid id", http.MethodPut, "/api/v1/comments/abc", bob.Token, map[string]string{"content": "x"}, http.StatusBadRequest},
+		{"update invalid json", http.MethodPut, path, bob.Token, "{", http.StatusBadRequest},
+		{"update not found", http.MethodPut, "/api/v1/comments/999", bob.Token, map[string]string{"content": "x"}, http.StatusNotFound},
+		{"update not owner", http.MethodPut, path, alice.Token, map[string]string{"content": "x"}, http.StatusForbidden},
+		{"update", http.MethodPut, path, bob.Token, map[string]string{"content": "Very nice post"}, http.StatusOK},
+		{"delete without token", http.MethodDelete, path, "", nil, http.StatusUnauthorized},
+		{"delete invalid id", http.MethodDelete, "/api/v1/comments/abc", bob.Token, nil, http.StatusBadRequest},
+		{"delete not found", http.MethodDelete, "/api/v1/comments/999", bob.Token, nil, http.StatusNotFound},
+		{"delete not owner", http.MethodDelete, path, alice.Token, nil, http.StatusForbidden},
+		{"delete", http.MethodDelete, path, bob.Token, nil, http.StatusNoContent},
+		{"get deleted", http.MethodGet, path, "", nil, http.StatusNotFound},
+		{"restore without token", http.MethodPost, path + "/restore", "", nil, http.StatusUnauthorized},
+		{"restore invalid id", http.MethodPost, "/api/v1/comments/abc/restore", bob.Token, nil, http.StatusBadRequest},
+		{"restore not found", http.MethodPost, "/api/v1/comments/999/restore", bob.Token, nil, http.StatusNotFound},
+		{"restore not owner", http.MethodPost, path + "/restore", alice.Token, nil, http.StatusForbidden},
+		{"restore", http.MethodPost, path + "/restore", bob.Token, nil, http.StatusOK},
+		{"get restored", http.MethodGet, path, "", nil, http.StatusOK},
+	})
+}
+
+func TestCommentRateLimit(t *testing.T) {
+	s := apitest.New(t, apitest.WithRateLimit(consts.RateLimitCommentCreate, 2, time.Hour))
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	post := s.CreatePost(alice, "Hello", "World")
+	create := fmt.Sprintf("/api/v1/posts/%d/comments", post.ID)
+
+	body := map[string]string{"content": "first!"}
+	runCases(t, s, []routeCase{
+		{"first", http.MethodPost, create, bob.Token, body, http.StatusCreated},
+		{"second", http.MethodPost, create, bob.Token, body, http.StatusCreated},
+		{"limited", http.MethodPost, create, bob.Token, body, http.StatusTooManyRequests},
+		{"other user", http.MethodPost, create, alice.Token, body, http.StatusCreated},
+	})
+}
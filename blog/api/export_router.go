@@ -0,0 +1,21 @@
+package api
+
+import (
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/controller"
+	"web-task/blog/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 定义数据导出路由
+func SetupExportRouter(router *gin.RouterGroup, ec *controller.ExportHandler, limiter *middleware.RateLimiter) {
+	me := router.Group("/users/me/export", middleware.AuthMiddleware())
+	{
+		me.POST("", limiter.Limit(consts.RateLimitExport), ec.StartExport) // 发起数据导出
+		me.GET("/:id", ec.GetExport)                                       // 导出任务状态
+	}
+
+	// 下载链接自带签名与有效期，无需登录
+	router.GET("/exports/:id/download", limiter.Limit(consts.RateLimitReads), middleware.TransferTimeout(), ec.Download)
+}
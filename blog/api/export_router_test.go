@@ -0,0 +1,84 @@
+package api_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/controller"
+)
+
+func TestExport(t *testing.T) {
+	s := apitest.New(t, apitest.WithRateLimit(consts.RateLimitExport, 1, time.Hour))
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	post := s.CreatePost(alice, "Hello", "World")
+	s.CreateComment(alice, post.ID, "First")
+
+	w := s.Do(http.MethodPost, "/api/v1/users/me/export", alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusAccepted)
+	job := apitest.DecodeData[controller.ExportJobResponse](t, w)
+	status := fmt.Sprintf("/api/v1/users/me/export/%d", job.ID)
+	if loc := w.Header().Get("Location"); loc != status {
+		t.Errorf("Location = %q, want %q", loc, status)
+	}
+	if job.Status != consts.ExportStatusPending {
+		t.Errorf("status = %q, want pending", job.Status)
+	}
+
+	runCases(t, s, []routeCase{
+		{"start without token", http.MethodPost, "/api/v1/users/me/export", "", nil, http.StatusUnauthorized},
+		{"start rate limited", http.MethodPost, "/api/v1/users/me/export", alice.Token, nil, http.StatusTooManyRequests},
+		{"status without token", http.MethodGet, status, "", nil, http.StatusUnauthorized},
+		{"status invalid id", http.MethodGet, "/api/v1/users/me/export/abc", alice.Token, nil, http.StatusNotFound},
+		{"status not found", http.MethodGet, "/api/v1/users/me/export/999", alice.Token, nil, http.StatusNotFound},
+		{"status of another user", http.MethodGet, status, bob.Token, nil, http.StatusNotFound},
+	})
+
+	if n, err := s.Exports.ProcessPending(context.Background()); err != nil || n != 1 {
+		t.Fatalf("ProcessPending = %d, %v; want 1 job", n, err)
+	}
+
+	w = s.Do(http.MethodGet, status, alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	job = apitest.DecodeData[controller.ExportJobResponse](t, w)
+	if job.Status != consts.ExportStatusCompleted || job.DownloadURL == "" {
+		t.Fatalf("job = %+v, want completed with a download url", job)
+	}
+	download := strings.TrimPrefix(job.DownloadURL, apitest.AppURL)
+
+	w = s.Do(http.MethodGet, download, "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("download is not a zip archive: %v", err)
+	}
+	if len(zr.File) == 0 {
+		t.Error("export archive is empty")
+	}
+
+	// 签名绑定在任务上，换成其他任务的ID后失效
+	w = s.Do(http.MethodPost, "/api/v1/users/me/export", bob.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusAccepted)
+	bobJob := apitest.DecodeData[controller.ExportJobResponse](t, w)
+	if _, err := s.Exports.ProcessPending(context.Background()); err != nil {
+		t.Fatalf("ProcessPending: %v", err)
+	}
+	swapped := strings.Replace(download, fmt.Sprintf("/exports/%d/", job.ID), fmt.Sprintf("/exports/%d/", bobJob.ID), 1)
+
+	runCases(t, s, []routeCase{
+		{"download invalid id", http.MethodGet, "/api/v1/exports/abc/download", "", nil, http.StatusNotFound},
+		{"download another job with this signature", http.MethodGet, swapped, "", nil, http.StatusForbidden},
+		{"download unknown job", http.MethodGet, strings.Replace(download, fmt.Sprintf("/exports/%d/", job.ID), "/exports/999/", 1), "", nil, http.StatusForbidden},
+		{"download without signature", http.MethodGet, fmt.Sprintf("/api/v1/exports/%d/download", job.ID), "", nil, http.StatusForbidden},
+		{"download tampered signature", http.MethodGet, download + "00", "", nil, http.StatusForbidden},
+		{"download expired link", http.MethodGet, fmt.Sprintf("/api/v1/exports/%d/download?expires=1&signature=x", job.ID), "", nil, http.StatusForbidden},
+	})
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/controller"
+	"web-task/blog/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupGraphQLRouter 注册 GraphQL 路由；可匿名访问，个人访问令牌至少需要 posts:read，其余权限范围由解析函数检查
+func SetupGraphQLRouter(router *gin.RouterGroup, gc *controller.GraphQLHandler, limiter *middleware.RateLimiter) {
+	router.POST("/graphql", limiter.Limit(consts.RateLimitReads), middleware.OptionalAuthMiddleware(consts.ScopePostsRead), gc.Query)
+}
@@ -0,0 +1,374 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// graphqlResponse 测试用的 GraphQL 响应
+type graphqlResponse[T any] struct {
+	Data   T `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+		Path    []any  `json:"path"`
+	} `json:"errors"`
+}
+
+func graphql(s *apitest.Server, token, query string, variables map[string]any) *httptest.ResponseRecorder {
+	return s.Do(http.MethodPost, "/api/v1/graphql", token, map[string]any{"query": query, "variables": variables})
+}
+
+const feedQuery = `{
+	posts(pageSize: 10) {
+		total
+		items {
+			title
+			author { username }
+			comments { content author { username } post { title } }
+		}
+	}
+}`
+
+type feed struct {
+	Posts struct {
+		Total int `json:"total"`
+		Items []struct {
+			Title  string `json:"title"`
+			Author struct {
+				Username string `json:"username"`
+			} `json:"author"`
+			Comments []struct {
+				Content string `json:"content"`
+				Author  struct {
+					Username string `json:"username"`
+				} `json:"author"`
+				Post struct {
+					Title string `json:"title"`
+				} `json:"post"`
+			} `json:"comments"`
+		} `json:"items"`
+	} `json:"posts"`
+}
+
+func TestGraphQLBatching(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+
+	var queries atomic.Int32
+	if err := s.DB.Callback().Query().After("gorm:query").Register("test:count", func(*gorm.DB) {
+		queries.Add(1)
+	}); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	// 查询次数不随文章与评论数量增长
+	counts := make(map[int]int32)
+	for _, n := range []int{2, 6} {
+		for i := len(counts) * 2; i < n; i++ {
+			post := s.CreatePost(alice, fmt.Sprintf("post %d", i), "content")
+			s.CreateComment(bob, post.ID, "by bob")
+			s.CreateComment(alice, post.ID, "by alice")
+		}
+
+		queries.Store(0)
+		w := graphql(s, "", feedQuery, nil)
+		apitest.ExpectStatus(t, w, http.StatusOK)
+		counts[n] = queries.Load()
+
+		res := apitest.DecodeJSON[graphqlResponse[feed]](t, w)
+		if len(res.Errors) > 0 {
+			t.Fatalf("errors = %+v", res.Errors)
+		}
+		if res.Data.Posts.Total != n || len(res.Data.Posts.Items) != n {
+			t.Fatalf("posts = %+v, want %d posts", res.Data.Posts, n)
+		}
+		for _, p := range res.Data.Posts.Items {
+			if p.Author.Username != "alice" || len(p.Comments) != 2 {
+				t.Fatalf("post = %+v, want alice's post with 2 comments", p)
+			}
+			c := p.Comments[0]
+			if c.Content != "by bob" || c.Author.Username != "bob" || c.Post.Title != p.Title {
+				t.Fatalf("comment = %+v, want bob's comment on %q", c, p.Title)
+			}
+		}
+	}
+	if counts[2] != counts[6] {
+		t.Fatalf("queries = %v, want the same count for 2 and 6 posts", counts)
+	}
+}
+
+func TestGraphQLCommentsBoundedPerPost(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	busy := s.CreatePost(alice, "busy", "content")
+	quiet := s.CreatePost(alice, "quiet", "content")
+
+	// 直接写库绕过评论限流：一篇文章的评论数超过 comments 的上限，另一篇只有一条已删除的评论
+	comments := make([]model.Comment, 0, consts.GraphQLMaxListSize+20)
+	for i := range cap(comments) {
+		comments = append(comments, model.Comment{Content: fmt.Sprintf("comment %d", i), UserID: alice.ID, PostID: busy.ID})
+	}
+	deleted := model.Comment{Content: "deleted", UserID: alice.ID, PostID: quiet.ID}
+	if err := s.DB.Create(&comments).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Create(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Delete(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 记录评论查询读到的行数：每篇文章最多读取上限条，不随评论总数增长
+	var maxRows atomic.Int64
+	if err := s.DB.Callback().Query().After("gorm:query").Register("test:rows", func(db *gorm.DB) {
+		if _, ok := db.Statement.Dest.(*[]model.Comment); ok && db.Statement.RowsAffected > maxRows.Load() {
+			maxRows.Store(db.Statement.RowsAffected)
+		}
+	}); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	w := graphql(s, "", `{ posts(pageSize: 10) { items { title comments(first: 3) { content } } } }`, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	resp := apitest.DecodeJSON[graphqlResponse[struct {
+		Posts struct {
+			Items []struct {
+				Title    string `json:"title"`
+				Comments []struct {
+					Content string `json:"content"`
+				} `json:"comments"`
+			} `json:"items"`
+		} `json:"posts"`
+	}]](t, w)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("errors = %+v", resp.Errors)
+	}
+	got := make(map[string][]string)
+	for _, item := range resp.Data.Posts.Items {
+		for _, c := range item.Comments {
+			got[item.Title] = append(got[item.Title], c.Content)
+		}
+	}
+	if want := []string{"comment 0", "comment 1", "comment 2"}; !slices.Equal(got["busy"], want) {
+		t.Errorf("busy comments = %v, want %v", got["busy"], want)
+	}
+	if len(got["quiet"]) != 0 {
+		t.Errorf("quiet comments = %v, want the deleted comment hidden", got["quiet"])
+	}
+	if n := maxRows.Load(); n == 0 || n > consts.GraphQLMaxListSize {
+		t.Errorf("comment query read %d rows, want at most %d", n, consts.GraphQLMaxListSize)
+	}
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+
+	const create = `mutation($title: String!) { createPost(title: $title, content: "body") { id title author { username } } }`
+	type created struct {
+		CreatePost *struct {
+			ID     string `json:"id"`
+			Title  string `json:"title"`
+			Author struct {
+				Username string `json:"username"`
+			} `json:"author"`
+		} `json:"createPost"`
+	}
+
+	// 匿名变更在字段上报错，请求本身已执行
+	w := graphql(s, "", create, map[string]any{"title": "anon"})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if res := apitest.DecodeJSON[graphqlResponse[created]](t, w); len(res.Errors) != 1 || res.Errors[0].Message != "authentication required" {
+		t.Fatalf("anonymous createPost = %+v, want authentication required", res)
+	}
+
+	w = graphql(s, alice.Token, create, map[string]any{"title": "Hello"})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	res := apitest.DecodeJSON[graphqlResponse[created]](t, w)
+	if len(res.Errors) > 0 || res.Data.CreatePost == nil || res.Data.CreatePost.Author.Username != "alice" {
+		t.Fatalf("createPost = %+v", res)
+	}
+	id := res.Data.CreatePost.ID
+
+	w = graphql(s, bob.Token, `mutation($id: ID!) { createComment(postId: $id, content: "nice") { content post { title } } }`, map[string]any{"id": id})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); !strings.Contains(body, `"content":"nice"`) || !strings.Contains(body, `"title":"Hello"`) {
+		t.Fatalf("createComment = %s", body)
+	}
+
+	// 只能修改自己的文章
+	w = graphql(s, bob.Token, `mutation($id: ID!) { deletePost(id: $id) }`, map[string]any{"id": id})
+	if body := w.Body.String(); !strings.Contains(body, "permission denied") {
+		t.Fatalf("deletePost by bob = %s, want permission denied", body)
+	}
+	w = graphql(s, alice.Token, `mutation($id: ID!) { updatePost(id: $id, title: "Edited") { title content } }`, map[string]any{"id": id})
+	if body := w.Body.String(); !strings.Contains(body, `"title":"Edited"`) || !strings.Contains(body, `"content":"body"`) {
+		t.Fatalf("updatePost = %s", body)
+	}
+	w = graphql(s, alice.Token, `mutation($id: ID!) { deletePost(id: $id) }`, map[string]any{"id": id})
+	if body := w.Body.String(); !strings.Contains(body, `"deletePost":true`) {
+		t.Fatalf("deletePost = %s", body)
+	}
+	w = graphql(s, "", `query($id: ID!) { post(id: $id) { title } }`, map[string]any{"id": id})
+	if body := w.Body.String(); body != `{"data":{"post":null}}` {
+		t.Fatalf("deleted post = %s, want null", body)
+	}
+
+	w = graphql(s, alice.Token, `{ me { username } user(username: "bob") { username } }`, nil)
+	if body := w.Body.String(); body != `{"data":{"me":{"username":"alice"},"user":{"username":"bob"}}}` {
+		t.Fatalf("me and user = %s", body)
+	}
+}
+
+func TestGraphQLScopes(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	post := s.CreatePost(alice, "Hello", "World")
+	s.CreateComment(alice, post.ID, "first")
+
+	// 没有 posts:read 的令牌不能访问
+	w := graphql(s, s.AccessToken(alice, consts.ScopeCommentsRead), `{ posts { total } }`, nil)
+	apitest.ExpectStatus(t, w, http.StatusForbidden)
+
+	// 读取评论与发表文章分别需要对应的权限范围
+	reader := s.AccessToken(alice, consts.ScopePostsRead)
+	w = graphql(s, reader, `{ posts { items { title comments { content } } } }`, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if body := w.Body.String(); !strings.Contains(body, "insufficient scope: comments:read required") {
+		t.Fatalf("comments with posts:read = %s, want insufficient scope", body)
+	}
+	w = graphql(s, reader, `mutation { createPost(title: "t", content: "c") { id } }`, nil)
+	if body := w.Body.String(); !strings.Contains(body, "insufficient scope: posts:write required") {
+		t.Fatalf("createPost with posts:read = %s, want insufficient scope", body)
+	}
+
+	w = graphql(s, s.AccessToken(alice, consts.ScopePostsRead, consts.ScopeCommentsRead), `{ posts { items { comments { content } } } }`, nil)
+	if body := w.Body.String(); body != `{"data":{"posts":{"items":[{"comments":[{"content":"first"}]}]}}}` {
+		t.Fatalf("comments with comments:read = %s", body)
+	}
+}
+
+func TestGraphQLLimits(t *testing.T) {
+	s := apitest.New(t)
+
+	cases := []struct {
+		name   string
+		query  string
+		vars   map[string]any
+		status int
+		error  string
+	}{
+		{
+			name:   "syntax error",
+			query:  `{ posts {`,
+			status: http.StatusBadRequest,
+			error:  "Syntax Error",
+		},
+		{
+			name:   "unknown field",
+			query:  `{ posts { secret } }`,
+			status: http.StatusBadRequest,
+			error:  `Cannot query field "secret"`,
+		},
+		{
+			name:   "too deep",
+			query:  `query($id: ID!) { post(id: $id) { comments { post { comments { post { comments { post { comments { post { title } } } } } } } } } }`,
+			vars:   map[string]any{"id": "1"},
+			status: http.StatusBadRequest,
+			error:  "query is too deep: depth 10 exceeds 8",
+		},
+		{
+			name:   "too complex",
+			query:  `{ posts(pageSize: 50) { items { title comments(first: 50) { content } } } }`,
+			status: http.StatusBadRequest,
+			error:  "query is too complex",
+		},
+		{
+			name:   "too complex via variables and fragments",
+			query:  `query($n: Int) { posts(pageSize: $n) { ...items } } fragment items on PostPage { items { comments(first: $n) { content author { username } } } }`,
+			vars:   map[string]any{"n": 40},
+			status: http.StatusBadRequest,
+			error:  "query is too complex",
+		},
+		{
+			name:   "too many mutations via aliases",
+			query:  `mutation { a: deletePost(id: "1") b: deletePost(id: "2") c: deletePost(id: "3") ...more } fragment more on Mutation { d: deletePost(id: "4") e: deletePost(id: "5") f: deletePost(id: "6") }`,
+			status: http.StatusBadRequest,
+			error:  "too many mutation fields: 6 exceeds 5",
+		},
+		{
+			name:   "within limits",
+			query:  `{ posts(pageSize: 5) { items { title comments(first: 5) { content } } } }`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "page size out of range",
+			query:  `{ posts(pageSize: 51) { total } }`,
+			status: http.StatusOK,
+			error:  "invalid input: pageSize must be between 1 and 50",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := graphql(s, "", tc.query, tc.vars)
+			apitest.ExpectStatus(t, w, tc.status)
+			res := apitest.DecodeJSON[graphqlResponse[any]](t, w)
+			if tc.error == "" {
+				if len(res.Errors) > 0 {
+					t.Fatalf("errors = %+v", res.Errors)
+				}
+				return
+			}
+			if len(res.Errors) == 0 || !strings.Contains(res.Errors[0].Message, tc.error) {
+				t.Fatalf("errors = %+v, want %q", res.Errors, tc.error)
+			}
+		})
+	}
+}
+
+// createComment 与 HTTP 发表评论共用限流额度，别名中的每个变更各自计数
+func TestGraphQLCommentRateLimit(t *testing.T) {
+	s := apitest.New(t, apitest.WithRateLimit(consts.RateLimitCommentCreate, 3, time.Hour))
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	post := s.CreatePost(alice, "Hello", "World")
+
+	w := s.Do(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", post.ID), bob.Token, map[string]string{"content": "http"})
+	apitest.ExpectStatus(t, w, http.StatusCreated)
+
+	const batch = `mutation($id: ID!) {
+		a: createComment(postId: $id, content: "a") { content }
+		b: createComment(postId: $id, content: "b") { content }
+		c: createComment(postId: $id, content: "c") { content }
+	}`
+	w = graphql(s, bob.Token, batch, map[string]any{"id": fmt.Sprint(post.ID)})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	res := apitest.DecodeJSON[graphqlResponse[any]](t, w)
+	if len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, "rate limit exceeded") || fmt.Sprint(res.Errors[0].Path) != "[c]" {
+		t.Fatalf("batched createComment errors = %+v, want c rate limited", res.Errors)
+	}
+
+	w = s.Do(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", post.ID), bob.Token, map[string]string{"content": "http"})
+	apitest.ExpectStatus(t, w, http.StatusTooManyRequests)
+
+	// 额度按用户计算
+	w = graphql(s, alice.Token, `mutation($id: ID!) { createComment(postId: $id, content: "mine") { content } }`, map[string]any{"id": fmt.Sprint(post.ID)})
+	if body := w.Body.String(); !strings.Contains(body, `"content":"mine"`) {
+		t.Fatalf("createComment by alice = %s", body)
+	}
+}
@@ -0,0 +1,347 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	blogv1 "web-task/blog/gen/blog/v1"
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/consts"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// expectCode 断言 gRPC 调用返回的状态码
+func expectCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != code {
+		t.Fatalf("status = %v (%v), want %v", got, err, code)
+	}
+}
+
+func TestGRPCPostsAndComments(t *testing.T) {
+	s := apitest.New(t)
+	conn := s.GRPC()
+	users := blogv1.NewUserServiceClient(conn)
+	posts := blogv1.NewPostServiceClient(conn)
+	comments := blogv1.NewCommentServiceClient(conn)
+	ctx := context.Background()
+
+	// 注册校验规则与 REST 相同
+	_, err := users.Register(ctx, &blogv1.RegisterRequest{Username: "al", Email: "al@example.com", Password: apitest.Password})
+	expectCode(t, err, codes.InvalidArgument)
+	if _, err := users.Register(ctx, &blogv1.RegisterRequest{Username: "alice", Email: "alice@example.com", Password: apitest.Password}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	_, err = users.Register(ctx, &blogv1.RegisterRequest{Username: "alice", Email: "other@example.com", Password: apitest.Password})
+	expectCode(t, err, codes.AlreadyExists)
+
+	_, err = users.Login(ctx, &blogv1.LoginRequest{Username: "alice", Password: "wrong-password"})
+	expectCode(t, err, codes.Unauthenticated)
+	login, err := users.Login(ctx, &blogv1.LoginRequest{Username: "alice", Password: apitest.Password})
+	if err != nil || login.Token == "" {
+		t.Fatalf("Login = %v, %v", login, err)
+	}
+	alice := apitest.WithToken(ctx, login.Token)
+
+	me, err := users.GetMe(alice, &blogv1.GetMeRequest{})
+	if err != nil || me.User.GetUsername() != "alice" || me.Email != "alice@example.com" {
+		t.Fatalf("GetMe = %v, %v", me, err)
+	}
+	_, err = users.GetMe(ctx, &blogv1.GetMeRequest{})
+	expectCode(t, err, codes.Unauthenticated)
+
+	// 写接口需要登录，个人访问令牌需要对应的权限范围
+	_, err = posts.CreatePost(ctx, &blogv1.CreatePostRequest{Title: "t", Content: "c"})
+	expectCode(t, err, codes.Unauthenticated)
+	reader := apitest.WithToken(ctx, s.AccessToken(&apitest.User{Username: "alice", Token: login.Token}, consts.ScopePostsRead))
+	_, err = posts.CreatePost(reader, &blogv1.CreatePostRequest{Title: "t", Content: "c"})
+	expectCode(t, err, codes.PermissionDenied)
+	_, err = posts.CreatePost(alice, &blogv1.CreatePostRequest{Title: "", Content: "c"})
+	expectCode(t, err, codes.InvalidArgument)
+
+	created, err := posts.CreatePost(alice, &blogv1.CreatePostRequest{Title: "Hello", Content: "World"})
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	postID := created.Post.Id
+
+	got, err := posts.GetPost(reader, &blogv1.GetPostRequest{Id: postID})
+	if err != nil || got.Post.Title != "Hello" || got.Post.User.GetUsername() != "alice" {
+		t.Fatalf("GetPost = %v, %v", got, err)
+	}
+	_, err = posts.GetPost(ctx, &blogv1.GetPostRequest{Id: 9999})
+	expectCode(t, err, codes.NotFound)
+	list, err := posts.ListPosts(ctx, &blogv1.ListPostsRequest{})
+	if err != nil || list.Total != 1 || list.Page != 1 || list.PageSize != 10 || len(list.Posts) != 1 {
+		t.Fatalf("ListPosts = %v, %v", list, err)
+	}
+
+	// 经 gRPC 写入的数据在 REST 中可见
+	w := s.Do(http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", postID), "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+
+	bob := apitest.WithToken(ctx, s.CreateUser("bob").Token)
+	comment, err := comments.CreateComment(bob, &blogv1.CreateCommentRequest{PostId: postID, Content: "nice"})
+	if err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	commentID := comment.Comment.Id
+	_, err = comments.UpdateComment(alice, &blogv1.UpdateCommentRequest{Id: commentID, Content: "edited"})
+	expectCode(t, err, codes.PermissionDenied)
+	if _, err := comments.UpdateComment(bob, &blogv1.UpdateCommentRequest{Id: commentID, Content: "edited"}); err != nil {
+		t.Fatalf("UpdateComment: %v", err)
+	}
+	if _, err := comments.DeleteComment(bob, &blogv1.DeleteCommentRequest{Id: commentID}); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	_, err = comments.GetComment(ctx, &blogv1.GetCommentRequest{Id: commentID})
+	expectCode(t, err, codes.NotFound)
+	if _, err := comments.RestoreComment(bob, &blogv1.RestoreCommentRequest{Id: commentID}); err != nil {
+		t.Fatalf("RestoreComment: %v", err)
+	}
+	if got, err := comments.GetComment(ctx, &blogv1.GetCommentRequest{Id: commentID}); err != nil || got.Comment.Content != "edited" {
+		t.Fatalf("GetComment = %v, %v", got, err)
+	}
+
+	_, err = posts.DeletePost(bob, &blogv1.DeletePostRequest{Id: postID})
+	expectCode(t, err, codes.PermissionDenied)
+	if _, err := posts.DeletePost(alice, &blogv1.DeletePostRequest{Id: postID}); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+	w = s.Do(http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", postID), "", nil)
+	apitest.ExpectStatus(t, w, http.StatusNotFound)
+}
+
+func TestGRPCReflection(t *testing.T) {
+	s := apitest.New(t)
+
+	stream, err := reflectionpb.NewServerReflectionClient(s.GRPC()).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatalf("ServerReflectionInfo: %v", err)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+
+	services := make(map[string]bool)
+	for _, svc := range resp.GetListServicesResponse().GetService() {
+		services[svc.Name] = true
+	}
+	for _, name := range []string{"blog.v1.UserService", "blog.v1.PostService", "blog.v1.CommentService"} {
+		if !services[name] {
+			t.Errorf("reflection does not list %s: %v", name, services)
+		}
+	}
+}
+
+// TestGatewayMatchesREST 网关的每条 HTTP 映射都对应一条 gin 注册的 REST 路由
+func TestGatewayMatchesREST(t *testing.T) {
+	s := apitest.New(t)
+
+	raw, err := os.ReadFile("../proto/blog/v1/gateway.yaml")
+	if err != nil {
+		t.Fatalf("read gateway config: %v", err)
+	}
+	var config struct {
+		HTTP struct {
+			Rules []map[string]string `yaml:"rules"`
+		} `yaml:"http"`
+	}
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		t.Fatalf("parse gateway config: %v", err)
+	}
+
+	routes := make(map[string]bool)
+	for _, r := range s.Engine.Routes() {
+		routes[r.Method+" "+r.Path] = true
+	}
+	param := regexp.MustCompile(`\{[^}]+\}`)
+	restParam := regexp.MustCompile(`:[^/]+`)
+	normalized := make(map[string]bool)
+	for route := range routes {
+		normalized[restParam.ReplaceAllString(route, "*")] = true
+	}
+
+	if len(config.HTTP.Rules) == 0 {
+		t.Fatal("gateway config has no rules")
+	}
+	for _, rule := range config.HTTP.Rules {
+		for _, method := range []string{"get", "post", "put", "patch", "delete"} {
+			path, ok := rule[method]
+			if !ok {
+				continue
+			}
+			route := strings.ToUpper(method) + " " + param.ReplaceAllString(path, "*")
+			if !normalized[route] {
+				t.Errorf("%s maps to %s %s, which has no REST route", rule["selector"], strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	// 同一篇文章经网关与 REST 返回相同的字段，作者都只包含公开资料
+	alice := s.CreateUser("alice")
+	post := s.CreatePost(alice, "Hello", "World")
+	path := fmt.Sprintf("/api/v1/posts/%d", post.ID)
+	rest := apitest.DecodeJSON[map[string]any](t, s.Do(http.MethodGet, path, "", nil))
+	w := s.Gateway(http.MethodGet, path, "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if gw := apitest.DecodeJSON[map[string]any](t, w); !reflect.DeepEqual(gw, rest) {
+		t.Fatalf("gateway post = %v, REST post = %v", gw, rest)
+	}
+
+	// 网关同样校验令牌
+	apitest.ExpectStatus(t, s.Gateway(http.MethodGet, "/api/v1/users/me", "", nil), http.StatusUnauthorized)
+	w = s.Gateway(http.MethodGet, "/api/v1/users/me", alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if me := apitest.DecodeJSON[struct {
+		User struct {
+			Username string `json:"username"`
+		} `json:"user"`
+	}](t, w); me.User.Username != "alice" {
+		t.Fatalf("gateway me = %+v", me)
+	}
+	w = s.Gateway(http.MethodPost, "/api/v1/posts", "", map[string]string{"title": "t", "content": "c"})
+	apitest.ExpectStatus(t, w, http.StatusUnauthorized)
+	w = s.Gateway(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", post.ID), alice.Token, map[string]string{"content": "nice"})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+}
+
+// 登录、注册与发表评论使用与 REST 相同的限流策略
+func TestGRPCRateLimits(t *testing.T) {
+	s := apitest.New(t,
+		apitest.WithRateLimit(consts.RateLimitLogin, 2, time.Hour),
+		apitest.WithRateLimit(consts.RateLimitEmail, 1, time.Hour),
+		apitest.WithRateLimit(consts.RateLimitCommentCreate, 1, time.Hour),
+	)
+	conn := s.GRPC()
+	users := blogv1.NewUserServiceClient(conn)
+	comments := blogv1.NewCommentServiceClient(conn)
+	ctx := context.Background()
+
+	if _, err := users.Register(ctx, &blogv1.RegisterRequest{Username: "alice", Email: "alice@example.com", Password: apitest.Password}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	_, err := users.Register(ctx, &blogv1.RegisterRequest{Username: "bob", Email: "bob@example.com", Password: apitest.Password})
+	expectCode(t, err, codes.ResourceExhausted)
+
+	login := &blogv1.LoginRequest{Username: "alice", Password: apitest.Password}
+	if _, err := users.Login(ctx, login); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, err = users.Login(ctx, &blogv1.LoginRequest{Username: "alice", Password: "wrong-password"})
+	expectCode(t, err, codes.Unauthenticated)
+	var header metadata.MD
+	_, err = users.Login(ctx, login, grpc.Header(&header))
+	expectCode(t, err, codes.ResourceExhausted)
+	if len(header.Get("retry-after")) == 0 {
+		t.Errorf("rate limited Login header = %v, want retry-after", header)
+	}
+	// 两步验证登录与登录共用额度
+	_, err = users.LoginMFA(ctx, &blogv1.LoginMFARequest{MfaToken: "token", Code: "123456"})
+	expectCode(t, err, codes.ResourceExhausted)
+
+	// 发表评论按用户计数
+	carol := s.CreateUser("carol")
+	post := s.CreatePost(carol, "Hello", "World")
+	authed := apitest.WithToken(ctx, carol.Token)
+	if _, err := comments.CreateComment(authed, &blogv1.CreateCommentRequest{PostId: uint32(post.ID), Content: "first"}); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	_, err = comments.CreateComment(authed, &blogv1.CreateCommentRequest{PostId: uint32(post.ID), Content: "second"})
+	expectCode(t, err, codes.ResourceExhausted)
+	w := s.Do(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", post.ID), carol.Token, map[string]string{"content": "third"})
+	apitest.ExpectStatus(t, w, http.StatusTooManyRequests)
+
+	// 经网关的调用按网关看到的客户端 IP 计数，与 REST 共用令牌桶：CreateUser 经 REST 登录已用掉一次；
+	// ResourceExhausted 映射为 429
+	body := map[string]string{"username": "alice", "password": apitest.Password}
+	apitest.ExpectStatus(t, s.Gateway(http.MethodPost, "/api/v1/users/login", "", body), http.StatusOK)
+	apitest.ExpectStatus(t, s.Gateway(http.MethodPost, "/api/v1/users/login", "", body), http.StatusTooManyRequests)
+}
+
+// 调用受 HTTP 请求相同的截止时间约束，到期后正在执行的查询被中断
+func TestGRPCDeadline(t *testing.T) {
+	s := apitest.New(t, apitest.WithTimeouts(time.Second, time.Second))
+	alice := s.CreateUser("alice")
+	s.CreatePost(alice, "Hello", "World")
+
+	err := s.DB.Callback().Query().Before("gorm:query").Register("test:wait_deadline", func(db *gorm.DB) {
+		if db.Statement.Table != "posts" {
+			return
+		}
+		select {
+		case <-db.Statement.Context.Done():
+			db.AddError(db.Statement.Context.Err())
+		case <-time.After(10 * time.Second):
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = blogv1.NewPostServiceClient(s.GRPC()).ListPosts(context.Background(), &blogv1.ListPostsRequest{})
+	expectCode(t, err, codes.DeadlineExceeded)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ListPosts returned after %s, want the 1s deadline", elapsed)
+	}
+}
+
+// 调用创建服务端 span，经网关的调用沿用请求头中的上游链路
+func TestGRPCTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+		provider.Shutdown(context.Background())
+	})
+
+	s := apitest.New(t)
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := s.NewRequest(http.MethodGet, "/api/v1/posts", "", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	s.RPC.Handler().ServeHTTP(w, req)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+
+	for _, span := range recorder.Ended() {
+		if span.Name() != "blog.v1.PostService/ListPosts" {
+			continue
+		}
+		if span.SpanKind() != trace.SpanKindServer || span.SpanContext().TraceID().String() != traceID {
+			t.Fatalf("ListPosts span kind %v trace %s, want server span in trace %s", span.SpanKind(), span.SpanContext().TraceID(), traceID)
+		}
+		return
+	}
+	t.Fatal("no span recorded for ListPosts")
+}
@@ -0,0 +1,29 @@
+package api_test
+
+import (
+	"testing"
+
+	"web-task/blog/internal/apitest"
+)
+
+// routeCase 一次请求及期望的状态码
+type routeCase struct {
+	name   string
+	method string
+	path   string
+	token  string
+	body   any
+	status int
+}
+
+// runCases 依次发送请求；用例之间共享同一个实例，顺序即执行顺序
+func runCases(t *testing.T, s *apitest.Server, cases []routeCase) {
+	t.Helper()
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := s.Do(tc.method, tc.path, tc.token, tc.body)
+			apitest.ExpectStatus(t, w, tc.status)
+		})
+	}
+}
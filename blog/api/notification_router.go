@@ -0,0 +1,18 @@
+package api
+
+import (
+	"web-task/blog/internal/controller"
+	"web-task/blog/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 定义站内通知路由
+func SetupNotificationRouter(router *gin.RouterGroup, nc *controller.NotificationHandler) {
+	notifications := router.Group("/notifications", middleware.AuthMiddleware())
+	{
+		notifications.GET("", nc.ListNotifications)                                  // 通知列表
+		notifications.POST("/read", nc.MarkNotificationsRead)                        // 标记已读
+		notifications.GET("/stream", middleware.NoTimeout(), nc.StreamNotifications) // 实时推送
+	}
+}
@@ -0,0 +1,226 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"web-task/blog/dto"
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/controller"
+	"web-task/blog/internal/model"
+)
+
+// sseEvent 事件流中的一个事件
+type sseEvent struct {
+	id, event, data string
+}
+
+// notificationStream 打开通知推送连接，测试结束时断开
+func notificationStream(t *testing.T, srv *httptest.Server, token, lastEventID string) *bufio.Reader {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/notifications/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("stream = %d %s, want 200 text/event-stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// nextEvent 读取下一个带数据的事件，跳过注释与只设置 retry 的块
+func nextEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if ev.data != "" {
+				return ev
+			}
+		case strings.HasPrefix(line, "id:"):
+			ev.id = strings.TrimPrefix(line, "id:")
+		case strings.HasPrefix(line, "event:"):
+			ev.event = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			ev.data += strings.TrimPrefix(line, "data:")
+		}
+	}
+}
+
+func decodeNotification(t *testing.T, ev sseEvent) model.Notification {
+	t.Helper()
+	var n model.Notification
+	if err := json.Unmarshal([]byte(ev.data), &n); err != nil {
+		t.Fatalf("decode notification %q: %v", ev.data, err)
+	}
+	return n
+}
+
+func TestNotifications(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+
+	post := s.CreatePost(alice, "Hello", "World")
+	// 评论自己的文章不产生通知
+	s.CreateComment(alice, post.ID, "first")
+	first := s.CreateComment(bob, post.ID, "nice post")
+	s.CreateComment(bob, post.ID, "really")
+
+	runCases(t, s, []routeCase{
+		{"list without token", http.MethodGet, "/api/v1/notifications", "", nil, http.StatusUnauthorized},
+		{"list with invalid unread", http.MethodGet, "/api/v1/notifications?unread=maybe", alice.Token, nil, http.StatusBadRequest},
+		{"mark read without ids", http.MethodPost, "/api/v1/notifications/read", alice.Token, map[string]any{}, http.StatusBadRequest},
+		{"mark read with ids and all", http.MethodPost, "/api/v1/notifications/read", alice.Token, map[string]any{"ids": []uint{1}, "all": true}, http.StatusBadRequest},
+		{"stream without token", http.MethodGet, "/api/v1/notifications/stream", "", nil, http.StatusUnauthorized},
+	})
+
+	w := s.Do(http.MethodGet, "/api/v1/notifications", alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	list := apitest.DecodeData[controller.NotificationListResponse](t, w)
+	if list.Total != 2 || list.Unread != 2 || len(list.Notifications) != 2 {
+		t.Fatalf("notifications = %+v, want 2 unread", list)
+	}
+	got := list.Notifications[1]
+	if got.Type != consts.NotificationTypeComment || got.ActorID != bob.ID || got.PostID != post.ID || got.CommentID != first.ID || got.Excerpt != "nice post" || got.ReadAt != nil {
+		t.Fatalf("oldest notification = %+v, want an unread comment notification for %d", got, first.ID)
+	}
+
+	w = s.Do(http.MethodGet, "/api/v1/notifications", bob.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if list := apitest.DecodeData[controller.NotificationListResponse](t, w); list.Total != 0 {
+		t.Fatalf("bob's notifications = %+v, want none", list)
+	}
+
+	// 不能标记别人的通知
+	w = s.Do(http.MethodPost, "/api/v1/notifications/read", bob.Token, map[string]any{"ids": []uint{got.ID}})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if res := apitest.DecodeData[controller.MarkNotificationsReadResponse](t, w); res.Updated != 0 {
+		t.Fatalf("mark read by bob = %+v, want nothing updated", res)
+	}
+
+	w = s.Do(http.MethodPost, "/api/v1/notifications/read", alice.Token, map[string]any{"ids": []uint{got.ID}})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if res := apitest.DecodeData[controller.MarkNotificationsReadResponse](t, w); res.Updated != 1 || res.Unread != 1 {
+		t.Fatalf("mark read = %+v, want 1 updated and 1 unread", res)
+	}
+
+	w = s.Do(http.MethodGet, "/api/v1/notifications?unread=true", alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	list = apitest.DecodeData[controller.NotificationListResponse](t, w)
+	if list.Total != 1 || list.Unread != 1 || len(list.Notifications) != 1 || list.Notifications[0].ID == got.ID {
+		t.Fatalf("unread notifications = %+v, want only the newer one", list)
+	}
+
+	w = s.Do(http.MethodPost, "/api/v1/notifications/read", alice.Token, map[string]any{"all": true})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if res := apitest.DecodeData[controller.MarkNotificationsReadResponse](t, w); res.Updated != 1 || res.Unread != 0 {
+		t.Fatalf("mark all read = %+v, want 1 updated and none unread", res)
+	}
+}
+
+func TestReplyNotifications(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	post := s.CreatePost(alice, "Hello", "World")
+	top := s.CreateComment(bob, post.ID, "nice post")
+	path := fmt.Sprintf("/api/v1/posts/%d/comments", post.ID)
+
+	runCases(t, s, []routeCase{
+		{"reply to unknown comment", http.MethodPost, path, alice.Token, map[string]any{"content": "hi", "parent_id": 999}, http.StatusBadRequest},
+	})
+
+	w := s.Do(http.MethodPost, path, alice.Token, map[string]any{"content": "thanks", "parent_id": top.ID})
+	apitest.ExpectStatus(t, w, http.StatusCreated)
+	reply := apitest.DecodeJSON[dto.Comment](t, w)
+	if reply.ParentID == nil || *reply.ParentID != top.ID {
+		t.Fatalf("reply parent_id = %v, want %d", reply.ParentID, top.ID)
+	}
+	runCases(t, s, []routeCase{
+		{"reply to a reply", http.MethodPost, path, bob.Token, map[string]any{"content": "hi", "parent_id": reply.ID}, http.StatusBadRequest},
+	})
+
+	// 回复通知父评论作者；文章作者自己回复时不再收到评论通知
+	w = s.Do(http.MethodGet, "/api/v1/notifications", bob.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	list := apitest.DecodeData[controller.NotificationListResponse](t, w)
+	if list.Total != 1 || list.Notifications[0].Type != consts.NotificationTypeReply || list.Notifications[0].ActorID != alice.ID || list.Notifications[0].CommentID != reply.ID {
+		t.Fatalf("bob's notifications = %+v, want one reply notification for %d", list, reply.ID)
+	}
+	w = s.Do(http.MethodGet, "/api/v1/notifications", alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	list = apitest.DecodeData[controller.NotificationListResponse](t, w)
+	if list.Total != 1 || list.Notifications[0].Type != consts.NotificationTypeComment || list.Notifications[0].CommentID != top.ID {
+		t.Fatalf("alice's notifications = %+v, want only the comment notification for %d", list, top.ID)
+	}
+}
+
+func TestNotificationStream(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	post := s.CreatePost(alice, "Hello", "World")
+	srv := httptest.NewServer(s.Engine)
+	t.Cleanup(srv.Close)
+
+	stream := notificationStream(t, srv, alice.Token, "")
+
+	// 自己的评论与其他人文章下的评论不推送
+	s.CreateComment(alice, post.ID, "mine")
+	other := s.CreatePost(bob, "Bob's post", "content")
+	s.CreateComment(alice, other.ID, "hi bob")
+	comment := s.CreateComment(bob, post.ID, "nice post")
+
+	ev := nextEvent(t, stream)
+	n := decodeNotification(t, ev)
+	if ev.event != "notification" || n.CommentID != comment.ID || n.UserID != alice.ID || ev.id != strconv.FormatUint(uint64(n.ID), 10) {
+		t.Fatalf("event = %+v, want a notification for comment %d", ev, comment.ID)
+	}
+
+	// 断线期间的通知按 Last-Event-ID 补发，之后继续推送新通知
+	missed := s.CreateComment(bob, post.ID, "while offline")
+	stream = notificationStream(t, srv, alice.Token, ev.id)
+	if n := decodeNotification(t, nextEvent(t, stream)); n.CommentID != missed.ID {
+		t.Fatalf("replayed notification = %+v, want comment %d", n, missed.ID)
+	}
+	live := s.CreateComment(bob, post.ID, "live")
+	if n := decodeNotification(t, nextEvent(t, stream)); n.CommentID != live.ID {
+		t.Fatalf("live notification = %+v, want comment %d", n, live.ID)
+	}
+
+	// 关闭 hub（服务退出）后服务端结束连接
+	s.Hub.Close()
+	if _, err := io.Copy(io.Discard, stream); err != nil {
+		t.Fatalf("stream did not end after the hub was closed: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/openapi"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	s := apitest.New(t)
+
+	w := s.Do(http.MethodGet, "/openapi.json", "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	doc := apitest.DecodeJSON[openapi.Document](t, w)
+	if doc.OpenAPI != openapi.Version {
+		t.Errorf("openapi = %q, want %q", doc.OpenAPI, openapi.Version)
+	}
+	for path, method := range map[string]string{
+		"/api/v1/posts/{id}":      "get",
+		"/api/v1/users/register":  "post",
+		"/api/v1/uploads":         "post",
+		"/api/v1/admin/import":    "post",
+		"/.well-known/jwks.json":  "get",
+		"/api/v1/comments/{id}":   "delete",
+		"/api/v1/users/me/export": "post",
+	} {
+		item, ok := doc.Paths[path]
+		if !ok || (*item)[method] == nil {
+			t.Errorf("document has no %s %s", strings.ToUpper(method), path)
+		}
+	}
+	if _, ok := doc.Components.SecuritySchemes["bearerAuth"]; !ok {
+		t.Error("document has no bearerAuth security scheme")
+	}
+
+	w = s.Do(http.MethodGet, "/docs", "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("/docs Content-Type = %q, want text/html", ct)
+	}
+
+	// 页面引用的脚本与样式都由服务自己提供，不加载第三方 CDN
+	refs := assetRefPattern.FindAllStringSubmatch(w.Body.String(), -1)
+	if len(refs) == 0 {
+		t.Fatal("/docs references no assets")
+	}
+	for _, ref := range refs {
+		if !strings.HasPrefix(ref[1], "/docs/assets/") {
+			t.Errorf("/docs loads %q, want a same-origin /docs/assets/ file", ref[1])
+			continue
+		}
+		w := s.Do(http.MethodGet, ref[1], "", nil)
+		apitest.ExpectStatus(t, w, http.StatusOK)
+		if w.Body.Len() == 0 || w.Header().Get("Content-Type") == "" {
+			t.Errorf("%s is empty or has no Content-Type", ref[1])
+		}
+	}
+	runCases(t, s, []routeCase{
+		{"asset not published", http.MethodGet, "/docs/assets/index.html", "", nil, http.StatusNotFound},
+		{"unknown asset", http.MethodGet, "/docs/assets/nothing.js", "", nil, http.StatusNotFound},
+	})
+}
+
+var assetRefPattern = regexp.MustCompile(`(?:src|href)="([^"]+)"`)
+
+func TestOpenAPIValidation(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+
+	// 不符合文档的请求体在进入处理器前被拒绝，错误信息指出出错的字段
+	for _, c := range []struct {
+		name, path, token string
+		body              any
+		field             string
+	}{
+		{"wrong type", "/api/v1/posts", alice.Token, map[string]any{"title": 1, "content": "c"}, "/title"},
+		{"missing field", "/api/v1/posts", alice.Token, map[string]any{"title": "t"}, "content"},
+		{"invalid email", "/api/v1/users/register", "", map[string]any{"username": "bob", "email": "bob", "password": "secret123"}, "/email"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := s.Do(http.MethodPost, c.path, c.token, c.body)
+			apitest.ExpectStatus(t, w, http.StatusBadRequest)
+			body := apitest.DecodeJSON[openapi.MiddlewareError](t, w)
+			if !strings.Contains(body.Error, c.field) {
+				t.Errorf("error = %q, want it to mention %q", body.Error, c.field)
+			}
+		})
+	}
+
+	// 文档未列出的状态码与不符合结构的响应体都视为处理器与文档不一致
+	for name, err := range map[string]error{
+		"undocumented status": s.Spec.ValidateResponse(http.MethodGet, "/api/v1/posts/:id", http.StatusTeapot, "application/json", []byte(`{}`)),
+		"wrong body":          s.Spec.ValidateResponse(http.MethodGet, "/api/v1/posts/:id", http.StatusOK, "application/json", []byte(`{"id":"1"}`)),
+	} {
+		if !errors.Is(err, openapi.ErrInvalidResponse) {
+			t.Errorf("%s: error = %v, want ErrInvalidResponse", name, err)
+		}
+	}
+	if err := s.Spec.ValidateResponse(http.MethodGet, "/api/v1/posts/:id", http.StatusNotFound, "application/json", []byte(`{"message":"文章不存在"}`)); err != nil {
+		t.Errorf("documented 404: %v", err)
+	}
+}
@@ -3,12 +3,13 @@ package api
 
 import (
 	"web-task/blog/internal/controller"
+	"web-task/blog/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupPostRouter 注册文章相关路由
-func SetupPostRouter(router *gin.RouterGroup, pc *controller.PostHandler) {
+func SetupPostRouter(router *gin.RouterGroup, pc *controller.PostHandler, limiter *middleware.RateLimiter) {
 	// 直接调用处理器自带的路由注册方法
-	pc.RegisterRoutes(router)
+	pc.RegisterRoutes(router, limiter)
 }
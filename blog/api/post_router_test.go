@@ -0,0 +1,97 @@
+package api_test
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"web-task/blog/dto"
+	"web-task/blog/internal/apitest"
+)
+
+func TestPosts(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	post := s.CreatePost(alice, "Hello", "World")
+	s.CreatePost(alice, "Second", "Post")
+	path := fmt.Sprintf("/api/v1/posts/%d", post.ID)
+
+	runCases(t, s, []routeCase{
+		{"create without token", http.MethodPost, "/api/v1/posts", "", map[string]string{"title": "t", "content": "c"}, http.StatusUnauthorized},
+		{"create invalid json", http.MethodPost, "/api/v1/posts", alice.Token, "{", http.StatusBadRequest},
+		{"create missing title", http.MethodPost, "/api/v1/posts", alice.Token, map[string]string{"content": "c"}, http.StatusBadRequest},
+		{"get", http.MethodGet, path, "", nil, http.StatusOK},
+		{"get with token", http.MethodGet, path, bob.Token, nil, http.StatusOK},
+		{"get invalid id", http.MethodGet, "/api/v1/posts/abc", "", nil, http.StatusBadRequest},
+		{"get not found", http.MethodGet, "/api/v1/posts/999", "", nil, http.StatusNotFound},
+		{"get with invalid token", http.MethodGet, path, "not-a-jwt", nil, http.StatusUnauthorized},
+		{"update without token", http.MethodPut, path, "", map[string]string{"title": "x"}, http.StatusUnauthorized},
+		{"update invalid id", http.MethodPut, "/api/v1/posts/abc", alice.Token, map[string]string{"title": "x"}, http.StatusBadRequest},
+		{"update invalid json", http.MethodPut, path, alice.Token, "{", http.StatusBadRequest},
+		{"update not found", http.MethodPut, "/api/v1/posts/999", alice.Token, map[string]string{"title": "x"}, http.StatusNotFound},
+		{"update not owner", http.MethodPut, path, bob.Token, map[string]string{"title": "x"}, http.StatusForbidden},
+		{"update", http.MethodPut, path, alice.Token, map[string]string{"title": "Hello again"}, http.StatusOK},
+		{"delete without token", http.MethodDelete, path, "", nil, http.StatusUnauthorized},
+		{"delete invalid id", http.MethodDelete, "/api/v1/posts/abc", alice.Token, nil, http.StatusBadRequest},
+		{"delete not found", http.MethodDelete, "/api/v1/posts/999", alice.Token, nil, http.StatusNotFound},
+		{"delete not owner", http.MethodDelete, path, bob.Token, nil, http.StatusForbidden},
+		{"delete", http.MethodDelete, path, alice.Token, nil, http.StatusNoContent},
+		{"get deleted", http.MethodGet, path, "", nil, http.StatusNotFound},
+	})
+
+	w := s.Do(http.MethodGet, "/api/v1/posts?page=1&pageSize=10", "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	list := apitest.DecodeJSON[dto.PostListResponse](t, w)
+	if list.Total != 1 || len(list.Posts) != 1 || list.Posts[0].Title != "Second" {
+		t.Errorf("list = %+v, want only the second post", list)
+	}
+}
+
+func TestPostAuthorIsPublic(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateAdmin("alice")
+	s.VerifyEmail(alice)
+	post := s.CreatePost(alice, "Hello", "World")
+	comment := s.CreateComment(alice, post.ID, "first")
+
+	// 匿名访问只能看到作者的公开资料，邮箱、角色与两步验证状态只在 /users/me 返回
+	public := []string{"avatar_url", "bio", "created_at", "display_name", "id", "username"}
+	checkAuthor := func(t *testing.T, name string, user map[string]any) {
+		t.Helper()
+		keys := slices.Sorted(maps.Keys(user))
+		if !slices.Equal(keys, public) {
+			t.Errorf("%s author fields = %v, want %v", name, keys, public)
+		}
+		if user["username"] != "alice" {
+			t.Errorf("%s author = %v, want alice", name, user["username"])
+		}
+	}
+	type author struct {
+		User map[string]any `json:"user"`
+	}
+
+	w := s.Do(http.MethodGet, "/api/v1/posts", "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	list := apitest.DecodeJSON[struct {
+		Posts []author `json:"posts"`
+	}](t, w)
+	if len(list.Posts) != 1 {
+		t.Fatalf("got %d posts, want 1", len(list.Posts))
+	}
+	checkAuthor(t, "list", list.Posts[0].User)
+
+	w = s.Do(http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", post.ID), "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	checkAuthor(t, "detail", apitest.DecodeJSON[author](t, w).User)
+
+	w = s.Do(http.MethodGet, fmt.Sprintf("/api/v1/comments/%d", comment.ID), "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	checkAuthor(t, "comment", apitest.DecodeJSON[author](t, w).User)
+	if strings.Contains(w.Body.String(), alice.Email) {
+		t.Errorf("comment detail leaks the author email: %s", w.Body.String())
+	}
+}
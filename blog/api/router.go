@@ -3,6 +3,8 @@ package api
 
 import (
 	"web-task/blog/internal/controller"
+	"web-task/blog/internal/metrics"
+	"web-task/blog/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,12 +15,42 @@ func SetupRouter(
 	userCtl *controller.UserController,
 	postCtl *controller.PostHandler,
 	commentCtl *controller.CommentHandler,
+	uploadCtl *controller.UploadHandler,
+	identityCtl *controller.IdentityController,
+	jwksCtl *controller.JWKSHandler,
+	exportCtl *controller.ExportHandler,
+	importCtl *controller.ImportHandler,
+	healthCtl *controller.HealthHandler,
+	openapiCtl *controller.OpenAPIHandler,
+	graphqlCtl *controller.GraphQLHandler,
+	webhookCtl *controller.WebhookHandler,
+	notificationCtl *controller.NotificationHandler,
+	limiter *middleware.RateLimiter,
 ) {
+	// 存活与就绪探针
+	r.GET("/healthz", middleware.QuietLog(), healthCtl.Healthz)
+	r.GET("/readyz", middleware.QuietLog(), healthCtl.Readyz)
+	// 公钥发布，供其它服务验证博客签发的令牌
+	r.GET("/.well-known/jwks.json", jwksCtl.JWKS)
+	// Prometheus 指标
+	r.GET("/metrics", middleware.MetricsAuth(), gin.WrapH(metrics.Handler()))
+	// OpenAPI 文档与在线文档页面
+	r.GET("/openapi.json", openapiCtl.Spec)
+	r.GET("/docs", openapiCtl.SwaggerUI)
+	r.GET("/docs/assets/:file", openapiCtl.UIAsset)
+
 	// 基础API前缀
 	api := r.Group("/api/v1")
 
 	// 注册各模块路由
-	SetupUserRouter(api, userCtl)       // 用户路由
-	SetupPostRouter(api, postCtl)       // 文章路由
-	SetupCommentRouter(api, commentCtl) // 评论路由
+	SetupUserRouter(api, userCtl, limiter)        // 用户路由
+	SetupPostRouter(api, postCtl, limiter)        // 文章路由
+	SetupCommentRouter(api, commentCtl, limiter)  // 评论路由
+	SetupUploadRouter(api, uploadCtl, limiter)    // 附件路由
+	SetupAuthRouter(api, identityCtl, limiter)    // 第三方登录路由
+	SetupExportRouter(api, exportCtl, limiter)    // 数据导出路由
+	SetupGraphQLRouter(api, graphqlCtl, limiter)  // GraphQL 路由
+	SetupWebhookRouter(api, webhookCtl)           // 出站 webhook 路由
+	SetupNotificationRouter(api, notificationCtl) // 站内通知路由
+	SetupAdminRouter(api, userCtl, importCtl)     // 管理员路由
 }
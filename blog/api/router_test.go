@@ -0,0 +1,197 @@
+package api_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/keyring"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/oidc"
+	"web-task/blog/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+func TestOperationalEndpoints(t *testing.T) {
+	s := apitest.New(t, apitest.WithMetricsToken("scrape-secret"))
+
+	runCases(t, s, []routeCase{
+		{"healthz", http.MethodGet, "/healthz", "", nil, http.StatusOK},
+		{"readyz", http.MethodGet, "/readyz", "", nil, http.StatusOK},
+		{"metrics without token", http.MethodGet, "/metrics", "", nil, http.StatusUnauthorized},
+		{"metrics", http.MethodGet, "/metrics", "scrape-secret", nil, http.StatusOK},
+		{"unknown route", http.MethodGet, "/api/v1/nothing", "", nil, http.StatusNotFound},
+	})
+
+	w := s.Do(http.MethodGet, "/.well-known/jwks.json", "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	jwks := apitest.DecodeJSON[struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+		} `json:"keys"`
+	}](t, w)
+	if len(jwks.Keys) == 0 || jwks.Keys[0].Kid == "" {
+		t.Fatalf("jwks = %+v, want at least one key", jwks)
+	}
+
+	s.Health.SetDraining()
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/readyz", "", nil), http.StatusServiceUnavailable)
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/healthz", "", nil), http.StatusOK)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	// 截止时间在进入处理器前已经过去，数据库查询随 request context 中断，各类处理器都应返回 504
+	s := apitest.New(t, apitest.WithTimeouts(time.Nanosecond, time.Nanosecond))
+	runCases(t, s, []routeCase{
+		{"list posts", http.MethodGet, "/api/v1/posts", "", nil, http.StatusGatewayTimeout},
+		{"get post", http.MethodGet, "/api/v1/posts/1", "", nil, http.StatusGatewayTimeout},
+		{"get comment", http.MethodGet, "/api/v1/comments/1", "", nil, http.StatusGatewayTimeout},
+		{"public profile", http.MethodGet, "/api/v1/users/alice", "", nil, http.StatusGatewayTimeout},
+		{"get upload", http.MethodGet, "/api/v1/uploads/1", "", nil, http.StatusGatewayTimeout},
+		{"forgot password", http.MethodPost, "/api/v1/users/password/forgot", "", map[string]string{"email": "alice@example.com"}, http.StatusGatewayTimeout},
+	})
+}
+
+func TestSlowQueryCanceledAtDeadline(t *testing.T) {
+	s := apitest.New(t, apitest.WithTimeouts(time.Second, time.Second))
+	alice := s.CreateUser("alice")
+	s.CreatePost(alice, "Hello", "World")
+
+	// 文章列表的第一个查询前先执行一个不会结束的递归查询，模拟慢查询；
+	// 它使用 request context，截止时间到达后应被驱动中断并把错误交给处理器
+	type result struct {
+		err     error
+		elapsed time.Duration
+	}
+	done := make(chan result, 1)
+	var slow atomic.Bool
+	slow.Store(true)
+	err := s.DB.Callback().Query().Before("gorm:query").Register("test:slow_query", func(db *gorm.DB) {
+		if db.Statement.Table != "posts" || !slow.CompareAndSwap(true, false) {
+			return
+		}
+		start := time.Now()
+		rows, err := db.Statement.ConnPool.QueryContext(db.Statement.Context,
+			"WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n")
+		if err == nil {
+			for rows.Next() {
+			}
+			err = rows.Err()
+			rows.Close()
+		}
+		done <- result{err, time.Since(start)}
+		db.AddError(err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := s.Do(http.MethodGet, "/api/v1/posts", "", nil)
+	apitest.ExpectStatus(t, w, http.StatusGatewayTimeout)
+
+	select {
+	case r := <-done:
+		if !errors.Is(r.err, context.DeadlineExceeded) {
+			t.Errorf("slow query error = %v, want context.DeadlineExceeded", r.err)
+		}
+		if r.elapsed > 5*time.Second {
+			t.Errorf("slow query ran for %s after the 1s deadline", r.elapsed)
+		}
+	default:
+		t.Fatal("the slow query never ran")
+	}
+}
+
+func TestCanceledRequest(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	s.CreatePost(alice, "Hello", "World")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// 客户端断开后处理器不再访问数据库，返回服务端错误而不是空列表
+	w := s.Serve(s.NewRequest(http.MethodGet, "/api/v1/posts", "", nil).WithContext(ctx))
+	if w.Code < http.StatusInternalServerError {
+		t.Errorf("canceled request status = %d, want a server error", w.Code)
+	}
+
+	memory, memoryUoW := repository.NewMemory()
+	for name, posts := range map[string]*logic.PostService{
+		"gorm":   s.Posts,
+		"memory": logic.NewPostService(memory, memoryUoW),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := posts.List(ctx, 1, 10); !errors.Is(err, context.Canceled) {
+				t.Errorf("List error = %v, want context.Canceled", err)
+			}
+			if _, err := posts.Create(ctx, alice.ID, "t", "c"); !errors.Is(err, context.Canceled) {
+				t.Errorf("Create error = %v, want context.Canceled", err)
+			}
+		})
+	}
+}
+
+func TestSigningKeysEncryptedAtRest(t *testing.T) {
+	s := apitest.New(t)
+	ctx := context.Background()
+
+	var stored []model.SigningKey
+	if err := s.DB.Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) == 0 {
+		t.Fatal("no signing keys stored")
+	}
+	for _, k := range stored {
+		if strings.Contains(k.PrivateKey, "PRIVATE KEY") {
+			t.Errorf("signing key %s is stored as plaintext PEM", k.Kid)
+		}
+	}
+
+	// 早期版本保存的明文私钥在启动时加密，加密后仍可用于验证
+	legacy, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired := time.Now()
+	record := model.SigningKey{Kid: "legacy", Algorithm: keyring.ES256, PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), RetiredAt: &retired}
+	if err := s.DB.Create(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	keys, err := keyring.New(ctx, s.DB, keyring.ES256, []byte(apitest.SigningKeySecret), 24*time.Hour, 48*time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.First(&record, record.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(record.PrivateKey, "PRIVATE KEY") {
+		t.Error("legacy signing key was not encrypted")
+	}
+	if !slices.ContainsFunc(keys.JWKS().Keys, func(k oidc.JWK) bool { return k.Kid == "legacy" }) {
+		t.Error("legacy signing key missing from JWKS after encryption")
+	}
+
+	// 密钥不一致的实例无法解密，拒绝启动而不是生成新的签名密钥
+	if _, err := keyring.New(ctx, s.DB, keyring.ES256, []byte("another-signing-key-secret-0123456789"), 24*time.Hour, 48*time.Hour, 0); err == nil {
+		t.Fatal("keyring loaded keys encrypted with a different secret")
+	}
+}
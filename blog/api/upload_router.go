@@ -0,0 +1,14 @@
+package api
+
+import (
+	"web-task/blog/internal/controller"
+	"web-task/blog/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupUploadRouter 注册附件上传相关路由
+func SetupUploadRouter(router *gin.RouterGroup, uh *controller.UploadHandler, limiter *middleware.RateLimiter) {
+	// 直接调用处理器自带的路由注册方法
+	uh.RegisterRoutes(router, limiter)
+}
@@ -0,0 +1,115 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/controller"
+)
+
+func pngImage(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := 0; x < 64; x++ {
+		img.Set(x, x%48, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngBomb 只有几十字节、文件头却声明 100000x100000 画布的 PNG
+func pngBomb(t *testing.T) []byte {
+	t.Helper()
+
+	data := bytes.Clone(pngImage(t))
+	// IHDR 数据块紧跟在 8 字节签名与 8 字节块头之后：宽、高各 4 字节，CRC 覆盖块类型与数据
+	ihdr := data[12 : 12+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:8], 100000)
+	binary.BigEndian.PutUint32(ihdr[8:12], 100000)
+	binary.BigEndian.PutUint32(data[12+4+13:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+func TestUploads(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	post := s.CreatePost(alice, "Hello", "World")
+	data := pngImage(t)
+
+	upload := func(token string, fields map[string]string, filename string, file []byte) *http.Request {
+		return s.Multipart(http.MethodPost, "/api/v1/uploads", token, fields, filename, file)
+	}
+	// 伪造的 X-User-ID 不能代替登录
+	spoofed := upload("", nil, "a.png", data)
+	spoofed.Header.Set("X-User-ID", strconv.FormatUint(uint64(alice.ID), 10))
+
+	uploadCases := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"without token", upload("", nil, "a.png", data), http.StatusUnauthorized},
+		{"with spoofed user header", spoofed, http.StatusUnauthorized},
+		{"image too large", upload(alice.Token, nil, "bomb.png", pngBomb(t)), http.StatusBadRequest},
+		{"without file", upload(alice.Token, nil, "", nil), http.StatusBadRequest},
+		{"invalid post id", upload(alice.Token, map[string]string{"post_id": "abc"}, "a.png", data), http.StatusBadRequest},
+		{"empty file", upload(alice.Token, nil, "a.png", []byte{}), http.StatusBadRequest},
+		{"unsupported type", upload(alice.Token, nil, "a.bin", []byte{0x7f, 'E', 'L', 'F', 2, 1, 1, 0, 0, 0}), http.StatusUnsupportedMediaType},
+		{"post not found", upload(alice.Token, map[string]string{"post_id": "999"}, "a.png", data), http.StatusNotFound},
+		{"post not owned", upload(bob.Token, map[string]string{"post_id": strconv.FormatUint(uint64(post.ID), 10)}, "a.png", data), http.StatusForbidden},
+	}
+	for _, tc := range uploadCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apitest.ExpectStatus(t, s.Serve(tc.req), tc.status)
+		})
+	}
+
+	w := s.Serve(upload(alice.Token, map[string]string{"post_id": strconv.FormatUint(uint64(post.ID), 10)}, "photo.png", data))
+	apitest.ExpectStatus(t, w, http.StatusCreated)
+	attachment := apitest.DecodeJSON[controller.AttachmentResponse](t, w)
+	if attachment.MimeType != "image/png" || attachment.Width != 64 || attachment.ThumbnailURL == "" {
+		t.Fatalf("attachment = %+v", attachment)
+	}
+	path := fmt.Sprintf("/api/v1/uploads/%d", attachment.ID)
+	if attachment.URL != path+"/content" {
+		t.Errorf("url = %q, want %q", attachment.URL, path+"/content")
+	}
+
+	w = s.Do(http.MethodGet, attachment.URL, "", nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if !bytes.Equal(w.Body.Bytes(), data) {
+		t.Error("downloaded content differs from the upload")
+	}
+
+	runCases(t, s, []routeCase{
+		{"get", http.MethodGet, path, "", nil, http.StatusOK},
+		{"get invalid id", http.MethodGet, "/api/v1/uploads/abc", "", nil, http.StatusBadRequest},
+		{"get not found", http.MethodGet, "/api/v1/uploads/999", "", nil, http.StatusNotFound},
+		{"thumbnail", http.MethodGet, path + "/thumbnail", "", nil, http.StatusOK},
+		{"content invalid id", http.MethodGet, "/api/v1/uploads/abc/content", "", nil, http.StatusBadRequest},
+		{"content not found", http.MethodGet, "/api/v1/uploads/999/content", "", nil, http.StatusNotFound},
+	})
+
+	runCases(t, s, []routeCase{
+		{"delete without token", http.MethodDelete, path, "", nil, http.StatusUnauthorized},
+		{"delete invalid id", http.MethodDelete, "/api/v1/uploads/abc", alice.Token, nil, http.StatusBadRequest},
+		{"delete not found", http.MethodDelete, "/api/v1/uploads/999", alice.Token, nil, http.StatusNotFound},
+		{"delete not owner", http.MethodDelete, path, bob.Token, nil, http.StatusForbidden},
+		{"delete", http.MethodDelete, path, alice.Token, nil, http.StatusNoContent},
+		{"delete again", http.MethodDelete, path, alice.Token, nil, http.StatusNotFound},
+	})
+}
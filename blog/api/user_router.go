@@ -1,16 +1,45 @@
 package api
 
 import (
+	"web-task/blog/internal/consts"
 	"web-task/blog/internal/controller"
+	"web-task/blog/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 // 定义用户相关路由
-func SetupUserRouter(router *gin.RouterGroup, uc *controller.UserController) {
+func SetupUserRouter(router *gin.RouterGroup, uc *controller.UserController, limiter *middleware.RateLimiter) {
 	userRouter := router.Group("/users")
 	{
-		userRouter.POST("/register", uc.Register) // 用户注册
-		userRouter.POST("/login", uc.Login)       // 用户登录
+		emailLimit := limiter.Limit(consts.RateLimitEmail)
+		userRouter.POST("/register", emailLimit, uc.Register)                            // 用户注册，发送验证邮件
+		userRouter.POST("/login", limiter.Limit(consts.RateLimitLogin), uc.Login)        // 用户登录
+		userRouter.POST("/login/mfa", limiter.Limit(consts.RateLimitLogin), uc.LoginMFA) // 两步验证登录
+
+		userRouter.GET("/:username", limiter.Limit(consts.RateLimitReads), uc.GetUserProfile) // 用户公开资料
+
+		userRouter.GET("/verify-email", uc.VerifyEmail)                                            // 邮件链接验证邮箱
+		userRouter.POST("/verify-email", uc.VerifyEmail)                                           // 提交令牌验证邮箱
+		userRouter.POST("/verify-email/resend", emailLimit, uc.ResendVerification)                 // 重新发送验证邮件
+		userRouter.POST("/password/forgot", emailLimit, uc.ForgotPassword)                         // 发送找回密码邮件
+		userRouter.POST("/password/reset", limiter.Limit(consts.RateLimitLogin), uc.ResetPassword) // 重置密码
+
+		me := userRouter.Group("/me", middleware.AuthMiddleware())
+		me.GET("", uc.GetMe)                    // 当前用户资料
+		me.PATCH("", uc.UpdateMe)               // 修改资料
+		me.DELETE("", uc.DeleteMe)              // 注销账号
+		me.POST("/password", uc.ChangePassword) // 修改密码
+		me.GET("/sessions", uc.Sessions)        // 登录记录
+
+		me.POST("/totp/enroll", uc.EnrollTOTP)                      // 开始注册两步验证
+		me.GET("/totp/qr.png", uc.TOTPQRCode)                       // 注册二维码
+		me.POST("/totp/confirm", uc.ConfirmTOTP)                    // 确认并启用两步验证
+		me.POST("/totp/disable", uc.DisableTOTP)                    // 关闭两步验证
+		me.POST("/totp/recovery-codes", uc.RegenerateRecoveryCodes) // 重新生成恢复码
+
+		me.POST("/tokens", uc.CreateAccessToken)       // 创建个人访问令牌
+		me.GET("/tokens", uc.ListAccessTokens)         // 个人访问令牌列表
+		me.DELETE("/tokens/:id", uc.RevokeAccessToken) // 撤销个人访问令牌
 	}
 }
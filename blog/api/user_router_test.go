@@ -0,0 +1,363 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"web-task/blog/dto"
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/totp"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	s := apitest.New(t)
+	s.Register("alice")
+
+	runCases(t, s, []routeCase{
+		{"register invalid json", http.MethodPost, "/api/v1/users/register", "", "{", http.StatusBadRequest},
+		{"register short password", http.MethodPost, "/api/v1/users/register", "", map[string]string{"username": "bob", "email": "bob@example.com", "password": "123"}, http.StatusBadRequest},
+		{"register invalid email", http.MethodPost, "/api/v1/users/register", "", map[string]string{"username": "bob", "email": "bob", "password": "secret123"}, http.StatusBadRequest},
+		{"register duplicate username", http.MethodPost, "/api/v1/users/register", "", map[string]string{"username": "alice", "email": "other@example.com", "password": "secret123"}, http.StatusBadRequest},
+		{"register duplicate email", http.MethodPost, "/api/v1/users/register", "", map[string]string{"username": "bob", "email": "alice@example.com", "password": "secret123"}, http.StatusBadRequest},
+		{"register", http.MethodPost, "/api/v1/users/register", "", map[string]string{"username": "bob", "email": "bob@example.com", "password": "secret123"}, http.StatusOK},
+		{"login missing password", http.MethodPost, "/api/v1/users/login", "", map[string]string{"username": "alice"}, http.StatusBadRequest},
+		{"login wrong password", http.MethodPost, "/api/v1/users/login", "", map[string]string{"username": "alice", "password": "wrong-password"}, http.StatusUnauthorized},
+		{"login unknown user", http.MethodPost, "/api/v1/users/login", "", map[string]string{"username": "nobody", "password": "secret123"}, http.StatusUnauthorized},
+		{"login", http.MethodPost, "/api/v1/users/login", "", map[string]string{"username": "alice", "password": apitest.Password}, http.StatusOK},
+		{"mfa invalid json", http.MethodPost, "/api/v1/users/login/mfa", "", "{", http.StatusBadRequest},
+		{"mfa invalid token", http.MethodPost, "/api/v1/users/login/mfa", "", map[string]string{"mfa_token": "bogus", "code": "123456"}, http.StatusUnauthorized},
+		{"public profile", http.MethodGet, "/api/v1/users/alice", "", nil, http.StatusOK},
+		{"public profile not found", http.MethodGet, "/api/v1/users/nobody", "", nil, http.StatusNotFound},
+	})
+}
+
+func TestLoginLockoutAndAdminUnlock(t *testing.T) {
+	s := apitest.New(t)
+	admin := s.CreateAdmin("admin")
+	user := s.CreateUser("mallory")
+	alice := s.Register("alice")
+
+	wrong := map[string]string{"username": "alice", "password": "wrong-password"}
+	for i := 0; i < consts.LoginMaxFailuresPerAccount; i++ {
+		apitest.ExpectStatus(t, s.Do(http.MethodPost, "/api/v1/users/login", "", wrong), http.StatusUnauthorized)
+	}
+
+	// 锁定期间正确的密码也不签发令牌，Retry-After 不超过首次锁定时长
+	w := s.Do(http.MethodPost, "/api/v1/users/login", "", map[string]string{"username": "alice", "password": apitest.Password})
+	apitest.ExpectStatus(t, w, http.StatusTooManyRequests)
+	if retry, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retry < 1 || retry > int(consts.LoginLockoutBase.Seconds()) {
+		t.Errorf("Retry-After = %q, want 1..%d seconds", w.Header().Get("Retry-After"), int(consts.LoginLockoutBase.Seconds()))
+	}
+	locked := apitest.DecodeJSON[struct {
+		Msg  string `json:"msg"`
+		Data any    `json:"data"`
+	}](t, w)
+	if locked.Msg != logic.ErrAccountLocked.Error() || locked.Data != nil {
+		t.Errorf("locked response = %s, want only %q", w.Body.String(), logic.ErrAccountLocked)
+	}
+	var sessions int64
+	if err := s.DB.Model(&model.LoginSession{}).Where("user_id = ?", alice.ID).Count(&sessions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if sessions != 0 {
+		t.Errorf("locked account has %d sessions, want none", sessions)
+	}
+
+	// 锁定只针对该账号，其他账号照常登录
+	s.Login(user.Username, user.Password)
+
+	runCases(t, s, []routeCase{
+		{"unlock without token", http.MethodPost, "/api/v1/admin/users/alice/unlock", "", nil, http.StatusUnauthorized},
+		{"unlock as non-admin", http.MethodPost, "/api/v1/admin/users/alice/unlock", user.Token, nil, http.StatusForbidden},
+		{"unlock invalid json", http.MethodPost, "/api/v1/admin/users/alice/unlock", admin.Token, "{", http.StatusBadRequest},
+	})
+	var cleared int64
+	if err := s.DB.Model(&model.LoginAttempt{}).Where("username = ? AND cleared = ?", "alice", true).Count(&cleared).Error; err != nil {
+		t.Fatal(err)
+	}
+	if cleared != 0 {
+		t.Fatalf("%d failures cleared by rejected unlock requests, want none", cleared)
+	}
+
+	apitest.ExpectStatus(t, s.Do(http.MethodPost, "/api/v1/admin/users/alice/unlock", admin.Token, nil), http.StatusOK)
+	if err := s.DB.Model(&model.LoginAttempt{}).Where("username = ? AND success = ? AND cleared = ?", "alice", false, false).Count(&cleared).Error; err != nil {
+		t.Fatal(err)
+	}
+	if cleared != 0 {
+		t.Fatalf("%d failures still counted after unlock, want none", cleared)
+	}
+	token := s.Login("alice", apitest.Password)
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, "/api/v1/users/me", token, nil), http.StatusOK)
+}
+
+func TestLoginRateLimitIgnoresForwardedFor(t *testing.T) {
+	login := func(s *apitest.Server, forwardedFor string) int {
+		req := s.NewRequest(http.MethodPost, "/api/v1/users/login", "", map[string]string{"username": "nobody", "password": "secret123"})
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		return s.Serve(req).Code
+	}
+
+	// 默认不信任代理，每次换一个 X-Forwarded-For 仍按连接地址计数
+	s := apitest.New(t, apitest.WithRateLimit(consts.RateLimitLogin, 2, time.Hour))
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if got := login(s, fmt.Sprintf("203.0.113.%d", i+1)); got != want {
+			t.Fatalf("login %d = %d, want %d", i+1, got, want)
+		}
+	}
+
+	// 来自受信任代理的请求按转发头中的客户端地址计数
+	s = apitest.New(t, apitest.WithRateLimit(consts.RateLimitLogin, 2, time.Hour), apitest.WithTrustedProxies("192.0.2.1"))
+	for i := 0; i < 3; i++ {
+		if got := login(s, fmt.Sprintf("203.0.113.%d", i+1)); got != http.StatusUnauthorized {
+			t.Fatalf("login %d through a trusted proxy = %d, want 401", i+1, got)
+		}
+	}
+}
+
+func TestEmailVerificationAndPasswordReset(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.Register("alice")
+	verifyToken := s.Mailbox.Token(alice.Email)
+	if verifyToken == "" {
+		t.Fatal("no verification email sent on register")
+	}
+
+	runCases(t, s, []routeCase{
+		{"verify invalid token", http.MethodGet, "/api/v1/users/verify-email?token=bogus", "", nil, http.StatusBadRequest},
+		{"verify missing token", http.MethodPost, "/api/v1/users/verify-email", "", map[string]string{}, http.StatusBadRequest},
+		{"verify via link", http.MethodGet, "/api/v1/users/verify-email?token=" + verifyToken, "", nil, http.StatusOK},
+		{"verify token reused", http.MethodPost, "/api/v1/users/verify-email", "", map[string]string{"token": verifyToken}, http.StatusBadRequest},
+		{"resend invalid email", http.MethodPost, "/api/v1/users/verify-email/resend", "", map[string]string{"email": "nope"}, http.StatusBadRequest},
+		{"resend unknown email", http.MethodPost, "/api/v1/users/verify-email/resend", "", map[string]string{"email": "nobody@example.com"}, http.StatusAccepted},
+		{"forgot invalid email", http.MethodPost, "/api/v1/users/password/forgot", "", map[string]string{"email": "nope"}, http.StatusBadRequest},
+		{"forgot unknown email", http.MethodPost, "/api/v1/users/password/forgot", "", map[string]string{"email": "nobody@example.com"}, http.StatusAccepted},
+		{"forgot", http.MethodPost, "/api/v1/users/password/forgot", "", map[string]string{"email": alice.Email}, http.StatusAccepted},
+	})
+
+	resetToken := s.Mailbox.Token(alice.Email)
+	if resetToken == "" || resetToken == verifyToken {
+		t.Fatalf("no password reset email sent, got token %q", resetToken)
+	}
+
+	runCases(t, s, []routeCase{
+		{"reset invalid json", http.MethodPost, "/api/v1/users/password/reset", "", "{", http.StatusBadRequest},
+		{"reset invalid token", http.MethodPost, "/api/v1/users/password/reset", "", map[string]string{"token": "bogus", "new_password": "newpass123"}, http.StatusBadRequest},
+		{"reset", http.MethodPost, "/api/v1/users/password/reset", "", map[string]string{"token": resetToken, "new_password": "newpass123"}, http.StatusOK},
+		{"reset token reused", http.MethodPost, "/api/v1/users/password/reset", "", map[string]string{"token": resetToken, "new_password": "another123"}, http.StatusBadRequest},
+		{"login with old password", http.MethodPost, "/api/v1/users/login", "", map[string]string{"username": "alice", "password": apitest.Password}, http.StatusUnauthorized},
+		{"login with new password", http.MethodPost, "/api/v1/users/login", "", map[string]string{"username": "alice", "password": "newpass123"}, http.StatusOK},
+	})
+}
+
+func TestProfile(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	s.CreateUser("bob")
+	otherSession := s.Login("alice", apitest.Password)
+
+	runCases(t, s, []routeCase{
+		{"me without token", http.MethodGet, "/api/v1/users/me", "", nil, http.StatusUnauthorized},
+		{"me with malformed header", http.MethodGet, "/api/v1/users/me", "not-a-jwt", nil, http.StatusUnauthorized},
+		{"me", http.MethodGet, "/api/v1/users/me", alice.Token, nil, http.StatusOK},
+		{"update invalid json", http.MethodPatch, "/api/v1/users/me", alice.Token, "{", http.StatusBadRequest},
+		{"update unsafe avatar", http.MethodPatch, "/api/v1/users/me", alice.Token, map[string]string{"avatar_url": "javascript:alert(1)"}, http.StatusBadRequest},
+		{"update email taken", http.MethodPatch, "/api/v1/users/me", alice.Token, map[string]string{"email": "bob@example.com"}, http.StatusConflict},
+		{"update", http.MethodPatch, "/api/v1/users/me", alice.Token, map[string]string{"display_name": "Alice", "bio": "hello"}, http.StatusOK},
+		{"sessions", http.MethodGet, "/api/v1/users/me/sessions", alice.Token, nil, http.StatusOK},
+		{"change password invalid json", http.MethodPost, "/api/v1/users/me/password", alice.Token, "{", http.StatusBadRequest},
+		{"change password wrong old", http.MethodPost, "/api/v1/users/me/password", alice.Token, map[string]string{"old_password": "wrong-password", "new_password": "newpass123"}, http.StatusForbidden},
+	})
+
+	w := s.Do(http.MethodPost, "/api/v1/users/me/password", alice.Token, map[string]string{"old_password": apitest.Password, "new_password": "newpass123"})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	token := apitest.DecodeData[struct {
+		Token string `json:"token"`
+	}](t, w).Token
+
+	// 修改密码吊销包括当前会话在内的全部旧令牌，只有返回的新令牌可用
+	for name, old := range map[string]string{"current": alice.Token, "other": otherSession} {
+		w := s.Do(http.MethodGet, "/api/v1/users/me", old, nil)
+		apitest.ExpectStatus(t, w, http.StatusUnauthorized)
+		if got := apitest.DecodeJSON[map[string]string](t, w)["error"]; got != "Token has been revoked" {
+			t.Errorf("%s session error = %q, want the token to be reported as revoked", name, got)
+		}
+	}
+
+	runCases(t, s, []routeCase{
+		{"new session", http.MethodGet, "/api/v1/users/me", token, nil, http.StatusOK},
+		{"delete invalid json", http.MethodDelete, "/api/v1/users/me", token, "{", http.StatusBadRequest},
+		{"delete wrong password", http.MethodDelete, "/api/v1/users/me", token, map[string]string{"password": apitest.Password}, http.StatusForbidden},
+		{"delete", http.MethodDelete, "/api/v1/users/me", token, map[string]string{"password": "newpass123"}, http.StatusOK},
+		{"deleted session", http.MethodGet, "/api/v1/users/me", token, nil, http.StatusUnauthorized},
+		{"deleted public profile", http.MethodGet, "/api/v1/users/alice", "", nil, http.StatusNotFound},
+	})
+}
+
+func TestAccountDeletionTombstoneCannotBeClaimed(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+
+	// 抢先注册按用户 ID 推算出的匿名用户名与邮箱，不影响该用户注销
+	tombstone := fmt.Sprintf("deleted-%d", alice.ID)
+	w := s.Do(http.MethodPost, "/api/v1/users/register", "", map[string]string{
+		"username": tombstone,
+		"email":    tombstone + "@deleted.invalid",
+		"password": apitest.Password,
+	})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+
+	runCases(t, s, []routeCase{
+		{"delete", http.MethodDelete, "/api/v1/users/me", alice.Token, map[string]string{"password": apitest.Password}, http.StatusOK},
+		{"deleted public profile", http.MethodGet, "/api/v1/users/alice", "", nil, http.StatusNotFound},
+		{"squatter unaffected", http.MethodGet, "/api/v1/users/" + tombstone, "", nil, http.StatusOK},
+	})
+}
+
+func TestTOTP(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+
+	runCases(t, s, []routeCase{
+		{"qr before enroll", http.MethodGet, "/api/v1/users/me/totp/qr.png", alice.Token, nil, http.StatusConflict},
+		{"confirm before enroll", http.MethodPost, "/api/v1/users/me/totp/confirm", alice.Token, map[string]string{"code": "123456"}, http.StatusConflict},
+		{"disable when not enabled", http.MethodPost, "/api/v1/users/me/totp/disable", alice.Token, map[string]string{"password": apitest.Password, "code": "123456"}, http.StatusConflict},
+	})
+
+	w := s.Do(http.MethodPost, "/api/v1/users/me/totp/enroll", alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	secret := apitest.DecodeData[struct {
+		Secret string `json:"secret"`
+	}](t, w).Secret
+	code, err := totp.Code(secret, totp.Counter(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w = s.Do(http.MethodGet, "/api/v1/users/me/totp/qr.png", alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("qr content type = %q, want image/png", ct)
+	}
+
+	runCases(t, s, []routeCase{
+		{"confirm invalid json", http.MethodPost, "/api/v1/users/me/totp/confirm", alice.Token, "{", http.StatusBadRequest},
+		{"confirm wrong code", http.MethodPost, "/api/v1/users/me/totp/confirm", alice.Token, map[string]string{"code": "000000x"}, http.StatusBadRequest},
+	})
+
+	w = s.Do(http.MethodPost, "/api/v1/users/me/totp/confirm", alice.Token, map[string]string{"code": code})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	codes := apitest.DecodeData[struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}](t, w).RecoveryCodes
+	if len(codes) < 2 {
+		t.Fatalf("got %d recovery codes", len(codes))
+	}
+
+	runCases(t, s, []routeCase{
+		{"enroll when enabled", http.MethodPost, "/api/v1/users/me/totp/enroll", alice.Token, nil, http.StatusConflict},
+		{"regenerate wrong code", http.MethodPost, "/api/v1/users/me/totp/recovery-codes", alice.Token, map[string]string{"code": "bogus"}, http.StatusBadRequest},
+		{"disable wrong code", http.MethodPost, "/api/v1/users/me/totp/disable", alice.Token, map[string]string{"password": apitest.Password, "code": "bogus"}, http.StatusBadRequest},
+	})
+
+	// 开启两步验证后登录需要第二步，这里用恢复码完成，避免与确认时的验证码重放冲突
+	w = s.Do(http.MethodPost, "/api/v1/users/login", "", map[string]string{"username": "alice", "password": apitest.Password})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	challenge := apitest.DecodeData[struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}](t, w)
+	if !challenge.MFARequired {
+		t.Fatal("login did not require a second factor")
+	}
+
+	runCases(t, s, []routeCase{
+		{"mfa wrong code", http.MethodPost, "/api/v1/users/login/mfa", "", map[string]string{"mfa_token": challenge.MFAToken, "code": "bogus"}, http.StatusUnauthorized},
+		{"mfa with access token", http.MethodPost, "/api/v1/users/login/mfa", "", map[string]string{"mfa_token": alice.Token, "code": codes[0]}, http.StatusUnauthorized},
+		{"mfa recovery code", http.MethodPost, "/api/v1/users/login/mfa", "", map[string]string{"mfa_token": challenge.MFAToken, "code": codes[0]}, http.StatusOK},
+		{"challenge token rejected by api", http.MethodGet, "/api/v1/users/me", challenge.MFAToken, nil, http.StatusUnauthorized},
+		{"regenerate with recovery code", http.MethodPost, "/api/v1/users/me/totp/recovery-codes", alice.Token, map[string]string{"code": codes[1]}, http.StatusOK},
+		{"disable with spent recovery code", http.MethodPost, "/api/v1/users/me/totp/disable", alice.Token, map[string]string{"password": apitest.Password, "code": codes[1]}, http.StatusBadRequest},
+	})
+}
+
+func TestAccessTokens(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	readOnly := s.AccessToken(alice, consts.ScopePostsRead)
+	writer := s.AccessToken(alice, consts.ScopePostsRead, consts.ScopePostsWrite)
+	post := s.CreatePost(alice, "Hello", "World")
+
+	runCases(t, s, []routeCase{
+		{"create invalid json", http.MethodPost, "/api/v1/users/me/tokens", alice.Token, "{", http.StatusBadRequest},
+		{"create without scopes", http.MethodPost, "/api/v1/users/me/tokens", alice.Token, map[string]any{"name": "ci", "scopes": []string{}}, http.StatusBadRequest},
+		{"create unknown scope", http.MethodPost, "/api/v1/users/me/tokens", alice.Token, map[string]any{"name": "ci", "scopes": []string{"admin"}}, http.StatusBadRequest},
+		{"create with access token", http.MethodPost, "/api/v1/users/me/tokens", readOnly, map[string]any{"name": "ci", "scopes": []string{consts.ScopePostsRead}}, http.StatusForbidden},
+		{"list", http.MethodGet, "/api/v1/users/me/tokens", alice.Token, nil, http.StatusOK},
+		{"read with scope", http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", post.ID), readOnly, nil, http.StatusOK},
+		{"invalid access token", http.MethodGet, "/api/v1/posts", consts.AccessTokenPrefix + "bogus", nil, http.StatusUnauthorized},
+	})
+
+	// 权限不足时返回所需的权限范围，且不产生任何写入
+	type scopeError struct {
+		Error          string   `json:"error"`
+		RequiredScopes []string `json:"required_scopes"`
+	}
+	for _, tc := range []struct {
+		name, path string
+		body       any
+		scope      string
+		model      any
+	}{
+		{"write without scope", "/api/v1/posts", map[string]string{"title": "t", "content": "c"}, consts.ScopePostsWrite, &model.Post{}},
+		{"comment without scope", fmt.Sprintf("/api/v1/posts/%d/comments", post.ID), map[string]string{"content": "c"}, consts.ScopeCommentsWrite, &model.Comment{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var before, after int64
+			s.DB.Model(tc.model).Count(&before)
+			w := s.Do(http.MethodPost, tc.path, readOnly, tc.body)
+			apitest.ExpectStatus(t, w, http.StatusForbidden)
+			got := apitest.DecodeJSON[scopeError](t, w)
+			if got.Error != "Insufficient scope" || !slices.Equal(got.RequiredScopes, []string{tc.scope}) {
+				t.Errorf("response = %+v, want insufficient scope %s", got, tc.scope)
+			}
+			if !strings.Contains(w.Header().Get("WWW-Authenticate"), `scope="`+tc.scope+`"`) {
+				t.Errorf("WWW-Authenticate = %q, want scope %s", w.Header().Get("WWW-Authenticate"), tc.scope)
+			}
+			s.DB.Model(tc.model).Count(&after)
+			if after != before {
+				t.Errorf("%d rows written by a denied request", after-before)
+			}
+		})
+	}
+
+	runCases(t, s, []routeCase{
+		{"revoke invalid id", http.MethodDelete, "/api/v1/users/me/tokens/abc", alice.Token, nil, http.StatusBadRequest},
+		{"revoke unknown id", http.MethodDelete, "/api/v1/users/me/tokens/999", alice.Token, nil, http.StatusNotFound},
+		{"revoke other user's token", http.MethodDelete, "/api/v1/users/me/tokens/1", bob.Token, nil, http.StatusNotFound},
+		{"token still valid after failed revokes", http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", post.ID), readOnly, nil, http.StatusOK},
+		{"revoke", http.MethodDelete, "/api/v1/users/me/tokens/1", alice.Token, nil, http.StatusOK},
+	})
+
+	// 撤销后令牌立即失效并从列表中移除，同一用户的其他令牌不受影响
+	w := s.Do(http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", post.ID), readOnly, nil)
+	apitest.ExpectStatus(t, w, http.StatusUnauthorized)
+	if body := w.Body.String(); strings.Contains(body, "Hello") {
+		t.Errorf("revoked token response leaks the post: %s", body)
+	}
+	w = s.Do(http.MethodGet, "/api/v1/users/me/tokens", alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	tokens := apitest.DecodeData[[]dto.AccessTokenResponse](t, w)
+	if len(tokens) != 1 || tokens[0].ID == 1 || tokens[0].Token != "" {
+		t.Errorf("tokens after revoke = %+v, want only the second token without its secret", tokens)
+	}
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", post.ID), writer, nil), http.StatusOK)
+}
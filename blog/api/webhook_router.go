@@ -0,0 +1,22 @@
+package api
+
+import (
+	"web-task/blog/internal/controller"
+	"web-task/blog/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 定义出站 webhook 路由
+func SetupWebhookRouter(router *gin.RouterGroup, wc *controller.WebhookHandler) {
+	hooks := router.Group("/users/me/webhooks", middleware.AuthMiddleware())
+	{
+		hooks.POST("", wc.CreateWebhook)                                  // 注册 webhook
+		hooks.GET("", wc.ListWebhooks)                                    // webhook 列表
+		hooks.GET("/:id", wc.GetWebhook)                                  // 获取 webhook
+		hooks.PATCH("/:id", wc.UpdateWebhook)                             // 修改 webhook
+		hooks.DELETE("/:id", wc.DeleteWebhook)                            // 删除 webhook
+		hooks.GET("/:id/deliveries", wc.ListDeliveries)                   // 投递记录
+		hooks.POST("/:id/deliveries/:deliveryID/redeliver", wc.Redeliver) // 重新投递
+	}
+}
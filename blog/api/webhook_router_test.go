@@ -0,0 +1,463 @@
+package api_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"web-task/blog/internal/apitest"
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/controller"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// webhookReceiver 记录收到的 webhook 请求，按 status 返回状态码
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	r := &webhookReceiver{status: http.StatusNoContent}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, receivedWebhook{header: req.Header.Clone(), body: body})
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *webhookReceiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// take 返回并清空已收到的请求
+func (r *webhookReceiver) take() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	requests := r.requests
+	r.requests = nil
+	return requests
+}
+
+// deliverWebhooks 分发发件箱事件并投递到期的请求
+func deliverWebhooks(t *testing.T, s *apitest.Server) int {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := s.Webhooks.FanOut(ctx); err != nil {
+		t.Fatalf("FanOut: %v", err)
+	}
+	n, err := s.Webhooks.DeliverDue(ctx)
+	if err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	return n
+}
+
+// verifyWebhook 按文档中的算法校验签名并解析请求体
+func verifyWebhook(t *testing.T, secret string, req receivedWebhook) (logic.WebhookPayload, logic.EventData) {
+	t.Helper()
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(req.header.Get(consts.WebhookTimestampHeader) + "."))
+	mac.Write(req.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := req.header.Get(consts.WebhookSignatureHeader); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Fatalf("signature = %q, want %q", got, want)
+	}
+
+	var payload logic.WebhookPayload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("decode payload %s: %v", req.body, err)
+	}
+	if got := req.header.Get(consts.WebhookEventHeader); got != payload.Type {
+		t.Errorf("%s = %q, want %q", consts.WebhookEventHeader, got, payload.Type)
+	}
+	var data logic.EventData
+	if err := json.Unmarshal(payload.Data, &data); err != nil {
+		t.Fatalf("decode event data %s: %v", payload.Data, err)
+	}
+	return payload, data
+}
+
+func TestWebhooks(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	receiver := newWebhookReceiver(t)
+
+	w := s.Do(http.MethodPost, "/api/v1/users/me/webhooks", alice.Token, map[string]any{
+		"url":         receiver.URL + "/hook",
+		"events":      []string{consts.WebhookEventPostCreated, consts.WebhookEventCommentCreated, consts.WebhookEventPostCreated},
+		"description": "ci",
+	})
+	apitest.ExpectStatus(t, w, http.StatusCreated)
+	hook := apitest.DecodeData[controller.WebhookResponse](t, w)
+	if hook.Secret == "" || len(hook.Events) != 2 || !hook.Active {
+		t.Fatalf("webhook = %+v, want an active webhook with a secret and 2 events", hook)
+	}
+	path := fmt.Sprintf("/api/v1/users/me/webhooks/%d", hook.ID)
+
+	runCases(t, s, []routeCase{
+		{"create without token", http.MethodPost, "/api/v1/users/me/webhooks", "", map[string]any{"url": receiver.URL, "events": []string{"post.created"}}, http.StatusUnauthorized},
+		{"create with invalid url", http.MethodPost, "/api/v1/users/me/webhooks", alice.Token, map[string]any{"url": "ftp://example.com", "events": []string{"post.created"}}, http.StatusBadRequest},
+		{"create with unknown event", http.MethodPost, "/api/v1/users/me/webhooks", alice.Token, map[string]any{"url": receiver.URL, "events": []string{"user.created"}}, http.StatusBadRequest},
+		{"create without events", http.MethodPost, "/api/v1/users/me/webhooks", alice.Token, map[string]any{"url": receiver.URL, "events": []string{}}, http.StatusBadRequest},
+		{"get", http.MethodGet, path, alice.Token, nil, http.StatusOK},
+		{"get of another user", http.MethodGet, path, bob.Token, nil, http.StatusNotFound},
+		{"get invalid id", http.MethodGet, "/api/v1/users/me/webhooks/abc", alice.Token, nil, http.StatusNotFound},
+		{"update of another user", http.MethodPatch, path, bob.Token, map[string]any{"active": false}, http.StatusNotFound},
+		{"update with unknown event", http.MethodPatch, path, alice.Token, map[string]any{"events": []string{"nope"}}, http.StatusBadRequest},
+		{"deliveries of another user", http.MethodGet, path + "/deliveries", bob.Token, nil, http.StatusNotFound},
+		{"deliveries with invalid status", http.MethodGet, path + "/deliveries?status=done", alice.Token, nil, http.StatusBadRequest},
+		{"redeliver unknown delivery", http.MethodPost, path + "/deliveries/999/redeliver", alice.Token, nil, http.StatusNotFound},
+	})
+
+	w = s.Do(http.MethodGet, "/api/v1/users/me/webhooks", alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if list := apitest.DecodeData[[]controller.WebhookResponse](t, w); len(list) != 1 || list[0].Secret != "" || list[0].Description != "ci" {
+		t.Fatalf("webhooks = %+v, want one webhook without its secret", list)
+	}
+
+	// 只投递订阅的事件，且只包括自己的文章及其评论
+	post := s.CreatePost(alice, "Hello", "World")
+	s.CreatePost(bob, "Bob's post", "content")
+	comment := s.CreateComment(bob, post.ID, "nice")
+	w = s.Do(http.MethodPut, fmt.Sprintf("/api/v1/posts/%d", post.ID), alice.Token, map[string]string{"title": "Edited"})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+
+	if n := deliverWebhooks(t, s); n != 2 {
+		t.Fatalf("delivered %d requests, want 2", n)
+	}
+	requests := receiver.take()
+	if len(requests) != 2 {
+		t.Fatalf("received %d requests, want 2", len(requests))
+	}
+	payload, data := verifyWebhook(t, hook.Secret, requests[0])
+	if payload.Type != consts.WebhookEventPostCreated || data.Post == nil || data.Post.ID != post.ID || data.Post.Title != "Hello" || data.Comment != nil {
+		t.Fatalf("first event = %+v %+v, want post.created for %d", payload, data, post.ID)
+	}
+	payload, data = verifyWebhook(t, hook.Secret, requests[1])
+	if payload.Type != consts.WebhookEventCommentCreated || data.Comment == nil || data.Comment.ID != comment.ID || data.Comment.UserID != bob.ID || data.Post.ID != post.ID {
+		t.Fatalf("second event = %+v %+v, want comment.created for %d", payload, data, comment.ID)
+	}
+	if n := deliverWebhooks(t, s); n != 0 {
+		t.Fatalf("delivered %d requests again, want 0", n)
+	}
+
+	// 订阅修改与删除后只收到之后发生的事件
+	w = s.Do(http.MethodPatch, path, alice.Token, map[string]any{"events": []string{consts.WebhookEventPostUpdated, consts.WebhookEventPostDeleted}})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	apitest.ExpectStatus(t, s.Do(http.MethodDelete, fmt.Sprintf("/api/v1/posts/%d", post.ID), alice.Token, nil), http.StatusNoContent)
+	deliverWebhooks(t, s)
+	requests = receiver.take()
+	if len(requests) != 1 {
+		t.Fatalf("received %d requests, want 1", len(requests))
+	}
+	if payload, data := verifyWebhook(t, hook.Secret, requests[0]); payload.Type != consts.WebhookEventPostDeleted || data.Post.Title != "Edited" {
+		t.Fatalf("event = %+v %+v, want post.deleted with the edited title", payload, data)
+	}
+
+	w = s.Do(http.MethodGet, path+"/deliveries?status=succeeded", alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	deliveries := apitest.DecodeData[[]controller.WebhookDeliveryResponse](t, w)
+	if len(deliveries) != 3 || deliveries[0].EventType != consts.WebhookEventPostDeleted || deliveries[0].ResponseStatus != http.StatusNoContent || deliveries[0].DeliveredAt == nil {
+		t.Fatalf("deliveries = %+v, want 3 succeeded deliveries, newest first", deliveries)
+	}
+
+	// 停用后不再创建投递
+	w = s.Do(http.MethodPatch, path, alice.Token, map[string]any{"active": false})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+	if got := apitest.DecodeData[controller.WebhookResponse](t, w); got.Active {
+		t.Fatalf("webhook = %+v, want inactive", got)
+	}
+	s.CreatePost(alice, "Quiet", "content")
+	if n := deliverWebhooks(t, s); n != 0 {
+		t.Fatalf("delivered %d requests to an inactive webhook", n)
+	}
+
+	apitest.ExpectStatus(t, s.Do(http.MethodDelete, path, bob.Token, nil), http.StatusNotFound)
+	apitest.ExpectStatus(t, s.Do(http.MethodDelete, path, alice.Token, nil), http.StatusOK)
+	apitest.ExpectStatus(t, s.Do(http.MethodGet, path, alice.Token, nil), http.StatusNotFound)
+}
+
+func TestWebhookRetries(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	receiver := newWebhookReceiver(t)
+	receiver.setStatus(http.StatusInternalServerError)
+
+	w := s.Do(http.MethodPost, "/api/v1/users/me/webhooks", alice.Token, map[string]any{
+		"url":    receiver.URL,
+		"events": []string{consts.WebhookEventPostCreated},
+	})
+	apitest.ExpectStatus(t, w, http.StatusCreated)
+	hook := apitest.DecodeData[controller.WebhookResponse](t, w)
+	deliveriesPath := fmt.Sprintf("/api/v1/users/me/webhooks/%d/deliveries", hook.ID)
+	s.CreatePost(alice, "Hello", "World")
+
+	latest := func() controller.WebhookDeliveryResponse {
+		t.Helper()
+		w := s.Do(http.MethodGet, deliveriesPath, alice.Token, nil)
+		apitest.ExpectStatus(t, w, http.StatusOK)
+		deliveries := apitest.DecodeData[[]controller.WebhookDeliveryResponse](t, w)
+		if len(deliveries) != 1 {
+			t.Fatalf("deliveries = %+v, want 1", deliveries)
+		}
+		return deliveries[0]
+	}
+	// expire 使下次尝试立即到期，代替等待退避时间
+	expire := func() {
+		t.Helper()
+		if err := s.DB.Model(&model.WebhookDelivery{}).Where("1 = 1").Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+			t.Fatalf("expire deliveries: %v", err)
+		}
+	}
+
+	// 每次失败后的等待时间翻倍
+	var lastWait time.Duration
+	for attempt := 1; attempt < consts.WebhookMaxAttempts; attempt++ {
+		before := time.Now()
+		if n := deliverWebhooks(t, s); n != 1 {
+			t.Fatalf("attempt %d delivered %d requests, want 1", attempt, n)
+		}
+		d := latest()
+		if d.Status != consts.WebhookDeliveryPending || d.Attempts != attempt || d.ResponseStatus != http.StatusInternalServerError || d.NextAttemptAt == nil {
+			t.Fatalf("attempt %d: delivery = %+v, want pending after a 500", attempt, d)
+		}
+		wait := d.NextAttemptAt.Sub(before)
+		want := min(consts.WebhookRetryBase<<(attempt-1), consts.WebhookRetryMax)
+		if wait < want-time.Second || wait > want+time.Second {
+			t.Fatalf("attempt %d: retry in %v, want %v", attempt, wait, want)
+		}
+		if attempt > 1 && wait <= lastWait {
+			t.Fatalf("attempt %d: retry in %v, want more than %v", attempt, wait, lastWait)
+		}
+		lastWait = wait
+
+		// 未到期的投递不会重试
+		if n := deliverWebhooks(t, s); n != 0 {
+			t.Fatalf("attempt %d retried early", attempt)
+		}
+		expire()
+	}
+
+	// 重试耗尽后进入死信
+	deliverWebhooks(t, s)
+	dead := latest()
+	if dead.Status != consts.WebhookDeliveryDead || dead.Attempts != consts.WebhookMaxAttempts || dead.NextAttemptAt != nil || dead.Error == "" {
+		t.Fatalf("delivery = %+v, want dead after %d attempts", dead, consts.WebhookMaxAttempts)
+	}
+	if got := len(receiver.take()); got != consts.WebhookMaxAttempts {
+		t.Fatalf("received %d requests, want %d", got, consts.WebhookMaxAttempts)
+	}
+	expire()
+	if n := deliverWebhooks(t, s); n != 0 {
+		t.Fatalf("dead delivery was retried %d times", n)
+	}
+	w = s.Do(http.MethodGet, deliveriesPath+"?status=dead", alice.Token, nil)
+	if deliveries := apitest.DecodeData[[]controller.WebhookDeliveryResponse](t, w); len(deliveries) != 1 {
+		t.Fatalf("dead deliveries = %+v, want 1", deliveries)
+	}
+
+	// 手动重新投递死信
+	receiver.setStatus(http.StatusOK)
+	w = s.Do(http.MethodPost, fmt.Sprintf("%s/%d/redeliver", deliveriesPath, dead.ID), alice.Token, nil)
+	apitest.ExpectStatus(t, w, http.StatusAccepted)
+	if d := apitest.DecodeData[controller.WebhookDeliveryResponse](t, w); d.Status != consts.WebhookDeliveryPending || d.Attempts != 0 {
+		t.Fatalf("redelivered = %+v, want pending with no attempts", d)
+	}
+	if n := deliverWebhooks(t, s); n != 1 {
+		t.Fatalf("redelivery delivered %d requests, want 1", n)
+	}
+	requests := receiver.take()
+	if len(requests) != 1 {
+		t.Fatalf("received %d requests, want 1", len(requests))
+	}
+	if payload, _ := verifyWebhook(t, hook.Secret, requests[0]); payload.ID != dead.EventID {
+		t.Fatalf("redelivered event %d, want %d", payload.ID, dead.EventID)
+	}
+	if d := latest(); d.Status != consts.WebhookDeliverySucceeded || d.Attempts != 1 || d.Error != "" {
+		t.Fatalf("delivery = %+v, want succeeded", d)
+	}
+}
+
+// TestWebhookOutboxTransaction 发件箱事件与文章、评论的写入在同一事务中提交或回滚
+func TestWebhookOutboxTransaction(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	post := s.CreatePost(alice, "Hello", "World")
+
+	countEvents := func() int64 {
+		t.Helper()
+		var n int64
+		if err := s.DB.Model(&model.OutboxEvent{}).Count(&n).Error; err != nil {
+			t.Fatalf("count outbox: %v", err)
+		}
+		return n
+	}
+	if n := countEvents(); n != 1 {
+		t.Fatalf("outbox has %d events, want 1", n)
+	}
+
+	// 写入事件失败时文章与评论一同回滚
+	errOutbox := errors.New("outbox unavailable")
+	if err := s.DB.Callback().Create().Before("gorm:create").Register("test:fail_outbox", func(db *gorm.DB) {
+		if db.Statement.Table == "outbox_events" {
+			db.AddError(errOutbox)
+		}
+	}); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	if _, err := s.Posts.Create(context.Background(), alice.ID, "Lost", "content"); !errors.Is(err, errOutbox) {
+		t.Fatalf("Create error = %v, want %v", err, errOutbox)
+	}
+	w := s.Do(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", post.ID), alice.Token, map[string]string{"content": "lost"})
+	apitest.ExpectStatus(t, w, http.StatusInternalServerError)
+	w = s.Do(http.MethodPut, fmt.Sprintf("/api/v1/posts/%d", post.ID), alice.Token, map[string]string{"title": "Lost"})
+	apitest.ExpectStatus(t, w, http.StatusInternalServerError)
+
+	var posts, comments int64
+	s.DB.Model(&model.Post{}).Count(&posts)
+	s.DB.Model(&model.Comment{}).Count(&comments)
+	if posts != 1 || comments != 0 {
+		t.Fatalf("posts = %d, comments = %d after failed writes, want 1 and 0", posts, comments)
+	}
+	w = s.Do(http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", post.ID), "", nil)
+	if got := apitest.DecodeJSON[model.Post](t, w); got.Title != "Hello" {
+		t.Fatalf("title = %q after a failed update, want Hello", got.Title)
+	}
+	if n := countEvents(); n != 1 {
+		t.Fatalf("outbox has %d events, want 1", n)
+	}
+
+	if err := s.DB.Callback().Create().Remove("test:fail_outbox"); err != nil {
+		t.Fatalf("remove callback: %v", err)
+	}
+	// 不存在的文章不能评论，也不记录事件
+	w = s.Do(http.MethodPost, "/api/v1/posts/999/comments", alice.Token, map[string]string{"content": "nice"})
+	apitest.ExpectStatus(t, w, http.StatusNotFound)
+	if n := countEvents(); n != 1 {
+		t.Fatalf("outbox has %d events, want 1", n)
+	}
+}
+
+// 不能把 webhook 指向回环、内网与云元数据等非公网地址；域名在投递时按解析结果检查
+func TestWebhookPrivateTargets(t *testing.T) {
+	s := apitest.New(t)
+	s.Webhooks.AllowPrivateTargets = false
+	alice := s.CreateUser("alice")
+	receiver := newWebhookReceiver(t)
+
+	create := func(url string) map[string]any {
+		return map[string]any{"url": url, "events": []string{consts.WebhookEventPostCreated}}
+	}
+	const hooks = "/api/v1/users/me/webhooks"
+	runCases(t, s, []routeCase{
+		{"loopback", http.MethodPost, hooks, alice.Token, create(receiver.URL), http.StatusBadRequest},
+		{"ipv6 loopback", http.MethodPost, hooks, alice.Token, create("http://[::1]/hook"), http.StatusBadRequest},
+		{"private", http.MethodPost, hooks, alice.Token, create("http://10.0.0.5/hook"), http.StatusBadRequest},
+		{"ipv4-mapped private", http.MethodPost, hooks, alice.Token, create("http://[::ffff:192.168.1.1]/hook"), http.StatusBadRequest},
+		{"metadata", http.MethodPost, hooks, alice.Token, create("http://169.254.169.254/latest/meta-data"), http.StatusBadRequest},
+		{"unspecified", http.MethodPost, hooks, alice.Token, create("http://0.0.0.0/hook"), http.StatusBadRequest},
+		{"public", http.MethodPost, hooks, alice.Token, create("https://203.0.113.10/hook"), http.StatusCreated},
+	})
+
+	// localhost 解析为回环地址，创建时无法判断，投递时在拨号前拒绝并直接进入死信
+	w := s.Do(http.MethodPost, hooks, alice.Token, create(strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)+"/hook"))
+	apitest.ExpectStatus(t, w, http.StatusCreated)
+	hook := apitest.DecodeData[controller.WebhookResponse](t, w)
+	// 停用上面指向文档地址段的 webhook，避免测试向外发起连接
+	if err := s.DB.Model(&model.Webhook{}).Where("id <> ?", hook.ID).Update("active", false).Error; err != nil {
+		t.Fatalf("disable webhooks: %v", err)
+	}
+
+	s.CreatePost(alice, "Hello", "World")
+	deliverWebhooks(t, s)
+	if got := receiver.take(); len(got) != 0 {
+		t.Fatalf("receiver got %d requests, want none", len(got))
+	}
+	var delivery model.WebhookDelivery
+	if err := s.DB.Where("webhook_id = ?", hook.ID).First(&delivery).Error; err != nil {
+		t.Fatalf("load delivery: %v", err)
+	}
+	if delivery.Status != consts.WebhookDeliveryDead || !strings.Contains(delivery.Error, logic.ErrWebhookTargetForbidden.Error()) {
+		t.Fatalf("delivery = %s %q, want dead with %q", delivery.Status, delivery.Error, logic.ErrWebhookTargetForbidden)
+	}
+}
+
+// 注销账号时删除用户的 webhook、尚未投递的记录与通知，不再向其地址发送事件
+func TestAccountDeletionRemovesWebhooks(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser("alice")
+	bob := s.CreateUser("bob")
+	receiver := newWebhookReceiver(t)
+
+	w := s.Do(http.MethodPost, "/api/v1/users/me/webhooks", alice.Token, map[string]any{
+		"url":    receiver.URL + "/hook",
+		"events": []string{consts.WebhookEventCommentCreated},
+	})
+	apitest.ExpectStatus(t, w, http.StatusCreated)
+	post := s.CreatePost(alice, "Hello", "World")
+	s.CreateComment(bob, post.ID, "nice")
+	if _, err := s.Webhooks.FanOut(context.Background()); err != nil {
+		t.Fatalf("FanOut: %v", err)
+	}
+
+	count := func(m any, query string, args ...any) int64 {
+		t.Helper()
+		var n int64
+		if err := s.DB.Unscoped().Model(m).Where(query, args...).Count(&n).Error; err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		return n
+	}
+	if count(&model.WebhookDelivery{}, "1 = 1") != 1 || count(&model.Notification{}, "user_id = ?", alice.ID) != 1 {
+		t.Fatal("expected a pending delivery and a notification before deleting the account")
+	}
+
+	w = s.Do(http.MethodDelete, "/api/v1/users/me", alice.Token, map[string]string{"password": apitest.Password})
+	apitest.ExpectStatus(t, w, http.StatusOK)
+
+	if n := count(&model.Webhook{}, "user_id = ?", alice.ID); n != 0 {
+		t.Errorf("%d webhooks left after account deletion", n)
+	}
+	if n := count(&model.WebhookDelivery{}, "1 = 1"); n != 0 {
+		t.Errorf("%d webhook deliveries left after account deletion", n)
+	}
+	if n := count(&model.Notification{}, "user_id = ?", alice.ID); n != 0 {
+		t.Errorf("%d notifications left after account deletion", n)
+	}
+	deliverWebhooks(t, s)
+	if got := receiver.take(); len(got) != 0 {
+		t.Fatalf("receiver got %d requests after account deletion, want none", len(got))
+	}
+}
@@ -0,0 +1,319 @@
+// Package client 博客 API 的 Go 客户端，供其它 Go 服务调用
+// 请求与响应使用 dto 包中与服务端共享的结构体；配置账号密码后自动登录并在令牌过期前重新登录，
+// 幂等请求（GET/PUT/DELETE）遇到网络错误、429 与 502/503/504 时按指数退避重试，错误响应统一解析为 *Error
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIPrefix 接口路径前缀
+const APIPrefix = "/api/v1"
+
+// refreshSkew 令牌剩余有效期不足该时长时提前重新登录
+const refreshSkew = 30 * time.Second
+
+// RetryPolicy 幂等请求的重试策略，第 n 次重试前等待 BaseDelay*2^(n-1)（不超过 MaxDelay）并加入随机抖动；
+// 服务端返回 Retry-After 时按其等待，超过 MaxDelay 则不再重试
+type RetryPolicy struct {
+	MaxAttempts int // 包含首次请求在内的最多尝试次数，1 表示不重试
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// Client 博客 API 客户端，可被多个 goroutine 并发使用
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	userAgent  string
+	retry      RetryPolicy
+
+	// 配置后令牌缺失、即将过期或被拒绝时自动重新登录
+	username string
+	password string
+
+	mu    sync.Mutex
+	token string
+
+	// 保证同一时刻只有一个 goroutine 在重新登录
+	refreshMu sync.Mutex
+}
+
+// Option 调整客户端配置
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义的 http.Client，默认超时 30s
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithToken 使用已有的 JWT 或个人访问令牌
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithCredentials 使用账号密码登录，令牌过期后自动重新登录；开启两步验证的账号无法自动登录
+func WithCredentials(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithRetry 替换默认的重试策略
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithUserAgent 设置 User-Agent，便于服务端区分调用方
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New 创建客户端，baseURL 为博客服务地址，如 http://blog:8080
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBaseURL, baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+	return c, nil
+}
+
+// Token 当前使用的令牌
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// SetToken 替换当前使用的令牌
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// request 一次接口调用
+type request struct {
+	method string
+	path   string // 不含 APIPrefix
+	query  url.Values
+	body   any
+	// public 无需登录的接口不携带令牌
+	public bool
+	// envelope 响应为 {code, msg, data}，out 对应 data
+	envelope bool
+}
+
+// call 发送请求并把响应解码到 out；令牌被拒绝且配置了账号密码时重新登录后重发一次
+func (c *Client) call(ctx context.Context, r request, out any) error {
+	var body []byte
+	if r.body != nil {
+		var err error
+		if body, err = json.Marshal(r.body); err != nil {
+			return fmt.Errorf("blog: encode request: %w", err)
+		}
+	}
+
+	var token string
+	if !r.public {
+		var err error
+		if token, err = c.authToken(ctx); err != nil {
+			return err
+		}
+	}
+
+	data, err := c.send(ctx, r, body, token)
+	if !r.public && c.username != "" && errors.Is(err, ErrUnauthorized) {
+		if token, err = c.refresh(ctx, token); err != nil {
+			return err
+		}
+		data, err = c.send(ctx, r, body, token)
+	}
+	if err != nil {
+		return err
+	}
+	return decode(data, r.envelope, out)
+}
+
+// send 发送请求，幂等请求按重试策略重试
+func (c *Client) send(ctx context.Context, r request, body []byte, token string) ([]byte, error) {
+	idempotent := r.method == http.MethodGet || r.method == http.MethodPut || r.method == http.MethodDelete
+	for attempt := 1; ; attempt++ {
+		data, err := c.roundTrip(ctx, r, body, token)
+		if err == nil || !idempotent || attempt >= c.retry.MaxAttempts || ctx.Err() != nil {
+			return data, err
+		}
+		delay, ok := c.retryDelay(attempt, err)
+		if !ok {
+			return nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) roundTrip(ctx context.Context, r request, body []byte, token string) ([]byte, error) {
+	u := *c.baseURL
+	u.Path += APIPrefix + r.path
+	u.RawQuery = r.query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("blog: build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("blog: read response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, newError(resp, data)
+	}
+	return data, nil
+}
+
+// retryDelay 第 attempt 次请求失败后的等待时间，ok 为 false 表示该错误不应重试
+func (c *Client) retryDelay(attempt int, err error) (time.Duration, bool) {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		default:
+			return 0, false
+		}
+		if apiErr.RetryAfter > 0 {
+			return apiErr.RetryAfter, apiErr.RetryAfter <= c.retry.MaxDelay
+		}
+	}
+
+	delay := c.retry.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+	// 在 [delay/2, delay] 之间随机，避免多个客户端同时重试
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int64N(half+1)), true
+}
+
+// authToken 返回请求要携带的令牌，配置了账号密码且令牌缺失或即将过期时先登录
+func (c *Client) authToken(ctx context.Context) (string, error) {
+	token := c.Token()
+	if c.username != "" && (token == "" || expiresSoon(token)) {
+		return c.refresh(ctx, token)
+	}
+	return token, nil
+}
+
+// refresh 用账号密码重新登录；stale 为失效的令牌，其它 goroutine 已经换了新令牌时直接使用
+func (c *Client) refresh(ctx context.Context, stale string) (string, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	if token := c.Token(); token != "" && token != stale && !expiresSoon(token) {
+		return token, nil
+	}
+	if err := c.Login(ctx, c.username, c.password); err != nil {
+		return "", err
+	}
+	return c.Token(), nil
+}
+
+// expiresSoon 按 JWT 的 exp 判断令牌是否即将过期；个人访问令牌等无法解析的令牌视为未过期
+func expiresSoon(token string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return false
+	}
+	return time.Until(time.Unix(claims.Exp, 0)) < refreshSkew
+}
+
+// decode 解码成功响应，envelope 为 true 时取其中的 data
+func decode(data []byte, envelope bool, out any) error {
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	var err error
+	if envelope {
+		err = json.Unmarshal(data, &struct {
+			Data any `json:"data"`
+		}{Data: out})
+	} else {
+		err = json.Unmarshal(data, out)
+	}
+	if err != nil {
+		return fmt.Errorf("blog: decode response: %w", err)
+	}
+	return nil
+}
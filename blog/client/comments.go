@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreateComment 在文章下发表评论
+func (c *Client) CreateComment(ctx context.Context, postID uint, req CreateCommentRequest) (*Comment, error) {
+	var out Comment
+	if err := c.call(ctx, request{method: http.MethodPost, path: postPath(postID) + "/comments", body: req}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetComment 评论详情
+func (c *Client) GetComment(ctx context.Context, id uint) (*Comment, error) {
+	var out Comment
+	if err := c.call(ctx, request{method: http.MethodGet, path: commentPath(id)}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateComment 修改自己的评论
+func (c *Client) UpdateComment(ctx context.Context, id uint, req UpdateCommentRequest) (*Comment, error) {
+	var out Comment
+	if err := c.call(ctx, request{method: http.MethodPut, path: commentPath(id), body: req}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteComment 删除自己的评论，includeChildren 为 true 时同时删除子评论
+func (c *Client) DeleteComment(ctx context.Context, id uint, includeChildren bool) error {
+	return c.call(ctx, request{method: http.MethodDelete, path: commentPath(id), query: childrenQuery(includeChildren)}, nil)
+}
+
+// RestoreComment 恢复已删除的评论，includeChildren 为 true 时同时恢复子评论
+func (c *Client) RestoreComment(ctx context.Context, id uint, includeChildren bool) error {
+	return c.call(ctx, request{method: http.MethodPost, path: commentPath(id) + "/restore", query: childrenQuery(includeChildren)}, nil)
+}
+
+func commentPath(id uint) string {
+	return "/comments/" + strconv.FormatUint(uint64(id), 10)
+}
+
+func childrenQuery(includeChildren bool) url.Values {
+	if !includeChildren {
+		return nil
+	}
+	return url.Values{"includeChildren": {"true"}}
+}
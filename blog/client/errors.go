@@ -0,0 +1,103 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 按状态码分类的错误，可用 errors.Is 判断 *Error 属于哪一类
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+
+	// ErrMFARequired 账号开启了两步验证，需调用 LoginMFA 完成登录
+	ErrMFARequired = errors.New("mfa required")
+	// ErrInvalidBaseURL 服务地址不是合法的绝对 URL
+	ErrInvalidBaseURL = errors.New("invalid base url")
+)
+
+// Error 服务端返回的错误响应
+// 用户类接口返回 {code, msg}，文章、评论与附件接口返回 {message}，认证与限流等中间件返回 {error}，统一解析到 Message
+type Error struct {
+	StatusCode int
+	Message    string
+	// RequiredScopes 个人访问令牌权限不足时所需的权限范围
+	RequiredScopes []string
+	// RetryAfter 限流或账号锁定时服务端建议的等待时间
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("blog: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("blog: %d %s", e.StatusCode, e.Message)
+}
+
+// Is 按状态码匹配 ErrNotFound 等分类错误
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// MFARequiredError 登录的账号开启了两步验证，MFAToken 用于调用 LoginMFA
+type MFARequiredError struct {
+	MFAToken string
+}
+
+func (e *MFARequiredError) Error() string {
+	return "blog: " + ErrMFARequired.Error()
+}
+
+func (e *MFARequiredError) Is(target error) bool {
+	return target == ErrMFARequired
+}
+
+func newError(resp *http.Response, body []byte) *Error {
+	e := &Error{StatusCode: resp.StatusCode}
+
+	var payload struct {
+		Msg            string   `json:"msg"`
+		Message        string   `json:"message"`
+		Error          string   `json:"error"`
+		RequiredScopes []string `json:"required_scopes"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		for _, m := range []string{payload.Msg, payload.Message, payload.Error} {
+			if m != "" {
+				e.Message = m
+				break
+			}
+		}
+		e.RequiredScopes = payload.RequiredScopes
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return e
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreatePost 发表文章
+func (c *Client) CreatePost(ctx context.Context, req CreatePostRequest) (*Post, error) {
+	var out Post
+	if err := c.call(ctx, request{method: http.MethodPost, path: "/posts", body: req}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPost 文章详情
+func (c *Client) GetPost(ctx context.Context, id uint) (*Post, error) {
+	var out Post
+	if err := c.call(ctx, request{method: http.MethodGet, path: postPath(id)}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPosts 分页获取文章列表，page 与 pageSize 为 0 时使用服务端默认值
+func (c *Client) ListPosts(ctx context.Context, page, pageSize int) (*PostListResponse, error) {
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if pageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(pageSize))
+	}
+
+	var out PostListResponse
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/posts", query: query}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePost 修改自己的文章，空字段不修改
+func (c *Client) UpdatePost(ctx context.Context, id uint, req UpdatePostRequest) (*Post, error) {
+	var out Post
+	if err := c.call(ctx, request{method: http.MethodPut, path: postPath(id), body: req}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeletePost 删除自己的文章
+func (c *Client) DeletePost(ctx context.Context, id uint) error {
+	return c.call(ctx, request{method: http.MethodDelete, path: postPath(id)}, nil)
+}
+
+func postPath(id uint) string {
+	return "/posts/" + strconv.FormatUint(uint64(id), 10)
+}
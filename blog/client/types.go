@@ -0,0 +1,27 @@
+package client
+
+import "web-task/blog/dto"
+
+// 服务端请求与响应结构体的别名，定义在只依赖标准库的 dto 包中，引用客户端不会引入服务端依赖
+type (
+	RegisterRequest          = dto.RegisterRequest
+	RegisterResponse         = dto.RegisterResponse
+	Me                       = dto.Me
+	PublicProfileResponse    = dto.PublicProfileResponse
+	UpdateProfileRequest     = dto.UpdateProfileRequest
+	ChangePasswordRequest    = dto.ChangePasswordRequest
+	CreateAccessTokenRequest = dto.CreateAccessTokenRequest
+	AccessTokenResponse      = dto.AccessTokenResponse
+
+	CreatePostRequest    = dto.CreatePostRequest
+	UpdatePostRequest    = dto.UpdatePostRequest
+	PostListResponse     = dto.PostListResponse
+	CreateCommentRequest = dto.CreateCommentRequest
+	UpdateCommentRequest = dto.UpdateCommentRequest
+
+	PublicUser = dto.PublicUser
+	Post       = dto.Post
+	Attachment = dto.Attachment
+	Tag        = dto.Tag
+	Comment    = dto.Comment
+)
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"web-task/blog/dto"
+)
+
+// Register 注册账号，注册后需另行登录
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (*RegisterResponse, error) {
+	var out RegisterResponse
+	if err := c.call(ctx, request{method: http.MethodPost, path: "/users/register", body: req, public: true, envelope: true}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Login 登录并保存令牌，之后的请求自动携带
+// 账号开启两步验证时返回 *MFARequiredError（errors.Is(err, ErrMFARequired)），用其中的 MFAToken 调用 LoginMFA
+func (c *Client) Login(ctx context.Context, username, password string) error {
+	var out struct {
+		dto.TokenResponse
+		dto.MFAChallengeResponse
+	}
+	req := dto.LoginRequest{Username: username, Password: password}
+	if err := c.call(ctx, request{method: http.MethodPost, path: "/users/login", body: req, public: true, envelope: true}, &out); err != nil {
+		return err
+	}
+	if out.MFARequired {
+		return &MFARequiredError{MFAToken: out.MFAToken}
+	}
+	c.SetToken(out.Token)
+	return nil
+}
+
+// LoginMFA 提交两步验证码或恢复码完成登录并保存令牌
+func (c *Client) LoginMFA(ctx context.Context, mfaToken, code string) error {
+	var out dto.TokenResponse
+	req := dto.MFALoginRequest{MFAToken: mfaToken, Code: code}
+	if err := c.call(ctx, request{method: http.MethodPost, path: "/users/login/mfa", body: req, public: true, envelope: true}, &out); err != nil {
+		return err
+	}
+	c.SetToken(out.Token)
+	return nil
+}
+
+// Me 当前用户资料
+func (c *Client) Me(ctx context.Context) (*Me, error) {
+	var out Me
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/users/me", envelope: true}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateMe 修改当前用户资料，未设置的字段不修改
+func (c *Client) UpdateMe(ctx context.Context, req UpdateProfileRequest) (*Me, error) {
+	var out Me
+	if err := c.call(ctx, request{method: http.MethodPatch, path: "/users/me", body: req, envelope: true}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ChangePassword 修改密码，旧令牌随之失效，改用服务端签发的新令牌
+func (c *Client) ChangePassword(ctx context.Context, req ChangePasswordRequest) error {
+	var out dto.TokenResponse
+	if err := c.call(ctx, request{method: http.MethodPost, path: "/users/me/password", body: req, envelope: true}, &out); err != nil {
+		return err
+	}
+	c.SetToken(out.Token)
+	return nil
+}
+
+// UserProfile 用户公开资料
+func (c *Client) UserProfile(ctx context.Context, username string) (*PublicProfileResponse, error) {
+	var out PublicProfileResponse
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/users/" + url.PathEscape(username), public: true, envelope: true}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateAccessToken 创建个人访问令牌，明文令牌只在返回值中出现一次
+func (c *Client) CreateAccessToken(ctx context.Context, req CreateAccessTokenRequest) (*AccessTokenResponse, error) {
+	var out AccessTokenResponse
+	if err := c.call(ctx, request{method: http.MethodPost, path: "/users/me/tokens", body: req, envelope: true}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAccessTokens 当前用户的个人访问令牌
+func (c *Client) ListAccessTokens(ctx context.Context) ([]AccessTokenResponse, error) {
+	var out []AccessTokenResponse
+	if err := c.call(ctx, request{method: http.MethodGet, path: "/users/me/tokens", envelope: true}, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RevokeAccessToken 撤销个人访问令牌
+func (c *Client) RevokeAccessToken(ctx context.Context, id uint) error {
+	return c.call(ctx, request{method: http.MethodDelete, path: "/users/me/tokens/" + strconv.FormatUint(uint64(id), 10), envelope: true}, nil)
+}
@@ -0,0 +1,78 @@
+// import 命令：将 Markdown 目录、Hugo/Jekyll 站点或 WordPress 导出文件批量导入博客
+//
+// 用法：
+//
+//	go run ./blog/cmd/import -author admin -map wp_alice=alice -dry-run ./site
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"web-task/blog/internal/importer"
+	"web-task/blog/internal/logic"
+	"web-task/blog/utility"
+)
+
+// authorMap 可重复的 -map 来源作者=本地用户名 参数
+type authorMap map[string]string
+
+func (m authorMap) String() string { return fmt.Sprint(map[string]string(m)) }
+
+func (m authorMap) Set(v string) error {
+	from, to, ok := strings.Cut(v, "=")
+	if !ok || from == "" || to == "" {
+		return errors.New("expected source_author=local_username")
+	}
+	m[from] = to
+	return nil
+}
+
+func main() {
+	authors := authorMap{}
+	format := flag.String("format", importer.FormatAuto, "auto, markdown, hugo, jekyll or wordpress")
+	source := flag.String("source", "", "source label used to match previously imported posts (default: the format)")
+	defaultAuthor := flag.String("author", "", "local username for posts whose author cannot be matched")
+	dryRun := flag.Bool("dry-run", false, "report what would be imported without writing")
+	flag.Var(authors, "map", "map a source author (login or email) to a local username, e.g. -map wp_alice=alice; repeatable")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <directory | export.xml>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	docs, resolved, err := importer.LoadPath(flag.Arg(0), *format)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", flag.Arg(0), err)
+	}
+	if *source == "" {
+		*source = resolved
+	}
+
+	utility.InitLogger()
+	utility.InitDB()
+	report, err := logic.NewImportService(utility.DB).Import(context.Background(), docs, logic.ImportOptions{
+		Source:        *source,
+		DefaultAuthor: *defaultAuthor,
+		AuthorMap:     authors,
+		DryRun:        *dryRun,
+	})
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	}
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+// migrate 命令：查看或执行数据库迁移，用于关闭 BLOG_AUTO_MIGRATE 的部署在发布前单独迁移
+//
+// 用法：
+//
+//	go run ./blog/cmd/migrate status
+//	go run ./blog/cmd/migrate up
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"web-task/blog/utility"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s status|up\n", os.Args[0])
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	utility.InitLogger()
+	utility.ConnectDB()
+	ctx := context.Background()
+
+	switch flag.Arg(0) {
+	case "status":
+		applied, err := utility.Migrator.Applied(ctx)
+		if err != nil {
+			log.Fatalf("Failed to query migrations: %v", err)
+		}
+		for _, m := range applied {
+			fmt.Printf("applied  %s  %s\n", m.Version, m.AppliedAt.Format("2006-01-02 15:04:05"))
+		}
+		pending, err := utility.Migrator.Pending(ctx)
+		if err != nil {
+			log.Fatalf("Failed to query migrations: %v", err)
+		}
+		for _, m := range pending {
+			fmt.Printf("pending  %s  %s\n", m.Version, m.Description)
+		}
+		if len(pending) > 0 {
+			os.Exit(1)
+		}
+	case "up":
+		applied, err := utility.Migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied  %s\n", m.Version)
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
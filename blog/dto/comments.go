@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+// CreateCommentRequest 发表评论请求参数结构体，ParentID 为同一文章下的顶级评论时作为回复
+type CreateCommentRequest struct {
+	Content  string `json:"content" binding:"required"`
+	ParentID uint   `json:"parent_id"`
+}
+
+// UpdateCommentRequest 修改评论请求参数结构体
+type UpdateCommentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// Comment 评论详情，Post 只在查看单条评论时返回
+type Comment struct {
+	ID        uint       `json:"id"`
+	Content   string     `json:"content"`
+	UserID    uint       `json:"user_id"`
+	User      PublicUser `json:"user"`
+	PostID    uint       `json:"post_id"`
+	ParentID  *uint      `json:"parent_id"`
+	Post      *Post      `json:"post,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,4 @@
+// Package dto 接口的请求与响应结构体，由服务端 controller 与 Go 客户端共用
+//
+// 本包只依赖标准库，引用客户端的服务不会因此引入 gin、gorm 等服务端依赖
+package dto
@@ -0,0 +1,55 @@
+package dto
+
+import "time"
+
+// CreatePostRequest 发表文章请求参数结构体
+type CreatePostRequest struct {
+	Title   string `json:"title" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
+
+// UpdatePostRequest 修改文章请求参数结构体，空字段不修改
+type UpdatePostRequest struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// Post 文章详情
+type Post struct {
+	ID          uint         `json:"id"`
+	Title       string       `json:"title"`
+	Content     string       `json:"content"`
+	UserID      uint         `json:"user_id"`
+	User        PublicUser   `json:"user"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	Tags        []Tag        `json:"tags,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// Attachment 文章附件
+type Attachment struct {
+	ID        uint      `json:"id"`
+	PostID    *uint     `json:"post_id"`
+	UserID    uint      `json:"user_id"`
+	Filename  string    `json:"filename"`
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	Width     int       `json:"width,omitempty"`
+	Height    int       `json:"height,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Tag 文章标签
+type Tag struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// PostListResponse 文章分页列表
+type PostListResponse struct {
+	Total    int64  `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+	Posts    []Post `json:"posts"`
+}
@@ -0,0 +1,107 @@
+package dto
+
+import "time"
+
+// RegisterRequest 注册请求参数结构体
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=20"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// LoginRequest 登录请求参数结构体
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// MFALoginRequest 两步验证登录请求参数结构体，Code 可以是 6 位验证码或恢复码
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// RegisterResponse 注册成功返回的账号信息
+type RegisterResponse struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// TokenResponse 登录成功或修改密码后签发的令牌
+type TokenResponse struct {
+	Token string `json:"token"`
+}
+
+// MFAChallengeResponse 开启两步验证的账号登录时返回的挑战令牌，需继续调用 /users/login/mfa
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// Me 当前用户自己的资料，只由 /users/me 返回；邮箱、角色与两步验证状态不出现在其他接口中
+type Me struct {
+	ID              uint       `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	DisplayName     string     `json:"display_name"`
+	Bio             string     `json:"bio"`
+	AvatarURL       string     `json:"avatar_url"`
+	Role            string     `json:"role"`
+	TOTPEnabled     bool       `json:"totp_enabled"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// PublicProfileResponse 公开资料
+type PublicProfileResponse struct {
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	PostCount   int64     `json:"post_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PublicUser 文章与评论中嵌套返回的作者信息，只包含公开资料
+type PublicUser struct {
+	ID          uint      `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// UpdateProfileRequest 修改资料请求参数结构体，未提供的字段不修改
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name" binding:"omitempty,max=50"`
+	Bio         *string `json:"bio" binding:"omitempty,max=500"`
+	AvatarURL   *string `json:"avatar_url" binding:"omitempty,max=255"`
+	Email       *string `json:"email" binding:"omitempty,email"`
+}
+
+// ChangePasswordRequest 修改密码请求参数结构体
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// CreateAccessTokenRequest 创建个人访问令牌请求参数结构体
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1"` // 不填默认 90 天
+}
+
+// AccessTokenResponse 个人访问令牌信息，明文令牌只在创建时返回
+type AccessTokenResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Token      string     `json:"token,omitempty"`
+}
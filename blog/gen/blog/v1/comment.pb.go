@@ -0,0 +1,597 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: blog/v1/comment.proto
+
+package blogv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CreateCommentRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	PostId  uint32                 `protobuf:"varint,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	Content string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	// 回复同一文章下的顶级评论，0 表示发表顶级评论
+	ParentId      uint32 `protobuf:"varint,3,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateCommentRequest) Reset() {
+	*x = CreateCommentRequest{}
+	mi := &file_blog_v1_comment_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateCommentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateCommentRequest) ProtoMessage() {}
+
+func (x *CreateCommentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_v1_comment_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateCommentRequest.ProtoReflect.Descriptor instead.
+func (*CreateCommentRequest) Descriptor() ([]byte, []int) {
+	return file_blog_v1_comment_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CreateCommentRequest) GetPostId() uint32 {
+	if x != nil {
+		return x.PostId
+	}
+	return 0
+}
+
+func (x *CreateCommentRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *CreateCommentRequest) GetParentId() uint32 {
+	if x != nil {
+		return x.ParentId
+	}
+	return 0
+}
+
+type CreateCommentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Comment       *Comment               `protobuf:"bytes,1,opt,name=comment,proto3" json:"comment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateCommentResponse) Reset() {
+	*x = CreateCommentResponse{}
+	mi := &file_blog_v1_comment_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateCommentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateCommentResponse) ProtoMessage() {}
+
+func (x *CreateCommentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_v1_comment_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateCommentResponse.ProtoReflect.Descriptor instead.
+func (*CreateCommentResponse) Descriptor() ([]byte, []int) {
+	return file_blog_v1_comment_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateCommentResponse) GetComment() *Comment {
+	if x != nil {
+		return x.Comment
+	}
+	return nil
+}
+
+type GetCommentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCommentRequest) Reset() {
+	*x = GetCommentRequest{}
+	mi := &file_blog_v1_comment_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCommentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCommentRequest) ProtoMessage() {}
+
+func (x *GetCommentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_v1_comment_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCommentRequest.ProtoReflect.Descriptor instead.
+func (*GetCommentRequest) Descriptor() ([]byte, []int) {
+	return file_blog_v1_comment_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetCommentRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type GetCommentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Comment       *Comment               `protobuf:"bytes,1,opt,name=comment,proto3" json:"comment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCommentResponse) Reset() {
+	*x = GetCommentResponse{}
+	mi := &file_blog_v1_comment_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCommentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCommentResponse) ProtoMessage() {}
+
+func (x *GetCommentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_v1_comment_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCommentResponse.ProtoReflect.Descriptor instead.
+func (*GetCommentResponse) Descriptor() ([]byte, []int) {
+	return file_blog_v1_comment_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetCommentResponse) GetComment() *Comment {
+	if x != nil {
+		return x.Comment
+	}
+	return nil
+}
+
+type UpdateCommentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateCommentRequest) Reset() {
+	*x = UpdateCommentRequest{}
+	mi := &file_blog_v1_comment_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateCommentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateCommentRequest) ProtoMessage() {}
+
+func (x *UpdateCommentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_v1_comment_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateCommentRequest.ProtoReflect.Descriptor instead.
+func (*UpdateCommentRequest) Descriptor() ([]byte, []int) {
+	return file_blog_v1_comment_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UpdateCommentRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateCommentRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+type UpdateCommentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Comment       *Comment               `protobuf:"bytes,1,opt,name=comment,proto3" json:"comment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateCommentResponse) Reset() {
+	*x = UpdateCommentResponse{}
+	mi := &file_blog_v1_comment_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateCommentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateCommentResponse) ProtoMessage() {}
+
+func (x *UpdateCommentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_v1_comment_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateCommentResponse.ProtoReflect.Descriptor instead.
+func (*UpdateCommentResponse) Descriptor() ([]byte, []int) {
+	return file_blog_v1_comment_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateCommentResponse) GetComment() *Comment {
+	if x != nil {
+		return x.Comment
+	}
+	return nil
+}
+
+type DeleteCommentRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// 是否同时删除子评论
+	IncludeChildren bool `protobuf:"varint,2,opt,name=include_children,json=includeChildren,proto3" json:"include_children,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *DeleteCommentRequest) Reset() {
+	*x = DeleteCommentRequest{}
+	mi := &file_blog_v1_comment_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteCommentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteCommentRequest) ProtoMessage() {}
+
+func (x *DeleteCommentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_v1_comment_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteCommentRequest.ProtoReflect.Descriptor instead.
+func (*DeleteCommentRequest) Descriptor() ([]byte, []int) {
+	return file_blog_v1_comment_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteCommentRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DeleteCommentRequest) GetIncludeChildren() bool {
+	if x != nil {
+		return x.IncludeChildren
+	}
+	return false
+}
+
+type DeleteCommentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteCommentResponse) Reset() {
+	*x = DeleteCommentResponse{}
+	mi := &file_blog_v1_comment_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteCommentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteCommentResponse) ProtoMessage() {}
+
+func (x *DeleteCommentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_v1_comment_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteCommentResponse.ProtoReflect.Descriptor instead.
+func (*DeleteCommentResponse) Descriptor() ([]byte, []int) {
+	return file_blog_v1_comment_proto_rawDescGZIP(), []int{7}
+}
+
+type RestoreCommentRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// 是否同时恢复子评论
+	IncludeChildren bool `protobuf:"varint,2,opt,name=include_children,json=includeChildren,proto3" json:"include_children,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RestoreCommentRequest) Reset() {
+	*x = RestoreCommentRequest{}
+	mi := &file_blog_v1_comment_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreCommentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreCommentRequest) ProtoMessage() {}
+
+func (x *RestoreCommentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_v1_comment_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreCommentRequest.ProtoReflect.Descriptor instead.
+func (*RestoreCommentRequest) Descriptor() ([]byte, []int) {
+	return file_blog_v1_comment_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RestoreCommentRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *RestoreCommentRequest) GetIncludeChildren() bool {
+	if x != nil {
+		return x.IncludeChildren
+	}
+	return false
+}
+
+type RestoreCommentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreCommentResponse) Reset() {
+	*x = RestoreCommentResponse{}
+	mi := &file_blog_v1_comment_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreCommentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreCommentResponse) ProtoMessage() {}
+
+func (x *RestoreCommentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blog_v1_comment_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreCommentResponse.ProtoReflect.Descriptor instead.
+func (*RestoreCommentResponse) Descriptor() ([]byte, []int) {
+	return file_blog_v1_comment_proto_rawDescGZIP(), []int{9}
+}
+
+var File_blog_v1_comment_proto protoreflect.FileDescriptor
+
+const file_blog_v1_comment_proto_rawDesc = "" +
+	"\n" +
+	"\x15blog/v1/comment.proto\x12\ablog.v1\x1a\x13blog/v1/types.proto\"f\n" +
+	"\x14CreateCommentRequest\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\rR\x06postId\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12\x1b\n" +
+	"\tparent_id\x18\x03 \x01(\rR\bparentId\"C\n" +
+	"\x15CreateCommentResponse\x12*\n" +
+	"\acomment\x18\x01 \x01(\v2\x10.blog.v1.CommentR\acomment\"#\n" +
+	"\x11GetCommentRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"@\n" +
+	"\x12GetCommentResponse\x12*\n" +
+	"\acomment\x18\x01 \x01(\v2\x10.blog.v1.CommentR\acomment\"@\n" +
+	"\x14UpdateCommentRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"C\n" +
+	"\x15UpdateCommentResponse\x12*\n" +
+	"\acomment\x18\x01 \x01(\v2\x10.blog.v1.CommentR\acomment\"Q\n" +
+	"\x14DeleteCommentRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12)\n" +
+	"\x10include_children\x18\x02 \x01(\bR\x0fincludeChildren\"\x17\n" +
+	"\x15DeleteCommentResponse\"R\n" +
+	"\x15RestoreCommentRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12)\n" +
+	"\x10include_children\x18\x02 \x01(\bR\x0fincludeChildren\"\x18\n" +
+	"\x16RestoreCommentResponse2\x9a\x03\n" +
+	"\x0eCommentService\x12N\n" +
+	"\rCreateComment\x12\x1d.blog.v1.CreateCommentRequest\x1a\x1e.blog.v1.CreateCommentResponse\x12E\n" +
+	"\n" +
+	"GetComment\x12\x1a.blog.v1.GetCommentRequest\x1a\x1b.blog.v1.GetCommentResponse\x12N\n" +
+	"\rUpdateComment\x12\x1d.blog.v1.UpdateCommentRequest\x1a\x1e.blog.v1.UpdateCommentResponse\x12N\n" +
+	"\rDeleteComment\x12\x1d.blog.v1.DeleteCommentRequest\x1a\x1e.blog.v1.DeleteCommentResponse\x12Q\n" +
+	"\x0eRestoreComment\x12\x1e.blog.v1.RestoreCommentRequest\x1a\x1f.blog.v1.RestoreCommentResponseB\"Z web-task/blog/gen/blog/v1;blogv1b\x06proto3"
+
+var (
+	file_blog_v1_comment_proto_rawDescOnce sync.Once
+	file_blog_v1_comment_proto_rawDescData []byte
+)
+
+func file_blog_v1_comment_proto_rawDescGZIP() []byte {
+	file_blog_v1_comment_proto_rawDescOnce.Do(func() {
+		file_blog_v1_comment_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_blog_v1_comment_proto_rawDesc), len(file_blog_v1_comment_proto_rawDesc)))
+	})
+	return file_blog_v1_comment_proto_rawDescData
+}
+
+var file_blog_v1_comment_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_blog_v1_comment_proto_goTypes = []any{
+	(*CreateCommentRequest)(nil),   // 0: blog.v1.CreateCommentRequest
+	(*CreateCommentResponse)(nil),  // 1: blog.v1.CreateCommentResponse
+	(*GetCommentRequest)(nil),      // 2: blog.v1.GetCommentRequest
+	(*GetCommentResponse)(nil),     // 3: blog.v1.GetCommentResponse
+	(*UpdateCommentRequest)(nil),   // 4: blog.v1.UpdateCommentRequest
+	(*UpdateCommentResponse)(nil),  // 5: blog.v1.UpdateCommentResponse
+	(*DeleteCommentRequest)(nil),   // 6: blog.v1.DeleteCommentRequest
+	(*DeleteCommentResponse)(nil),  // 7: blog.v1.DeleteCommentResponse
+	(*RestoreCommentRequest)(nil),  // 8: blog.v1.RestoreCommentRequest
+	(*RestoreCommentResponse)(nil), // 9: blog.v1.RestoreCommentResponse
+	(*Comment)(nil),                // 10: blog.v1.Comment
+}
+var file_blog_v1_comment_proto_depIdxs = []int32{
+	10, // 0: blog.v1.CreateCommentResponse.comment:type_name -> blog.v1.Comment
+	10, // 1: blog.v1.GetCommentResponse.comment:type_name -> blog.v1.Comment
+	10, // 2: blog.v1.UpdateCommentResponse.comment:type_name -> blog.v1.Comment
+	0,  // 3: blog.v1.CommentService.CreateComment:input_type -> blog.v1.CreateCommentRequest
+	2,  // 4: blog.v1.CommentService.GetComment:input_type -> blog.v1.GetCommentRequest
+	4,  // 5: blog.v1.CommentService.UpdateComment:input_type -> blog.v1.UpdateCommentRequest
+	6,  // 6: blog.v1.CommentService.DeleteComment:input_type -> blog.v1.DeleteCommentRequest
+	8,  // 7: blog.v1.CommentService.RestoreComment:input_type -> blog.v1.RestoreCommentRequest
+	1,  // 8: blog.v1.CommentService.CreateComment:output_type -> blog.v1.CreateCommentResponse
+	3,  // 9: blog.v1.CommentService.GetComment:output_type -> blog.v1.GetCommentResponse
+	5,  // 10: blog.v1.CommentService.UpdateComment:output_type -> blog.v1.UpdateCommentResponse
+	7,  // 11: blog.v1.CommentService.DeleteComment:output_type -> blog.v1.DeleteCommentResponse
+	9,  // 12: blog.v1.CommentService.RestoreComment:output_type -> blog.v1.RestoreCommentResponse
+	8,  // [8:13] is the sub-list for method output_type
+	3,  // [3:8] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_blog_v1_comment_proto_init() }
+func file_blog_v1_comment_proto_init() {
+	if File_blog_v1_comment_proto != nil {
+		return
+	}
+	file_blog_v1_types_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_blog_v1_comment_proto_rawDesc), len(file_blog_v1_comment_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_blog_v1_comment_proto_goTypes,
+		DependencyIndexes: file_blog_v1_comment_proto_depIdxs,
+		MessageInfos:      file_blog_v1_comment_proto_msgTypes,
+	}.Build()
+	File_blog_v1_comment_proto = out.File
+	file_blog_v1_comment_proto_goTypes = nil
+	file_blog_v1_comment_proto_depIdxs = nil
+}
@@ -0,0 +1,117 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// NewRequest 构造请求；body 为 nil 时不带请求体，string 或 []byte 原样发送，其它值编码为 JSON
+// token 非空时设置 Authorization: Bearer <token>
+func (s *Server) NewRequest(method, path, token string, body any) *http.Request {
+	s.t.Helper()
+
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = bytes.NewBufferString(b)
+	case []byte:
+		r = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			s.t.Fatalf("apitest: encode request body: %v", err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, r)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+// Serve 把请求交给路由处理并返回记录的响应
+func (s *Server) Serve(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.Engine.ServeHTTP(w, req)
+	return w
+}
+
+// Do 发送请求，参数含义同 NewRequest
+func (s *Server) Do(method, path, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+	return s.Serve(s.NewRequest(method, path, token, body))
+}
+
+// Multipart 构造 multipart/form-data 请求，file 非空时以 filename 作为 file 字段上传
+func (s *Server) Multipart(method, path, token string, fields map[string]string, filename string, file []byte) *http.Request {
+	s.t.Helper()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			s.t.Fatalf("apitest: write form field: %v", err)
+		}
+	}
+	if filename != "" {
+		fw, err := mw.CreateFormFile("file", filename)
+		if err != nil {
+			s.t.Fatalf("apitest: create form file: %v", err)
+		}
+		fw.Write(file)
+	}
+	if err := mw.Close(); err != nil {
+		s.t.Fatalf("apitest: close multipart writer: %v", err)
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+// Envelope 用户、第三方登录、导出等接口统一的 {code, msg, data} 响应
+type Envelope[T any] struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data T      `json:"data"`
+}
+
+// DecodeJSON 解析响应体
+func DecodeJSON[T any](t testing.TB, w *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("apitest: decode response %q: %v", w.Body.String(), err)
+	}
+	return v
+}
+
+// DecodeData 解析 {code, msg, data} 响应中的 data
+func DecodeData[T any](t testing.TB, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	return DecodeJSON[Envelope[T]](t, w).Data
+}
+
+// ExpectStatus 响应状态码不符时终止测试并输出响应体
+func ExpectStatus(t testing.TB, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", w.Code, status, w.Body.String())
+	}
+}
@@ -0,0 +1,120 @@
+package apitest
+
+import (
+	"fmt"
+	"net/http"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+)
+
+// User 经接口注册的用户，Token 为登录得到的 JWT
+type User struct {
+	ID       uint
+	Username string
+	Email    string
+	Password string
+	Token    string
+}
+
+// Register 注册用户，邮箱为 <username>@example.com，密码为 Password
+func (s *Server) Register(username string) *User {
+	s.t.Helper()
+
+	u := &User{Username: username, Email: username + "@example.com", Password: Password}
+	w := s.Do(http.MethodPost, "/api/v1/users/register", "", map[string]string{
+		"username": u.Username,
+		"email":    u.Email,
+		"password": u.Password,
+	})
+	ExpectStatus(s.t, w, http.StatusOK)
+	u.ID = DecodeData[struct {
+		ID uint `json:"id"`
+	}](s.t, w).ID
+	return u
+}
+
+// Login 登录并返回令牌，账号开启两步验证时测试失败
+func (s *Server) Login(username, password string) string {
+	s.t.Helper()
+
+	w := s.Do(http.MethodPost, "/api/v1/users/login", "", map[string]string{
+		"username": username,
+		"password": password,
+	})
+	ExpectStatus(s.t, w, http.StatusOK)
+	data := DecodeData[struct {
+		Token       string `json:"token"`
+		MFARequired bool   `json:"mfa_required"`
+	}](s.t, w)
+	if data.MFARequired || data.Token == "" {
+		s.t.Fatalf("apitest: login %s: no token in %s", username, w.Body.String())
+	}
+	return data.Token
+}
+
+// CreateUser 注册并登录普通用户
+func (s *Server) CreateUser(username string) *User {
+	s.t.Helper()
+
+	u := s.Register(username)
+	u.Token = s.Login(u.Username, u.Password)
+	return u
+}
+
+// CreateAdmin 注册用户并设为管理员后登录，令牌中带有管理员角色
+func (s *Server) CreateAdmin(username string) *User {
+	s.t.Helper()
+
+	u := s.Register(username)
+	if err := s.DB.Model(&model.User{}).Where("id = ?", u.ID).Update("role", consts.RoleAdmin).Error; err != nil {
+		s.t.Fatalf("apitest: promote %s: %v", username, err)
+	}
+	u.Token = s.Login(u.Username, u.Password)
+	return u
+}
+
+// VerifyEmail 使用验证邮件中的令牌验证用户邮箱
+func (s *Server) VerifyEmail(u *User) {
+	s.t.Helper()
+
+	w := s.Do(http.MethodPost, "/api/v1/users/verify-email", "", map[string]string{"token": s.Mailbox.Token(u.Email)})
+	ExpectStatus(s.t, w, http.StatusOK)
+}
+
+// AccessToken 为用户创建指定权限范围的个人访问令牌
+func (s *Server) AccessToken(u *User, scopes ...string) string {
+	s.t.Helper()
+
+	w := s.Do(http.MethodPost, "/api/v1/users/me/tokens", u.Token, map[string]any{
+		"name":   fmt.Sprintf("token-%d", len(scopes)),
+		"scopes": scopes,
+	})
+	ExpectStatus(s.t, w, http.StatusCreated)
+	return DecodeData[struct {
+		Token string `json:"token"`
+	}](s.t, w).Token
+}
+
+// CreatePost 以用户身份发表文章
+func (s *Server) CreatePost(u *User, title, content string) model.Post {
+	s.t.Helper()
+
+	w := s.Do(http.MethodPost, "/api/v1/posts", u.Token, map[string]string{
+		"title":   title,
+		"content": content,
+	})
+	ExpectStatus(s.t, w, http.StatusCreated)
+	return DecodeJSON[model.Post](s.t, w)
+}
+
+// CreateComment 以用户身份评论文章
+func (s *Server) CreateComment(u *User, postID uint, content string) model.Comment {
+	s.t.Helper()
+
+	w := s.Do(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", postID), u.Token, map[string]string{
+		"content": content,
+	})
+	ExpectStatus(s.t, w, http.StatusCreated)
+	return DecodeJSON[model.Comment](s.t, w)
+}
@@ -0,0 +1,38 @@
+package apitest
+
+import (
+	"context"
+	"net/http/httptest"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// GRPC 启动 gRPC 端口上的服务并返回连接，测试结束时关闭
+func (s *Server) GRPC() *grpc.ClientConn {
+	s.t.Helper()
+
+	srv := httptest.NewServer(s.RPC.Handler())
+	s.t.Cleanup(srv.Close)
+	conn, err := grpc.NewClient(srv.Listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		s.t.Fatalf("apitest: grpc dial: %v", err)
+	}
+	s.t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// Gateway 经 grpc-gateway 发送 JSON 请求，参数与 Do 相同
+func (s *Server) Gateway(method, path, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	w := httptest.NewRecorder()
+	s.RPC.Handler().ServeHTTP(w, s.NewRequest(method, path, token, body))
+	return w
+}
+
+// WithToken 在调用的 metadata 中携带 Bearer 令牌
+func WithToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
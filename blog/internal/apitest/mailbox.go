@@ -0,0 +1,60 @@
+package apitest
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"sync"
+
+	"web-task/blog/internal/mailer"
+)
+
+// Mailbox 记录已发送邮件的 Mailer，用于从验证、找回密码邮件中取出令牌
+type Mailbox struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+// Send 实现 mailer.Mailer
+func (m *Mailbox) Send(ctx context.Context, msg mailer.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages 按发送顺序返回发给 to 的全部邮件
+func (m *Mailbox) Messages(to string) []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var result []mailer.Message
+	for _, msg := range m.messages {
+		if msg.To == to {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+var linkTokenPattern = regexp.MustCompile(`[?&]token=([^&\s"<]+)`)
+
+// Token 返回最近一封发给 to 的邮件中链接携带的令牌，没有时返回空串
+func (m *Mailbox) Token(to string) string {
+	messages := m.Messages(to)
+	if len(messages) == 0 {
+		return ""
+	}
+	match := linkTokenPattern.FindStringSubmatch(messages[len(messages)-1].Text)
+	if match == nil {
+		return ""
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		return ""
+	}
+	return token
+}
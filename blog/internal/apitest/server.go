@@ -0,0 +1,297 @@
+// Package apitest 启动完整的 API 供集成测试使用
+// 每个测试使用独立的 SQLite 内存数据库，经 api.SetupRouter 注册全部路由并按接口文档校验请求与响应，
+// 并提供发送请求、注册登录、获取令牌以及创建文章和评论的辅助方法
+package apitest
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-task/blog/api"
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/controller"
+	"web-task/blog/internal/graph"
+	"web-task/blog/internal/grpcapi"
+	"web-task/blog/internal/keyring"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/oidc"
+	"web-task/blog/internal/oidc/oidctest"
+	"web-task/blog/internal/openapi"
+	"web-task/blog/internal/pubsub"
+	"web-task/blog/internal/repository"
+	"web-task/blog/internal/storage"
+	"web-task/blog/middleware"
+	"web-task/blog/utility"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// AppURL 测试环境的站点地址，邮件链接与第三方登录回调均以此为前缀
+	AppURL = "http://blog.test"
+	// OIDCProvider 模拟第三方登录提供方的名称
+	OIDCProvider = "mock"
+	// Password 辅助方法注册用户时使用的密码
+	Password = "secret123"
+	// SigningKeySecret 加密数据库中 JWT 签名私钥的密钥
+	SigningKeySecret = "apitest-signing-key-secret-0123456789"
+)
+
+// dbSeq 为每个测试生成不同的内存数据库名，避免共享缓存的库互相干扰
+var dbSeq atomic.Int64
+
+// Server 一个测试专用的完整 API 实例
+// 中间件的令牌校验、会话校验与超时是包级配置，New 会覆盖它们，因此使用 Server 的测试不能并行执行
+type Server struct {
+	t testing.TB
+
+	Engine  *gin.Engine
+	Spec    *openapi.Spec
+	DB      *gorm.DB
+	Keys    *keyring.KeyRing
+	Mailbox *Mailbox
+	OIDC    *oidctest.Provider
+
+	Users    *logic.UserService
+	Posts    *logic.PostService
+	Exports  *logic.ExportService
+	Webhooks *logic.WebhookService
+	Health   *logic.HealthService
+
+	// Hub 实时推送的发布订阅，测试结束时关闭
+	Hub *pubsub.MemoryHub
+
+	// RPC gRPC 服务，经 GRPC 与 Gateway 访问
+	RPC *grpcapi.Server
+}
+
+type options struct {
+	policies        map[string]middleware.RateLimitPolicy
+	requestTimeout  time.Duration
+	transferTimeout time.Duration
+	metricsToken    string
+	trustedProxies  []string
+}
+
+// Option 调整测试实例的配置
+type Option func(*options)
+
+// WithRateLimit 替换同名的限流策略，用于测试限流；默认策略足够宽松，不会触发限流
+func WithRateLimit(name string, burst int, window time.Duration) Option {
+	return func(o *options) {
+		p := o.policies[name]
+		p.Rate = float64(burst) / window.Seconds()
+		p.Burst = burst
+		o.policies[name] = p
+	}
+}
+
+// WithTimeouts 设置普通请求与上传下载类请求的截止时间，默认不限制
+func WithTimeouts(request, transfer time.Duration) Option {
+	return func(o *options) {
+		o.requestTimeout = request
+		o.transferTimeout = transfer
+	}
+}
+
+// WithMetricsToken 要求抓取 /metrics 时携带该令牌
+func WithMetricsToken(token string) Option {
+	return func(o *options) {
+		o.metricsToken = token
+	}
+}
+
+// WithTrustedProxies 信任来自这些地址的转发头；默认与 main.go 相同，不信任任何代理
+// httptest 请求的对端地址为 192.0.2.1
+func WithTrustedProxies(proxies ...string) Option {
+	return func(o *options) {
+		o.trustedProxies = proxies
+	}
+}
+
+// New 创建数据库、执行迁移并按 main.go 的方式装配服务与路由，测试结束时自动释放资源
+func New(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	o := &options{policies: map[string]middleware.RateLimitPolicy{
+		consts.RateLimitLogin:         {KeyBy: middleware.KeyByIP},
+		consts.RateLimitCommentCreate: {KeyBy: middleware.KeyByUser},
+		consts.RateLimitReads:         {KeyBy: middleware.KeyByIP},
+		consts.RateLimitEmail:         {KeyBy: middleware.KeyByIP},
+		consts.RateLimitExport:        {KeyBy: middleware.KeyByUser},
+	}}
+	for name, p := range o.policies {
+		p.Name, p.Rate, p.Burst = name, 1000, 1000
+		o.policies[name] = p
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	ctx := context.Background()
+	db := openDB(t)
+
+	migrator := utility.NewMigrator(db)
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("apitest: migrate: %v", err)
+	}
+
+	keys, err := keyring.New(ctx, db, "ES256", []byte(SigningKeySecret), 24*time.Hour, 48*time.Hour, 0)
+	if err != nil {
+		t.Fatalf("apitest: keyring: %v", err)
+	}
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("apitest: storage: %v", err)
+	}
+
+	provider := oidctest.NewProvider()
+	t.Cleanup(provider.Close)
+	mock, err := oidc.NewProvider(ctx, provider.Config(OIDCProvider, AppURL+"/api/v1/auth/"+OIDCProvider+"/callback"), nil)
+	if err != nil {
+		t.Fatalf("apitest: oidc provider: %v", err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mailbox := &Mailbox{}
+
+	repos, uow := repository.NewGorm(db)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatalf("apitest: token secret: %v", err)
+	}
+	userService := logic.NewUserService(repos, uow, mailbox, keys, secret, AppURL, log)
+	// 测试不需要抗暴力破解的哈希强度，最低成本让 -race 下的用例也能在合理时间内完成
+	userService.PasswordCost = bcrypt.MinCost
+	postService := logic.NewPostService(repos, uow)
+	hub := pubsub.NewMemoryHub()
+	t.Cleanup(func() { hub.Close() })
+	commentService := logic.NewCommentService(repos, uow, hub)
+	uploadService := logic.NewUploadService(db, store)
+	identityService := logic.NewIdentityService(db, userService, map[string]*oidc.Provider{OIDCProvider: mock})
+	exportService := logic.NewExportService(db, store, userService, postService, commentService, log)
+	webhookService := logic.NewWebhookService(db, log)
+	// 测试的接收端是本机的 httptest 服务
+	webhookService.AllowPrivateTargets = true
+	healthService := logic.NewHealthService(db, migrator)
+	policies := make([]middleware.RateLimitPolicy, 0, len(o.policies))
+	for _, p := range o.policies {
+		policies = append(policies, p)
+	}
+	limiter := middleware.NewRateLimiter(middleware.NewMemoryRateLimitStore(), policies...)
+
+	rpcServer, err := grpcapi.NewServer(userService, postService, commentService, limiter, log)
+	if err != nil {
+		t.Fatalf("apitest: grpc: %v", err)
+	}
+	t.Cleanup(rpcServer.Close)
+	graphServer, err := graph.NewServer(userService, postService, commentService, limiter, graph.Limits{
+		MaxDepth:          consts.GraphQLMaxDepth,
+		MaxComplexity:     consts.GraphQLMaxComplexity,
+		MaxMutationFields: consts.GraphQLMaxMutationFields,
+	})
+	if err != nil {
+		t.Fatalf("apitest: graphql: %v", err)
+	}
+
+	middleware.UseKeyRing(keys)
+	middleware.UseAccessTokens(userService)
+	middleware.UseSessions(userService)
+	middleware.UseMetricsToken(o.metricsToken)
+	middleware.UseTimeouts(o.requestTimeout, o.transferTimeout)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(o.trustedProxies); err != nil {
+		t.Fatalf("apitest: trusted proxies: %v", err)
+	}
+	r.Use(middleware.RequestID(), middleware.Tracing(), middleware.AccessLog(log), middleware.Metrics(), middleware.Recovery(log), middleware.Timeout())
+	// 处理器与接口文档不一致时响应改为 500，由各测试的状态码断言暴露出来
+	spec := controller.NewSpec(r)
+	r.Use(middleware.ValidateOpenAPI(spec))
+	r.MaxMultipartMemory = 8 << 20
+
+	api.SetupRouter(r,
+		controller.NewUserController(userService),
+		controller.NewPostHandler(postService),
+		controller.NewCommentHandler(commentService),
+		controller.NewUploadHandler(uploadService),
+		controller.NewIdentityController(identityService),
+		controller.NewJWKSHandler(keys),
+		controller.NewExportHandler(exportService),
+		controller.NewImportHandler(logic.NewImportService(db)),
+		controller.NewHealthHandler(healthService),
+		controller.NewOpenAPIHandler(spec),
+		controller.NewGraphQLHandler(graphServer),
+		controller.NewWebhookHandler(webhookService),
+		controller.NewNotificationHandler(logic.NewNotificationService(db, hub)),
+		limiter,
+	)
+	if err := spec.Build(); err != nil {
+		t.Fatalf("apitest: openapi: %v", err)
+	}
+
+	return &Server{
+		t:        t,
+		Engine:   r,
+		Spec:     spec,
+		DB:       db,
+		Keys:     keys,
+		Mailbox:  mailbox,
+		OIDC:     provider,
+		Users:    userService,
+		Posts:    postService,
+		Exports:  exportService,
+		Webhooks: webhookService,
+		Health:   healthService,
+		Hub:      hub,
+		RPC:      rpcServer,
+	}
+}
+
+// openDB 打开一个新的 SQLite 内存数据库，测试结束时关闭
+func openDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:apitest%d?mode=memory&cache=shared", dbSeq.Add(1))
+	db, err := gorm.Open(dialector{sqlite.Dialector{DSN: dsn}}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("apitest: open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("apitest: open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// dialector 模型的主键带有 auto_increment 标签，SQLite 驱动会在列类型中写入 PRIMARY KEY AUTOINCREMENT，
+// 与建表语句末尾的 PRIMARY KEY 子句重复导致建表失败；这里自增主键只声明为 integer，由末尾子句设为主键
+type dialector struct {
+	sqlite.Dialector
+}
+
+func (d dialector) DataTypeOf(field *schema.Field) string {
+	if field.PrimaryKey && field.AutoIncrement {
+		return "integer"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+func (d dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	m := d.Dialector.Migrator(db).(sqlite.Migrator)
+	m.Dialector = d
+	return m
+}
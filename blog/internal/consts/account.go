@@ -0,0 +1,19 @@
+package consts
+
+import "time"
+
+// 一次性账号令牌用途
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+const (
+	// VerifyEmailTokenTTL 邮箱验证链接有效期
+	VerifyEmailTokenTTL = 48 * time.Hour
+	// ResetPasswordTokenTTL 找回密码链接有效期
+	ResetPasswordTokenTTL = time.Hour
+)
+
+// AccountTokenSecretMinLength 一次性令牌签名密钥的最短长度（字节）
+const AccountTokenSecretMinLength = 32
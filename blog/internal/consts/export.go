@@ -0,0 +1,19 @@
+package consts
+
+import "time"
+
+const (
+	ExportStatusPending   = "pending"
+	ExportStatusRunning   = "running"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+
+	// ExportRetention 导出归档保留时长，过期后删除
+	ExportRetention = 7 * 24 * time.Hour
+	// ExportLinkTTL 下载链接有效期，每次查询任务状态都会生成新链接
+	ExportLinkTTL = 15 * time.Minute
+	// ExportStaleAfter 处于 running 状态超过该时长视为实例中断，重新排队
+	ExportStaleAfter = 30 * time.Minute
+	// ExportPollInterval 后台任务检查待处理任务与清理过期归档的间隔
+	ExportPollInterval = time.Minute
+)
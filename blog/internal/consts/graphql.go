@@ -0,0 +1,12 @@
+package consts
+
+// GraphQL 接口的限制
+const (
+	GraphQLMaxDepth      = 8    // 查询的最大嵌套深度
+	GraphQLMaxComplexity = 2000 // 查询的最大复杂度：每个字段计 1，列表字段的子字段按请求的条数倍乘
+	GraphQLPageSize      = 10   // posts 默认每页条数
+	GraphQLCommentsFirst = 20   // 文章的 comments 默认返回条数
+	GraphQLMaxListSize   = 50   // posts 每页与 comments 最多返回的条数
+
+	GraphQLMaxMutationFields = 5 // 一次变更操作最多包含的顶层字段数（含别名）
+)
@@ -0,0 +1,17 @@
+package consts
+
+// 导入结果中每篇文章的处理方式
+const (
+	ImportActionCreated   = "created"
+	ImportActionUpdated   = "updated"
+	ImportActionUnchanged = "unchanged"
+	ImportActionSkipped   = "skipped" // 草稿或本地已删除的文章
+	ImportActionFailed    = "failed"
+)
+
+const (
+	// ImportMaxSize 管理员上传的导入文件（WXR 或 zip）大小上限
+	ImportMaxSize = 100 << 20
+	// TagMaxLength 标签名最大长度
+	TagMaxLength = 64
+)
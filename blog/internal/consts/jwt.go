@@ -0,0 +1,22 @@
+package consts
+
+import "time"
+
+const (
+	// JWTAlgorithm 默认签名算法，可选 RS256、ES256、EdDSA
+	JWTAlgorithm = "RS256"
+	// JWTKeyRotationInterval 签名密钥使用期限，到期自动轮换
+	JWTKeyRotationInterval = 30 * 24 * time.Hour
+	// JWTKeyGrace 退役密钥继续用于验证的时长；新密钥过了发布等待期才接替签名，
+	// 旧密钥签发的最后一个令牌在退役后 JWTKeyPublishDelay + TokenTTL 才过期，宽限期不能比这更短
+	JWTKeyGrace = 2 * TokenTTL
+	// JWTKeyCheckInterval 后台检查轮换与同步其它实例密钥的间隔
+	JWTKeyCheckInterval = time.Minute
+	// JWTKeyPublishDelay 新密钥发布后开始签名前的等待时长，需大于实例同步间隔与 JWKS 缓存时长
+	JWTKeyPublishDelay = JWTKeyCheckInterval + JWKSCacheMaxAge
+	// JWKSCacheMaxAge JWKS 响应允许缓存的时长
+	JWKSCacheMaxAge = 5 * time.Minute
+)
+
+// JWTKeySecretMinLength 签名私钥加密密钥的最短长度（字节）
+const JWTKeySecretMinLength = 32
@@ -0,0 +1,27 @@
+package consts
+
+import "time"
+
+// 文章评论直播间推送的事件类型
+const (
+	LiveEventCommentCreated = "comment.created"
+	LiveEventCommentUpdated = "comment.updated"
+	LiveEventCommentDeleted = "comment.deleted"
+)
+
+const (
+	// LiveSubprotocol 浏览器无法为 WebSocket 设置请求头，令牌以子协议传递：
+	// Sec-WebSocket-Protocol: bearer, <token>，服务端选择 bearer 作为协商结果
+	LiveSubprotocol = "bearer"
+
+	// LiveSendBuffer 每个连接缓冲的待发送事件数，缓冲满时断开连接，由客户端重连后重新拉取评论
+	LiveSendBuffer = 64
+	// LiveWriteWait 单条消息的写超时
+	LiveWriteWait = 10 * time.Second
+	// LivePongWait 等待客户端 pong 的最长时间，超时视为连接已断开
+	LivePongWait = 60 * time.Second
+	// LivePingPeriod 发送 ping 的间隔，需小于 LivePongWait
+	LivePingPeriod = LivePongWait * 9 / 10
+	// LiveMaxMessageSize 客户端消息的大小上限；直播间只读，客户端消息只用于保活
+	LiveMaxMessageSize = 512
+)
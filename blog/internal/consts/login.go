@@ -0,0 +1,26 @@
+package consts
+
+import "time"
+
+const (
+	// RoleUser 普通用户角色
+	RoleUser = "user"
+	// RoleAdmin 管理员角色
+	RoleAdmin = "admin"
+
+	// TokenTTL 登录令牌有效期
+	TokenTTL = 24 * time.Hour
+
+	// LoginMaxFailuresPerAccount 同一账号连续失败多少次后锁定
+	LoginMaxFailuresPerAccount = 5
+	// LoginMaxFailuresPerIP 同一 IP 在 LoginIPWindow 内失败多少次后锁定
+	LoginMaxFailuresPerIP = 20
+	// LoginAccountWindow 统计账号连续失败次数的时间窗口
+	LoginAccountWindow = 24 * time.Hour
+	// LoginIPWindow 统计 IP 失败次数的时间窗口
+	LoginIPWindow = time.Hour
+	// LoginLockoutBase 首次锁定时长，之后每再达到一次阈值翻倍
+	LoginLockoutBase = time.Minute
+	// LoginLockoutMax 锁定时长上限
+	LoginLockoutMax = time.Hour
+)
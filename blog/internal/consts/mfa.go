@@ -0,0 +1,14 @@
+package consts
+
+import "time"
+
+const (
+	// TOTPIssuer 验证器应用中显示的发行方名称
+	TOTPIssuer = "Blog"
+	// MFAChallengeTTL 登录第一步通过后，提交两步验证码的时限
+	MFAChallengeTTL = 5 * time.Minute
+	// RecoveryCodeCount 每次生成的恢复码数量
+	RecoveryCodeCount = 10
+	// TokenTypeMFAChallenge 两步验证挑战令牌的 typ 声明，不能用于访问接口
+	TokenTypeMFAChallenge = "mfa_challenge"
+)
@@ -0,0 +1,30 @@
+package consts
+
+import "time"
+
+// 通知类型；站点目前没有关注与点赞功能，只有评论与回复会产生通知
+const (
+	// NotificationTypeComment 文章收到评论
+	NotificationTypeComment = "comment"
+	// NotificationTypeReply 评论收到回复
+	NotificationTypeReply = "reply"
+)
+
+const (
+	// NotificationExcerptLength 通知中内容摘要的最大字节数
+	NotificationExcerptLength = 200
+	// NotificationMaxPageSize 通知列表每页条数上限
+	NotificationMaxPageSize = 100
+
+	// NotificationStreamBuffer 每个推送连接缓冲的通知数，缓冲满时断开连接，由客户端重连后补齐
+	NotificationStreamBuffer = 32
+	// NotificationStreamHeartbeat 推送连接的心跳间隔，防止代理因空闲断开连接
+	NotificationStreamHeartbeat = 25 * time.Second
+	// NotificationStreamMaxAge 单个推送连接的最长时间，需短于 HTTP 服务的 WriteTimeout；
+	// 到期后服务端主动结束，客户端按 Last-Event-ID 重连
+	NotificationStreamMaxAge = 10 * time.Minute
+	// NotificationStreamRetry 建议客户端断开后的重连间隔
+	NotificationStreamRetry = 3 * time.Second
+	// NotificationStreamBacklog 重连时按 Last-Event-ID 补发的通知数上限
+	NotificationStreamBacklog = 100
+)
@@ -0,0 +1,12 @@
+package consts
+
+import "time"
+
+const (
+	// OAuthStateTTL 发起第三方登录到回调之间的时限
+	OAuthStateTTL = 10 * time.Minute
+	// OAuthStateCookie 发起授权时下发给浏览器的 state Cookie，回调时必须与查询参数中的 state 一致
+	OAuthStateCookie = "blog_oauth_state"
+	// OAuthStateCookiePath state Cookie 的路径，只随授权回调发送
+	OAuthStateCookiePath = "/api/v1/auth"
+)
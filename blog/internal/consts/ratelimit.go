@@ -0,0 +1,10 @@
+package consts
+
+// 限流策略名称，对应 utility.InitRateLimiter 中配置的各路由组策略
+const (
+	RateLimitLogin         = "login"          // 登录：按 IP 限制，防止暴力破解
+	RateLimitCommentCreate = "comment_create" // 发表评论：按用户限制，防止刷评论
+	RateLimitReads         = "reads"          // 只读接口：按 IP 限制
+	RateLimitEmail         = "email"          // 触发发信的接口：按 IP 限制，防止邮件轰炸
+	RateLimitExport        = "export"         // 发起数据导出：按用户限制，导出开销较大
+)
@@ -0,0 +1,23 @@
+package consts
+
+import "time"
+
+const (
+	// 个人访问令牌的权限范围；登录令牌（JWT）不受权限范围限制
+	ScopePostsRead     = "posts:read"
+	ScopePostsWrite    = "posts:write"
+	ScopeCommentsRead  = "comments:read"
+	ScopeCommentsWrite = "comments:write"
+
+	// AccessTokenPrefix 个人访问令牌的固定前缀，便于识别和密钥扫描
+	AccessTokenPrefix = "blogpat_"
+	// AccessTokenDefaultTTL 未指定有效期时的默认有效期
+	AccessTokenDefaultTTL = 90 * 24 * time.Hour
+	// AccessTokenMaxTTL 有效期上限
+	AccessTokenMaxTTL = 365 * 24 * time.Hour
+	// AccessTokenMaxPerUser 每个用户最多持有的有效令牌数
+	AccessTokenMaxPerUser = 50
+)
+
+// Scopes 全部可授予的权限范围
+var Scopes = []string{ScopePostsRead, ScopePostsWrite, ScopeCommentsRead, ScopeCommentsWrite}
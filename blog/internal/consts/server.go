@@ -0,0 +1,16 @@
+package consts
+
+import "time"
+
+const (
+	// ReadinessCheckTimeout 就绪检查中单项检查（数据库连通、迁移状态）的超时
+	ReadinessCheckTimeout = 2 * time.Second
+)
+
+// 就绪检查项的状态
+const (
+	CheckOK       = "ok"
+	CheckFailed   = "failed"
+	CheckPending  = "pending"
+	CheckDraining = "draining"
+)
@@ -0,0 +1,28 @@
+package consts
+
+import "time"
+
+const (
+	// UploadMaxSize 单个上传文件的大小上限（字节）
+	UploadMaxSize = 10 << 20
+	// UploadMaxPixels 图片的像素数上限（宽×高），解码前按文件头检查，
+	// 防止声明超大画布的小文件（解压炸弹）在解码时耗尽内存
+	UploadMaxPixels = 40_000_000
+	// ThumbnailMaxDimension 缩略图最长边像素
+	ThumbnailMaxDimension = 320
+	// UnattachedUploadTTL 未关联文章的附件保留时长，超时后由垃圾回收清理
+	UnattachedUploadTTL = 24 * time.Hour
+	// UploadGCInterval 附件垃圾回收的执行间隔
+	UploadGCInterval = time.Hour
+)
+
+// AllowedUploadTypes 允许上传的 MIME 类型（以内容嗅探结果为准，不信任客户端声明）
+var AllowedUploadTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+	"application/zip": ".zip",
+}
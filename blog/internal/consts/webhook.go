@@ -0,0 +1,44 @@
+package consts
+
+import "time"
+
+const (
+	// 出站 webhook 事件类型
+	WebhookEventPostCreated    = "post.created"
+	WebhookEventPostUpdated    = "post.updated"
+	WebhookEventPostDeleted    = "post.deleted"
+	WebhookEventCommentCreated = "comment.created"
+
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead" // 重试耗尽，进入死信，可手动重新投递
+
+	// WebhookSignatureHeader 签名头，值为 sha256=<hex>，签名内容为 "<时间戳>.<请求体>"
+	WebhookSignatureHeader = "X-Blog-Signature"
+	// WebhookTimestampHeader 签名时间戳（Unix 秒），接收方可据此拒绝重放的旧请求
+	WebhookTimestampHeader = "X-Blog-Timestamp"
+	WebhookEventHeader     = "X-Blog-Event"
+	WebhookDeliveryHeader  = "X-Blog-Delivery"
+
+	// WebhookMaxPerUser 每个用户最多注册的 webhook 数
+	WebhookMaxPerUser = 10
+	// WebhookMaxAttempts 投递失败的最大尝试次数，之后进入死信
+	WebhookMaxAttempts = 8
+	// WebhookRetryBase 首次重试的等待时间，之后每次翻倍
+	WebhookRetryBase = 30 * time.Second
+	// WebhookRetryMax 重试等待时间上限
+	WebhookRetryMax = 6 * time.Hour
+	// WebhookTimeout 单次投递的超时时间
+	WebhookTimeout = 10 * time.Second
+	// WebhookClaimTTL 认领投递后未回写结果（实例中断）超过该时长，重新投递
+	WebhookClaimTTL = 5 * time.Minute
+	// WebhookBatchSize 每轮处理的事件与投递数量上限
+	WebhookBatchSize = 100
+	// WebhookRetention 已完成的投递记录与已分发的事件保留时长
+	WebhookRetention = 30 * 24 * time.Hour
+	// WebhookPollInterval 后台任务检查待分发事件与到期投递的间隔
+	WebhookPollInterval = 5 * time.Second
+)
+
+// WebhookEvents 全部可订阅的事件类型
+var WebhookEvents = []string{WebhookEventPostCreated, WebhookEventPostUpdated, WebhookEventPostDeleted, WebhookEventCommentCreated}
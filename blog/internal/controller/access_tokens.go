@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-task/blog/dto"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newAccessTokenResponse(t model.PersonalAccessToken) dto.AccessTokenResponse {
+	return dto.AccessTokenResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     t.ScopeList(),
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// CreateAccessToken 创建个人访问令牌
+func (uc *UserController) CreateAccessToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	var req dto.CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, record, err := uc.userService.CreateAccessToken(c.Request.Context(), userID, req.Name, req.Scopes, ttl)
+	if err != nil {
+		respondAccessTokenError(c, err)
+		return
+	}
+
+	resp := newAccessTokenResponse(*record)
+	resp.Token = token
+	c.JSON(http.StatusCreated, gin.H{
+		"code": 201,
+		"msg":  "token created, it will not be shown again",
+		"data": resp,
+	})
+}
+
+// ListAccessTokens 列出当前用户的个人访问令牌
+func (uc *UserController) ListAccessTokens(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	tokens, err := uc.userService.ListAccessTokens(c.Request.Context(), userID)
+	if err != nil {
+		respondAccessTokenError(c, err)
+		return
+	}
+
+	data := make([]dto.AccessTokenResponse, 0, len(tokens))
+	for _, t := range tokens {
+		data = append(data, newAccessTokenResponse(t))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": data,
+	})
+}
+
+// RevokeAccessToken 撤销个人访问令牌
+func (uc *UserController) RevokeAccessToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "invalid token id",
+		})
+		return
+	}
+
+	if err := uc.userService.RevokeAccessToken(c.Request.Context(), userID, uint(tokenID)); err != nil {
+		respondAccessTokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "token revoked",
+	})
+}
+
+func respondAccessTokenError(c *gin.Context, err error) {
+	c.Error(err)
+	status := serverErrorStatus(err)
+	switch {
+	case errors.Is(err, logic.ErrAccessTokenNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, logic.ErrInvalidInput), errors.Is(err, logic.ErrInvalidScope):
+		status = http.StatusBadRequest
+	case errors.Is(err, logic.ErrTooManyAccessTokens):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"code": status,
+		"msg":  err.Error(),
+	})
+}
+
+// accessTokenEndpoints 个人访问令牌接口的 OpenAPI 描述
+func accessTokenEndpoints() []openapi.Endpoint {
+	tags := []string{"users"}
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*UserController).CreateAccessToken,
+			Summary:     "创建个人访问令牌",
+			Description: "明文令牌只在创建时返回一次，不填有效期默认 90 天",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Body:        dto.CreateAccessTokenRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusCreated, Description: "创建成功", Body: openapi.Envelope(dto.AccessTokenResponse{})},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler: (*UserController).ListAccessTokens,
+			Summary: "个人访问令牌列表",
+			Tags:    tags,
+			Auth:    openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "令牌列表，不含明文令牌", Body: openapi.Envelope([]dto.AccessTokenResponse{})},
+			}, envelopeErrors(http.StatusUnauthorized, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler: (*UserController).RevokeAccessToken,
+			Summary: "撤销个人访问令牌",
+			Tags:    tags,
+			Auth:    openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "撤销成功", Body: openapi.Envelope(nil)},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+	}
+}
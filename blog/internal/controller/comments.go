@@ -6,7 +6,12 @@ import (
 	"net/http"
 	"strconv"
 
+	"web-task/blog/dto"
+	"web-task/blog/internal/consts"
 	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/openapi"
+	"web-task/blog/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,23 +27,11 @@ func NewCommentHandler(commentService *logic.CommentService) *CommentHandler {
 }
 
 // CreateComment 创建评论
-// @Summary 创建新评论
-// @Description 为指定文章创建评论，需要用户认证
-// @Tags comments
-// @Accept json
-// @Produce json
-// @Param Authorization header string true "Bearer token"
-// @Param postID path int true "文章ID"
-// @Param comment body CreateCommentRequest true "评论信息"
-// @Success 201 {object} model.Comment
-// @Failure 400 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /posts/{postID}/comments [post]
 func (h *CommentHandler) CreateComment(c *gin.Context) {
-	// 从请求头获取用户ID（实际项目中应从JWT解析）
-	userID, err := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的用户ID"})
+	// 当前用户由 AuthMiddleware 写入上下文
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "请先登录"})
 		return
 	}
 
@@ -50,36 +43,32 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 	}
 
 	// 绑定请求体
-	var req CreateCommentRequest
+	var req dto.CreateCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
 		return
 	}
 
 	// 调用服务层
-	comment, err := h.commentService.Create(uint(userID), uint(postID), req.Content)
+	comment, err := h.commentService.Create(c.Request.Context(), userID, uint(postID), req.ParentID, req.Content)
 	if err != nil {
 		if errors.Is(err, logic.ErrInvalidInput) {
 			c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		if errors.Is(err, logic.ErrPostNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "文章不存在"})
+			return
+		}
+		c.Error(err)
+		c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, comment)
+	c.JSON(http.StatusCreated, newCommentResponse(comment))
 }
 
 // GetCommentByID 获取评论详情
-// @Summary 获取评论详情
-// @Description 根据ID获取评论详情，包含作者、文章等关联信息
-// @Tags comments
-// @Produce json
-// @Param id path int true "评论ID"
-// @Success 200 {object} model.Comment
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /comments/{id} [get]
 func (h *CommentHandler) GetCommentByID(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -87,38 +76,25 @@ func (h *CommentHandler) GetCommentByID(c *gin.Context) {
 		return
 	}
 
-	comment, err := h.commentService.GetByID(uint(id))
+	comment, err := h.commentService.GetByID(c.Request.Context(), uint(id))
 	if err != nil {
 		if errors.Is(err, logic.ErrCommentNotFound) {
 			c.JSON(http.StatusNotFound, ErrorResponse{Message: "评论不存在"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		c.Error(err)
+		c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, comment)
+	c.JSON(http.StatusOK, newCommentResponse(comment))
 }
 
 // UpdateComment 更新评论
-// @Summary 更新评论内容
-// @Description 更新指定评论的内容，仅评论作者可操作
-// @Tags comments
-// @Accept json
-// @Produce json
-// @Param Authorization header string true "Bearer token"
-// @Param id path int true "评论ID"
-// @Param comment body UpdateCommentRequest true "更新的评论内容"
-// @Success 200 {object} model.Comment
-// @Failure 400 {object} ErrorResponse
-// @Failure 403 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /comments/{id} [put]
 func (h *CommentHandler) UpdateComment(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的用户ID"})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "请先登录"})
 		return
 	}
 
@@ -128,13 +104,13 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 		return
 	}
 
-	var req UpdateCommentRequest
+	var req dto.UpdateCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	comment, err := h.commentService.Update(uint(userID), uint(id), req.Content)
+	comment, err := h.commentService.Update(c.Request.Context(), userID, uint(id), req.Content)
 	if err != nil {
 		switch {
 		case errors.Is(err, logic.ErrCommentNotFound):
@@ -144,32 +120,20 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 		case err.Error() == "permission denied":
 			c.JSON(http.StatusForbidden, ErrorResponse{Message: "没有权限更新此评论"})
 		default:
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+			c.Error(err)
+			c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, comment)
+	c.JSON(http.StatusOK, newCommentResponse(comment))
 }
 
 // DeleteComment 删除评论
-// @Summary 删除评论
-// @Description 软删除指定评论，仅作者可操作，可选择是否删除子评论
-// @Tags comments
-// @Produce json
-// @Param Authorization header string true "Bearer token"
-// @Param id path int true "评论ID"
-// @Param includeChildren query bool false "是否同时删除子评论，默认false"
-// @Success 204 {string} string ""
-// @Failure 400 {object} ErrorResponse
-// @Failure 403 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /comments/{id} [delete]
 func (h *CommentHandler) DeleteComment(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的用户ID"})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "请先登录"})
 		return
 	}
 
@@ -181,7 +145,7 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 
 	includeChildren, _ := strconv.ParseBool(c.DefaultQuery("includeChildren", "false"))
 
-	err = h.commentService.Delete(uint(userID), uint(id), includeChildren)
+	err = h.commentService.Delete(c.Request.Context(), userID, uint(id), includeChildren)
 	if err != nil {
 		switch {
 		case errors.Is(err, logic.ErrCommentNotFound):
@@ -189,7 +153,8 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 		case err.Error() == "permission denied":
 			c.JSON(http.StatusForbidden, ErrorResponse{Message: "没有权限删除此评论"})
 		default:
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+			c.Error(err)
+			c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
 		}
 		return
 	}
@@ -198,23 +163,10 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 }
 
 // RestoreComment 恢复评论
-// @Summary 恢复已删除的评论
-// @Description 恢复被软删除的评论，仅作者可操作，可选择是否恢复子评论
-// @Tags comments
-// @Produce json
-// @Param Authorization header string true "Bearer token"
-// @Param id path int true "评论ID"
-// @Param includeChildren query bool false "是否同时恢复子评论，默认false"
-// @Success 200 {object} SuccessResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 403 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /comments/{id}/restore [post]
 func (h *CommentHandler) RestoreComment(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的用户ID"})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "请先登录"})
 		return
 	}
 
@@ -226,7 +178,7 @@ func (h *CommentHandler) RestoreComment(c *gin.Context) {
 
 	includeChildren, _ := strconv.ParseBool(c.DefaultQuery("includeChildren", "false"))
 
-	err = h.commentService.Restore(uint(userID), uint(id), includeChildren)
+	err = h.commentService.Restore(c.Request.Context(), userID, uint(id), includeChildren)
 	if err != nil {
 		switch {
 		case errors.Is(err, logic.ErrCommentNotFound):
@@ -234,7 +186,8 @@ func (h *CommentHandler) RestoreComment(c *gin.Context) {
 		case err.Error() == "permission denied":
 			c.JSON(http.StatusForbidden, ErrorResponse{Message: "没有权限恢复此评论"})
 		default:
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+			c.Error(err)
+			c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
 		}
 		return
 	}
@@ -243,32 +196,123 @@ func (h *CommentHandler) RestoreComment(c *gin.Context) {
 }
 
 // 请求和响应结构体定义
-type CreateCommentRequest struct {
-	Content string `json:"content" binding:"required"`
-}
-
-type UpdateCommentRequest struct {
-	Content string `json:"content" binding:"required"`
-}
-
 type SuccessResponse struct {
 	Message string `json:"message"`
 }
 
+// newCommentResponse 评论响应，作者只返回公开资料；已加载所属文章时一并返回
+func newCommentResponse(c *model.Comment) dto.Comment {
+	resp := dto.Comment{
+		ID:        c.ID,
+		Content:   c.Content,
+		UserID:    c.UserID,
+		User:      c.User.Public(),
+		PostID:    c.PostID,
+		ParentID:  c.ParentID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+	if c.Post.ID != 0 {
+		post := newPostResponse(&c.Post)
+		resp.Post = &post
+	}
+	return resp
+}
+
 // 注册路由
-func (h *CommentHandler) RegisterRoutes(router *gin.RouterGroup) {
-	// 文章下的评论路由
+func (h *CommentHandler) RegisterRoutes(router *gin.RouterGroup, limiter *middleware.RateLimiter) {
+	read := middleware.OptionalAuthMiddleware(consts.ScopeCommentsRead)
+	write := middleware.AuthMiddleware(consts.ScopeCommentsWrite)
+
+	// 文章下的评论路由；先认证再限流，使评论限流按用户计数
 	posts := router.Group("/posts")
 	{
-		posts.POST("/:postID/comments", h.CreateComment)
+		posts.POST("/:postID/comments", write, limiter.Limit(consts.RateLimitCommentCreate), h.CreateComment)
+		// 评论直播间是长连接，不设请求截止时间；参数名需与 GET /posts/:id 一致
+		posts.GET("/:id/live", middleware.WebSocketAuthMiddleware(consts.ScopeCommentsRead), middleware.NoTimeout(), h.LiveComments)
 	}
 
 	// 评论自身的路由
 	comments := router.Group("/comments")
 	{
-		comments.GET("/:id", h.GetCommentByID)
-		comments.PUT("/:id", h.UpdateComment)
-		comments.DELETE("/:id", h.DeleteComment)
-		comments.POST("/:id/restore", h.RestoreComment)
+		comments.GET("/:id", limiter.Limit(consts.RateLimitReads), read, h.GetCommentByID)
+		comments.PUT("/:id", write, h.UpdateComment)
+		comments.DELETE("/:id", write, h.DeleteComment)
+		comments.POST("/:id/restore", write, h.RestoreComment)
+	}
+}
+
+// commentEndpoints 评论接口的 OpenAPI 描述
+func commentEndpoints() []openapi.Endpoint {
+	tags := []string{"comments"}
+	read := []string{consts.ScopeCommentsRead}
+	write := []string{consts.ScopeCommentsWrite}
+	includeChildren := func(action string) []openapi.Param {
+		return []openapi.Param{{Name: "includeChildren", Description: "是否同时" + action + "子评论，默认false", Schema: openapi.Boolean()}}
+	}
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*CommentHandler).CreateComment,
+			Summary:     "创建新评论",
+			Description: "为指定文章创建评论，parent_id 为同一文章下的顶级评论时作为回复并通知其作者，按用户限流",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Scopes:      write,
+			RateLimited: true,
+			Body:        dto.CreateCommentRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusCreated, Description: "创建成功", Body: dto.Comment{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*CommentHandler).GetCommentByID,
+			Summary:     "获取评论详情",
+			Description: "根据ID获取评论详情，包含作者、文章等关联信息",
+			Tags:        tags,
+			Auth:        openapi.AuthOptional,
+			Scopes:      read,
+			RateLimited: true,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "评论详情", Body: dto.Comment{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*CommentHandler).UpdateComment,
+			Summary:     "更新评论内容",
+			Description: "更新指定评论的内容，仅评论作者可操作",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Scopes:      write,
+			Body:        dto.UpdateCommentRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "更新后的评论", Body: dto.Comment{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*CommentHandler).DeleteComment,
+			Summary:     "删除评论",
+			Description: "软删除指定评论，仅作者可操作，可选择是否删除子评论",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Scopes:      write,
+			Query:       includeChildren("删除"),
+			Responses: append([]openapi.Response{
+				{Status: http.StatusNoContent, Description: "删除成功"},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*CommentHandler).RestoreComment,
+			Summary:     "恢复已删除的评论",
+			Description: "恢复被软删除的评论，仅作者可操作，可选择是否恢复子评论",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Scopes:      write,
+			Query:       includeChildren("恢复"),
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "恢复成功", Body: SuccessResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		liveEndpoint(),
 	}
 }
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/openapi"
+	"web-task/blog/internal/pubsub"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+var liveUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	Subprotocols:    []string{consts.LiveSubprotocol},
+	// 令牌由客户端显式携带而不依赖 Cookie，跨站页面无法冒用用户身份，因此不限制 Origin
+	CheckOrigin: func(*http.Request) bool { return true },
+	Error: func(w http.ResponseWriter, _ *http.Request, status int, reason error) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: reason.Error()})
+	},
+}
+
+// LiveComments 文章评论直播间
+func (h *CommentHandler) LiveComments(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的文章ID"})
+		return
+	}
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		c.Header("Upgrade", "websocket")
+		c.JSON(http.StatusUpgradeRequired, ErrorResponse{Message: "需要 WebSocket 连接"})
+		return
+	}
+
+	// 先订阅再升级，文章不存在等错误仍以普通 HTTP 响应返回
+	sub, err := h.commentService.Subscribe(c.Request.Context(), uint(postID))
+	if err != nil {
+		switch {
+		case errors.Is(err, logic.ErrPostNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "文章不存在"})
+		case errors.Is(err, logic.ErrLiveUnavailable):
+			c.JSON(http.StatusServiceUnavailable, ErrorResponse{Message: err.Error()})
+		default:
+			c.Error(err)
+			c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
+		}
+		return
+	}
+	defer sub.Close()
+
+	conn, err := liveUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// 握手失败时 Upgrader 已写出错误响应
+		return
+	}
+	defer conn.Close()
+	serveLive(conn, sub)
+}
+
+// serveLive 把订阅到的事件写给客户端，直到客户端断开、心跳超时或订阅结束
+// 每个连接只有一个写循环；事件在订阅中缓冲，客户端读得太慢导致缓冲写满时订阅被关闭，
+// 连接以 1013 (try again later) 关闭，客户端重连后重新拉取评论列表
+func serveLive(conn *websocket.Conn, sub pubsub.Subscription) {
+	// 读循环只处理 pong 与关闭帧，客户端断开或心跳超时时通知写循环退出
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadLimit(consts.LiveMaxMessageSize)
+		conn.SetReadDeadline(time.Now().Add(consts.LivePongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(consts.LivePongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(consts.LivePingPeriod)
+	defer ping.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(consts.LiveWriteWait)); err != nil {
+				return
+			}
+		case payload, ok := <-sub.C():
+			if !ok {
+				code, text := websocket.CloseGoingAway, "server shutting down"
+				if errors.Is(sub.Err(), pubsub.ErrSlowConsumer) {
+					code, text = websocket.CloseTryAgainLater, "too slow, reconnect"
+				}
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(consts.LiveWriteWait))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(consts.LiveWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// liveEndpoint 评论直播间的 OpenAPI 描述
+func liveEndpoint() openapi.Endpoint {
+	return openapi.Endpoint{
+		Handler: (*CommentHandler).LiveComments,
+		Summary: "实时评论",
+		Description: "WebSocket 接口，加入文章的评论直播间，推送评论的创建、修改与删除；每条消息为 {type, comment} JSON，" +
+			"type 为 comment.created、comment.updated 或 comment.deleted。浏览器无法设置请求头，" +
+			"可用子协议携带令牌：Sec-WebSocket-Protocol: bearer, <token>。客户端读取过慢时连接以 1013 关闭，应重连并重新拉取评论",
+		Tags:   []string{"comments"},
+		Auth:   openapi.AuthRequired,
+		Scopes: []string{consts.ScopeCommentsRead},
+		Headers: []openapi.Param{
+			{Name: "Sec-WebSocket-Protocol", Description: "bearer, <token>，未携带 Authorization 时使用"},
+		},
+		Responses: append([]openapi.Response{
+			{Status: http.StatusSwitchingProtocols, Description: "切换为 WebSocket 协议"},
+		}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusUpgradeRequired, http.StatusInternalServerError, http.StatusServiceUnavailable)...),
+	}
+}
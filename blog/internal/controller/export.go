@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportHandler 用户数据导出
+type ExportHandler struct {
+	exportService *logic.ExportService
+}
+
+// NewExportHandler 构造函数
+func NewExportHandler(es *logic.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: es}
+}
+
+// ExportJobResponse 导出任务状态，任务完成后附带限时下载链接
+type ExportJobResponse struct {
+	ID                uint       `json:"id"`
+	Status            string     `json:"status"`
+	Size              int64      `json:"size"`
+	Error             string     `json:"error,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty"`
+}
+
+func (h *ExportHandler) newExportJobResponse(job *model.ExportJob) ExportJobResponse {
+	resp := ExportJobResponse{
+		ID:          job.ID,
+		Status:      job.Status,
+		Size:        job.Size,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		CompletedAt: job.CompletedAt,
+		ExpiresAt:   job.ExpiresAt,
+	}
+	if job.Status == consts.ExportStatusCompleted {
+		url, expiresAt := h.exportService.DownloadURL(job)
+		resp.DownloadURL = url
+		resp.DownloadExpiresAt = &expiresAt
+	}
+	return resp
+}
+
+// StartExport 发起数据导出，已有进行中的任务时返回该任务
+func (h *ExportHandler) StartExport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	job, err := h.exportService.StartExport(c.Request.Context(), userID)
+	if err != nil {
+		respondExportError(c, err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/users/me/export/%d", job.ID))
+	c.JSON(http.StatusAccepted, gin.H{
+		"code": 202,
+		"msg":  "export started",
+		"data": h.newExportJobResponse(job),
+	})
+}
+
+// GetExport 查询导出任务状态
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondExportError(c, logic.ErrExportNotFound)
+		return
+	}
+
+	job, err := h.exportService.GetJob(c.Request.Context(), userID, uint(jobID))
+	if err != nil {
+		respondExportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": h.newExportJobResponse(job),
+	})
+}
+
+// Download 通过签名链接下载导出归档，无需登录
+func (h *ExportHandler) Download(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondExportError(c, logic.ErrExportNotFound)
+		return
+	}
+
+	rc, job, err := h.exportService.OpenDownload(c.Request.Context(), uint(jobID), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		respondExportError(c, err)
+		return
+	}
+	defer rc.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="blog-export-%d.zip"`, job.ID))
+	c.Header("Content-Length", strconv.FormatInt(job.Size, 10))
+	c.Header("Cache-Control", "private, no-store")
+	c.Status(http.StatusOK)
+	c.Writer.Header().Set("Content-Type", "application/zip")
+	io.Copy(c.Writer, rc)
+}
+
+func respondExportError(c *gin.Context, err error) {
+	c.Error(err)
+	status := serverErrorStatus(err)
+	msg := "internal server error"
+	switch {
+	case errors.Is(err, logic.ErrExportNotFound):
+		status, msg = http.StatusNotFound, err.Error()
+	case errors.Is(err, logic.ErrInvalidToken):
+		status, msg = http.StatusForbidden, "download link is invalid or has expired"
+	case status == http.StatusGatewayTimeout:
+		msg = "request timed out"
+	}
+
+	c.JSON(status, gin.H{
+		"code": status,
+		"msg":  msg,
+	})
+}
+
+// exportEndpoints 数据导出接口的 OpenAPI 描述
+func exportEndpoints() []openapi.Endpoint {
+	tags := []string{"exports"}
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*ExportHandler).StartExport,
+			Summary:     "发起数据导出",
+			Description: "异步打包当前用户的资料、文章与评论，已有进行中的任务时返回该任务",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			RateLimited: true,
+			Responses: append([]openapi.Response{
+				{
+					Status:      http.StatusAccepted,
+					Description: "已受理",
+					Body:        openapi.Envelope(ExportJobResponse{}),
+					Headers:     map[string]string{"Location": "任务状态地址"},
+				},
+			}, envelopeErrors(http.StatusUnauthorized, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*ExportHandler).GetExport,
+			Summary:     "导出任务状态",
+			Description: "任务完成后附带限时下载链接",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "任务状态", Body: openapi.Envelope(ExportJobResponse{})},
+			}, envelopeErrors(http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*ExportHandler).Download,
+			Summary:     "下载导出归档",
+			Description: "下载链接自带签名与有效期，无需登录",
+			Tags:        tags,
+			RateLimited: true,
+			Query: []openapi.Param{
+				{Name: "expires", Description: "链接过期时间（Unix 秒）", Required: true},
+				{Name: "signature", Description: "链接签名", Required: true},
+			},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "zip 归档", Body: openapi.Binary(), ContentType: "application/zip"},
+			}, envelopeErrors(http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+	}
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"net/http"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/graph"
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GraphQLHandler GraphQL 接口
+type GraphQLHandler struct {
+	server *graph.Server
+}
+
+// NewGraphQLHandler 构造函数
+func NewGraphQLHandler(server *graph.Server) *GraphQLHandler {
+	return &GraphQLHandler{server: server}
+}
+
+type GraphQLRequest struct {
+	Query         string         `json:"query" binding:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// GraphQLResponse 无法执行的查询 data 为 null
+type GraphQLResponse struct {
+	Data   any            `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+type GraphQLError struct {
+	Message   string            `json:"message"`
+	Locations []GraphQLLocation `json:"locations,omitempty"`
+	Path      []any             `json:"path,omitempty"`
+}
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Query 执行 GraphQL 查询或变更
+// 无法解析、不符合 schema 或超出深度与复杂度限制的查询返回 400；执行过的查询即使部分字段出错也返回 200，错误在 errors 中
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req GraphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: err.Error()}}})
+		return
+	}
+
+	// 登录令牌不写入 scopes，个人访问令牌按授予的权限范围限制
+	var viewer graph.Viewer
+	viewer.UserID, _ = currentUserID(c)
+	viewer.Username = c.GetString("username")
+	viewer.ClientIP = c.ClientIP()
+	if v, ok := c.Get("scopes"); ok {
+		viewer.Scopes, _ = v.([]string)
+	}
+
+	res := h.server.Execute(c.Request.Context(), viewer, graph.Request{
+		Query:         req.Query,
+		OperationName: req.OperationName,
+		Variables:     req.Variables,
+	})
+	for _, err := range res.Internal {
+		c.Error(err)
+	}
+
+	resp := GraphQLResponse{Data: res.Data}
+	for _, e := range res.Errors {
+		ge := GraphQLError{Message: e.Message, Path: e.Path}
+		for _, loc := range e.Locations {
+			ge.Locations = append(ge.Locations, GraphQLLocation{Line: loc.Line, Column: loc.Column})
+		}
+		resp.Errors = append(resp.Errors, ge)
+	}
+
+	status := http.StatusOK
+	if res.Rejected {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, resp)
+}
+
+// graphqlEndpoints GraphQL 接口的 OpenAPI 描述
+func graphqlEndpoints() []openapi.Endpoint {
+	return []openapi.Endpoint{
+		{
+			Handler: (*GraphQLHandler).Query,
+			Summary: "GraphQL 查询",
+			Description: "查询用户、文章与评论，发表、修改与删除文章和评论。" +
+				"个人访问令牌需要 posts:read，读取评论与各项变更另需对应的权限范围；查询深度与复杂度超出限制或一次变更包含过多字段时返回 400。" +
+				"createComment 与 POST /posts/{postID}/comments 共用发表评论的限流额度，超出时该字段返回 rate limit exceeded",
+			Tags:        []string{"graphql"},
+			Auth:        openapi.AuthOptional,
+			Scopes:      []string{consts.ScopePostsRead},
+			RateLimited: true,
+			Body:        GraphQLRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Description: "执行结果，字段错误在 errors 中", Body: GraphQLResponse{}},
+				{Status: http.StatusBadRequest, Description: "查询无法执行", Body: GraphQLResponse{}},
+			},
+		},
+	}
+}
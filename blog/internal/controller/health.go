@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"net/http"
+
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler 存活与就绪探针
+type HealthHandler struct {
+	healthService *logic.HealthService
+}
+
+// NewHealthHandler 构造函数
+func NewHealthHandler(healthService *logic.HealthService) *HealthHandler {
+	return &HealthHandler{healthService: healthService}
+}
+
+// Healthz 存活探针：进程能处理请求即返回 200，不检查依赖，避免数据库故障导致实例被反复重启
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "ok",
+	})
+}
+
+// Readyz 就绪探针：数据库可用、迁移已全部执行且未进入退出流程时返回 200，否则返回 503
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	readiness := h.healthService.Ready(c.Request.Context())
+	if !readiness.Ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code": 503,
+			"msg":  "not ready",
+			"data": readiness,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "ready",
+		"data": readiness,
+	})
+}
+
+// healthEndpoints 探针接口的 OpenAPI 描述
+func healthEndpoints() []openapi.Endpoint {
+	tags := []string{"operations"}
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*HealthHandler).Healthz,
+			Summary:     "存活探针",
+			Description: "进程能处理请求即返回 200，不检查依赖",
+			Tags:        tags,
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Description: "存活", Body: openapi.Envelope(nil)},
+			},
+		},
+		{
+			Handler:     (*HealthHandler).Readyz,
+			Summary:     "就绪探针",
+			Description: "数据库可用、迁移已全部执行且未进入退出流程时返回 200",
+			Tags:        tags,
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Description: "就绪", Body: openapi.Envelope(logic.Readiness{})},
+				{Status: http.StatusServiceUnavailable, Description: "未就绪", Body: openapi.Envelope(logic.Readiness{})},
+			},
+		},
+	}
+}
@@ -0,0 +1,299 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-task/blog/dto"
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/oidc"
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdentityController 第三方登录与身份绑定
+type IdentityController struct {
+	identityService *logic.IdentityService
+}
+
+// 构造函数，接收第三方登录服务实例
+func NewIdentityController(is *logic.IdentityService) *IdentityController {
+	return &IdentityController{
+		identityService: is,
+	}
+}
+
+// OIDCLoginResponse 第三方登录成功签发的令牌，NewUser 表示本次登录新建了账号
+type OIDCLoginResponse struct {
+	Token   string `json:"token"`
+	NewUser bool   `json:"new_user"`
+}
+
+// AuthorizationURLResponse 发起绑定时返回的提供方授权地址
+type AuthorizationURLResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// Providers 列出已启用的第三方登录提供方
+func (ic *IdentityController) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": ic.identityService.ProviderNames(),
+	})
+}
+
+// Login 跳转到提供方授权页面
+func (ic *IdentityController) Login(c *gin.Context) {
+	authURL, state, err := ic.identityService.AuthorizationURL(c.Request.Context(), c.Param("provider"), nil)
+	if err != nil {
+		respondIdentityError(c, err)
+		return
+	}
+	ic.setStateCookie(c, state, int(consts.OAuthStateTTL/time.Second))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback 提供方授权回调，登录模式返回令牌，绑定模式返回绑定的身份
+func (ic *IdentityController) Callback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "authorization failed: " + errCode,
+		})
+		return
+	}
+
+	// state 只能使用一次，无论回调是否成功都清除浏览器保存的 state
+	browserState, _ := c.Cookie(consts.OAuthStateCookie)
+	ic.setStateCookie(c, "", -1)
+
+	result, err := ic.identityService.Callback(c.Request.Context(), c.Param("provider"),
+		c.Query("code"), c.Query("state"), browserState, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondIdentityError(c, err)
+		return
+	}
+
+	if result.Login == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 200,
+			"msg":  "identity linked",
+			"data": result.Identity,
+		})
+		return
+	}
+
+	// 开启两步验证的账号需继续调用 /users/login/mfa
+	if result.Login.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 200,
+			"msg":  "mfa required",
+			"data": dto.MFAChallengeResponse{
+				MFARequired: true,
+				MFAToken:    result.Login.MFAToken,
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "login success",
+		"data": OIDCLoginResponse{
+			Token:   result.Login.Token,
+			NewUser: result.NewUser,
+		},
+	})
+}
+
+// ListIdentities 查看当前用户绑定的第三方身份
+func (ic *IdentityController) ListIdentities(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	identities, err := ic.identityService.ListIdentities(c.Request.Context(), userID)
+	if err != nil {
+		respondIdentityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": identities,
+	})
+}
+
+// LinkIdentity 为当前用户发起绑定，返回授权地址，由客户端引导用户打开
+// 与登录相同，state Cookie 下发给调用方的浏览器，只有在同一浏览器中完成授权才能绑定
+func (ic *IdentityController) LinkIdentity(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	authURL, state, err := ic.identityService.AuthorizationURL(c.Request.Context(), c.Param("provider"), &userID)
+	if err != nil {
+		respondIdentityError(c, err)
+		return
+	}
+	ic.setStateCookie(c, state, int(consts.OAuthStateTTL/time.Second))
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": AuthorizationURLResponse{AuthorizationURL: authURL},
+	})
+}
+
+// UnlinkIdentity 解除绑定
+func (ic *IdentityController) UnlinkIdentity(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	identityID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "invalid identity id",
+		})
+		return
+	}
+
+	if err := ic.identityService.Unlink(c.Request.Context(), userID, uint(identityID)); err != nil {
+		respondIdentityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "identity unlinked",
+	})
+}
+
+// setStateCookie 设置或清除（maxAge < 0）授权 state Cookie
+// SameSite=Lax 时提供方跳转回来的顶层 GET 请求仍会带上 Cookie，而跨站页面无法读取或伪造它
+func (ic *IdentityController) setStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := strings.HasPrefix(ic.identityService.Users.AppURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(consts.OAuthStateCookie, state, maxAge, consts.OAuthStateCookiePath, "", secure, true)
+}
+
+func respondIdentityError(c *gin.Context, err error) {
+	c.Error(err)
+	status := serverErrorStatus(err)
+	switch {
+	case errors.Is(err, logic.ErrUnknownProvider), errors.Is(err, logic.ErrIdentityNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, logic.ErrInvalidOAuthState),
+		errors.Is(err, logic.ErrIdentityEmailMissing),
+		errors.Is(err, oidc.ErrInvalidIDToken),
+		errors.Is(err, oidc.ErrNonceMismatch):
+		status = http.StatusBadRequest
+	case errors.Is(err, logic.ErrIdentityLinked),
+		errors.Is(err, logic.ErrIdentityEmailInUse),
+		errors.Is(err, logic.ErrLastLoginMethod):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"code": status,
+		"msg":  err.Error(),
+	})
+}
+
+// stateCookieDescription 授权 state Cookie 的响应头说明
+const stateCookieDescription = consts.OAuthStateCookie + "，HttpOnly、SameSite=Lax，回调时校验"
+
+// identityEndpoints 第三方登录与身份绑定接口的 OpenAPI 描述
+func identityEndpoints() []openapi.Endpoint {
+	tags := []string{"auth"}
+	identityErrors := func(statuses ...int) []openapi.Response {
+		return envelopeErrors(append(statuses, http.StatusInternalServerError, http.StatusGatewayTimeout)...)
+	}
+
+	return []openapi.Endpoint{
+		{
+			Handler: (*IdentityController).Providers,
+			Summary: "已启用的第三方登录提供方",
+			Tags:    tags,
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Description: "提供方名称", Body: openapi.Envelope([]string{})},
+			},
+		},
+		{
+			Handler:     (*IdentityController).Login,
+			OperationID: "oidcLogin",
+			Summary:     "跳转到提供方授权",
+			Tags:        tags,
+			RateLimited: true,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusFound, Description: "跳转到提供方授权页面", Headers: map[string]string{"Location": "提供方授权地址", "Set-Cookie": stateCookieDescription}},
+			}, identityErrors(http.StatusNotFound)...),
+		},
+		{
+			Handler:     (*IdentityController).Callback,
+			OperationID: "oidcCallback",
+			Summary:     "授权回调",
+			Description: "登录模式返回令牌（开启两步验证的账号返回挑战令牌），绑定模式返回绑定的身份。" +
+				"请求须携带发起授权时下发的 " + consts.OAuthStateCookie + " Cookie 且与 state 一致，否则返回 400",
+			Tags:        tags,
+			RateLimited: true,
+			Query: []openapi.Param{
+				{Name: "code", Description: "授权码"},
+				{Name: "state", Description: "发起授权时生成的 state"},
+				{Name: "error", Description: "提供方返回的错误码"},
+			},
+			Responses: append([]openapi.Response{
+				{
+					Status:      http.StatusOK,
+					Description: "登录成功、需要两步验证或绑定成功",
+					Body:        openapi.Envelope(openapi.AnyOf(OIDCLoginResponse{}, dto.MFAChallengeResponse{}, model.Identity{})),
+				},
+			}, identityErrors(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict)...),
+		},
+		{
+			Handler: (*IdentityController).ListIdentities,
+			Summary: "已绑定的第三方身份",
+			Tags:    tags,
+			Auth:    openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "身份列表", Body: openapi.Envelope([]model.Identity{})},
+			}, identityErrors(http.StatusUnauthorized)...),
+		},
+		{
+			Handler:     (*IdentityController).LinkIdentity,
+			Summary:     "发起绑定",
+			Description: "返回提供方授权地址，由客户端在同一浏览器中引导用户打开，授权后回调完成绑定；须由浏览器携带 Cookie 调用（如 fetch 的 credentials: include）以保存 state Cookie",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "授权地址", Headers: map[string]string{"Set-Cookie": stateCookieDescription}, Body: openapi.Envelope(AuthorizationURLResponse{})},
+			}, identityErrors(http.StatusUnauthorized, http.StatusNotFound)...),
+		},
+		{
+			Handler:     (*IdentityController).UnlinkIdentity,
+			Summary:     "解除绑定",
+			Description: "不能解除唯一的登录方式",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "解除成功", Body: openapi.Envelope(nil)},
+			}, identityErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict)...),
+		},
+	}
+}
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/importer"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler 管理员批量导入文章
+type ImportHandler struct {
+	importService *logic.ImportService
+}
+
+// NewImportHandler 构造函数
+func NewImportHandler(is *logic.ImportService) *ImportHandler {
+	return &ImportHandler{importService: is}
+}
+
+// Import 导入文章
+// 以 multipart/form-data 上传 WordPress 导出的 .xml，或 Markdown 目录、Hugo/Jekyll 站点打包的 .zip；
+// 表单字段：format（默认 auto）、source（默认与格式相同）、default_author、author_map（JSON 对象）、dry_run
+func (h *ImportHandler) Import(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, consts.ImportMaxSize+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondImportError(c, http.StatusRequestEntityTooLarge, "file too large")
+			return
+		}
+		respondImportError(c, http.StatusBadRequest, "file is required")
+		return
+	}
+
+	opts := logic.ImportOptions{
+		Source:        strings.TrimSpace(c.PostForm("source")),
+		DefaultAuthor: strings.TrimSpace(c.PostForm("default_author")),
+	}
+	if raw := c.PostForm("author_map"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.AuthorMap); err != nil {
+			respondImportError(c, http.StatusBadRequest, "author_map must be a JSON object")
+			return
+		}
+	}
+	if raw := c.PostForm("dry_run"); raw != "" {
+		if opts.DryRun, err = strconv.ParseBool(raw); err != nil {
+			respondImportError(c, http.StatusBadRequest, "invalid dry_run")
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondImportError(c, http.StatusBadRequest, "failed to read file")
+		return
+	}
+	defer file.Close()
+
+	var (
+		docs   []importer.Document
+		format = c.DefaultPostForm("format", importer.FormatAuto)
+	)
+	switch strings.ToLower(path.Ext(fileHeader.Filename)) {
+	case ".xml":
+		format = importer.FormatWordPress
+		docs, err = importer.ParseWXR(file)
+	case ".zip":
+		var zr *zip.Reader
+		if zr, err = zip.NewReader(file, fileHeader.Size); err == nil {
+			docs, format, err = importer.Load(zr, format)
+		}
+	default:
+		respondImportError(c, http.StatusUnsupportedMediaType, "file must be a WXR .xml or a .zip archive")
+		return
+	}
+	if err != nil {
+		respondImportError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if opts.Source == "" {
+		opts.Source = format
+	}
+	report, err := h.importService.Import(c.Request.Context(), docs, opts)
+	switch {
+	case errors.Is(err, logic.ErrImportFailed):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"code": 422,
+			"msg":  err.Error(),
+			"data": report,
+		})
+	case errors.Is(err, logic.ErrInvalidInput):
+		respondImportError(c, http.StatusBadRequest, err.Error())
+	case err != nil:
+		c.Error(err)
+		respondImportError(c, serverErrorStatus(err), "internal server error")
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"code": 200,
+			"msg":  "success",
+			"data": report,
+		})
+	}
+}
+
+func respondImportError(c *gin.Context, status int, msg string) {
+	c.JSON(status, gin.H{
+		"code": status,
+		"msg":  msg,
+	})
+}
+
+// importEndpoints 批量导入接口的 OpenAPI 描述
+func importEndpoints() []openapi.Endpoint {
+	return []openapi.Endpoint{
+		{
+			Handler:     (*ImportHandler).Import,
+			Summary:     "批量导入文章",
+			Description: "上传 WordPress 导出的 .xml，或 Markdown 目录、Hugo/Jekyll 站点打包的 .zip；任一文章失败则整体回滚",
+			Tags:        []string{"admin"},
+			Auth:        openapi.AuthAdmin,
+			Form: []openapi.Param{
+				{Name: "file", Description: "WXR .xml 或 .zip 归档", Required: true, Schema: openapi.Binary()},
+				{Name: "format", Description: "zip 归档的格式，默认 auto", Schema: &openapi.Schema{
+					Type: openapi.Types{"string"},
+					Enum: []any{importer.FormatAuto, importer.FormatMarkdown, importer.FormatHugo, importer.FormatJekyll},
+				}},
+				{Name: "source", Description: "来源标识，默认与格式相同"},
+				{Name: "default_author", Description: "找不到作者时使用的用户名"},
+				{Name: "author_map", Description: "原作者到本站用户名的映射（JSON 对象）"},
+				{Name: "dry_run", Description: "只生成报告，不保存", Schema: openapi.Boolean()},
+			},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "导入报告", Body: openapi.Envelope(logic.ImportReport{})},
+				{Status: http.StatusUnprocessableEntity, Description: "部分文章导入失败，已全部回滚", Body: openapi.Envelope(logic.ImportReport{})},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType,
+				http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+	}
+}
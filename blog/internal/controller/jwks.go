@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/keyring"
+	"web-task/blog/internal/oidc"
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler 发布 JWT 验证公钥
+type JWKSHandler struct {
+	keys *keyring.KeyRing
+}
+
+// NewJWKSHandler 构造函数
+func NewJWKSHandler(keys *keyring.KeyRing) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// JWKS 返回当前签名密钥与宽限期内退役密钥的公钥
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(consts.JWKSCacheMaxAge.Seconds())))
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
+
+// jwksEndpoints 公钥发布接口的 OpenAPI 描述
+func jwksEndpoints() []openapi.Endpoint {
+	return []openapi.Endpoint{
+		{
+			Handler:     (*JWKSHandler).JWKS,
+			Summary:     "JWT 验证公钥",
+			Description: "当前签名密钥与宽限期内退役密钥的公钥，供其它服务验证博客签发的令牌",
+			Tags:        []string{"operations"},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Description: "JWK Set", Body: oidc.JWKS{}, Headers: map[string]string{"Cache-Control": "公钥缓存时间"}},
+			},
+		},
+	}
+}
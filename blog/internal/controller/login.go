@@ -1,8 +1,15 @@
 package controller
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+
+	"web-task/blog/dto"
 	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/openapi"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,22 +25,9 @@ func NewUserController(us *logic.UserService) *UserController {
 	}
 }
 
-// RegisterRequest 注册请求参数结构体
-type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=20"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
-}
-
-// LoginRequest 登录请求参数结构体
-type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
-}
-
 // Register 处理用户注册请求
 func (uc *UserController) Register(c *gin.Context) {
-	var req RegisterRequest
+	var req dto.RegisterRequest
 	// 绑定并验证请求参数
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -44,7 +38,7 @@ func (uc *UserController) Register(c *gin.Context) {
 	}
 
 	// 调用服务层进行注册
-	user, err := uc.userService.Register(req.Username, req.Email, req.Password)
+	user, err := uc.userService.Register(c.Request.Context(), req.Username, req.Email, req.Password)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code": 400,
@@ -57,17 +51,17 @@ func (uc *UserController) Register(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"msg":  "register success",
-		"data": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+		"data": dto.RegisterResponse{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
 		},
 	})
 }
 
 // Login 处理用户登录请求
 func (uc *UserController) Login(c *gin.Context) {
-	var req LoginRequest
+	var req dto.LoginRequest
 	// 绑定并验证请求参数
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -78,11 +72,21 @@ func (uc *UserController) Login(c *gin.Context) {
 	}
 
 	// 调用服务层进行登录
-	token, err := uc.userService.Login(req.Username, req.Password)
+	result, err := uc.userService.Login(c.Request.Context(), req.Username, req.Password, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"code": 401,
-			"msg":  err.Error(),
+		respondLoginError(c, err)
+		return
+	}
+
+	// 开启两步验证的账号需继续调用 /users/login/mfa
+	if result.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 200,
+			"msg":  "mfa required",
+			"data": dto.MFAChallengeResponse{
+				MFARequired: true,
+				MFAToken:    result.MFAToken,
+			},
 		})
 		return
 	}
@@ -91,8 +95,333 @@ func (uc *UserController) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"msg":  "login success",
-		"data": gin.H{
-			"token": token,
-		},
+		"data": dto.TokenResponse{Token: result.Token},
 	})
 }
+
+// Sessions 查看当前用户最近的登录记录（时间、IP、UA）
+func (uc *UserController) Sessions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	sessions, err := uc.userService.ListSessions(c.Request.Context(), userID, limit)
+	if err != nil {
+		respondServerError(c, err, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": sessions,
+	})
+}
+
+// UnlockRequest 管理员解锁请求参数结构体
+type UnlockRequest struct {
+	IP string `json:"ip"`
+}
+
+// Unlock 管理员解除账号锁定，可同时解除某个 IP 的锁定
+func (uc *UserController) Unlock(c *gin.Context) {
+	var req UnlockRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := uc.userService.Unlock(c.Request.Context(), c.Param("username"), req.IP); err != nil {
+		c.Error(err)
+		respondServerError(c, err, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "unlock success",
+	})
+}
+
+// EmailRequest 仅包含邮箱的请求参数结构体
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// TokenRequest 仅包含令牌的请求参数结构体
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResetPasswordRequest 重置密码请求参数结构体
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// VerifyEmail 验证邮箱，支持邮件链接直接 GET 访问（?token=）或 POST JSON
+func (uc *UserController) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if c.Request.Method == http.MethodPost {
+		var req TokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  err.Error(),
+			})
+			return
+		}
+		token = req.Token
+	}
+
+	if err := uc.userService.VerifyEmail(c.Request.Context(), token); err != nil {
+		if errors.Is(err, logic.ErrInvalidToken) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  err.Error(),
+			})
+			return
+		}
+		c.Error(err)
+		respondServerError(c, err, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "email verified",
+	})
+}
+
+// ResendVerification 重新发送验证邮件；无论邮箱是否存在都返回相同结果
+func (uc *UserController) ResendVerification(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	if err := uc.userService.ResendVerification(c.Request.Context(), req.Email); err != nil {
+		c.Error(err)
+		respondServerError(c, err, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code": 202,
+		"msg":  "if the email is registered and unverified, a verification link has been sent",
+	})
+}
+
+// ForgotPassword 发送找回密码邮件；无论邮箱是否存在都返回相同结果
+func (uc *UserController) ForgotPassword(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	if err := uc.userService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		c.Error(err)
+		respondServerError(c, err, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code": 202,
+		"msg":  "if the email is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPassword 使用邮件中的令牌设置新密码
+func (uc *UserController) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	if err := uc.userService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, logic.ErrInvalidToken), errors.Is(err, logic.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  err.Error(),
+			})
+		default:
+			c.Error(err)
+			respondServerError(c, err, "internal server error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "password reset success",
+	})
+}
+
+// respondLoginError 将登录相关错误映射为响应；锁定时带上 Retry-After
+func respondLoginError(c *gin.Context, err error) {
+	c.Error(err)
+	var lockout *logic.LockoutError
+	switch {
+	case errors.As(err, &lockout):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"code": 429,
+			"msg":  logic.ErrAccountLocked.Error(),
+		})
+	case errors.Is(err, logic.ErrInvalidCredentials),
+		errors.Is(err, logic.ErrInvalidMFACode),
+		errors.Is(err, logic.ErrInvalidToken):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code": 401,
+			"msg":  err.Error(),
+		})
+	default:
+		respondServerError(c, err, "internal server error")
+	}
+}
+
+// currentUserID 读取 AuthMiddleware 写入上下文的用户 ID
+func currentUserID(c *gin.Context) (uint, bool) {
+	v, ok := c.Get("user_id")
+	if !ok {
+		return 0, false
+	}
+	id, ok := v.(uint)
+	return id, ok && id != 0
+}
+
+// userEndpoints 注册、登录、邮箱验证、找回密码与登录记录接口的 OpenAPI 描述
+func userEndpoints() []openapi.Endpoint {
+	tags := []string{"users"}
+	message := openapi.Envelope(nil)
+	lockout := openapi.Response{
+		Status:      http.StatusTooManyRequests,
+		Description: "账号或 IP 已被临时锁定",
+		Body:        message,
+		Headers:     map[string]string{"Retry-After": "距离解除锁定的秒数"},
+	}
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*UserController).Register,
+			Summary:     "用户注册",
+			Description: "注册后发送验证邮件，与其它发信接口共用限流额度",
+			Tags:        tags,
+			RateLimited: true,
+			Body:        dto.RegisterRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "注册成功", Body: openapi.Envelope(dto.RegisterResponse{})},
+			}, envelopeErrors(http.StatusBadRequest)...),
+		},
+		{
+			Handler:     (*UserController).Login,
+			Summary:     "用户登录",
+			Description: "开启两步验证的账号返回挑战令牌，需继续调用 /users/login/mfa",
+			Tags:        tags,
+			RateLimited: true,
+			Body:        dto.LoginRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "登录成功或需要两步验证", Body: openapi.Envelope(openapi.AnyOf(dto.TokenResponse{}, dto.MFAChallengeResponse{}))},
+				lockout,
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*UserController).VerifyEmail,
+			Method:      http.MethodGet,
+			OperationID: "verifyEmailLink",
+			Summary:     "邮件链接验证邮箱",
+			Tags:        tags,
+			Query:       []openapi.Param{{Name: "token", Description: "邮件中的验证令牌", Required: true}},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "验证成功", Body: message},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler: (*UserController).VerifyEmail,
+			Method:  http.MethodPost,
+			Summary: "提交令牌验证邮箱",
+			Tags:    tags,
+			Body:    TokenRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "验证成功", Body: message},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*UserController).ResendVerification,
+			Summary:     "重新发送验证邮件",
+			Description: "无论邮箱是否存在都返回相同结果",
+			Tags:        tags,
+			RateLimited: true,
+			Body:        EmailRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusAccepted, Description: "已受理", Body: message},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*UserController).ForgotPassword,
+			Summary:     "发送找回密码邮件",
+			Description: "无论邮箱是否存在都返回相同结果",
+			Tags:        tags,
+			RateLimited: true,
+			Body:        EmailRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusAccepted, Description: "已受理", Body: message},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*UserController).ResetPassword,
+			Summary:     "重置密码",
+			Description: "使用邮件中的令牌设置新密码",
+			Tags:        tags,
+			RateLimited: true,
+			Body:        ResetPasswordRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "重置成功", Body: message},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*UserController).Sessions,
+			Summary:     "登录记录",
+			Description: "当前用户最近的登录记录（时间、IP、UA）",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Query:       []openapi.Param{{Name: "limit", Description: "条数，默认20，最多100", Schema: openapi.Integer()}},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "登录记录", Body: openapi.Envelope([]model.LoginSession{})},
+			}, envelopeErrors(http.StatusUnauthorized, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:      (*UserController).Unlock,
+			Summary:      "解除登录锁定",
+			Description:  "管理员解除账号锁定，可同时解除某个 IP 的锁定",
+			Tags:         []string{"admin"},
+			Auth:         openapi.AuthAdmin,
+			Body:         UnlockRequest{},
+			OptionalBody: true,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "解锁成功", Body: message},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+	}
+}
@@ -0,0 +1,295 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler 站内通知与实时推送
+type NotificationHandler struct {
+	notificationService *logic.NotificationService
+}
+
+// NewNotificationHandler 构造函数
+func NewNotificationHandler(ns *logic.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: ns}
+}
+
+// MarkNotificationsReadRequest 标记已读请求参数结构体，ids 与 all 二选一
+type MarkNotificationsReadRequest struct {
+	IDs []uint `json:"ids" binding:"max=100"`
+	All bool   `json:"all"`
+}
+
+// NotificationListResponse 通知列表
+type NotificationListResponse struct {
+	Total         int64                `json:"total"`
+	Unread        int64                `json:"unread"`
+	Page          int                  `json:"page"`
+	PageSize      int                  `json:"page_size"`
+	Notifications []model.Notification `json:"notifications"`
+}
+
+// MarkNotificationsReadResponse 标记已读结果
+type MarkNotificationsReadResponse struct {
+	Updated int64 `json:"updated"`
+	Unread  int64 `json:"unread"`
+}
+
+// ListNotifications 当前用户的通知，按时间倒序
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	unreadOnly, err := strconv.ParseBool(c.DefaultQuery("unread", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "unread must be true or false",
+		})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > consts.NotificationMaxPageSize {
+		pageSize = consts.NotificationMaxPageSize
+	}
+
+	notifications, total, unread, err := h.notificationService.List(c.Request.Context(), userID, unreadOnly, page, pageSize)
+	if err != nil {
+		c.Error(err)
+		respondServerError(c, err, "failed to list notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": NotificationListResponse{
+			Total:         total,
+			Unread:        unread,
+			Page:          page,
+			PageSize:      pageSize,
+			Notifications: notifications,
+		},
+	})
+}
+
+// MarkNotificationsRead 批量标记已读
+func (h *NotificationHandler) MarkNotificationsRead(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	var req MarkNotificationsReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	if req.All == (len(req.IDs) > 0) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "either ids or all is required",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	updated, err := h.notificationService.MarkRead(ctx, userID, req.IDs)
+	if err != nil {
+		c.Error(err)
+		respondServerError(c, err, "failed to mark notifications read")
+		return
+	}
+	unread, err := h.notificationService.UnreadCount(ctx, userID)
+	if err != nil {
+		c.Error(err)
+		respondServerError(c, err, "failed to mark notifications read")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": MarkNotificationsReadResponse{Updated: updated, Unread: unread},
+	})
+}
+
+// StreamNotifications 以 server-sent events 推送当前用户的新通知
+// 事件 ID 为通知ID，断线重连时浏览器携带 Last-Event-ID，补发其后的通知；
+// 连接最长保持 NotificationStreamMaxAge，服务端关闭或处理过慢时结束，由客户端重连
+func (h *NotificationHandler) StreamNotifications(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+	var lastID uint64
+	if raw := c.GetHeader("Last-Event-ID"); raw != "" {
+		var err error
+		if lastID, err = strconv.ParseUint(raw, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  "invalid Last-Event-ID",
+			})
+			return
+		}
+	}
+
+	// 先订阅再补发，补发期间产生的通知留在订阅缓冲中，按ID去重
+	ctx := c.Request.Context()
+	sub, err := h.notificationService.Subscribe(ctx, userID)
+	if err != nil {
+		c.Error(err)
+		if errors.Is(err, logic.ErrNotificationStreamUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"code": 503,
+				"msg":  err.Error(),
+			})
+			return
+		}
+		respondServerError(c, err, "failed to subscribe notifications")
+		return
+	}
+	defer sub.Close()
+
+	var backlog []model.Notification
+	if lastID > 0 {
+		if backlog, err = h.notificationService.Since(ctx, userID, uint(lastID), consts.NotificationStreamBacklog); err != nil {
+			c.Error(err)
+			respondServerError(c, err, "failed to list notifications")
+			return
+		}
+	}
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // 关闭 nginx 等反向代理的响应缓冲
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	if _, err := c.Writer.WriteString("retry:" + strconv.FormatInt(consts.NotificationStreamRetry.Milliseconds(), 10) + "\n\n"); err != nil {
+		return
+	}
+
+	send := func(id uint, payload any) bool {
+		if uint64(id) <= lastID {
+			return true
+		}
+		lastID = uint64(id)
+		err := sse.Encode(c.Writer, sse.Event{
+			Id:    strconv.FormatUint(uint64(id), 10),
+			Event: "notification",
+			Data:  payload,
+		})
+		c.Writer.Flush()
+		return err == nil
+	}
+	for i := range backlog {
+		if !send(backlog[i].ID, backlog[i]) {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(consts.NotificationStreamHeartbeat)
+	defer heartbeat.Stop()
+	maxAge := time.NewTimer(consts.NotificationStreamMaxAge)
+	defer maxAge.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-maxAge.C:
+			return
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case payload, ok := <-sub.C():
+			if !ok {
+				// 服务关闭或缓冲已满，客户端重连后按 Last-Event-ID 补齐
+				return
+			}
+			var n struct {
+				ID uint `json:"id"`
+			}
+			if err := json.Unmarshal(payload, &n); err != nil {
+				continue
+			}
+			if !send(n.ID, payload) {
+				return
+			}
+		}
+	}
+}
+
+// notificationEndpoints 通知接口的 OpenAPI 描述
+func notificationEndpoints() []openapi.Endpoint {
+	tags := []string{"notifications"}
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*NotificationHandler).ListNotifications,
+			Summary:     "通知列表",
+			Description: "分页获取当前用户的通知，按时间倒序；同时返回未读总数",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Query: []openapi.Param{
+				{Name: "unread", Description: "为 true 时只返回未读通知"},
+				{Name: "page", Description: "页码，默认1", Schema: openapi.Integer()},
+				{Name: "pageSize", Description: "每页条数，默认20，最大100", Schema: openapi.Integer()},
+			},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "通知列表", Body: openapi.Envelope(NotificationListResponse{})},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*NotificationHandler).MarkNotificationsRead,
+			Summary:     "标记已读",
+			Description: "把指定的通知或全部通知标记为已读，已读或不属于当前用户的通知被忽略",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Body:        MarkNotificationsReadRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "本次标记的条数与剩余未读数", Body: openapi.Envelope(MarkNotificationsReadResponse{})},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler: (*NotificationHandler).StreamNotifications,
+			Summary: "实时通知",
+			Description: "以 server-sent events 推送新通知，事件名为 notification，data 为通知 JSON，id 为通知ID；" +
+				"重连时携带 Last-Event-ID 补发断线期间的通知。连接定期发送注释行保活，最长保持10分钟，客户端应自动重连",
+			Tags: tags,
+			Auth: openapi.AuthRequired,
+			Headers: []openapi.Param{
+				{Name: "Last-Event-ID", Description: "最后收到的通知ID", Schema: openapi.Integer()},
+			},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "事件流", Body: openapi.String(), ContentType: "text/event-stream"},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusServiceUnavailable)...),
+		},
+	}
+}
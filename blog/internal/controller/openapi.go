@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"net/http"
+
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIHandler 发布 OpenAPI 文档与在线文档页面
+type OpenAPIHandler struct {
+	spec *openapi.Spec
+}
+
+// NewOpenAPIHandler 构造函数
+func NewOpenAPIHandler(spec *openapi.Spec) *OpenAPIHandler {
+	return &OpenAPIHandler{spec: spec}
+}
+
+// NewSpec 创建博客接口文档，在 r 上注册完所有路由后调用 Build 生成
+func NewSpec(r *gin.Engine) *openapi.Spec {
+	info := openapi.Info{
+		Title:       "Blog API",
+		Version:     "1.0.0",
+		Description: "博客服务接口，由路由注册与处理器的接口描述生成",
+	}
+	tags := []openapi.Tag{
+		{Name: "users", Description: "注册、登录与账号管理"},
+		{Name: "auth", Description: "第三方登录与身份绑定"},
+		{Name: "posts", Description: "文章"},
+		{Name: "comments", Description: "评论"},
+		{Name: "graphql", Description: "GraphQL 接口"},
+		{Name: "uploads", Description: "附件"},
+		{Name: "exports", Description: "数据导出"},
+		{Name: "webhooks", Description: "出站 webhook 与投递记录"},
+		{Name: "notifications", Description: "站内通知与实时推送"},
+		{Name: "admin", Description: "管理员接口"},
+		{Name: "operations", Description: "探针、指标与公钥"},
+		{Name: "docs", Description: "接口文档"},
+	}
+	return openapi.NewSpec(r, info, tags, Endpoints())
+}
+
+// Endpoints 所有接口的 OpenAPI 描述
+func Endpoints() []openapi.Endpoint {
+	var endpoints []openapi.Endpoint
+	for _, group := range [][]openapi.Endpoint{
+		userEndpoints(),
+		profileEndpoints(),
+		totpEndpoints(),
+		accessTokenEndpoints(),
+		identityEndpoints(),
+		postEndpoints(),
+		commentEndpoints(),
+		graphqlEndpoints(),
+		uploadEndpoints(),
+		exportEndpoints(),
+		webhookEndpoints(),
+		notificationEndpoints(),
+		importEndpoints(),
+		healthEndpoints(),
+		jwksEndpoints(),
+		openAPIEndpoints(),
+	} {
+		endpoints = append(endpoints, group...)
+	}
+	return endpoints
+}
+
+// Spec 返回 OpenAPI 文档
+func (h *OpenAPIHandler) Spec(c *gin.Context) {
+	raw, err := h.spec.JSON()
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 500,
+			"msg":  "openapi document is unavailable",
+		})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", raw)
+}
+
+// SwaggerUI Swagger UI 页面
+func (h *OpenAPIHandler) SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.SwaggerUI())
+}
+
+// UIAsset 文档页面引用的脚本、样式与图标
+func (h *OpenAPIHandler) UIAsset(c *gin.Context) {
+	data, contentType, ok := openapi.UIAsset(c.Param("file"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 404,
+			"msg":  "asset not found",
+		})
+		return
+	}
+	// 文件随程序版本变化，缓存一天
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// errorResponses 文章、评论与附件接口的错误响应 {message}
+func errorResponses(statuses ...int) []openapi.Response {
+	return openapi.Errors(ErrorResponse{}, statuses...)
+}
+
+// envelopeErrors 用户、身份、导出等接口的错误响应 {code, msg}
+func envelopeErrors(statuses ...int) []openapi.Response {
+	return openapi.Errors(openapi.Envelope(nil), statuses...)
+}
+
+// openAPIEndpoints 文档与指标接口的 OpenAPI 描述
+func openAPIEndpoints() []openapi.Endpoint {
+	tags := []string{"docs"}
+	page := openapi.Response{Status: http.StatusOK, Description: "HTML 页面", Body: openapi.String(), ContentType: "text/html"}
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*OpenAPIHandler).Spec,
+			Summary:     "OpenAPI 文档",
+			Description: "由路由注册与处理器的接口描述生成的 OpenAPI 3.1 文档",
+			Tags:        tags,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "OpenAPI 文档", Body: &openapi.Schema{Type: openapi.Types{"object"}}},
+			}, envelopeErrors(http.StatusInternalServerError)...),
+		},
+		{
+			Handler:   (*OpenAPIHandler).SwaggerUI,
+			Summary:   "Swagger UI",
+			Tags:      tags,
+			Responses: []openapi.Response{page},
+		},
+		{
+			Handler:     (*OpenAPIHandler).UIAsset,
+			Summary:     "文档页面静态文件",
+			Description: "Swagger UI 的脚本、样式与图标，随程序一起编译发布",
+			Tags:        tags,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "脚本", Body: openapi.String(), ContentType: "text/javascript"},
+				{Status: http.StatusOK, Description: "样式", Body: openapi.String(), ContentType: "text/css"},
+				{Status: http.StatusOK, Description: "图标", Body: openapi.String(), ContentType: "image/png"},
+			}, envelopeErrors(http.StatusNotFound)...),
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/metrics",
+			OperationID: "metrics",
+			Summary:     "Prometheus 指标",
+			Description: "配置了 BLOG_METRICS_TOKEN 时需以 Bearer 方式携带该令牌",
+			Tags:        []string{"operations"},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Description: "Prometheus 文本格式的指标", Body: openapi.String(), ContentType: "text/plain"},
+				{Status: http.StatusUnauthorized, Description: "缺少或错误的抓取令牌"},
+			},
+		},
+	}
+}
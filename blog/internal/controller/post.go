@@ -4,8 +4,12 @@ import (
 	"net/http"
 	"strconv"
 
+	"web-task/blog/dto"
+	"web-task/blog/internal/consts"
 	"web-task/blog/internal/logic"
 	"web-task/blog/internal/model"
+	"web-task/blog/internal/openapi"
+	"web-task/blog/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,50 +25,31 @@ func NewPostHandler(postService *logic.PostService) *PostHandler {
 }
 
 // CreatePost 创建文章
-// @Summary 创建新文章
-// @Description 创建新的博客文章，需要用户ID、标题和内容
-// @Tags posts
-// @Accept json
-// @Produce json
-// @Param Authorization header string true "Bearer token"
-// @Param post body CreatePostRequest true "文章信息"
-// @Success 201 {object} model.Post
-// @Failure 400 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /posts [post]
 func (h *PostHandler) CreatePost(c *gin.Context) {
-	// 实际项目中应从JWT令牌中解析用户ID，这里简化处理
-	userID, err := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的用户ID"})
+	// 当前用户由 AuthMiddleware 写入上下文
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "请先登录"})
 		return
 	}
 
-	var req CreatePostRequest
+	var req dto.CreatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	post, err := h.postService.Create(uint(userID), req.Title, req.Content)
+	post, err := h.postService.Create(c.Request.Context(), userID, req.Title, req.Content)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		c.Error(err)
+		c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, post)
+	c.JSON(http.StatusCreated, newPostResponse(post))
 }
 
 // GetPostByID 获取文章详情
-// @Summary 获取文章详情
-// @Description 根据ID获取文章详情，包含作者信息
-// @Tags posts
-// @Produce json
-// @Param id path int true "文章ID"
-// @Success 200 {object} model.Post
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /posts/{id} [get]
 func (h *PostHandler) GetPostByID(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -72,66 +57,49 @@ func (h *PostHandler) GetPostByID(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postService.GetByID(uint(id))
+	post, err := h.postService.GetByID(c.Request.Context(), uint(id))
 	if err != nil {
 		if err == logic.ErrPostNotFound {
 			c.JSON(http.StatusNotFound, ErrorResponse{Message: "文章不存在"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		c.Error(err)
+		c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, post)
+	c.JSON(http.StatusOK, newPostResponse(post))
 }
 
 // ListPosts 文章列表
-// @Summary 获取文章列表
-// @Description 分页获取文章列表，包含作者信息，按创建时间倒序
-// @Tags posts
-// @Produce json
-// @Param page query int false "页码，默认1"
-// @Param pageSize query int false "每页条数，默认10"
-// @Success 200 {object} PostListResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /posts [get]
 func (h *PostHandler) ListPosts(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
 
-	posts, total, err := h.postService.List(page, pageSize)
+	posts, total, err := h.postService.List(c.Request.Context(), page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		c.Error(err)
+		c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, PostListResponse{
+	resp := dto.PostListResponse{
 		Total:    total,
 		Page:     page,
 		PageSize: pageSize,
-		Posts:    posts,
-	})
+		Posts:    make([]dto.Post, 0, len(posts)),
+	}
+	for i := range posts {
+		resp.Posts = append(resp.Posts, newPostResponse(&posts[i]))
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // UpdatePost 更新文章
-// @Summary 更新文章
-// @Description 更新指定ID的文章，只能更新自己的文章
-// @Tags posts
-// @Accept json
-// @Produce json
-// @Param Authorization header string true "Bearer token"
-// @Param id path int true "文章ID"
-// @Param post body UpdatePostRequest true "更新的文章信息"
-// @Success 200 {object} model.Post
-// @Failure 400 {object} ErrorResponse
-// @Failure 403 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /posts/{id} [put]
 func (h *PostHandler) UpdatePost(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的用户ID"})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "请先登录"})
 		return
 	}
 
@@ -141,13 +109,13 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	var req UpdatePostRequest
+	var req dto.UpdatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
 		return
 	}
 
-	post, err := h.postService.Update(uint(userID), uint(id), req.Title, req.Content)
+	post, err := h.postService.Update(c.Request.Context(), userID, uint(id), req.Title, req.Content)
 	if err != nil {
 		switch err {
 		case logic.ErrPostNotFound:
@@ -159,31 +127,20 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 				c.JSON(http.StatusForbidden, ErrorResponse{Message: "没有权限更新此文章"})
 				return
 			}
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+			c.Error(err)
+			c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, post)
+	c.JSON(http.StatusOK, newPostResponse(post))
 }
 
 // DeletePost 删除文章
-// @Summary 删除文章
-// @Description 删除指定ID的文章，只能删除自己的文章
-// @Tags posts
-// @Produce json
-// @Param Authorization header string true "Bearer token"
-// @Param id path int true "文章ID"
-// @Success 204 {string} string ""
-// @Failure 400 {object} ErrorResponse
-// @Failure 403 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
-// @Router /posts/{id} [delete]
 func (h *PostHandler) DeletePost(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的用户ID"})
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "请先登录"})
 		return
 	}
 
@@ -193,7 +150,7 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	err = h.postService.Delete(uint(userID), uint(id))
+	err = h.postService.Delete(c.Request.Context(), userID, uint(id))
 	if err != nil {
 		switch err {
 		case logic.ErrPostNotFound:
@@ -203,7 +160,8 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 				c.JSON(http.StatusForbidden, ErrorResponse{Message: "没有权限删除此文章"})
 				return
 			}
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+			c.Error(err)
+			c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
 		}
 		return
 	}
@@ -212,35 +170,126 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 }
 
 // 请求和响应结构体定义
-type CreatePostRequest struct {
-	Title   string `json:"title" binding:"required"`
-	Content string `json:"content" binding:"required"`
-}
-
-type UpdatePostRequest struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
-}
-
 type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
-type PostListResponse struct {
-	Total    int64        `json:"total"`
-	Page     int          `json:"page"`
-	PageSize int          `json:"page_size"`
-	Posts    []model.Post `json:"posts"`
+// newPostResponse 文章响应，作者只返回公开资料
+func newPostResponse(p *model.Post) dto.Post {
+	resp := dto.Post{
+		ID:        p.ID,
+		Title:     p.Title,
+		Content:   p.Content,
+		UserID:    p.UserID,
+		User:      p.User.Public(),
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+	for _, a := range p.Attachments {
+		resp.Attachments = append(resp.Attachments, dto.Attachment{
+			ID:        a.ID,
+			PostID:    a.PostID,
+			UserID:    a.UserID,
+			Filename:  a.Filename,
+			MimeType:  a.MimeType,
+			Size:      a.Size,
+			Width:     a.Width,
+			Height:    a.Height,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	for _, t := range p.Tags {
+		resp.Tags = append(resp.Tags, dto.Tag{ID: t.ID, Name: t.Name})
+	}
+	return resp
 }
 
 // 注册路由
-func (h *PostHandler) RegisterRoutes(router *gin.RouterGroup) {
+func (h *PostHandler) RegisterRoutes(router *gin.RouterGroup, limiter *middleware.RateLimiter) {
+	reads := limiter.Limit(consts.RateLimitReads)
+
+	read := middleware.OptionalAuthMiddleware(consts.ScopePostsRead)
+	write := middleware.AuthMiddleware(consts.ScopePostsWrite)
+
 	posts := router.Group("/posts")
 	{
-		posts.POST("", h.CreatePost)
-		posts.GET("", h.ListPosts)
-		posts.GET("/:id", h.GetPostByID)
-		posts.PUT("/:id", h.UpdatePost)
-		posts.DELETE("/:id", h.DeletePost)
+		posts.POST("", write, h.CreatePost)
+		posts.GET("", reads, read, h.ListPosts)
+		posts.GET("/:id", reads, read, h.GetPostByID)
+		posts.PUT("/:id", write, h.UpdatePost)
+		posts.DELETE("/:id", write, h.DeletePost)
+	}
+}
+
+// postEndpoints 文章接口的 OpenAPI 描述
+func postEndpoints() []openapi.Endpoint {
+	tags := []string{"posts"}
+	read := []string{consts.ScopePostsRead}
+	write := []string{consts.ScopePostsWrite}
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*PostHandler).CreatePost,
+			Summary:     "创建新文章",
+			Description: "创建新的博客文章，作者为当前用户",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Scopes:      write,
+			Body:        dto.CreatePostRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusCreated, Description: "创建成功", Body: dto.Post{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*PostHandler).ListPosts,
+			Summary:     "获取文章列表",
+			Description: "分页获取文章列表，包含作者信息，按创建时间倒序",
+			Tags:        tags,
+			Auth:        openapi.AuthOptional,
+			Scopes:      read,
+			RateLimited: true,
+			Query: []openapi.Param{
+				{Name: "page", Description: "页码，默认1", Schema: openapi.Integer()},
+				{Name: "pageSize", Description: "每页条数，默认10", Schema: openapi.Integer()},
+			},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "文章列表", Body: dto.PostListResponse{}},
+			}, errorResponses(http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*PostHandler).GetPostByID,
+			Summary:     "获取文章详情",
+			Description: "根据ID获取文章详情，包含作者信息",
+			Tags:        tags,
+			Auth:        openapi.AuthOptional,
+			Scopes:      read,
+			RateLimited: true,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "文章详情", Body: dto.Post{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*PostHandler).UpdatePost,
+			Summary:     "更新文章",
+			Description: "更新指定ID的文章，只能更新自己的文章",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Scopes:      write,
+			Body:        dto.UpdatePostRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "更新后的文章", Body: dto.Post{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*PostHandler).DeletePost,
+			Summary:     "删除文章",
+			Description: "删除指定ID的文章，只能删除自己的文章",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Scopes:      write,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusNoContent, Description: "删除成功"},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
 	}
 }
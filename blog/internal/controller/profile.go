@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"web-task/blog/dto"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteAccountRequest 注销账号请求参数结构体
+type DeleteAccountRequest struct {
+	Password    string `json:"password" binding:"required"`
+	Code        string `json:"code"` // 开启两步验证时必填，验证码或恢复码
+	DeletePosts bool   `json:"delete_posts"`
+}
+
+func newMeResponse(u *model.User) dto.Me {
+	return dto.Me{
+		ID:              u.ID,
+		Username:        u.Username,
+		Email:           u.Email,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		DisplayName:     u.DisplayName,
+		Bio:             u.Bio,
+		AvatarURL:       u.AvatarURL,
+		Role:            u.Role,
+		TOTPEnabled:     u.TOTPEnabledAt != nil,
+		CreatedAt:       u.CreatedAt,
+	}
+}
+
+// GetMe 查看当前用户资料
+func (uc *UserController) GetMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	user, err := uc.userService.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": newMeResponse(user),
+	})
+}
+
+// UpdateMe 修改当前用户资料
+func (uc *UserController) UpdateMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	var req dto.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	user, err := uc.userService.UpdateProfile(c.Request.Context(), userID, logic.ProfileUpdate{
+		DisplayName: req.DisplayName,
+		Bio:         req.Bio,
+		AvatarURL:   req.AvatarURL,
+		Email:       req.Email,
+	})
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "profile updated",
+		"data": newMeResponse(user),
+	})
+}
+
+// GetUserProfile 查看用户公开资料
+func (uc *UserController) GetUserProfile(c *gin.Context) {
+	profile, err := uc.userService.GetPublicProfile(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": dto.PublicProfileResponse{
+			Username:    profile.User.Username,
+			DisplayName: profile.User.DisplayName,
+			Bio:         profile.User.Bio,
+			AvatarURL:   profile.User.AvatarURL,
+			PostCount:   profile.PostCount,
+			CreatedAt:   profile.User.CreatedAt,
+		},
+	})
+}
+
+// ChangePassword 修改密码，其它设备上的登录令牌全部失效，返回当前客户端使用的新令牌
+func (uc *UserController) ChangePassword(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	token, err := uc.userService.ChangePassword(c.Request.Context(), userID, req.OldPassword, req.NewPassword, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "password changed",
+		"data": dto.TokenResponse{Token: token},
+	})
+}
+
+// DeleteMe 注销当前账号
+func (uc *UserController) DeleteMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	if err := uc.userService.DeleteAccount(c.Request.Context(), userID, req.Password, req.Code, req.DeletePosts); err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "account deleted",
+	})
+}
+
+func respondProfileError(c *gin.Context, err error) {
+	c.Error(err)
+	status := serverErrorStatus(err)
+	switch {
+	case errors.Is(err, logic.ErrUserNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, logic.ErrInvalidInput):
+		status = http.StatusBadRequest
+	case errors.Is(err, logic.ErrIncorrectPassword), errors.Is(err, logic.ErrInvalidMFACode):
+		status = http.StatusForbidden
+	case errors.Is(err, logic.ErrEmailTaken):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"code": status,
+		"msg":  err.Error(),
+	})
+}
+
+// profileEndpoints 个人资料接口的 OpenAPI 描述
+func profileEndpoints() []openapi.Endpoint {
+	tags := []string{"users"}
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*UserController).GetUserProfile,
+			Summary:     "用户公开资料",
+			Tags:        tags,
+			RateLimited: true,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "公开资料", Body: openapi.Envelope(dto.PublicProfileResponse{})},
+			}, envelopeErrors(http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler: (*UserController).GetMe,
+			Summary: "当前用户资料",
+			Tags:    tags,
+			Auth:    openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "当前用户资料", Body: openapi.Envelope(dto.Me{})},
+			}, envelopeErrors(http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*UserController).UpdateMe,
+			Summary:     "修改资料",
+			Description: "未提供的字段不修改，修改邮箱后需要重新验证",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Body:        dto.UpdateProfileRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "修改后的资料", Body: openapi.Envelope(dto.Me{})},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict,
+				http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*UserController).DeleteMe,
+			Summary:     "注销账号",
+			Description: "需要当前密码，开启两步验证时还需要验证码或恢复码",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Body:        DeleteAccountRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "注销成功", Body: openapi.Envelope(nil)},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+				http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*UserController).ChangePassword,
+			Summary:     "修改密码",
+			Description: "其它设备上的登录令牌全部失效，返回当前客户端使用的新令牌",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Body:        dto.ChangePasswordRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "修改成功", Body: openapi.Envelope(dto.TokenResponse{})},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+				http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+	}
+}
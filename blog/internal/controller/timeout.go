@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serverErrorStatus 服务端错误的状态码：请求超过截止时间（见 middleware.Timeout）返回 504，其余返回 500
+func serverErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// respondServerError 以 {code, msg} 格式返回服务端错误，超时统一提示 request timed out
+func respondServerError(c *gin.Context, err error, msg string) {
+	status := serverErrorStatus(err)
+	if status == http.StatusGatewayTimeout {
+		msg = "request timed out"
+	}
+	c.JSON(status, gin.H{
+		"code": status,
+		"msg":  msg,
+	})
+}
@@ -0,0 +1,298 @@
+package controller
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"web-task/blog/dto"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TOTPCodeRequest 仅包含验证码的请求参数结构体
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTOTPRequest 关闭两步验证请求参数结构体
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// TOTPEnrollmentResponse 两步验证注册信息，二维码为 Base64 编码的 PNG
+type TOTPEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCodePNG  string `json:"qr_code_png"`
+}
+
+// RecoveryCodesResponse 恢复码，只在生成时返回一次
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginMFA 登录第二步，用挑战令牌和验证码换取正式令牌
+func (uc *UserController) LoginMFA(c *gin.Context) {
+	var req dto.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	token, err := uc.userService.LoginMFA(c.Request.Context(), req.MFAToken, req.Code, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "login success",
+		"data": dto.TokenResponse{Token: token},
+	})
+}
+
+// EnrollTOTP 开始注册两步验证，返回密钥、otpauth 地址与二维码（Base64 PNG）
+func (uc *UserController) EnrollTOTP(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	enrollment, err := uc.userService.EnrollTOTP(c.Request.Context(), userID)
+	if err != nil {
+		respondTOTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "scan the qr code and confirm with a code",
+		"data": TOTPEnrollmentResponse{
+			Secret:     enrollment.Secret,
+			OTPAuthURL: enrollment.OTPAuthURL,
+			QRCodePNG:  base64.StdEncoding.EncodeToString(enrollment.QRCodePNG),
+		},
+	})
+}
+
+// TOTPQRCode 以 PNG 图片返回待确认密钥的二维码
+func (uc *UserController) TOTPQRCode(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	png, err := uc.userService.TOTPQRCode(c.Request.Context(), userID)
+	if err != nil {
+		respondTOTPError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// ConfirmTOTP 确认注册并启用两步验证，返回恢复码（仅展示一次）
+func (uc *UserController) ConfirmTOTP(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	codes, err := uc.userService.ConfirmTOTP(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondTOTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "two-factor authentication enabled, store the recovery codes safely",
+		"data": RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// DisableTOTP 关闭两步验证
+func (uc *UserController) DisableTOTP(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	var req DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	if err := uc.userService.DisableTOTP(c.Request.Context(), userID, req.Password, req.Code); err != nil {
+		respondTOTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部失效
+func (uc *UserController) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	codes, err := uc.userService.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondTOTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "recovery codes regenerated",
+		"data": RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+func respondTOTPError(c *gin.Context, err error) {
+	c.Error(err)
+	switch {
+	case errors.Is(err, logic.ErrTOTPAlreadyEnabled),
+		errors.Is(err, logic.ErrTOTPNotEnabled),
+		errors.Is(err, logic.ErrTOTPNotEnrolled):
+		c.JSON(http.StatusConflict, gin.H{
+			"code": 409,
+			"msg":  err.Error(),
+		})
+	case errors.Is(err, logic.ErrInvalidMFACode), errors.Is(err, logic.ErrInvalidCredentials):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+	case errors.Is(err, logic.ErrUserNotFound):
+		respondUnauthorized(c)
+	default:
+		respondServerError(c, err, "internal server error")
+	}
+}
+
+func respondUnauthorized(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"code": 401,
+		"msg":  "please login again",
+	})
+}
+
+// totpEndpoints 两步验证接口的 OpenAPI 描述
+func totpEndpoints() []openapi.Endpoint {
+	tags := []string{"users"}
+	totpErrors := envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict,
+		http.StatusInternalServerError, http.StatusGatewayTimeout)
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*UserController).LoginMFA,
+			Summary:     "两步验证登录",
+			Description: "用登录返回的挑战令牌和验证码（或恢复码）换取正式令牌",
+			Tags:        tags,
+			RateLimited: true,
+			Body:        dto.MFALoginRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "登录成功", Body: openapi.Envelope(dto.TokenResponse{})},
+				{
+					Status:      http.StatusTooManyRequests,
+					Description: "账号或 IP 已被临时锁定",
+					Body:        openapi.Envelope(nil),
+					Headers:     map[string]string{"Retry-After": "距离解除锁定的秒数"},
+				},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*UserController).EnrollTOTP,
+			Summary:     "开始注册两步验证",
+			Description: "返回密钥、otpauth 地址与二维码，确认后才启用",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "注册信息", Body: openapi.Envelope(TOTPEnrollmentResponse{})},
+			}, totpErrors...),
+		},
+		{
+			Handler: (*UserController).TOTPQRCode,
+			Summary: "两步验证注册二维码",
+			Tags:    tags,
+			Auth:    openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "PNG 二维码", Body: openapi.Binary(), ContentType: "image/png"},
+			}, totpErrors...),
+		},
+		{
+			Handler:     (*UserController).ConfirmTOTP,
+			Summary:     "确认并启用两步验证",
+			Description: "返回恢复码，只显示一次",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Body:        TOTPCodeRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "已启用", Body: openapi.Envelope(RecoveryCodesResponse{})},
+			}, totpErrors...),
+		},
+		{
+			Handler: (*UserController).DisableTOTP,
+			Summary: "关闭两步验证",
+			Tags:    tags,
+			Auth:    openapi.AuthRequired,
+			Body:    DisableTOTPRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "已关闭", Body: openapi.Envelope(nil)},
+			}, totpErrors...),
+		},
+		{
+			Handler:     (*UserController).RegenerateRecoveryCodes,
+			Summary:     "重新生成恢复码",
+			Description: "旧的恢复码全部失效",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Body:        TOTPCodeRequest{},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "新的恢复码", Body: openapi.Envelope(RecoveryCodesResponse{})},
+			}, totpErrors...),
+		},
+	}
+}
@@ -0,0 +1,298 @@
+// internal/handler/uploads.go
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/openapi"
+	"web-task/blog/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead multipart 编码及其它表单字段的额外预留空间
+const multipartOverhead = 1 << 20
+
+type UploadHandler struct {
+	uploadService *logic.UploadService
+}
+
+func NewUploadHandler(uploadService *logic.UploadService) *UploadHandler {
+	return &UploadHandler{
+		uploadService: uploadService,
+	}
+}
+
+// Upload 上传附件
+func (h *UploadHandler) Upload(c *gin.Context) {
+	// 当前用户由 AuthMiddleware 写入上下文
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "请先登录"})
+		return
+	}
+
+	// 在解析表单前限制请求体大小，防止超大请求占满磁盘或内存
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, consts.UploadMaxSize+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Message: "文件过大"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "缺少上传文件"})
+		return
+	}
+	if fileHeader.Size > consts.UploadMaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Message: "文件过大"})
+		return
+	}
+
+	var postID *uint
+	if raw := c.PostForm("post_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的文章ID"})
+			return
+		}
+		pid := uint(id)
+		postID = &pid
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.uploadService.Upload(c.Request.Context(), userID, postID, fileHeader.Filename, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, logic.ErrFileTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Message: "文件过大"})
+		case errors.Is(err, logic.ErrUnsupportedFileType):
+			c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{Message: err.Error()})
+		case errors.Is(err, logic.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		case errors.Is(err, logic.ErrPostNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "文章不存在"})
+		case err.Error() == "permission denied":
+			c.JSON(http.StatusForbidden, ErrorResponse{Message: "没有权限向此文章添加附件"})
+		default:
+			c.Error(err)
+			c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, newAttachmentResponse(c, attachment))
+}
+
+// GetUpload 获取附件信息
+func (h *UploadHandler) GetUpload(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的附件ID"})
+		return
+	}
+
+	attachment, err := h.uploadService.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, logic.ErrAttachmentNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "附件不存在"})
+			return
+		}
+		c.Error(err)
+		c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newAttachmentResponse(c, attachment))
+}
+
+// GetUploadContent 下载附件内容
+func (h *UploadHandler) GetUploadContent(c *gin.Context) {
+	h.serve(c, false)
+}
+
+// GetUploadThumbnail 获取图片缩略图
+func (h *UploadHandler) GetUploadThumbnail(c *gin.Context) {
+	h.serve(c, true)
+}
+
+func (h *UploadHandler) serve(c *gin.Context, thumbnail bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的附件ID"})
+		return
+	}
+
+	rc, contentType, attachment, err := h.uploadService.Open(c.Request.Context(), uint(id), thumbnail)
+	if err != nil {
+		if errors.Is(err, logic.ErrAttachmentNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "附件不存在"})
+			return
+		}
+		c.Error(err)
+		c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+	defer rc.Close()
+
+	// 非图片一律以附件形式下载，避免浏览器内联渲染上传内容
+	disposition := "inline"
+	if !thumbnail && attachment.Width == 0 {
+		disposition = "attachment"
+	}
+	c.DataFromReader(http.StatusOK, -1, contentType, rc, map[string]string{
+		"Content-Disposition":    fmt.Sprintf("%s; filename=%q", disposition, attachment.Filename),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// DeleteUpload 删除附件
+func (h *UploadHandler) DeleteUpload(c *gin.Context) {
+	// 当前用户由 AuthMiddleware 写入上下文
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "请先登录"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "无效的附件ID"})
+		return
+	}
+
+	err = h.uploadService.Delete(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, logic.ErrAttachmentNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "附件不存在"})
+		case err.Error() == "permission denied":
+			c.JSON(http.StatusForbidden, ErrorResponse{Message: "没有权限删除此附件"})
+		default:
+			c.Error(err)
+			c.JSON(serverErrorStatus(err), ErrorResponse{Message: err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AttachmentResponse 附件信息及访问地址
+type AttachmentResponse struct {
+	model.Attachment
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+func newAttachmentResponse(c *gin.Context, a *model.Attachment) AttachmentResponse {
+	base := fmt.Sprintf("%s/%d", uploadsPathPrefix(c), a.ID)
+	resp := AttachmentResponse{Attachment: *a, URL: base + "/content"}
+	if a.ThumbnailKey != "" {
+		resp.ThumbnailURL = base + "/thumbnail"
+	}
+	return resp
+}
+
+// uploadsPathPrefix 根据当前路由推导 /uploads 的完整前缀（含 /api/v1）
+func uploadsPathPrefix(c *gin.Context) string {
+	full := c.FullPath()
+	if i := strings.Index(full, "/uploads"); i >= 0 {
+		return full[:i+len("/uploads")]
+	}
+	return "/uploads"
+}
+
+// 注册路由
+func (h *UploadHandler) RegisterRoutes(router *gin.RouterGroup, limiter *middleware.RateLimiter) {
+	reads := limiter.Limit(consts.RateLimitReads)
+	write := middleware.AuthMiddleware(consts.ScopePostsWrite)
+
+	uploads := router.Group("/uploads", middleware.TransferTimeout())
+	{
+		uploads.POST("", write, h.Upload)
+		uploads.GET("/:id", reads, h.GetUpload)
+		uploads.GET("/:id/content", reads, h.GetUploadContent)
+		uploads.GET("/:id/thumbnail", reads, h.GetUploadThumbnail)
+		uploads.DELETE("/:id", write, h.DeleteUpload)
+	}
+}
+
+// uploadEndpoints 附件接口的 OpenAPI 描述
+func uploadEndpoints() []openapi.Endpoint {
+	tags := []string{"uploads"}
+	write := []string{consts.ScopePostsWrite}
+	serveErrors := errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*UploadHandler).Upload,
+			Summary:     "上传附件",
+			Description: "以 multipart/form-data 上传文件，类型以内容嗅探为准，图片会生成缩略图；可选关联到自己的文章",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Scopes:      write,
+			Form: []openapi.Param{
+				{Name: "file", Description: "上传的文件", Required: true, Schema: openapi.Binary()},
+				{Name: "post_id", Description: "关联的文章ID", Schema: openapi.Integer()},
+			},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusCreated, Description: "上传成功", Body: AttachmentResponse{}},
+			}, errorResponses(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusRequestEntityTooLarge,
+				http.StatusUnsupportedMediaType, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*UploadHandler).GetUpload,
+			Summary:     "获取附件信息",
+			Tags:        tags,
+			RateLimited: true,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "附件信息及访问地址", Body: AttachmentResponse{}},
+			}, serveErrors...),
+		},
+		{
+			Handler:     (*UploadHandler).GetUploadContent,
+			Summary:     "下载附件内容",
+			Description: "内容类型为上传时嗅探得到的类型，非图片以附件形式下载",
+			Tags:        tags,
+			RateLimited: true,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "附件内容", Body: openapi.Binary(), ContentType: "*/*"},
+			}, serveErrors...),
+		},
+		{
+			Handler:     (*UploadHandler).GetUploadThumbnail,
+			Summary:     "获取图片缩略图",
+			Tags:        tags,
+			RateLimited: true,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "缩略图", Body: openapi.Binary(), ContentType: "image/*"},
+			}, serveErrors...),
+		},
+		{
+			Handler:     (*UploadHandler).DeleteUpload,
+			Summary:     "删除附件",
+			Description: "删除附件及其存储文件，仅上传者可操作",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Scopes:      write,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusNoContent, Description: "删除成功"},
+			}, errorResponses(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+	}
+}
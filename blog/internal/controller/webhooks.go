@@ -0,0 +1,448 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler 出站 webhook 与投递记录
+type WebhookHandler struct {
+	webhookService *logic.WebhookService
+}
+
+// NewWebhookHandler 构造函数
+func NewWebhookHandler(ws *logic.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: ws}
+}
+
+// CreateWebhookRequest 注册 webhook 请求参数结构体
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=2048"`
+	Events      []string `json:"events" binding:"required,min=1"` // post.created、post.updated、post.deleted、comment.created
+	Description string   `json:"description" binding:"max=255"`
+}
+
+// UpdateWebhookRequest 修改 webhook 请求参数结构体，未提供的字段不修改
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url" binding:"omitempty,url,max=2048"`
+	Events      []string `json:"events" binding:"omitempty,min=1"`
+	Active      *bool    `json:"active"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+}
+
+// WebhookResponse webhook 信息，签名密钥只在创建时返回
+type WebhookResponse struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Active      bool      `json:"active"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Secret      string    `json:"secret,omitempty"`
+}
+
+func newWebhookResponse(w *model.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:          w.ID,
+		URL:         w.URL,
+		Events:      w.EventList(),
+		Active:      w.Active,
+		Description: w.Description,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+	}
+}
+
+// WebhookDeliveryResponse 投递记录
+type WebhookDeliveryResponse struct {
+	ID             uint       `json:"id"`
+	EventID        uint       `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"` // 仅 pending 状态有值
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus int        `json:"response_status"`
+	Error          string     `json:"error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func newWebhookDeliveryResponse(d *model.WebhookDelivery) WebhookDeliveryResponse {
+	resp := WebhookDeliveryResponse{
+		ID:             d.ID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastAttemptAt:  d.LastAttemptAt,
+		ResponseStatus: d.ResponseStatus,
+		Error:          d.Error,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Status == consts.WebhookDeliveryPending {
+		next := d.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	return resp
+}
+
+// CreateWebhook 注册 webhook
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	hook, secret, err := h.webhookService.Create(c.Request.Context(), userID, req.URL, req.Events, req.Description)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	resp := newWebhookResponse(hook)
+	resp.Secret = secret
+	c.Header("Location", fmt.Sprintf("/api/v1/users/me/webhooks/%d", hook.ID))
+	c.JSON(http.StatusCreated, gin.H{
+		"code": 201,
+		"msg":  "webhook created, the secret will not be shown again",
+		"data": resp,
+	})
+}
+
+// ListWebhooks 列出当前用户的 webhook
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+
+	hooks, err := h.webhookService.List(c.Request.Context(), userID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	data := make([]WebhookResponse, 0, len(hooks))
+	for i := range hooks {
+		data = append(data, newWebhookResponse(&hooks[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": data,
+	})
+}
+
+// GetWebhook 获取 webhook
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+	id, ok := webhookParam(c, "id")
+	if !ok {
+		return
+	}
+
+	hook, err := h.webhookService.Get(c.Request.Context(), userID, id)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": newWebhookResponse(hook),
+	})
+}
+
+// UpdateWebhook 修改 webhook
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+	id, ok := webhookParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	hook, err := h.webhookService.Update(c.Request.Context(), userID, id, logic.WebhookUpdate{
+		URL:         req.URL,
+		Events:      req.Events,
+		Active:      req.Active,
+		Description: req.Description,
+	})
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "webhook updated",
+		"data": newWebhookResponse(hook),
+	})
+}
+
+// DeleteWebhook 删除 webhook
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+	id, ok := webhookParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.Delete(c.Request.Context(), userID, id); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "webhook deleted",
+	})
+}
+
+// ListDeliveries webhook 的投递记录，按时间倒序
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+	id, ok := webhookParam(c, "id")
+	if !ok {
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", consts.WebhookDeliveryPending, consts.WebhookDeliverySucceeded, consts.WebhookDeliveryDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "status must be pending, succeeded or dead",
+		})
+		return
+	}
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"msg":  "limit must be between 1 and 100",
+			})
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request.Context(), userID, id, status, limit)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	data := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		data = append(data, newWebhookDeliveryResponse(&deliveries[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "success",
+		"data": data,
+	})
+}
+
+// Redeliver 重新投递成功或进入死信的投递
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		respondUnauthorized(c)
+		return
+	}
+	id, ok := webhookParam(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := webhookParam(c, "deliveryID")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(c.Request.Context(), userID, id, deliveryID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code": 202,
+		"msg":  "delivery queued",
+		"data": newWebhookDeliveryResponse(delivery),
+	})
+}
+
+// webhookParam 解析路径中的 ID，无效时直接返回 404
+func webhookParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		err := logic.ErrWebhookNotFound
+		if name == "deliveryID" {
+			err = logic.ErrDeliveryNotFound
+		}
+		respondWebhookError(c, err)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	c.Error(err)
+	status := serverErrorStatus(err)
+	msg := "internal server error"
+	switch {
+	case errors.Is(err, logic.ErrWebhookNotFound), errors.Is(err, logic.ErrDeliveryNotFound):
+		status, msg = http.StatusNotFound, err.Error()
+	case errors.Is(err, logic.ErrInvalidInput), errors.Is(err, logic.ErrInvalidEvent):
+		status, msg = http.StatusBadRequest, err.Error()
+	case errors.Is(err, logic.ErrTooManyWebhooks):
+		status, msg = http.StatusConflict, err.Error()
+	case status == http.StatusGatewayTimeout:
+		msg = "request timed out"
+	}
+
+	c.JSON(status, gin.H{
+		"code": status,
+		"msg":  msg,
+	})
+}
+
+// webhookEndpoints webhook 接口的 OpenAPI 描述
+func webhookEndpoints() []openapi.Endpoint {
+	tags := []string{"webhooks"}
+	single := func(status int, description string) []openapi.Response {
+		return append([]openapi.Response{
+			{Status: status, Description: description, Body: openapi.Envelope(WebhookResponse{})},
+		}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...)
+	}
+
+	return []openapi.Endpoint{
+		{
+			Handler:     (*WebhookHandler).CreateWebhook,
+			Summary:     "注册 webhook",
+			Description: "订阅自己文章的发布、修改、删除以及文章下的新评论；请求体用返回的密钥签名，密钥只在创建时返回一次",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Body:        CreateWebhookRequest{},
+			Responses: append([]openapi.Response{
+				{
+					Status:      http.StatusCreated,
+					Description: "创建成功",
+					Body:        openapi.Envelope(WebhookResponse{}),
+					Headers:     map[string]string{"Location": "webhook 地址"},
+				},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler: (*WebhookHandler).ListWebhooks,
+			Summary: "webhook 列表",
+			Tags:    tags,
+			Auth:    openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "webhook 列表，不含密钥", Body: openapi.Envelope([]WebhookResponse{})},
+			}, envelopeErrors(http.StatusUnauthorized, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:   (*WebhookHandler).GetWebhook,
+			Summary:   "获取 webhook",
+			Tags:      tags,
+			Auth:      openapi.AuthRequired,
+			Responses: single(http.StatusOK, "webhook 信息"),
+		},
+		{
+			Handler:     (*WebhookHandler).UpdateWebhook,
+			Summary:     "修改 webhook",
+			Description: "修改接收地址、订阅的事件、启用状态或备注，未提供的字段不修改；停用后尚未完成的投递进入死信",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Body:        UpdateWebhookRequest{},
+			Responses:   single(http.StatusOK, "修改成功"),
+		},
+		{
+			Handler: (*WebhookHandler).DeleteWebhook,
+			Summary: "删除 webhook",
+			Tags:    tags,
+			Auth:    openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "删除成功", Body: openapi.Envelope(nil)},
+			}, envelopeErrors(http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*WebhookHandler).ListDeliveries,
+			Summary:     "投递记录",
+			Description: "按时间倒序列出最近的投递，失败的投递按指数退避重试，重试耗尽后状态为 dead",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Query: []openapi.Param{
+				{Name: "status", Description: "按状态过滤：pending、succeeded 或 dead"},
+				{Name: "limit", Description: "返回条数，1-100，默认50", Schema: openapi.Integer()},
+			},
+			Responses: append([]openapi.Response{
+				{Status: http.StatusOK, Description: "投递记录", Body: openapi.Envelope([]WebhookDeliveryResponse{})},
+			}, envelopeErrors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+		{
+			Handler:     (*WebhookHandler).Redeliver,
+			Summary:     "重新投递",
+			Description: "重新投递成功或进入死信的请求，尝试次数清零；仍在重试中的投递原样返回",
+			Tags:        tags,
+			Auth:        openapi.AuthRequired,
+			Responses: append([]openapi.Response{
+				{Status: http.StatusAccepted, Description: "已加入投递队列", Body: openapi.Envelope(WebhookDeliveryResponse{})},
+			}, envelopeErrors(http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout)...),
+		},
+	}
+}
@@ -0,0 +1,216 @@
+// Package graph 博客的 GraphQL 接口：查询用户、文章与评论，发表、修改与删除文章和评论
+// 解析函数复用 logic 中的服务；作者、文章评论与评论所属文章经请求内的批量加载器合并查询，
+// 执行前按深度与复杂度限制拒绝过大的查询
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/middleware"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+var (
+	ErrUnauthenticated   = errors.New("authentication required")
+	ErrInsufficientScope = errors.New("insufficient scope")
+	ErrInternal          = errors.New("internal server error")
+	ErrQueryTooDeep      = errors.New("query is too deep")
+	ErrQueryTooComplex   = errors.New("query is too complex")
+	ErrTooManyMutations  = errors.New("too many mutation fields")
+	ErrRateLimited       = errors.New("rate limit exceeded")
+)
+
+// Limits 查询的深度与复杂度上限及一次变更操作最多包含的顶层字段数，0 表示不限制
+type Limits struct {
+	MaxDepth          int
+	MaxComplexity     int
+	MaxMutationFields int
+}
+
+// Viewer 发起请求的用户；UserID 为 0 表示匿名，Scopes 为 nil 表示登录令牌，不受权限范围限制
+// Username 与 ClientIP 用作限流键，与 HTTP 路由共用令牌桶
+type Viewer struct {
+	UserID   uint
+	Username string
+	ClientIP string
+	Scopes   []string
+}
+
+func (v Viewer) allows(scope string) bool {
+	return v.Scopes == nil || slices.Contains(v.Scopes, scope)
+}
+
+// Request 一次 GraphQL 请求
+type Request struct {
+	Query         string
+	OperationName string
+	Variables     map[string]any
+}
+
+// Result 一次请求的结果
+type Result struct {
+	Data   any
+	Errors []gqlerrors.FormattedError
+	// Rejected 查询无法解析、不符合 schema 或超出限制，没有执行
+	Rejected bool
+	// Internal 执行中出现的服务端错误；响应中只显示 ErrInternal，原始错误由调用方记录
+	Internal []error
+}
+
+// Server 执行 GraphQL 请求
+type Server struct {
+	schema   graphql.Schema
+	limits   Limits
+	users    *logic.UserService
+	posts    *logic.PostService
+	comments *logic.CommentService
+	limiter  *middleware.RateLimiter
+}
+
+// NewServer 构造函数；limiter 为 nil 时变更不限流
+func NewServer(users *logic.UserService, posts *logic.PostService, comments *logic.CommentService, limiter *middleware.RateLimiter, limits Limits) (*Server, error) {
+	s := &Server{limits: limits, users: users, posts: posts, comments: comments, limiter: limiter}
+	schema, err := s.buildSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build graphql schema: %w", err)
+	}
+	s.schema = schema
+	return s, nil
+}
+
+// Execute 解析、校验并执行查询
+func (s *Server) Execute(ctx context.Context, viewer Viewer, req Request) *Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		return &Result{Errors: gqlerrors.FormatErrors(err), Rejected: true}
+	}
+	if v := graphql.ValidateDocument(&s.schema, doc, nil); !v.IsValid {
+		return &Result{Errors: v.Errors, Rejected: true}
+	}
+	if err := s.limits.check(doc, req.OperationName, req.Variables); err != nil {
+		return &Result{Errors: gqlerrors.FormatErrors(err), Rejected: true}
+	}
+
+	state := &requestState{viewer: viewer, loaders: s.newLoaders()}
+	res := graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       context.WithValue(ctx, stateKey{}, state),
+	})
+	return &Result{Data: res.Data, Errors: res.Errors, Internal: state.internal}
+}
+
+// requestState 单次请求内共享的状态
+type requestState struct {
+	viewer   Viewer
+	loaders  *loaders
+	internal []error
+}
+
+type stateKey struct{}
+
+func stateFrom(ctx context.Context) *requestState {
+	st, _ := ctx.Value(stateKey{}).(*requestState)
+	return st
+}
+
+// loaders 请求内的批量加载器
+type loaders struct {
+	users    *Loader[uint, *model.User]
+	posts    *Loader[uint, *model.Post]
+	comments *Loader[uint, []*model.Comment] // 按文章 ID，每篇最多 GraphQLMaxListSize 条
+}
+
+func (s *Server) newLoaders() *loaders {
+	return &loaders{
+		users: NewLoader(func(ctx context.Context, ids []uint) (map[uint]*model.User, error) {
+			users, err := s.users.GetUsers(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uint]*model.User, len(users))
+			for i := range users {
+				byID[users[i].ID] = &users[i]
+			}
+			return byID, nil
+		}),
+		posts: NewLoader(func(ctx context.Context, ids []uint) (map[uint]*model.Post, error) {
+			posts, err := s.posts.GetByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uint]*model.Post, len(posts))
+			for i := range posts {
+				byID[posts[i].ID] = &posts[i]
+			}
+			return byID, nil
+		}),
+		comments: NewLoader(func(ctx context.Context, postIDs []uint) (map[uint][]*model.Comment, error) {
+			// 同一篇文章可能在不同位置以不同的 first 请求，按上限加载后由字段各自截取
+			comments, err := s.comments.ListByPosts(ctx, postIDs, consts.GraphQLMaxListSize)
+			if err != nil {
+				return nil, err
+			}
+			// 没有评论的文章也返回空列表，避免被当作不存在
+			byPost := make(map[uint][]*model.Comment, len(postIDs))
+			for _, id := range postIDs {
+				byPost[id] = []*model.Comment{}
+			}
+			for i := range comments {
+				byPost[comments[i].PostID] = append(byPost[comments[i].PostID], &comments[i])
+			}
+			return byPost, nil
+		}),
+	}
+}
+
+// fail 把服务层错误转换为返回给客户端的错误：业务错误原样返回，其它错误记录后只返回 ErrInternal
+func fail(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, logic.ErrInvalidInput),
+		errors.Is(err, logic.ErrPostNotFound),
+		errors.Is(err, logic.ErrCommentNotFound),
+		errors.Is(err, ErrUnauthenticated),
+		errors.Is(err, ErrInsufficientScope),
+		err.Error() == "permission denied":
+		return err
+	}
+	if st := stateFrom(ctx); st != nil {
+		st.internal = append(st.internal, err)
+	}
+	return ErrInternal
+}
+
+// takeToken 按策略为当前用户取一个令牌，与 HTTP 路由共用令牌桶，一次请求中的多个变更字段各自计数
+func (s *Server) takeToken(ctx context.Context, policy string) error {
+	v := stateFrom(ctx).viewer
+	res := s.limiter.Take(ctx, policy, v.Username, v.ClientIP)
+	if !res.Allowed {
+		return fmt.Errorf("%w: retry after %s", ErrRateLimited, res.RetryAfter.Round(time.Second))
+	}
+	return nil
+}
+
+// viewer 取当前用户，scope 非空时要求个人访问令牌持有该权限范围
+func viewer(ctx context.Context, scope string, required bool) (Viewer, error) {
+	v := stateFrom(ctx).viewer
+	if required && v.UserID == 0 {
+		return v, ErrUnauthenticated
+	}
+	if scope != "" && v.UserID != 0 && !v.allows(scope) {
+		return v, fmt.Errorf("%w: %s required", ErrInsufficientScope, scope)
+	}
+	return v, nil
+}
@@ -0,0 +1,160 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"web-task/blog/internal/consts"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// listArgs 返回列表的字段及决定条数的参数与默认值，计算复杂度时子字段的开销按条数放大
+var listArgs = map[string]struct {
+	arg  string
+	dflt int
+}{
+	"posts":    {"pageSize", consts.GraphQLPageSize},
+	"comments": {"first", consts.GraphQLCommentsFirst},
+}
+
+// check 在执行前估算查询的深度与复杂度：每个字段计 1，列表字段的子字段按可能返回的条数累乘；
+// 内省字段不计入
+func (l Limits) check(doc *ast.Document, operationName string, variables map[string]any) error {
+	var op *ast.OperationDefinition
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				op = d
+			}
+		case *ast.FragmentDefinition:
+			fragments[d.Name.Value] = d
+		}
+	}
+	// 校验已保证操作存在，找不到时交由执行阶段报错
+	if op == nil {
+		return nil
+	}
+
+	w := &walker{fragments: fragments, variables: variables}
+	// 别名可以让一次请求包含任意多个变更，限制顶层字段数，避免绕过按请求计数的限流
+	if l.MaxMutationFields > 0 && op.Operation == ast.OperationTypeMutation {
+		if n := w.rootFields(op.SelectionSet, map[string]bool{}); n > l.MaxMutationFields {
+			return fmt.Errorf("%w: %d exceeds %d", ErrTooManyMutations, n, l.MaxMutationFields)
+		}
+	}
+	depth, cost := w.selections(op.SelectionSet, 1, map[string]bool{})
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return fmt.Errorf("%w: depth %d exceeds %d", ErrQueryTooDeep, depth, l.MaxDepth)
+	}
+	if l.MaxComplexity > 0 && cost > l.MaxComplexity {
+		return fmt.Errorf("%w: complexity %d exceeds %d", ErrQueryTooComplex, cost, l.MaxComplexity)
+	}
+	return nil
+}
+
+type walker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+// selections 返回选择集的最大深度与总开销；visiting 记录展开中的片段，防止循环引用
+func (w *walker) selections(set *ast.SelectionSet, depth int, visiting map[string]bool) (maxDepth, cost int) {
+	if set == nil {
+		return depth - 1, 0
+	}
+	maxDepth = depth - 1
+	for _, sel := range set.Selections {
+		var d, c int
+		switch s := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			d, c = w.selections(s.SelectionSet, depth+1, visiting)
+			c = min(1+c*w.multiplier(s), maxCost)
+			d = max(d, depth)
+		case *ast.InlineFragment:
+			d, c = w.selections(s.SelectionSet, depth, visiting)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			frag, ok := w.fragments[name]
+			if !ok || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			d, c = w.selections(frag.SelectionSet, depth, visiting)
+			delete(visiting, name)
+		}
+		maxDepth = max(maxDepth, d)
+		cost = min(cost+c, maxCost)
+	}
+	return maxDepth, cost
+}
+
+// rootFields 统计选择集的顶层字段数，展开片段，不计内省字段
+func (w *walker) rootFields(set *ast.SelectionSet, visiting map[string]bool) int {
+	n := 0
+	for _, sel := range set.Selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			if !strings.HasPrefix(s.Name.Value, "__") {
+				n++
+			}
+		case *ast.InlineFragment:
+			n += w.rootFields(s.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			frag, ok := w.fragments[name]
+			if !ok || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			n += w.rootFields(frag.SelectionSet, visiting)
+			delete(visiting, name)
+		}
+	}
+	return n
+}
+
+// maxCost 开销上限，避免多层列表累乘时溢出
+const maxCost = 1 << 30
+
+// multiplier 列表字段按请求的条数放大子字段的开销
+func (w *walker) multiplier(f *ast.Field) int {
+	spec, ok := listArgs[f.Name.Value]
+	if !ok {
+		return 1
+	}
+	n := spec.dflt
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != spec.arg {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if i, err := strconv.Atoi(v.Value); err == nil {
+				n = i
+			}
+		case *ast.Variable:
+			if i, ok := intVariable(w.variables[v.Name.Value]); ok {
+				n = i
+			}
+		}
+	}
+	return min(max(n, 1), maxCost)
+}
+
+// intVariable 变量来自 JSON 解码，数字为 float64
+func intVariable(v any) (int, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), true
+	case int:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// Loader 请求内的批量加载器
+// Load 只登记键并返回取结果的函数；graphql-go 先执行完同一层全部字段的解析函数，再逐层调用它们返回的 thunk，
+// 第一个 thunk 取结果时一次性加载所有已登记的键，从而把一层内的 N 次查询合并为一次。结果在请求内缓存
+type Loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	values  map[K]V
+	errs    map[K]error
+}
+
+// NewLoader fetch 按一组键批量加载，不存在的键不出现在返回值中
+func NewLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:  fetch,
+		queued: make(map[K]bool),
+		values: make(map[K]V),
+		errs:   make(map[K]error),
+	}
+}
+
+// Load 登记 key，返回的函数取得结果；key 不存在时 ok 为 false
+func (l *Loader[K, V]) Load(ctx context.Context, key K) func() (value V, ok bool, err error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, bool, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			l.dispatch(ctx)
+		}
+		if err := l.errs[key]; err != nil {
+			var zero V
+			return zero, false, err
+		}
+		v, ok := l.values[key]
+		return v, ok, nil
+	}
+}
+
+// dispatch 加载全部已登记但未加载的键，调用方持有 mu
+func (l *Loader[K, V]) dispatch(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+
+	values, err := l.fetch(ctx, keys)
+	for _, k := range keys {
+		if err != nil {
+			l.errs[k] = err
+		} else if v, ok := values[k]; ok {
+			l.values[k] = v
+		}
+	}
+}
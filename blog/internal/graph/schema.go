@@ -0,0 +1,434 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+
+	"github.com/graphql-go/graphql"
+)
+
+// postPage posts 查询的一页
+type postPage struct {
+	total    int64
+	page     int
+	pageSize int
+	items    []*model.Post
+}
+
+func (s *Server) buildSchema() (graphql.Schema, error) {
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "User",
+		Description: "用户公开资料",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: field(func(u *model.User) any { return u.ID })},
+			"username":    &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(u *model.User) any { return u.Username })},
+			"displayName": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(u *model.User) any { return u.DisplayName })},
+			"bio":         &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(u *model.User) any { return u.Bio })},
+			"avatarUrl":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(u *model.User) any { return u.AvatarURL })},
+			"createdAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: field(func(u *model.User) any { return u.CreatedAt })},
+		},
+	})
+
+	// 文章与评论互相引用，字段延迟到两个类型都创建后再定义
+	var postType, commentType *graphql.Object
+	postType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Post",
+		Description: "文章",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: field(func(p *model.Post) any { return p.ID })},
+				"title":     &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(p *model.Post) any { return p.Title })},
+				"content":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(p *model.Post) any { return p.Content })},
+				"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: field(func(p *model.Post) any { return p.CreatedAt })},
+				"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: field(func(p *model.Post) any { return p.UpdatedAt })},
+				"author":    &graphql.Field{Type: userType, Description: "作者，已注销时为 null", Resolve: s.postAuthor},
+				"comments": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(commentType))),
+					Description: "评论，按发表时间正序",
+					Args: graphql.FieldConfigArgument{
+						"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: consts.GraphQLCommentsFirst, Description: "最多返回的条数"},
+					},
+					Resolve: s.postComments,
+				},
+			}
+		}),
+	})
+	commentType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Comment",
+		Description: "评论",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: field(func(c *model.Comment) any { return c.ID })},
+				"content":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(c *model.Comment) any { return c.Content })},
+				"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: field(func(c *model.Comment) any { return c.CreatedAt })},
+				"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: field(func(c *model.Comment) any { return c.UpdatedAt })},
+				"parentId":  &graphql.Field{Type: graphql.ID, Description: "回复的顶级评论，顶级评论为 null", Resolve: field(func(c *model.Comment) any { return c.ParentID })},
+				"author":    &graphql.Field{Type: userType, Description: "评论者，已注销时为 null", Resolve: s.commentAuthor},
+				"post":      &graphql.Field{Type: postType, Description: "所属文章，已删除时为 null", Resolve: s.commentPost},
+			}
+		}),
+	})
+
+	pageType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "PostPage",
+		Description: "文章列表的一页，按发表时间倒序",
+		Fields: graphql.Fields{
+			"total":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: field(func(p *postPage) any { return p.total })},
+			"page":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: field(func(p *postPage) any { return p.page })},
+			"pageSize": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: field(func(p *postPage) any { return p.pageSize })},
+			"items":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postType))), Resolve: field(func(p *postPage) any { return p.items })},
+		},
+	})
+
+	id := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}
+	text := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}
+	optionalText := &graphql.ArgumentConfig{Type: graphql.String, Description: "不传时不修改"}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"post": &graphql.Field{Type: postType, Description: "文章，不存在时为 null", Args: graphql.FieldConfigArgument{"id": id}, Resolve: s.post},
+			"posts": &graphql.Field{
+				Type:        graphql.NewNonNull(pageType),
+				Description: "分页获取文章",
+				Args: graphql.FieldConfigArgument{
+					"page":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+					"pageSize": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: consts.GraphQLPageSize},
+				},
+				Resolve: s.postList,
+			},
+			"comment": &graphql.Field{Type: commentType, Description: "评论，不存在时为 null", Args: graphql.FieldConfigArgument{"id": id}, Resolve: s.comment},
+			"user": &graphql.Field{
+				Type:        userType,
+				Description: "按用户名查找用户，不存在时为 null",
+				Args:        graphql.FieldConfigArgument{"username": text},
+				Resolve:     s.user,
+			},
+			"me": &graphql.Field{Type: graphql.NewNonNull(userType), Description: "当前登录的用户", Resolve: s.me},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createPost": &graphql.Field{
+				Type:    graphql.NewNonNull(postType),
+				Args:    graphql.FieldConfigArgument{"title": text, "content": text},
+				Resolve: s.createPost,
+			},
+			"updatePost": &graphql.Field{
+				Type:        graphql.NewNonNull(postType),
+				Description: "修改自己的文章",
+				Args:        graphql.FieldConfigArgument{"id": id, "title": optionalText, "content": optionalText},
+				Resolve:     s.updatePost,
+			},
+			"deletePost": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "删除自己的文章",
+				Args:        graphql.FieldConfigArgument{"id": id},
+				Resolve:     s.deletePost,
+			},
+			"createComment": &graphql.Field{
+				Type:        graphql.NewNonNull(commentType),
+				Description: "发表评论；parentId 为同一文章下的顶级评论时作为回复",
+				Args: graphql.FieldConfigArgument{
+					"postId":   id,
+					"content":  text,
+					"parentId": &graphql.ArgumentConfig{Type: graphql.ID},
+				},
+				Resolve: s.createComment,
+			},
+			"updateComment": &graphql.Field{
+				Type:        graphql.NewNonNull(commentType),
+				Description: "修改自己的评论",
+				Args:        graphql.FieldConfigArgument{"id": id, "content": text},
+				Resolve:     s.updateComment,
+			},
+			"deleteComment": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "删除自己的评论",
+				Args:        graphql.FieldConfigArgument{"id": id},
+				Resolve:     s.deleteComment,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// field 按来源对象的类型读取字段
+func field[T any](get func(*T) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		src, ok := p.Source.(*T)
+		if !ok {
+			return nil, nil
+		}
+		return get(src), nil
+	}
+}
+
+// thunk 把加载器的结果包装为 graphql-go 按层求值的 thunk，不存在时返回 null
+func thunk[V any](ctx context.Context, load func() (V, bool, error)) func() (any, error) {
+	return func() (any, error) {
+		v, ok, err := load()
+		if err != nil {
+			return nil, fail(ctx, err)
+		}
+		if !ok {
+			return nil, nil
+		}
+		return v, nil
+	}
+}
+
+func (s *Server) postAuthor(p graphql.ResolveParams) (any, error) {
+	post := p.Source.(*model.Post)
+	// 文章详情与列表已预加载作者
+	if post.User.ID != 0 {
+		return &post.User, nil
+	}
+	return thunk(p.Context, stateFrom(p.Context).loaders.users.Load(p.Context, post.UserID)), nil
+}
+
+func (s *Server) postComments(p graphql.ResolveParams) (any, error) {
+	if _, err := viewer(p.Context, consts.ScopeCommentsRead, false); err != nil {
+		return nil, err
+	}
+	first, err := listSize(p.Args, "first")
+	if err != nil {
+		return nil, err
+	}
+
+	post := p.Source.(*model.Post)
+	load := stateFrom(p.Context).loaders.comments.Load(p.Context, post.ID)
+	return func() (any, error) {
+		comments, _, err := load()
+		if err != nil {
+			return nil, fail(p.Context, err)
+		}
+		return comments[:min(first, len(comments))], nil
+	}, nil
+}
+
+func (s *Server) commentAuthor(p graphql.ResolveParams) (any, error) {
+	comment := p.Source.(*model.Comment)
+	if comment.User.ID != 0 {
+		return &comment.User, nil
+	}
+	return thunk(p.Context, stateFrom(p.Context).loaders.users.Load(p.Context, comment.UserID)), nil
+}
+
+func (s *Server) commentPost(p graphql.ResolveParams) (any, error) {
+	comment := p.Source.(*model.Comment)
+	if comment.Post.ID != 0 {
+		return &comment.Post, nil
+	}
+	return thunk(p.Context, stateFrom(p.Context).loaders.posts.Load(p.Context, comment.PostID)), nil
+}
+
+func (s *Server) post(p graphql.ResolveParams) (any, error) {
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.posts.GetByID(p.Context, id)
+	if errors.Is(err, logic.ErrPostNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fail(p.Context, err)
+	}
+	return post, nil
+}
+
+func (s *Server) postList(p graphql.ResolveParams) (any, error) {
+	page, _ := p.Args["page"].(int)
+	if page < 1 {
+		return nil, fmt.Errorf("%w: page must be at least 1", logic.ErrInvalidInput)
+	}
+	pageSize, err := listSize(p.Args, "pageSize")
+	if err != nil {
+		return nil, err
+	}
+
+	posts, total, err := s.posts.List(p.Context, page, pageSize)
+	if err != nil {
+		return nil, fail(p.Context, err)
+	}
+	items := make([]*model.Post, len(posts))
+	for i := range posts {
+		items[i] = &posts[i]
+	}
+	return &postPage{total: total, page: page, pageSize: pageSize, items: items}, nil
+}
+
+func (s *Server) comment(p graphql.ResolveParams) (any, error) {
+	if _, err := viewer(p.Context, consts.ScopeCommentsRead, false); err != nil {
+		return nil, err
+	}
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.comments.GetByID(p.Context, id)
+	if errors.Is(err, logic.ErrCommentNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fail(p.Context, err)
+	}
+	return comment, nil
+}
+
+func (s *Server) user(p graphql.ResolveParams) (any, error) {
+	username, _ := p.Args["username"].(string)
+	profile, err := s.users.GetPublicProfile(p.Context, username)
+	if errors.Is(err, logic.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fail(p.Context, err)
+	}
+	return &profile.User, nil
+}
+
+func (s *Server) me(p graphql.ResolveParams) (any, error) {
+	v, err := viewer(p.Context, "", true)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetProfile(p.Context, v.UserID)
+	if err != nil {
+		return nil, fail(p.Context, err)
+	}
+	return user, nil
+}
+
+func (s *Server) createPost(p graphql.ResolveParams) (any, error) {
+	v, err := viewer(p.Context, consts.ScopePostsWrite, true)
+	if err != nil {
+		return nil, err
+	}
+	title, _ := p.Args["title"].(string)
+	content, _ := p.Args["content"].(string)
+	post, err := s.posts.Create(p.Context, v.UserID, title, content)
+	if err != nil {
+		return nil, fail(p.Context, err)
+	}
+	return post, nil
+}
+
+func (s *Server) updatePost(p graphql.ResolveParams) (any, error) {
+	v, err := viewer(p.Context, consts.ScopePostsWrite, true)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	title, _ := p.Args["title"].(string)
+	content, _ := p.Args["content"].(string)
+	post, err := s.posts.Update(p.Context, v.UserID, id, title, content)
+	if err != nil {
+		return nil, fail(p.Context, err)
+	}
+	return post, nil
+}
+
+func (s *Server) deletePost(p graphql.ResolveParams) (any, error) {
+	v, err := viewer(p.Context, consts.ScopePostsWrite, true)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	if err := s.posts.Delete(p.Context, v.UserID, id); err != nil {
+		return nil, fail(p.Context, err)
+	}
+	return true, nil
+}
+
+func (s *Server) createComment(p graphql.ResolveParams) (any, error) {
+	v, err := viewer(p.Context, consts.ScopeCommentsWrite, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.takeToken(p.Context, consts.RateLimitCommentCreate); err != nil {
+		return nil, err
+	}
+	postID, err := parseID(p.Args["postId"])
+	if err != nil {
+		return nil, err
+	}
+	var parentID uint
+	if raw, ok := p.Args["parentId"]; ok && raw != nil {
+		if parentID, err = parseID(raw); err != nil {
+			return nil, err
+		}
+	}
+	content, _ := p.Args["content"].(string)
+	comment, err := s.comments.Create(p.Context, v.UserID, postID, parentID, content)
+	if err != nil {
+		return nil, fail(p.Context, err)
+	}
+	return comment, nil
+}
+
+func (s *Server) updateComment(p graphql.ResolveParams) (any, error) {
+	v, err := viewer(p.Context, consts.ScopeCommentsWrite, true)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	content, _ := p.Args["content"].(string)
+	comment, err := s.comments.Update(p.Context, v.UserID, id, content)
+	if err != nil {
+		return nil, fail(p.Context, err)
+	}
+	return comment, nil
+}
+
+func (s *Server) deleteComment(p graphql.ResolveParams) (any, error) {
+	v, err := viewer(p.Context, consts.ScopeCommentsWrite, true)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	if err := s.comments.Delete(p.Context, v.UserID, id, false); err != nil {
+		return nil, fail(p.Context, err)
+	}
+	return true, nil
+}
+
+func parseID(v any) (uint, error) {
+	s, _ := v.(string)
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%w: invalid id %q", logic.ErrInvalidInput, s)
+	}
+	return uint(id), nil
+}
+
+// listSize 读取列表条数参数，取值范围为 1 到 consts.GraphQLMaxListSize
+func listSize(args map[string]any, name string) (int, error) {
+	n, _ := args[name].(int)
+	if n < 1 || n > consts.GraphQLMaxListSize {
+		return 0, fmt.Errorf("%w: %s must be between 1 and %d", logic.ErrInvalidInput, name, consts.GraphQLMaxListSize)
+	}
+	return n, nil
+}
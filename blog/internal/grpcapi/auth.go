@@ -0,0 +1,186 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strings"
+
+	blogv1 "web-task/blog/gen/blog/v1"
+	"web-task/blog/internal/consts"
+	"web-task/blog/middleware"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// policy 方法的认证要求，与对应 REST 路由的 AuthMiddleware/OptionalAuthMiddleware 参数一致
+type policy struct {
+	public   bool // 不检查令牌
+	required bool
+	scopes   []string
+}
+
+var (
+	postsRead     = policy{scopes: []string{consts.ScopePostsRead}}
+	postsWrite    = policy{required: true, scopes: []string{consts.ScopePostsWrite}}
+	commentsRead  = policy{scopes: []string{consts.ScopeCommentsRead}}
+	commentsWrite = policy{required: true, scopes: []string{consts.ScopeCommentsWrite}}
+)
+
+// policies 各方法的认证要求；未列出的方法一律拒绝，避免新增方法时遗漏认证
+var policies = map[string]policy{
+	blogv1.UserService_Register_FullMethodName:       {public: true},
+	blogv1.UserService_Login_FullMethodName:          {public: true},
+	blogv1.UserService_LoginMFA_FullMethodName:       {public: true},
+	blogv1.UserService_GetUserProfile_FullMethodName: {public: true},
+	blogv1.UserService_GetMe_FullMethodName:          {required: true},
+
+	blogv1.PostService_CreatePost_FullMethodName: postsWrite,
+	blogv1.PostService_GetPost_FullMethodName:    postsRead,
+	blogv1.PostService_ListPosts_FullMethodName:  postsRead,
+	blogv1.PostService_UpdatePost_FullMethodName: postsWrite,
+	blogv1.PostService_DeletePost_FullMethodName: postsWrite,
+
+	blogv1.CommentService_CreateComment_FullMethodName:  commentsWrite,
+	blogv1.CommentService_GetComment_FullMethodName:     commentsRead,
+	blogv1.CommentService_UpdateComment_FullMethodName:  commentsWrite,
+	blogv1.CommentService_DeleteComment_FullMethodName:  commentsWrite,
+	blogv1.CommentService_RestoreComment_FullMethodName: commentsWrite,
+}
+
+type principalKey struct{}
+
+// principal 拦截器写入的调用者，匿名调用返回 nil
+func principal(ctx context.Context) *middleware.Principal {
+	p, _ := ctx.Value(principalKey{}).(*middleware.Principal)
+	return p
+}
+
+// currentUserID 需要登录的方法中取当前用户 ID
+func currentUserID(ctx context.Context) (uint, error) {
+	if p := principal(ctx); p != nil && p.UserID != 0 {
+		return p.UserID, nil
+	}
+	return 0, status.Error(codes.Unauthenticated, "authentication required")
+}
+
+// authorize 按方法的认证要求校验 metadata 中的 Bearer 令牌
+func authorize(ctx context.Context, method string, logger *slog.Logger) (context.Context, error) {
+	p, ok := policies[method]
+	if !ok {
+		// 服务反射只暴露接口定义
+		if strings.HasPrefix(method, "/grpc.reflection.") {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.PermissionDenied, "no access policy for method")
+	}
+	if p.public {
+		return ctx, nil
+	}
+
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		if p.required {
+			return nil, status.Error(codes.Unauthenticated, "authorization metadata is required")
+		}
+		return ctx, nil
+	}
+	token, ok := middleware.BearerToken(values[0])
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization format")
+	}
+
+	principal, err := middleware.VerifyToken(ctx, token, p.scopes)
+	switch {
+	case err == nil:
+	case errors.Is(err, middleware.ErrInvalidToken), errors.Is(err, middleware.ErrTokenRevoked):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, middleware.ErrAccessTokenNotAccepted), errors.Is(err, middleware.ErrInsufficientScope):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	default:
+		logger.ErrorContext(ctx, "grpc authentication failed", "method", method, "error", err)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+func authUnary(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authorize(ctx, info.FullMethod, logger)
+		if err != nil {
+			return nil, err
+		}
+		if p := principal(ctx); p != nil && p.UserID != 0 {
+			trace.SpanFromContext(ctx).SetAttributes(attribute.String("enduser.id", fmt.Sprint(p.UserID)))
+		}
+		return handler(ctx, req)
+	}
+}
+
+func authStream(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, err := authorize(ss.Context(), info.FullMethod, logger); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// recoverUnary 处理函数 panic 时返回 Internal，与 HTTP 的 Recovery 中间件一致
+func recoverUnary(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.ErrorContext(ctx, "grpc panic recovered", "method", info.FullMethod, "panic", r, "stack", string(debug.Stack()))
+				err = status.Error(codes.Internal, "internal server error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func recoverStream(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.ErrorContext(ss.Context(), "grpc panic recovered", "method", info.FullMethod, "panic", r, "stack", string(debug.Stack()))
+				err = status.Error(codes.Internal, "internal server error")
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// clientInfo 登录记录使用的客户端 IP 与 User-Agent；经网关的调用取网关转发的原始请求信息
+func clientInfo(ctx context.Context) (ip, userAgent string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	p, _ := peer.FromContext(ctx)
+	if p != nil && p.Addr.Network() == "bufconn" {
+		// 网关追加的 x-forwarded-for 最后一项为直连网关的客户端
+		forwarded := strings.Split(first("x-forwarded-for"), ",")
+		return strings.TrimSpace(forwarded[len(forwarded)-1]), first("grpcgateway-user-agent")
+	}
+	if p != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	return ip, first("user-agent")
+}
@@ -0,0 +1,101 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+
+	"web-task/blog/dto"
+	blogv1 "web-task/blog/gen/blog/v1"
+	"web-task/blog/internal/logic"
+)
+
+type commentServer struct {
+	blogv1.UnimplementedCommentServiceServer
+	comments *logic.CommentService
+	log      *slog.Logger
+}
+
+func (s *commentServer) CreateComment(ctx context.Context, req *blogv1.CreateCommentRequest) (*blogv1.CreateCommentResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	postID, err := id(req.PostId, "post id")
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(dto.CreateCommentRequest{Content: req.Content}); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.comments.Create(ctx, userID, postID, uint(req.ParentId), req.Content)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.CreateCommentResponse{Comment: commentMessage(comment)}, nil
+}
+
+func (s *commentServer) GetComment(ctx context.Context, req *blogv1.GetCommentRequest) (*blogv1.GetCommentResponse, error) {
+	commentID, err := id(req.Id, "comment id")
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.comments.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.GetCommentResponse{Comment: commentMessage(comment)}, nil
+}
+
+func (s *commentServer) UpdateComment(ctx context.Context, req *blogv1.UpdateCommentRequest) (*blogv1.UpdateCommentResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	commentID, err := id(req.Id, "comment id")
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(dto.UpdateCommentRequest{Content: req.Content}); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.comments.Update(ctx, userID, commentID, req.Content)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.UpdateCommentResponse{Comment: commentMessage(comment)}, nil
+}
+
+func (s *commentServer) DeleteComment(ctx context.Context, req *blogv1.DeleteCommentRequest) (*blogv1.DeleteCommentResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	commentID, err := id(req.Id, "comment id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.comments.Delete(ctx, userID, commentID, req.IncludeChildren); err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.DeleteCommentResponse{}, nil
+}
+
+func (s *commentServer) RestoreComment(ctx context.Context, req *blogv1.RestoreCommentRequest) (*blogv1.RestoreCommentResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	commentID, err := id(req.Id, "comment id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.comments.Restore(ctx, userID, commentID, req.IncludeChildren); err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.RestoreCommentResponse{}, nil
+}
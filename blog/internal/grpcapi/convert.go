@@ -0,0 +1,103 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	blogv1 "web-task/blog/gen/blog/v1"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// validate 按 REST 请求结构体上的 binding 规则校验参数，两种接口的校验保持一致
+func validate(req any) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// toStatus 把服务层错误转换为 gRPC 状态码，未识别的错误记录日志后只返回 Internal
+func toStatus(ctx context.Context, logger *slog.Logger, err error) error {
+	var lockout *logic.LockoutError
+	switch {
+	case errors.Is(err, logic.ErrInvalidInput):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, logic.ErrPostNotFound),
+		errors.Is(err, logic.ErrCommentNotFound),
+		errors.Is(err, logic.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case err.Error() == "permission denied":
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.As(err, &lockout):
+		return status.Error(codes.ResourceExhausted, logic.ErrAccountLocked.Error())
+	case errors.Is(err, logic.ErrInvalidCredentials),
+		errors.Is(err, logic.ErrInvalidMFACode),
+		errors.Is(err, logic.ErrInvalidToken):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "request timed out")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	logger.ErrorContext(ctx, "grpc request failed", "error", err)
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// userMessage 与 REST 接口一样只返回 model.User.Public 中的公开资料，未预加载的关联用户返回 nil
+func userMessage(u *model.User) *blogv1.User {
+	if u == nil || u.ID == 0 {
+		return nil
+	}
+	public := u.Public()
+	return &blogv1.User{
+		Id:          uint32(public.ID),
+		Username:    public.Username,
+		DisplayName: public.DisplayName,
+		Bio:         public.Bio,
+		AvatarUrl:   public.AvatarURL,
+		CreatedAt:   timestamppb.New(public.CreatedAt),
+	}
+}
+
+func postMessage(p *model.Post) *blogv1.Post {
+	return &blogv1.Post{
+		Id:        uint32(p.ID),
+		Title:     p.Title,
+		Content:   p.Content,
+		UserId:    uint32(p.UserID),
+		User:      userMessage(&p.User),
+		CreatedAt: timestamppb.New(p.CreatedAt),
+		UpdatedAt: timestamppb.New(p.UpdatedAt),
+	}
+}
+
+func commentMessage(c *model.Comment) *blogv1.Comment {
+	msg := &blogv1.Comment{
+		Id:        uint32(c.ID),
+		Content:   c.Content,
+		UserId:    uint32(c.UserID),
+		PostId:    uint32(c.PostID),
+		User:      userMessage(&c.User),
+		CreatedAt: timestamppb.New(c.CreatedAt),
+		UpdatedAt: timestamppb.New(c.UpdatedAt),
+	}
+	if c.ParentID != nil {
+		msg.ParentId = uint32(*c.ParentID)
+	}
+	return msg
+}
+
+// id 校验请求中的 ID 并转换为数据库主键类型
+func id(v uint32, name string) (uint, error) {
+	if v == 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s", name)
+	}
+	return uint(v), nil
+}
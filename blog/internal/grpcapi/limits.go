@@ -0,0 +1,61 @@
+package grpcapi
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	blogv1 "web-task/blog/gen/blog/v1"
+	"web-task/blog/internal/consts"
+	"web-task/blog/middleware"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// rateLimits 各方法的限流策略，与对应 REST 路由一致并共用令牌桶；未列出的方法不限流
+var rateLimits = map[string]string{
+	blogv1.UserService_Register_FullMethodName:         consts.RateLimitEmail,
+	blogv1.UserService_Login_FullMethodName:            consts.RateLimitLogin,
+	blogv1.UserService_LoginMFA_FullMethodName:         consts.RateLimitLogin,
+	blogv1.CommentService_CreateComment_FullMethodName: consts.RateLimitCommentCreate,
+}
+
+// rateLimitUnary 按方法的限流策略取令牌，已认证的调用按用户名、匿名调用按客户端 IP 计数；
+// 被拒绝时返回 ResourceExhausted（网关映射为 429），retry-after 放在响应头 metadata 中
+func rateLimitUnary(limiter *middleware.RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		name, ok := rateLimits[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		var user string
+		if p := principal(ctx); p != nil {
+			user = p.Username
+		}
+		ip, _ := clientInfo(ctx)
+		if res := limiter.Take(ctx, name, user, ip); !res.Allowed {
+			retryAfter := strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds())))
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter))
+			return nil, status.Error(codes.ResourceExhausted, "too many requests")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// timeoutUnary 为每次调用设置与 HTTP 普通请求相同的截止时间，客户端声明的更早截止时间仍然有效；
+// 到期后 context 被取消，经 logic 服务传入 gorm 的查询随之中断
+func timeoutUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		d := middleware.RequestTimeout()
+		if d <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,99 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+
+	"web-task/blog/dto"
+	blogv1 "web-task/blog/gen/blog/v1"
+	"web-task/blog/internal/logic"
+)
+
+type postServer struct {
+	blogv1.UnimplementedPostServiceServer
+	posts *logic.PostService
+	log   *slog.Logger
+}
+
+func (s *postServer) CreatePost(ctx context.Context, req *blogv1.CreatePostRequest) (*blogv1.CreatePostResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(dto.CreatePostRequest{Title: req.Title, Content: req.Content}); err != nil {
+		return nil, err
+	}
+
+	post, err := s.posts.Create(ctx, userID, req.Title, req.Content)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.CreatePostResponse{Post: postMessage(post)}, nil
+}
+
+func (s *postServer) GetPost(ctx context.Context, req *blogv1.GetPostRequest) (*blogv1.GetPostResponse, error) {
+	postID, err := id(req.Id, "post id")
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.posts.GetByID(ctx, postID)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.GetPostResponse{Post: postMessage(post)}, nil
+}
+
+// ListPosts 未传分页参数时与 REST 的默认值相同
+func (s *postServer) ListPosts(ctx context.Context, req *blogv1.ListPostsRequest) (*blogv1.ListPostsResponse, error) {
+	page, pageSize := int(req.Page), int(req.PageSize)
+	if page == 0 {
+		page = 1
+	}
+	if pageSize == 0 {
+		pageSize = 10
+	}
+
+	posts, total, err := s.posts.List(ctx, page, pageSize)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	resp := &blogv1.ListPostsResponse{Total: int32(total), Page: int32(page), PageSize: int32(pageSize)}
+	for i := range posts {
+		resp.Posts = append(resp.Posts, postMessage(&posts[i]))
+	}
+	return resp, nil
+}
+
+func (s *postServer) UpdatePost(ctx context.Context, req *blogv1.UpdatePostRequest) (*blogv1.UpdatePostResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	postID, err := id(req.Id, "post id")
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.posts.Update(ctx, userID, postID, req.Title, req.Content)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.UpdatePostResponse{Post: postMessage(post)}, nil
+}
+
+func (s *postServer) DeletePost(ctx context.Context, req *blogv1.DeletePostRequest) (*blogv1.DeletePostResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	postID, err := id(req.Id, "post id")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.posts.Delete(ctx, userID, postID); err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.DeletePostResponse{}, nil
+}
@@ -0,0 +1,110 @@
+// Package grpcapi 博客的 gRPC 接口：用户、文章与评论服务调用与 REST 相同的 logic 服务，
+// 令牌经 metadata 的 authorization 传递并由拦截器校验；同一端口上还提供 grpc-gateway 生成的 JSON 接口，
+// 路径与 REST 一致，proto 定义见 blog/proto
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	blogv1 "web-task/blog/gen/blog/v1"
+	"web-task/blog/internal/logic"
+	"web-task/blog/middleware"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Server gRPC 服务及其 grpc-gateway 反向代理
+type Server struct {
+	grpc    *grpc.Server
+	gateway *runtime.ServeMux
+	inproc  *bufconn.Listener
+	conn    *grpc.ClientConn
+}
+
+// NewServer 构造函数；网关经进程内连接调用 gRPC 服务，同样经过链路追踪、截止时间、认证与限流拦截器
+func NewServer(users *logic.UserService, posts *logic.PostService, comments *logic.CommentService, limiter *middleware.RateLimiter, logger *slog.Logger) (*Server, error) {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoverUnary(logger), tracingUnary(), timeoutUnary(), authUnary(logger), rateLimitUnary(limiter)),
+		grpc.ChainStreamInterceptor(recoverStream(logger), authStream(logger)),
+	)
+	blogv1.RegisterUserServiceServer(srv, &userServer{users: users, log: logger})
+	blogv1.RegisterPostServiceServer(srv, &postServer{posts: posts, log: logger})
+	blogv1.RegisterCommentServiceServer(srv, &commentServer{comments: comments, log: logger})
+	// 服务反射，供 grpcurl 等工具查询接口定义
+	reflection.Register(srv)
+
+	s := &Server{grpc: srv, inproc: bufconn.Listen(1 << 20)}
+	go srv.Serve(s.inproc)
+
+	conn, err := grpc.NewClient("passthrough:///inprocess",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.inproc.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		srv.Stop()
+		return nil, fmt.Errorf("failed to connect gateway: %w", err)
+	}
+	s.conn = conn
+
+	// 字段名与 REST 一致使用下划线形式，零值字段也输出；W3C 链路头转发给 gRPC 服务
+	s.gateway = runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher),
+	)
+	for _, register := range []func(context.Context, *runtime.ServeMux, *grpc.ClientConn) error{
+		blogv1.RegisterUserServiceHandler,
+		blogv1.RegisterPostServiceHandler,
+		blogv1.RegisterCommentServiceHandler,
+	} {
+		if err := register(context.Background(), s.gateway, conn); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to register gateway: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// gatewayHeaderMatcher 在默认规则之外转发 traceparent 与 tracestate
+func gatewayHeaderMatcher(key string) (string, bool) {
+	switch strings.ToLower(key) {
+	case "traceparent", "tracestate":
+		return strings.ToLower(key), true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// Handler 同一端口上按 Content-Type 分发：application/grpc 交给 gRPC 服务，其余交给网关；
+// 以 h2c 支持不经 TLS 的 HTTP/2，TLS 由前置的负载均衡终止
+func (s *Server) Handler() http.Handler {
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			s.grpc.ServeHTTP(w, r)
+			return
+		}
+		s.gateway.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
+
+// Close 关闭服务与网关连接，需在 Handler 所在的 HTTP 服务 Shutdown 之后调用，此时已没有进行中的调用；
+// 经 ServeHTTP 建立的连接不支持 GracefulStop 的 drain，因此直接 Stop
+func (s *Server) Close() {
+	s.conn.Close()
+	s.grpc.Stop()
+}
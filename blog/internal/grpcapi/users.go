@@ -0,0 +1,93 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"web-task/blog/dto"
+	blogv1 "web-task/blog/gen/blog/v1"
+	"web-task/blog/internal/logic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type userServer struct {
+	blogv1.UnimplementedUserServiceServer
+	users *logic.UserService
+	log   *slog.Logger
+}
+
+func (s *userServer) Register(ctx context.Context, req *blogv1.RegisterRequest) (*blogv1.RegisterResponse, error) {
+	if err := validate(dto.RegisterRequest{Username: req.Username, Email: req.Email, Password: req.Password}); err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.Register(ctx, req.Username, req.Email, req.Password)
+	if err != nil {
+		// 用户名或邮箱已被占用
+		if strings.HasSuffix(err.Error(), "already exists") {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.RegisterResponse{Id: uint32(user.ID), Username: user.Username, Email: user.Email}, nil
+}
+
+func (s *userServer) Login(ctx context.Context, req *blogv1.LoginRequest) (*blogv1.LoginResponse, error) {
+	if err := validate(dto.LoginRequest{Username: req.Username, Password: req.Password}); err != nil {
+		return nil, err
+	}
+
+	ip, userAgent := clientInfo(ctx)
+	result, err := s.users.Login(ctx, req.Username, req.Password, ip, userAgent)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.LoginResponse{Token: result.Token, MfaRequired: result.MFARequired, MfaToken: result.MFAToken}, nil
+}
+
+func (s *userServer) LoginMFA(ctx context.Context, req *blogv1.LoginMFARequest) (*blogv1.LoginMFAResponse, error) {
+	if err := validate(dto.MFALoginRequest{MFAToken: req.MfaToken, Code: req.Code}); err != nil {
+		return nil, err
+	}
+
+	ip, userAgent := clientInfo(ctx)
+	token, err := s.users.LoginMFA(ctx, req.MfaToken, req.Code, ip, userAgent)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.LoginMFAResponse{Token: token}, nil
+}
+
+func (s *userServer) GetMe(ctx context.Context, _ *blogv1.GetMeRequest) (*blogv1.GetMeResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	resp := &blogv1.GetMeResponse{
+		User:        userMessage(user),
+		Email:       user.Email,
+		Role:        user.Role,
+		TotpEnabled: user.TOTPEnabledAt != nil,
+	}
+	if user.EmailVerifiedAt != nil {
+		resp.EmailVerifiedAt = timestamppb.New(*user.EmailVerifiedAt)
+	}
+	return resp, nil
+}
+
+func (s *userServer) GetUserProfile(ctx context.Context, req *blogv1.GetUserProfileRequest) (*blogv1.GetUserProfileResponse, error) {
+	profile, err := s.users.GetPublicProfile(ctx, req.Username)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, err)
+	}
+	return &blogv1.GetUserProfileResponse{User: userMessage(&profile.User), PostCount: int32(profile.PostCount)}, nil
+}
@@ -0,0 +1,137 @@
+// Package importer 解析外部博客的导出内容，转换为统一的 Document 供 logic.ImportService 写入
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// 支持的导入格式
+const (
+	FormatAuto      = "auto"
+	FormatMarkdown  = "markdown"  // 任意目录下带 front matter 的 Markdown 文件
+	FormatHugo      = "hugo"      // Hugo 站点，读取 content 目录
+	FormatJekyll    = "jekyll"    // Jekyll 站点，读取 _posts 目录
+	FormatWordPress = "wordpress" // WordPress 导出的 WXR 文件
+)
+
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// Document 来源中的一篇文章
+type Document struct {
+	SourceID    string // 来源中的唯一标识：WordPress 为 post_id，其余为相对路径
+	Title       string
+	Content     string
+	Author      string // 来源中的作者登录名或名称
+	AuthorEmail string
+	Tags        []string  // 标签与分类
+	CreatedAt   time.Time // 零值表示来源未提供
+	UpdatedAt   time.Time // 零值表示来源未提供
+	Draft       bool      // 草稿、私密等未公开发布的文章
+}
+
+// Load 从目录（或 zip 归档）读取文章，format 为空或 auto 时自动识别，返回实际使用的格式
+func Load(fsys fs.FS, format string) ([]Document, string, error) {
+	fsys = unwrapSingleDir(fsys)
+	if format == "" || format == FormatAuto {
+		format = DetectFormat(fsys)
+	}
+
+	var (
+		docs []Document
+		err  error
+	)
+	switch format {
+	case FormatMarkdown:
+		docs, err = parseMarkdownTree(fsys, ".", format)
+	case FormatHugo:
+		docs, err = parseMarkdownTree(fsys, "content", format)
+	case FormatJekyll:
+		docs, err = parseMarkdownTree(fsys, "_posts", format)
+	case FormatWordPress:
+		docs, err = parseWXRFiles(fsys)
+	default:
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	return docs, format, err
+}
+
+// LoadPath 读取本地路径：.xml 文件按 WXR 解析，目录按 Load 处理
+func LoadPath(p, format string) ([]Document, string, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, "", err
+	}
+	if info.IsDir() {
+		return Load(os.DirFS(p), format)
+	}
+
+	if format != "" && format != FormatAuto && format != FormatWordPress {
+		return nil, "", fmt.Errorf("%w: %s expects a directory", ErrUnknownFormat, format)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	docs, err := ParseWXR(f)
+	return docs, FormatWordPress, err
+}
+
+// DetectFormat 根据目录结构识别格式
+func DetectFormat(fsys fs.FS) string {
+	if isDir(fsys, "_posts") {
+		return FormatJekyll
+	}
+	if isDir(fsys, "content") {
+		for _, name := range []string{"hugo.toml", "hugo.yaml", "hugo.json", "config.toml", "config.yaml", "config.json"} {
+			if _, err := fs.Stat(fsys, name); err == nil {
+				return FormatHugo
+			}
+		}
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err == nil {
+		hasXML, hasMarkdown := false, false
+		for _, e := range entries {
+			switch {
+			case strings.EqualFold(path.Ext(e.Name()), ".xml"):
+				hasXML = true
+			case isMarkdownFile(e.Name()), e.IsDir():
+				hasMarkdown = true
+			}
+		}
+		if hasXML && !hasMarkdown {
+			return FormatWordPress
+		}
+	}
+	return FormatMarkdown
+}
+
+// unwrapSingleDir 归档中只有一个顶层目录时（常见的打包方式）进入该目录；
+// 站点自身的 _posts、content 目录除外
+func unwrapSingleDir(fsys fs.FS) fs.FS {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return fsys
+	}
+	if name := entries[0].Name(); name == "_posts" || name == "content" {
+		return fsys
+	}
+	sub, err := fs.Sub(fsys, entries[0].Name())
+	if err != nil {
+		return fsys
+	}
+	return sub
+}
+
+func isDir(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,219 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Jekyll 文章文件名格式：YYYY-MM-DD-title.md
+var jekyllFilename = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-(.+)$`)
+
+// 日期字段在不同生成器中的名称，按优先级排列；created_at/updated_at 与数据导出的 front matter 一致
+var (
+	createdKeys = []string{"date", "created_at", "publishdate", "publishDate"}
+	updatedKeys = []string{"lastmod", "updated", "updated_at", "last_modified_at", "modified"}
+)
+
+// parseMarkdownTree 遍历 root 下的 Markdown 文件，SourceID 为相对 root 的路径
+func parseMarkdownTree(fsys fs.FS, root, format string) ([]Document, error) {
+	if !isDir(fsys, root) {
+		return nil, fmt.Errorf("%s site has no %s directory", format, root)
+	}
+
+	var docs []Document
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if p != root && strings.HasPrefix(name, ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		// Hugo 的 _index.md 是栏目列表页，不是文章
+		if d.IsDir() || !isMarkdownFile(name) || (format == FormatHugo && strings.HasPrefix(name, "_index.")) {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		doc, err := parseMarkdown(rel, data, format)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// parseMarkdown 解析单个文件：YAML（---）或 TOML（+++）front matter 加正文
+func parseMarkdown(rel string, data []byte, format string) (Document, error) {
+	meta, body, err := splitFrontMatter(data)
+	if err != nil {
+		return Document{}, err
+	}
+
+	doc := Document{
+		SourceID: rel,
+		Title:    stringField(meta, "title"),
+		Content:  strings.TrimSpace(string(body)),
+		Tags:     append(stringList(meta["tags"]), stringList(meta["categories"])...),
+	}
+
+	authors := stringList(meta["author"])
+	if len(authors) == 0 {
+		authors = stringList(meta["authors"])
+	}
+	if len(authors) > 0 {
+		doc.Author = authors[0]
+	}
+	doc.AuthorEmail = stringField(meta, "author_email")
+
+	for _, key := range createdKeys {
+		if t, ok := timeField(meta[key]); ok {
+			doc.CreatedAt = t
+			break
+		}
+	}
+	for _, key := range updatedKeys {
+		if t, ok := timeField(meta[key]); ok {
+			doc.UpdatedAt = t
+			break
+		}
+	}
+
+	if draft, ok := meta["draft"].(bool); ok && draft {
+		doc.Draft = true
+	}
+	if published, ok := meta["published"].(bool); ok && !published {
+		doc.Draft = true
+	}
+
+	// 没有标题时使用文件名；Jekyll 文件名同时携带发布日期
+	base := strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+	if format == FormatHugo && base == "index" {
+		base = path.Base(path.Dir(rel)) // 页面包 posts/<slug>/index.md
+	}
+	if format == FormatJekyll {
+		if m := jekyllFilename.FindStringSubmatch(base); m != nil {
+			base = m[2]
+			if doc.CreatedAt.IsZero() {
+				doc.CreatedAt, _ = time.Parse("2006-01-02", m[1])
+			}
+		}
+	}
+	if doc.Title == "" {
+		doc.Title = strings.ReplaceAll(base, "-", " ")
+	}
+	return doc, nil
+}
+
+func splitFrontMatter(data []byte) (map[string]interface{}, []byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	meta := map[string]interface{}{}
+
+	for _, delim := range []string{"---", "+++"} {
+		if !bytes.HasPrefix(data, []byte(delim+"\n")) && !bytes.HasPrefix(data, []byte(delim+"\r\n")) {
+			continue
+		}
+		rest := data[bytes.IndexByte(data, '\n')+1:]
+		end := bytes.Index(rest, []byte("\n"+delim))
+		if end < 0 {
+			return nil, nil, fmt.Errorf("unterminated front matter")
+		}
+		header := rest[:end]
+		body := rest[end+1+len(delim):]
+
+		var err error
+		if delim == "---" {
+			err = yaml.Unmarshal(header, &meta)
+		} else {
+			err = toml.Unmarshal(header, &meta)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid front matter: %w", err)
+		}
+		return meta, body, nil
+	}
+	return meta, data, nil
+}
+
+func stringField(meta map[string]interface{}, key string) string {
+	s, _ := meta[key].(string)
+	return strings.TrimSpace(s)
+}
+
+// stringList 兼容单个字符串、逗号分隔字符串与列表三种写法
+func stringList(v interface{}) []string {
+	var out []string
+	switch v := v.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				out = append(out, strings.TrimSpace(s))
+			}
+		}
+	}
+	return out
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// timeField YAML 与 TOML 解码后的日期可能是 time.Time、字符串或 TOML 本地日期类型
+func timeField(v interface{}) (time.Time, bool) {
+	var s string
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		s = v
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		return time.Time{}, false
+	}
+
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func isMarkdownFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown", ".mdown":
+		return true
+	}
+	return false
+}
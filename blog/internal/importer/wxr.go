@@ -0,0 +1,114 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// wxr WordPress eXtended RSS 导出文件中用到的字段
+// wp 命名空间的地址随导出版本变化，按本地名匹配；content:encoded 与 excerpt:encoded 本地名相同，需带命名空间
+type wxr struct {
+	Channel struct {
+		Authors []struct {
+			Login string `xml:"author_login"`
+			Email string `xml:"author_email"`
+		} `xml:"author"`
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrItem struct {
+	Title           string `xml:"title"`
+	Creator         string `xml:"creator"`
+	Content         string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PostID          string `xml:"post_id"`
+	PostDate        string `xml:"post_date"`
+	PostDateGMT     string `xml:"post_date_gmt"`
+	PostModified    string `xml:"post_modified"`
+	PostModifiedGMT string `xml:"post_modified_gmt"`
+	Status          string `xml:"status"`
+	PostType        string `xml:"post_type"`
+	Categories      []struct {
+		Domain string `xml:"domain,attr"`
+		Name   string `xml:",chardata"`
+	} `xml:"category"`
+}
+
+// ParseWXR 解析 WordPress 导出文件，只保留文章（不含页面、附件与菜单），未发布的文章标记为草稿
+func ParseWXR(r io.Reader) ([]Document, error) {
+	var feed wxr
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	if err := dec.Decode(&feed); err != nil {
+		return nil, fmt.Errorf("invalid WXR file: %w", err)
+	}
+
+	emails := make(map[string]string, len(feed.Channel.Authors))
+	for _, a := range feed.Channel.Authors {
+		emails[a.Login] = a.Email
+	}
+
+	var docs []Document
+	for _, item := range feed.Channel.Items {
+		if item.PostType != "post" {
+			continue
+		}
+		doc := Document{
+			SourceID:    strings.TrimSpace(item.PostID),
+			Title:       strings.TrimSpace(item.Title),
+			Content:     strings.TrimSpace(item.Content),
+			Author:      item.Creator,
+			AuthorEmail: emails[item.Creator],
+			CreatedAt:   wxrTime(item.PostDateGMT, item.PostDate),
+			UpdatedAt:   wxrTime(item.PostModifiedGMT, item.PostModified),
+			Draft:       item.Status != "publish",
+		}
+		for _, c := range item.Categories {
+			if (c.Domain == "post_tag" || c.Domain == "category") && strings.TrimSpace(c.Name) != "" {
+				doc.Tags = append(doc.Tags, strings.TrimSpace(c.Name))
+			}
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// parseWXRFiles 解析目录顶层的所有 .xml 文件
+func parseWXRFiles(fsys fs.FS) ([]Document, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []Document
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(path.Ext(e.Name()), ".xml") {
+			continue
+		}
+		f, err := fsys.Open(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParseWXR(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		docs = append(docs, parsed...)
+	}
+	return docs, nil
+}
+
+// wxrTime 优先使用 GMT 时间；草稿的 GMT 时间为 0000-00-00，此时退回站点本地时间
+func wxrTime(gmt, local string) time.Time {
+	if t, err := time.Parse("2006-01-02 15:04:05", gmt); err == nil {
+		return t
+	}
+	t, _ := time.Parse("2006-01-02 15:04:05", local)
+	return t
+}
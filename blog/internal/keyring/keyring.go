@@ -0,0 +1,431 @@
+// internal/keyring/keyring.go
+// Package keyring 管理 JWT 非对称签名密钥：按 kid 选择密钥、定期轮换、退役密钥宽限期内继续验证，
+// 并以 JWKS 形式发布公钥，供其它服务在没有密钥的情况下校验博客签发的令牌
+package keyring
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownKey           = errors.New("unknown signing key")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+)
+
+// refreshInterval 未知 kid 触发重新加载的最小间隔，避免伪造 kid 造成大量查库
+const refreshInterval = 10 * time.Second
+
+// sealedPrefix 加密保存的私钥前缀，之后是 base64 编码的 nonce 与密文；没有该前缀的是早期版本保存的明文 PEM
+const sealedPrefix = "aes256gcm:"
+
+type signingKey struct {
+	kid       string
+	alg       string
+	private   crypto.Signer
+	createdAt time.Time
+	retiredAt *time.Time
+}
+
+// KeyRing 签名密钥环，密钥保存在数据库中由所有实例共享
+type KeyRing struct {
+	DB               *gorm.DB
+	Algorithm        string        // 新密钥使用的算法
+	RotationInterval time.Duration // 签名密钥的使用期限，到期后轮换
+	Grace            time.Duration // 退役密钥继续用于验证的时长，应不小于令牌有效期加 PublishDelay
+	// PublishDelay 新密钥先在 JWKS 中发布、经过该时长才开始签名，
+	// 使其它实例和缓存了 JWKS 的外部服务在收到新密钥签发的令牌前已能获取其公钥
+	PublishDelay time.Duration
+
+	// aead 加密数据库中私钥的密钥，由不落库的密钥派生，数据库泄露时私钥仍无法直接使用
+	aead cipher.AEAD
+
+	mu          sync.RWMutex
+	keys        []*signingKey // 按创建时间倒序
+	refreshedAt time.Time
+}
+
+// New 构造函数：加载已有密钥，没有可用签名密钥（或算法配置变更）时立即轮换生成
+// secret 用于加密数据库中的私钥，所有实例需使用相同的值；早期版本保存的明文私钥在此时加密
+func New(ctx context.Context, db *gorm.DB, algorithm string, secret []byte, rotation, grace, publishDelay time.Duration) (*KeyRing, error) {
+	if _, err := generateKey(algorithm, true); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return nil, err
+	}
+	kr := &KeyRing{DB: db, Algorithm: algorithm, RotationInterval: rotation, Grace: grace, PublishDelay: publishDelay, aead: aead}
+	if err := kr.sealPlaintextKeys(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := kr.RotateIfDue(ctx); err != nil {
+		return nil, err
+	}
+	return kr, nil
+}
+
+// Sign 使用当前签名密钥签发令牌，头部携带 kid
+func (kr *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	kr.mu.RLock()
+	key := kr.signerLocked(time.Now())
+	kr.mu.RUnlock()
+	if key == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.alg), claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// Parse 校验令牌签名与有效期；只接受非对称算法，且算法必须与 kid 对应密钥一致
+func (kr *KeyRing) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, kr.Keyfunc,
+		jwt.WithValidMethods([]string{RS256, ES256, EdDSA}),
+		jwt.WithExpirationRequired(),
+	)
+}
+
+// Keyfunc 按 kid 选择验证公钥，可直接用于 jwt.Parse
+func (kr *KeyRing) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrUnknownKey
+	}
+
+	key := kr.lookup(kid)
+	if key == nil {
+		// 可能是其它实例刚轮换出的新密钥
+		kr.mu.RLock()
+		stale := time.Since(kr.refreshedAt) > refreshInterval
+		kr.mu.RUnlock()
+		if stale {
+			if err := kr.Refresh(context.Background()); err != nil {
+				return nil, err
+			}
+			key = kr.lookup(kid)
+		}
+	}
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	if key.alg != t.Method.Alg() {
+		return nil, fmt.Errorf("key %s is for %s", kid, key.alg)
+	}
+	return key.private.Public(), nil
+}
+
+// JWKS 当前可用于验证的全部公钥
+func (kr *KeyRing) JWKS() oidc.JWKS {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	set := oidc.JWKS{Keys: make([]oidc.JWK, 0, len(kr.keys))}
+	for _, k := range kr.keys {
+		if !kr.verifiableLocked(k, time.Now()) {
+			continue
+		}
+		if jwk, err := oidc.NewJWK(k.kid, k.alg, k.private.Public()); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// Refresh 从数据库重新加载密钥
+func (kr *KeyRing) Refresh(ctx context.Context) error {
+	var records []model.SigningKey
+	if err := kr.DB.WithContext(ctx).
+		Where("retired_at IS NULL OR retired_at > ?", time.Now().Add(-kr.Grace)).
+		Order("created_at desc, id desc").
+		Find(&records).Error; err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	keys := make([]*signingKey, 0, len(records))
+	for _, r := range records {
+		private, err := kr.openPrivateKey(r.Kid, r.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("failed to decode signing key %s: %w", r.Kid, err)
+		}
+		keys = append(keys, &signingKey{kid: r.Kid, alg: r.Algorithm, private: private, createdAt: r.CreatedAt, retiredAt: r.RetiredAt})
+	}
+
+	kr.mu.Lock()
+	kr.keys = keys
+	kr.refreshedAt = time.Now()
+	kr.mu.Unlock()
+	return nil
+}
+
+// RotateIfDue 当前签名密钥超过使用期限、算法与配置不一致或不存在时轮换，并清理超过宽限期的退役密钥
+// 由后台任务定期调用；多个实例同时调用时只有一个会成功轮换
+func (kr *KeyRing) RotateIfDue(ctx context.Context) (bool, error) {
+	if err := kr.Refresh(ctx); err != nil {
+		return false, err
+	}
+
+	kr.mu.RLock()
+	active := kr.activeLocked()
+	kr.mu.RUnlock()
+
+	rotated := false
+	if active == nil || active.alg != kr.Algorithm || time.Since(active.createdAt) >= kr.RotationInterval {
+		if err := kr.rotate(ctx, active); err != nil {
+			return false, err
+		}
+		rotated = true
+	}
+
+	if err := kr.DB.WithContext(ctx).Unscoped().
+		Where("retired_at IS NOT NULL AND retired_at <= ?", time.Now().Add(-kr.Grace)).
+		Delete(&model.SigningKey{}).Error; err != nil {
+		return rotated, fmt.Errorf("failed to prune signing keys: %w", err)
+	}
+	return rotated, nil
+}
+
+// Rotate 立即轮换：生成新签名密钥，当前密钥退役
+func (kr *KeyRing) Rotate(ctx context.Context) error {
+	if err := kr.Refresh(ctx); err != nil {
+		return err
+	}
+	kr.mu.RLock()
+	active := kr.activeLocked()
+	kr.mu.RUnlock()
+	return kr.rotate(ctx, active)
+}
+
+func (kr *KeyRing) rotate(ctx context.Context, current *signingKey) error {
+	private, err := generateKey(kr.Algorithm, false)
+	if err != nil {
+		return err
+	}
+	kid, err := newKid()
+	if err != nil {
+		return err
+	}
+	encoded, err := kr.sealPrivateKey(kid, private)
+	if err != nil {
+		return err
+	}
+
+	err = kr.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 先退役当前密钥（条件更新），并发轮换时只有一个实例能成功
+		retire := tx.Model(&model.SigningKey{}).Where("retired_at IS NULL")
+		if current != nil {
+			retire = retire.Where("kid = ?", current.kid)
+		}
+		result := retire.Update("retired_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to retire signing key: %w", result.Error)
+		}
+		if current != nil && result.RowsAffected != 1 {
+			return errRotationRaced
+		}
+
+		record := model.SigningKey{Kid: kid, Algorithm: kr.Algorithm, PrivateKey: encoded}
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to save signing key: %w", err)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRotationRaced) {
+		return err
+	}
+	return kr.Refresh(ctx)
+}
+
+var errRotationRaced = errors.New("signing key already rotated")
+
+func (kr *KeyRing) lookup(kid string) *signingKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	for _, k := range kr.keys {
+		if k.kid == kid && kr.verifiableLocked(k, time.Now()) {
+			return k
+		}
+	}
+	return nil
+}
+
+// activeLocked 最新的未退役密钥（可能仍在发布等待期内）
+func (kr *KeyRing) activeLocked() *signingKey {
+	for _, k := range kr.keys {
+		if k.retiredAt == nil {
+			return k
+		}
+	}
+	return nil
+}
+
+// signerLocked 用于签名的密钥：已过发布等待期的最新密钥；
+// 首次启动等没有密钥满足条件时，所有密钥都是刚生成的，使用其中最早的一个
+func (kr *KeyRing) signerLocked(now time.Time) *signingKey {
+	for _, k := range kr.keys {
+		if now.Sub(k.createdAt) >= kr.PublishDelay && kr.verifiableLocked(k, now) {
+			return k
+		}
+	}
+	if len(kr.keys) > 0 {
+		return kr.keys[len(kr.keys)-1]
+	}
+	return nil
+}
+
+func (kr *KeyRing) verifiableLocked(k *signingKey, now time.Time) bool {
+	return k.retiredAt == nil || now.Before(k.retiredAt.Add(kr.Grace))
+}
+
+// generateKey 生成指定算法的私钥；dryRun 只校验算法
+func generateKey(algorithm string, dryRun bool) (crypto.Signer, error) {
+	switch algorithm {
+	case RS256, ES256, EdDSA:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, algorithm)
+	}
+	if dryRun {
+		return nil, nil
+	}
+
+	switch algorithm {
+	case RS256:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case ES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	}
+}
+
+func encodePrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode signing key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func decodePrivateKey(encoded string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("invalid pem")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
+// sealPlaintextKeys 加密早期版本以明文 PEM 保存的私钥
+func (kr *KeyRing) sealPlaintextKeys(ctx context.Context) error {
+	var records []model.SigningKey
+	if err := kr.DB.WithContext(ctx).Where("private_key NOT LIKE ?", sealedPrefix+"%").Find(&records).Error; err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	for _, r := range records {
+		private, err := decodePrivateKey(r.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("failed to decode signing key %s: %w", r.Kid, err)
+		}
+		sealed, err := kr.sealPrivateKey(r.Kid, private)
+		if err != nil {
+			return err
+		}
+		if err := kr.DB.WithContext(ctx).Model(&model.SigningKey{}).
+			Where("id = ? AND private_key = ?", r.ID, r.PrivateKey).
+			Update("private_key", sealed).Error; err != nil {
+			return fmt.Errorf("failed to encrypt signing key %s: %w", r.Kid, err)
+		}
+	}
+	return nil
+}
+
+// newAEAD 由配置的密钥派生 AES-256-GCM 密钥
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("signing key encryption secret is empty")
+	}
+	key := sha256.Sum256(secret)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealPrivateKey 加密私钥；kid 作为附加数据，密文不能挪到其它记录上使用
+func (kr *KeyRing) sealPrivateKey(kid string, key crypto.Signer) (string, error) {
+	encoded, err := encodePrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, kr.aead.NonceSize(), kr.aead.NonceSize()+len(encoded)+kr.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := kr.aead.Seal(nonce, nonce, []byte(encoded), []byte(kid))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openPrivateKey 解密并解析私钥，兼容尚未加密的明文 PEM
+func (kr *KeyRing) openPrivateKey(kid, stored string) (crypto.Signer, error) {
+	raw, ok := strings.CutPrefix(stored, sealedPrefix)
+	if !ok {
+		return decodePrivateKey(stored)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil || len(sealed) < kr.aead.NonceSize() {
+		return nil, errors.New("invalid encrypted key")
+	}
+	nonce, ciphertext := sealed[:kr.aead.NonceSize()], sealed[kr.aead.NonceSize():]
+	encoded, err := kr.aead.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return nil, errors.New("cannot decrypt key, check that the encryption secret matches the other instances")
+	}
+	return decodePrivateKey(string(encoded))
+}
+
+func newKid() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate kid: %w", err)
+	}
+	return time.Now().UTC().Format("20060102") + "-" + hex.EncodeToString(buf), nil
+}
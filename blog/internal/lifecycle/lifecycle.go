@@ -0,0 +1,86 @@
+// Package lifecycle 管理后台任务与资源的生命周期：退出时先取消并等待后台任务，
+// 再按注册的逆序释放资源（如刷新链路追踪、关闭数据库连接）
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Manager 后台任务与退出钩子的管理器
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	log    *slog.Logger
+
+	mu    sync.Mutex
+	hooks []hook
+}
+
+type hook struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// New 构造函数
+func New(logger *slog.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{ctx: ctx, cancel: cancel, log: logger}
+}
+
+// Go 启动后台任务，fn 应在 ctx 取消后尽快返回；panic 会被记录，不影响其它任务
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				m.log.Error("background worker panicked", "worker", name, "panic", fmt.Sprint(r))
+			}
+		}()
+		fn(m.ctx)
+		m.log.Debug("background worker stopped", "worker", name)
+	}()
+}
+
+// OnStop 注册退出钩子，在全部后台任务结束后按注册的逆序执行
+func (m *Manager) OnStop(name string, fn func(context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{name: name, fn: fn})
+}
+
+// Shutdown 取消后台任务并等待其结束，然后执行退出钩子
+// ctx 到期时不再等待未结束的任务，直接执行钩子；返回所有钩子的错误
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	var errs []error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("background workers did not stop: %w", ctx.Err()))
+	}
+
+	m.mu.Lock()
+	hooks := m.hooks
+	m.hooks = nil
+	m.mu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hooks[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
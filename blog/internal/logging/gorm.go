@@ -0,0 +1,28 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// NewGormLogger 将 gorm 的慢查询与错误日志写入 slog；SQL 只记录占位符，不带参数值
+func NewGormLogger(l *slog.Logger) gormlogger.Interface {
+	return gormlogger.New(gormWriter{l}, gormlogger.Config{
+		SlowThreshold:             200 * time.Millisecond,
+		LogLevel:                  gormlogger.Warn,
+		IgnoreRecordNotFoundError: true,
+		ParameterizedQueries:      true,
+	})
+}
+
+type gormWriter struct {
+	l *slog.Logger
+}
+
+func (w gormWriter) Printf(format string, args ...interface{}) {
+	w.l.Warn("gorm", "detail", strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
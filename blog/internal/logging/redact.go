@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Redacted 替换敏感值的占位符
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys 字段名（不区分大小写）包含这些片段时整体脱敏
+var sensitiveKeys = []string{
+	"password", "passwd", "secret", "token", "authorization", "cookie",
+	"api_key", "apikey", "private_key", "recovery_code", "signature", "code_verifier",
+}
+
+// sensitiveValues 即使字段名无害，值中出现的令牌也会被替换：Bearer/Basic 凭据、个人访问令牌与 JWT
+var sensitiveValues = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`),
+	regexp.MustCompile(`blogpat_[A-Za-z0-9_-]+`),
+	regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+}
+
+// sensitiveParams URL 查询串中携带凭据的参数，只替换参数值
+var sensitiveParams = regexp.MustCompile(`(?i)([?&](?:token|access_token|refresh_token|signature|code|state|password)=)[^&\s]*`)
+
+// IsSensitiveKey 判断字段名是否属于敏感字段
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString 替换字符串中出现的凭据
+func RedactString(s string) string {
+	for _, re := range sensitiveValues {
+		s = re.ReplaceAllString(s, Redacted)
+	}
+	return sensitiveParams.ReplaceAllString(s, "${1}"+Redacted)
+}
+
+// redactAttr 作为 slog.HandlerOptions.ReplaceAttr，对每个字段脱敏
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if IsSensitiveKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			return slog.String(a.Key, RedactString(v.Error()))
+		case http.Header:
+			return slog.Any(a.Key, redactHeader(v))
+		case map[string]string:
+			return slog.Any(a.Key, redactMap(v))
+		case map[string]interface{}:
+			return slog.Any(a.Key, redactAnyMap(v))
+		case fmt.Stringer:
+			return slog.String(a.Key, RedactString(v.String()))
+		}
+	}
+	return a
+}
+
+func redactHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		if IsSensitiveKey(k) {
+			out[k] = []string{Redacted}
+			continue
+		}
+		redacted := make([]string, len(vs))
+		for i, v := range vs {
+			redacted[i] = RedactString(v)
+		}
+		out[k] = redacted
+	}
+	return out
+}
+
+func redactMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if IsSensitiveKey(k) {
+			out[k] = Redacted
+		} else {
+			out[k] = RedactString(v)
+		}
+	}
+	return out
+}
+
+func redactAnyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		switch {
+		case IsSensitiveKey(k):
+			out[k] = Redacted
+		case isString(v):
+			out[k] = RedactString(v.(string))
+		default:
+			if nested, ok := v.(map[string]interface{}); ok {
+				v = redactAnyMap(nested)
+			}
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
@@ -0,0 +1,150 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/repository"
+)
+
+var (
+	ErrAccessTokenNotFound = errors.New("access token not found")
+	ErrInvalidScope        = errors.New("invalid scope")
+	ErrTooManyAccessTokens = errors.New("too many access tokens")
+)
+
+// CreateAccessToken 创建个人访问令牌，返回的明文令牌只在创建时展示一次
+// 令牌格式为 blogpat_<8位标识>_<随机串>，前 16 个字符作为可见前缀保存，便于用户识别
+func (s *UserService) CreateAccessToken(ctx context.Context, userID uint, name string, scopes []string, ttl time.Duration) (string, *model.PersonalAccessToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", nil, fmt.Errorf("%w: name must be 1-100 characters", ErrInvalidInput)
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	if ttl <= 0 {
+		ttl = consts.AccessTokenDefaultTTL
+	}
+	if ttl > consts.AccessTokenMaxTTL {
+		return "", nil, fmt.Errorf("%w: expiry must not exceed %d days", ErrInvalidInput, int(consts.AccessTokenMaxTTL.Hours()/24))
+	}
+
+	count, err := s.Users.CountActiveAccessTokens(ctx, userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to count access tokens: %w", err)
+	}
+	if count >= consts.AccessTokenMaxPerUser {
+		return "", nil, ErrTooManyAccessTokens
+	}
+
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	prefix := consts.AccessTokenPrefix + base64.RawURLEncoding.EncodeToString(id)
+	token := prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	record := model.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		TokenHash: hashAccessToken(token),
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.Users.CreateAccessToken(ctx, &record); err != nil {
+		return "", nil, fmt.Errorf("failed to save access token: %w", err)
+	}
+
+	return token, &record, nil
+}
+
+// ListAccessTokens 列出用户未撤销的令牌（含已过期）
+func (s *UserService) ListAccessTokens(ctx context.Context, userID uint) ([]model.PersonalAccessToken, error) {
+	tokens, err := s.Users.ListAccessTokens(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeAccessToken 撤销令牌（软删除，保留记录用于审计）
+func (s *UserService) RevokeAccessToken(ctx context.Context, userID, tokenID uint) error {
+	found, err := s.Users.RevokeAccessToken(ctx, userID, tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	if !found {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateAccessToken 校验个人访问令牌，返回所属用户和权限范围，供 AuthMiddleware 使用
+func (s *UserService) AuthenticateAccessToken(ctx context.Context, token string) (*model.User, []string, error) {
+	record, err := s.Users.FindAccessTokenByHash(ctx, hashAccessToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, fmt.Errorf("failed to query access token: %w", err)
+	}
+
+	user, err := s.getUser(ctx, record.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+
+	// 最近使用时间每分钟最多更新一次，避免每个请求都写库
+	now := time.Now()
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) > time.Minute {
+		if err := s.Users.TouchAccessToken(ctx, record.ID, now); err != nil {
+			s.Log.WarnContext(ctx, "failed to update access token usage", "token_id", record.ID, "error", err)
+		}
+	}
+
+	return user, record.ScopeList(), nil
+}
+
+// normalizeScopes 校验并去重权限范围
+func normalizeScopes(scopes []string) ([]string, error) {
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(consts.Scopes, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		if !slices.Contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	slices.Sort(result)
+	return result, nil
+}
+
+func hashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,167 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/mailer"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/repository"
+)
+
+// ResendVerification 重新发送验证邮件
+// 邮箱不存在或已验证时静默返回成功，避免通过该接口探测注册邮箱
+func (s *UserService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.Users.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to query user: %w", err)
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	// 旧链接作废，只保留最新一封邮件中的链接
+	if err := s.Users.RevokeAccountTokens(ctx, user.ID, consts.TokenPurposeVerifyEmail); err != nil {
+		return fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// VerifyEmail 使用验证令牌确认邮箱
+func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
+	return s.UoW.Do(ctx, func(repos repository.Repositories) error {
+		userID, err := s.consumeAccountToken(ctx, repos.Users, token, consts.TokenPurposeVerifyEmail)
+		if err != nil {
+			return err
+		}
+
+		user, err := repos.Users.FindByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil
+			}
+			return fmt.Errorf("failed to query user: %w", err)
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := repos.Users.Update(ctx, user, "email_verified_at"); err != nil {
+			return fmt.Errorf("failed to verify email: %w", err)
+		}
+		return nil
+	})
+}
+
+// RequestPasswordReset 发送找回密码邮件
+// 邮箱不存在时静默返回成功，避免通过该接口探测注册邮箱
+func (s *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.Users.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to query user: %w", err)
+	}
+
+	token, err := s.newAccountToken(ctx, s.Users, user.ID, consts.TokenPurposeResetPassword, consts.ResetPasswordTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.AppURL, url.QueryEscape(token))
+	msg, err := mailer.Render(mailer.TemplateResetPassword, user.Email, "重置你的博客密码", map[string]any{
+		"Username":  user.Username,
+		"Link":      link,
+		"ExpiresIn": formatTTL(consts.ResetPasswordTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, msg)
+}
+
+// ResetPassword 使用找回密码令牌设置新密码
+// 成功后作废该用户其它未使用的找回密码令牌、吊销全部登录会话，并清除登录失败记录
+func (s *UserService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if len(newPassword) < 6 {
+		return fmt.Errorf("%w: password must be at least 6 characters", ErrInvalidInput)
+	}
+
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	return s.UoW.Do(ctx, func(repos repository.Repositories) error {
+		userID, err := s.consumeAccountToken(ctx, repos.Users, token, consts.TokenPurposeResetPassword)
+		if err != nil {
+			return err
+		}
+
+		user, err := repos.Users.FindByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrInvalidToken
+			}
+			return fmt.Errorf("failed to query user: %w", err)
+		}
+
+		// 能收到邮件即证明邮箱属于本人，顺带完成邮箱验证
+		user.Password = hashedPassword
+		columns := []string{"password"}
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+			columns = append(columns, "email_verified_at")
+		}
+		if err := repos.Users.Update(ctx, user, columns...); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		if err := repos.Users.RevokeAccountTokens(ctx, user.ID, consts.TokenPurposeResetPassword); err != nil {
+			return fmt.Errorf("failed to revoke tokens: %w", err)
+		}
+		if err := revokeSessions(ctx, repos.Users, user.ID); err != nil {
+			return err
+		}
+		if err := repos.Users.ClearLoginFailures(ctx, user.Username, ""); err != nil {
+			return fmt.Errorf("failed to clear login attempts: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *UserService) sendVerificationEmail(ctx context.Context, user *model.User) error {
+	token, err := s.newAccountToken(ctx, s.Users, user.ID, consts.TokenPurposeVerifyEmail, consts.VerifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/v1/users/verify-email?token=%s", s.AppURL, url.QueryEscape(token))
+	msg, err := mailer.Render(mailer.TemplateVerifyEmail, user.Email, "验证你的博客邮箱", map[string]any{
+		"Username":  user.Username,
+		"Link":      link,
+		"ExpiresIn": formatTTL(consts.VerifyEmailTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, msg)
+}
+
+// formatTTL 将有效期格式化为邮件中展示的文字
+func formatTTL(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%d 小时", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d 分钟", int(d.Minutes()))
+}
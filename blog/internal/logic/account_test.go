@@ -0,0 +1,99 @@
+package logic_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var linkTokenPattern = regexp.MustCompile(`[?&]token=([^&\s"<]+)`)
+
+// mailedToken 取出最近一封邮件中链接携带的令牌
+func mailedToken(t *testing.T, m *memoryMailer) string {
+	t.Helper()
+
+	match := linkTokenPattern.FindStringSubmatch(m.last().Text)
+	if match == nil {
+		t.Fatal("no token link in the last email")
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// failingClearUsers 清除登录失败记录时失败，此时重置密码的其它修改已在事务中写入
+type failingClearUsers struct {
+	repository.UserRepository
+}
+
+func (failingClearUsers) ClearLoginFailures(context.Context, string, string) error {
+	return errInjected
+}
+
+func newUserService(repos repository.Repositories, uow repository.UnitOfWork, m *memoryMailer, secret string) *logic.UserService {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	users := logic.NewUserService(repos, uow, m, nil, []byte(secret), "http://blog.test", log)
+	// 测试不需要抗暴力破解的哈希强度
+	users.PasswordCost = bcrypt.MinCost
+	return users
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	repos, uow := repository.NewMemory()
+	mail := &memoryMailer{}
+	users := newUserService(repos, uow, mail, "0123456789abcdef0123456789abcdef")
+	alice := createUser(t, repos, "alice", "secret123")
+
+	if err := users.RequestPasswordReset(ctx, alice.Email); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	token := mailedToken(t, mail)
+
+	// 其它密钥签名的同一令牌无效
+	other := newUserService(repos, uow, mail, "fedcba9876543210fedcba9876543210")
+	if err := other.ResetPassword(ctx, token, "newpass123"); !errors.Is(err, logic.ErrInvalidToken) {
+		t.Fatalf("ResetPassword with another secret error = %v, want ErrInvalidToken", err)
+	}
+
+	// 事务最后一步失败时令牌的使用标记与新密码一起回滚，令牌仍可使用
+	failing := newUserService(repos, wrapUoW{uow, func(r *repository.Repositories) {
+		r.Users = failingClearUsers{r.Users}
+	}}, mail, "0123456789abcdef0123456789abcdef")
+	if err := failing.ResetPassword(ctx, token, "newpass123"); !errors.Is(err, errInjected) {
+		t.Fatalf("ResetPassword error = %v, want the injected failure", err)
+	}
+	user, err := repos.Users.FindByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consts.CheckPassword("secret123", user.Password) != nil || user.EmailVerifiedAt != nil {
+		t.Fatal("failed reset changed the password or verified the email")
+	}
+
+	if err := users.ResetPassword(ctx, token, "newpass123"); err != nil {
+		t.Fatalf("ResetPassword after rollback: %v", err)
+	}
+	if err := users.ResetPassword(ctx, token, "another123"); !errors.Is(err, logic.ErrInvalidToken) {
+		t.Fatalf("reused token error = %v, want ErrInvalidToken", err)
+	}
+	user, err = repos.Users.FindByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consts.CheckPassword("newpass123", user.Password) != nil || user.EmailVerifiedAt == nil {
+		t.Fatal("reset did not set the new password and verify the email")
+	}
+}
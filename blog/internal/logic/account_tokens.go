@@ -0,0 +1,102 @@
+package logic
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/repository"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// newAccountToken 生成签名的一次性令牌并保存其哈希
+// 令牌格式为 base64url(用途:用户ID:过期时间:随机串) + "." + base64url(HMAC-SHA256)，
+// 签名用于在查库前拒绝伪造令牌，数据库记录用于保证只能使用一次
+func (s *UserService) newAccountToken(ctx context.Context, users repository.UserRepository, userID uint, purpose string, ttl time.Duration) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	payload := fmt.Sprintf("%s:%d:%d:%s", purpose, userID, expiresAt.Unix(), hex.EncodeToString(nonce))
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.signAccountToken(payload))
+
+	record := model.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashAccountToken(token),
+		ExpiresAt: expiresAt,
+	}
+	if err := users.CreateAccountToken(ctx, &record); err != nil {
+		return "", fmt.Errorf("failed to save token: %w", err)
+	}
+
+	return token, nil
+}
+
+// consumeAccountToken 校验令牌并标记为已使用，返回令牌所属用户ID
+// 必须使用事务中的仓储调用，标记使用与后续业务修改一同提交
+func (s *UserService) consumeAccountToken(ctx context.Context, users repository.UserRepository, token, purpose string) (uint, error) {
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, s.signAccountToken(string(payload))) {
+		return 0, ErrInvalidToken
+	}
+
+	parts := strings.Split(string(payload), ":")
+	if len(parts) != 4 || parts[0] != purpose {
+		return 0, ErrInvalidToken
+	}
+	userID, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return 0, ErrInvalidToken
+	}
+
+	// 条件更新保证并发请求中只有一个能使用成功
+	consumed, err := users.ConsumeAccountToken(ctx, hashAccountToken(token), uint(userID), purpose)
+	if err != nil {
+		return 0, fmt.Errorf("failed to consume token: %w", err)
+	}
+	if !consumed {
+		return 0, ErrInvalidToken
+	}
+
+	return uint(userID), nil
+}
+
+// signAccountToken 以 TokenSecret 计算 HMAC-SHA256 签名
+func (s *UserService) signAccountToken(payload string) []byte {
+	mac := hmac.New(sha256.New, s.TokenSecret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func hashAccountToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,12 +2,18 @@
 package logic
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/metrics"
 	"web-task/blog/internal/model"
+	"web-task/blog/internal/pubsub"
+	"web-task/blog/internal/repository"
+	"web-task/blog/internal/tracing"
 
-	"gorm.io/gorm"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
@@ -17,17 +23,23 @@ var (
 
 // CommentService 评论服务
 type CommentService struct {
-	DB *gorm.DB
+	Comments repository.CommentRepository
+	Posts    repository.PostRepository
+	UoW      repository.UnitOfWork // 发表评论与发件箱事件、通知在同一事务中提交
+	Hub      pubsub.Hub            // 提交后推送通知与评论事件，为 nil 时不推送
 }
 
 // NewCommentService 构造函数
-func NewCommentService(db *gorm.DB) *CommentService {
-	return &CommentService{DB: db}
+func NewCommentService(repos repository.Repositories, uow repository.UnitOfWork, hub pubsub.Hub) *CommentService {
+	return &CommentService{Comments: repos.Comments, Posts: repos.Posts, UoW: uow, Hub: hub}
 }
 
 // Create 创建评论（需要已认证用户）
-// postID 为被评论的文章ID；parentID 为父评论ID（可选，为0表示顶级评论）
-func (s *CommentService) Create(userID uint, postID uint, content string) (*model.Comment, error) {
+// postID 为被评论的文章ID；parentID 为父评论ID（可选，为0表示顶级评论），只能回复同一文章下的顶级评论
+func (s *CommentService) Create(ctx context.Context, userID uint, postID uint, parentID uint, content string) (*model.Comment, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.Create", attribute.Int("post.id", int(postID)))
+	defer span.End()
+
 	if content == "" {
 		return nil, fmt.Errorf("%w: content is required", ErrInvalidInput)
 	}
@@ -38,39 +50,132 @@ func (s *CommentService) Create(userID uint, postID uint, content string) (*mode
 		PostID:  postID,
 	}
 
-	if err := s.DB.Create(&comment).Error; err != nil {
+	var notifications []*model.Notification
+	err := s.UoW.Do(ctx, func(repos repository.Repositories) error {
+		notifications = nil
+		// 事件按文章作者分发，同时拒绝评论不存在的文章
+		post, err := repos.Posts.FindByID(ctx, postID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrPostNotFound
+			}
+			return err
+		}
+		// 回复只有一层，删除评论时可以连同其回复一起删除
+		var parent *model.Comment
+		if parentID != 0 {
+			parent, err = repos.Comments.FindByID(ctx, parentID, false)
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			if err != nil || parent.PostID != postID || parent.ParentID != nil {
+				return fmt.Errorf("%w: parent_id must be a top-level comment on the same post", ErrInvalidInput)
+			}
+			comment.ParentID = &parent.ID
+		}
+		if err := repos.Comments.Create(ctx, &comment); err != nil {
+			return err
+		}
+		if err := appendCommentEvent(ctx, repos.Outbox, post, &comment); err != nil {
+			return err
+		}
+
+		// 回复通知父评论作者，评论通知文章作者；不通知自己，父评论作者即文章作者时只发回复通知
+		notify := func(recipient uint, kind string) error {
+			if recipient == userID {
+				return nil
+			}
+			notification := &model.Notification{
+				UserID:    recipient,
+				Type:      kind,
+				ActorID:   userID,
+				PostID:    postID,
+				CommentID: comment.ID,
+				Excerpt:   truncate(content, consts.NotificationExcerptLength),
+			}
+			if err := repos.Notifications.Create(ctx, notification); err != nil {
+				return fmt.Errorf("failed to create notification: %w", err)
+			}
+			notifications = append(notifications, notification)
+			return nil
+		}
+		if parent != nil {
+			if err := notify(parent.UserID, consts.NotificationTypeReply); err != nil {
+				return err
+			}
+			if parent.UserID == post.UserID {
+				return nil
+			}
+		}
+		return notify(post.UserID, consts.NotificationTypeComment)
+	})
+	if errors.Is(err, ErrPostNotFound) || errors.Is(err, ErrInvalidInput) {
+		return nil, err
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to create comment: %w", err)
 	}
+	metrics.CommentsCreated.Inc()
+	for _, notification := range notifications {
+		publishNotification(ctx, s.Hub, notification)
+	}
+	publishCommentEvent(ctx, s.Hub, consts.LiveEventCommentCreated, &comment)
 
 	// 直接返回创建的评论（不含关联数据）
 	return &comment, nil
 }
 
-// GetByID 根据ID获取评论（含作者、文章、父评论作者）
-// GetByID 已经通过 Preload 加载了关联，无需再处理
-func (s *CommentService) GetByID(id uint) (*model.Comment, error) {
-	var comment model.Comment
-	if err := s.DB.Preload("User").
-		Preload("Post").
-		Preload("Parent.User").
-		First(&comment, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+// GetByID 根据ID获取评论（含作者与所属文章）
+func (s *CommentService) GetByID(ctx context.Context, id uint) (*model.Comment, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.GetByID", attribute.Int("comment.id", int(id)))
+	defer span.End()
+
+	comment, err := s.Comments.FindDetail(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrCommentNotFound
 		}
 		return nil, fmt.Errorf("failed to get comment: %w", err)
 	}
-	return &comment, nil
+	return comment, nil
+}
+
+// ListByUser 获取用户发表的全部评论，按创建时间正序
+func (s *CommentService) ListByUser(ctx context.Context, userID uint) ([]model.Comment, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.ListByUser", attribute.Int("user.id", int(userID)))
+	defer span.End()
+
+	comments, err := s.Comments.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	return comments, nil
+}
+
+// ListByPosts 获取多篇文章下的评论（不含关联），按创建时间正序，每篇文章最多 perPost 条
+func (s *CommentService) ListByPosts(ctx context.Context, postIDs []uint, perPost int) ([]model.Comment, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.ListByPosts", attribute.Int("post.count", len(postIDs)))
+	defer span.End()
+
+	comments, err := s.Comments.ListByPosts(ctx, postIDs, perPost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	return comments, nil
 }
 
 // Update 更新评论内容（仅作者可编辑）
-func (s *CommentService) Update(userID uint, id uint, content string) (*model.Comment, error) {
+func (s *CommentService) Update(ctx context.Context, userID uint, id uint, content string) (*model.Comment, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.Update", attribute.Int("comment.id", int(id)))
+	defer span.End()
+
 	if content == "" {
 		return nil, fmt.Errorf("%w: content is required", ErrInvalidInput)
 	}
 
-	var comment model.Comment
-	if err := s.DB.First(&comment, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+	comment, err := s.Comments.FindByID(ctx, id, false)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrCommentNotFound
 		}
 		return nil, fmt.Errorf("failed to get comment: %w", err)
@@ -80,20 +185,25 @@ func (s *CommentService) Update(userID uint, id uint, content string) (*model.Co
 		return nil, errors.New("permission denied")
 	}
 
-	if err := s.DB.Model(&comment).Update("content", content).Error; err != nil {
+	comment.Content = content
+	if err := s.Comments.Update(ctx, comment, "content"); err != nil {
 		return nil, fmt.Errorf("failed to update comment: %w", err)
 	}
+	publishCommentEvent(ctx, s.Hub, consts.LiveEventCommentUpdated, comment)
 
 	// 直接返回更新后的评论（不含关联数据）
-	return &comment, nil
+	return comment, nil
 }
 
 // Delete 删除评论（软删除；仅作者可删除）
 // includeChildren 是否同时软删除其所有子评论（默认 false）
-func (s *CommentService) Delete(userID uint, id uint, includeChildren bool) error {
-	var comment model.Comment
-	if err := s.DB.First(&comment, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+func (s *CommentService) Delete(ctx context.Context, userID uint, id uint, includeChildren bool) error {
+	ctx, span := tracing.Start(ctx, "CommentService.Delete", attribute.Int("comment.id", int(id)))
+	defer span.End()
+
+	comment, err := s.Comments.FindByID(ctx, id, false)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return ErrCommentNotFound
 		}
 		return fmt.Errorf("failed to get comment: %w", err)
@@ -104,34 +214,26 @@ func (s *CommentService) Delete(userID uint, id uint, includeChildren bool) erro
 		return errors.New("permission denied")
 	}
 
-	db := s.DB
-
-	// 批量删除子评论（软删除）
-	if includeChildren {
-		subQuery := s.DB.Model(&model.Comment{}).
-			Select("id").
-			Where("parent_id = ?", comment.ID)
-
-		if err := db.Where("id = ? OR parent_id IN (?)", comment.ID, subQuery).
-			UpdateColumn("deleted_at", gorm.Expr("CURRENT_TIMESTAMP")).Error; err != nil {
+	if err := s.Comments.Delete(ctx, comment, includeChildren); err != nil {
+		if includeChildren {
 			return fmt.Errorf("failed to delete comment and children: %w", err)
 		}
-	} else {
-		// 仅删除当前评论
-		if err := db.Delete(&comment).Error; err != nil {
-			return fmt.Errorf("failed to delete comment: %w", err)
-		}
+		return fmt.Errorf("failed to delete comment: %w", err)
 	}
+	publishCommentEvent(ctx, s.Hub, consts.LiveEventCommentDeleted, comment)
 
 	return nil
 }
 
 // Restore 恢复已软删的评论（仅作者可恢复）
 // includeChildren 是否同时恢复其所有子评论（默认 false）
-func (s *CommentService) Restore(userID uint, id uint, includeChildren bool) error {
-	var comment model.Comment
-	if err := s.DB.Unscoped().First(&comment, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+func (s *CommentService) Restore(ctx context.Context, userID uint, id uint, includeChildren bool) error {
+	ctx, span := tracing.Start(ctx, "CommentService.Restore", attribute.Int("comment.id", int(id)))
+	defer span.End()
+
+	comment, err := s.Comments.FindByID(ctx, id, true)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return ErrCommentNotFound
 		}
 		return fmt.Errorf("failed to get comment: %w", err)
@@ -147,24 +249,11 @@ func (s *CommentService) Restore(userID uint, id uint, includeChildren bool) err
 		return errors.New("permission denied")
 	}
 
-	db := s.DB
-
-	// 批量恢复子评论
-	if includeChildren {
-		subQuery := s.DB.Model(&model.Comment{}).
-			Select("id").
-			Where("parent_id = ?", comment.ID).
-			Unscoped() // 子评论可能已被软删，需 Unscoped 查询
-
-		if err := db.Unscoped().Where("id = ? OR parent_id IN (?)", comment.ID, subQuery).
-			UpdateColumn("deleted_at", nil).Error; err != nil {
+	if err := s.Comments.Restore(ctx, comment, includeChildren); err != nil {
+		if includeChildren {
 			return fmt.Errorf("failed to restore comment and children: %w", err)
 		}
-	} else {
-		// 仅恢复当前评论
-		if err := db.Model(&comment).UpdateColumn("deleted_at", nil).Error; err != nil {
-			return fmt.Errorf("failed to restore comment: %w", err)
-		}
+		return fmt.Errorf("failed to restore comment: %w", err)
 	}
 
 	return nil
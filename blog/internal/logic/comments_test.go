@@ -0,0 +1,256 @@
+package logic_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/pubsub"
+	"web-task/blog/internal/repository"
+)
+
+// subscribe 订阅主题，测试结束时取消
+func subscribe(t *testing.T, hub pubsub.Hub, topic string) pubsub.Subscription {
+	t.Helper()
+
+	sub, err := hub.Subscribe(context.Background(), topic, 8)
+	if err != nil {
+		t.Fatalf("subscribe %s: %v", topic, err)
+	}
+	t.Cleanup(sub.Close)
+	return sub
+}
+
+// pending 取出订阅中已收到的消息；MemoryHub 在 Publish 返回前投递
+func pending(sub pubsub.Subscription) [][]byte {
+	var messages [][]byte
+	for {
+		select {
+		case payload := <-sub.C():
+			messages = append(messages, payload)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestCommentCreateNotifiesPostAuthor(t *testing.T) {
+	ctx := context.Background()
+	repos, uow := repository.NewMemory()
+	hub := pubsub.NewMemoryHub()
+	t.Cleanup(func() { hub.Close() })
+	alice := createUser(t, repos, "alice", "secret123")
+	bob := createUser(t, repos, "bob", "secret123")
+	post, err := logic.NewPostService(repos, uow).Create(ctx, alice.ID, "Hello", "World")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comments := logic.NewCommentService(repos, uow, hub)
+	inbox := subscribe(t, hub, logic.NotificationTopic(alice.ID))
+	live := subscribe(t, hub, logic.CommentTopic(post.ID))
+
+	if _, err := comments.Create(ctx, bob.ID, 999, 0, "lost"); !errors.Is(err, logic.ErrPostNotFound) {
+		t.Fatalf("Create on unknown post error = %v, want ErrPostNotFound", err)
+	}
+	if _, err := comments.Create(ctx, alice.ID, post.ID, 0, "mine"); err != nil {
+		t.Fatal(err)
+	}
+	comment, err := comments.Create(ctx, bob.ID, post.ID, 0, "nice post")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 只有别人的评论通知文章作者
+	notifications := pending(inbox)
+	if len(notifications) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(notifications))
+	}
+	var n model.Notification
+	if err := json.Unmarshal(notifications[0], &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.ID == 0 || n.UserID != alice.ID || n.ActorID != bob.ID || n.CommentID != comment.ID || n.Type != consts.NotificationTypeComment {
+		t.Fatalf("notification = %+v, want a saved comment notification from bob", n)
+	}
+
+	// 直播间收到两条评论的创建事件
+	events := pending(live)
+	if len(events) != 2 {
+		t.Fatalf("got %d live events, want 2", len(events))
+	}
+	var ev logic.CommentEvent
+	if err := json.Unmarshal(events[1], &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Type != consts.LiveEventCommentCreated || ev.Comment == nil || ev.Comment.ID != comment.ID {
+		t.Fatalf("live event = %+v, want comment.created for %d", ev, comment.ID)
+	}
+}
+
+func TestCommentReplyNotifiesParentAuthor(t *testing.T) {
+	ctx := context.Background()
+	repos, uow := repository.NewMemory()
+	hub := pubsub.NewMemoryHub()
+	t.Cleanup(func() { hub.Close() })
+	alice := createUser(t, repos, "alice", "secret123")
+	bob := createUser(t, repos, "bob", "secret123")
+	carol := createUser(t, repos, "carol", "secret123")
+	posts := logic.NewPostService(repos, uow)
+	post, err := posts.Create(ctx, alice.ID, "Hello", "World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := posts.Create(ctx, alice.ID, "Other", "Post")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comments := logic.NewCommentService(repos, uow, hub)
+	top, err := comments.Create(ctx, bob.ID, post.ID, 0, "top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inboxes := map[uint]pubsub.Subscription{
+		alice.ID: subscribe(t, hub, logic.NotificationTopic(alice.ID)),
+		bob.ID:   subscribe(t, hub, logic.NotificationTopic(bob.ID)),
+		carol.ID: subscribe(t, hub, logic.NotificationTopic(carol.ID)),
+	}
+	// received 取出各用户收到的通知类型
+	received := func() map[uint][]string {
+		got := make(map[uint][]string)
+		for id, sub := range inboxes {
+			for _, payload := range pending(sub) {
+				var n model.Notification
+				if err := json.Unmarshal(payload, &n); err != nil {
+					t.Fatal(err)
+				}
+				got[id] = append(got[id], n.Type)
+			}
+		}
+		return got
+	}
+
+	// 回复通知父评论作者，文章作者收到评论通知
+	reply, err := comments.Create(ctx, carol.ID, post.ID, top.ID, "reply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.ParentID == nil || *reply.ParentID != top.ID {
+		t.Fatalf("reply parent = %v, want %d", reply.ParentID, top.ID)
+	}
+	want := map[uint][]string{bob.ID: {consts.NotificationTypeReply}, alice.ID: {consts.NotificationTypeComment}}
+	if got := received(); !maps.EqualFunc(got, want, slices.Equal) {
+		t.Fatalf("notifications = %v, want %v", got, want)
+	}
+
+	// 文章作者回复时自己不收到通知；回复自己的评论时只通知文章作者
+	if _, err := comments.Create(ctx, alice.ID, post.ID, top.ID, "thanks"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comments.Create(ctx, bob.ID, post.ID, top.ID, "edit"); err != nil {
+		t.Fatal(err)
+	}
+	want = map[uint][]string{bob.ID: {consts.NotificationTypeReply}, alice.ID: {consts.NotificationTypeComment}}
+	if got := received(); !maps.EqualFunc(got, want, slices.Equal) {
+		t.Fatalf("notifications = %v, want %v", got, want)
+	}
+
+	// 只能回复同一文章下的顶级评论
+	for name, parentID := range map[string]uint{"reply to a reply": reply.ID, "unknown parent": 999} {
+		if _, err := comments.Create(ctx, carol.ID, post.ID, parentID, "nested"); !errors.Is(err, logic.ErrInvalidInput) {
+			t.Errorf("%s error = %v, want ErrInvalidInput", name, err)
+		}
+	}
+	if _, err := comments.Create(ctx, carol.ID, other.ID, top.ID, "elsewhere"); !errors.Is(err, logic.ErrInvalidInput) {
+		t.Errorf("parent on another post error = %v, want ErrInvalidInput", err)
+	}
+
+	// 连同回复一起删除
+	if err := comments.Delete(ctx, bob.ID, top.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comments.GetByID(ctx, reply.ID); !errors.Is(err, logic.ErrCommentNotFound) {
+		t.Fatalf("reply after deleting its parent error = %v, want ErrCommentNotFound", err)
+	}
+}
+
+func TestCommentCreateRollsBackWhenNotificationFails(t *testing.T) {
+	ctx := context.Background()
+	repos, uow := repository.NewMemory()
+	hub := pubsub.NewMemoryHub()
+	t.Cleanup(func() { hub.Close() })
+	alice := createUser(t, repos, "alice", "secret123")
+	bob := createUser(t, repos, "bob", "secret123")
+	post, err := logic.NewPostService(repos, uow).Create(ctx, alice.ID, "Hello", "World")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comments := logic.NewCommentService(repos, wrapUoW{uow, func(r *repository.Repositories) {
+		r.Notifications = failingNotifications{}
+	}}, hub)
+	live := subscribe(t, hub, logic.CommentTopic(post.ID))
+
+	if _, err := comments.Create(ctx, bob.ID, post.ID, 0, "nice post"); !errors.Is(err, errInjected) {
+		t.Fatalf("Create error = %v, want the notification failure", err)
+	}
+
+	// 评论与发件箱事件随事务回滚，也不推送给直播间
+	if list, err := repos.Comments.ListByPosts(ctx, []uint{post.ID}, 10); err != nil || len(list) != 0 {
+		t.Fatalf("comments after rollback = %+v, %v; want none", list, err)
+	}
+	if events := pending(live); len(events) != 0 {
+		t.Fatalf("live events after rollback = %d, want none", len(events))
+	}
+
+	// 回滚后的内存存储仍可正常写入
+	if _, err := logic.NewCommentService(repos, uow, hub).Create(ctx, bob.ID, post.ID, 0, "retry"); err != nil {
+		t.Fatalf("Create after rollback: %v", err)
+	}
+}
+
+func TestCommentDeleteAndRestore(t *testing.T) {
+	ctx := context.Background()
+	repos, uow := repository.NewMemory()
+	alice := createUser(t, repos, "alice", "secret123")
+	bob := createUser(t, repos, "bob", "secret123")
+	post, err := logic.NewPostService(repos, uow).Create(ctx, alice.ID, "Hello", "World")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comments := logic.NewCommentService(repos, uow, nil)
+	comment, err := comments.Create(ctx, bob.ID, post.ID, 0, "nice post")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := comments.Delete(ctx, alice.ID, comment.ID, false); err == nil {
+		t.Fatal("Delete by another user succeeded")
+	}
+	if err := comments.Delete(ctx, bob.ID, comment.ID, false); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := comments.GetByID(ctx, comment.ID); !errors.Is(err, logic.ErrCommentNotFound) {
+		t.Fatalf("GetByID after delete error = %v, want ErrCommentNotFound", err)
+	}
+
+	// 恢复需要越过软删除范围读取并更新该行
+	if err := comments.Restore(ctx, alice.ID, comment.ID, false); err == nil {
+		t.Fatal("Restore by another user succeeded")
+	}
+	if err := comments.Restore(ctx, bob.ID, comment.ID, false); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	restored, err := comments.GetByID(ctx, comment.ID)
+	if err != nil || restored.Content != "nice post" {
+		t.Fatalf("GetByID after restore = %+v, %v; want the comment back", restored, err)
+	}
+}
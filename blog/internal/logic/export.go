@@ -0,0 +1,426 @@
+package logic
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/storage"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrExportNotFound = errors.New("export not found")
+)
+
+// ExportService 用户数据导出：异步生成包含文章、评论与个人资料的 ZIP 归档
+type ExportService struct {
+	DB       *gorm.DB
+	Storage  storage.Storage
+	Users    *UserService
+	Posts    *PostService
+	Comments *CommentService
+	Log      *slog.Logger
+
+	wake chan struct{}
+}
+
+// NewExportService 构造函数
+func NewExportService(db *gorm.DB, store storage.Storage, users *UserService, posts *PostService, comments *CommentService, logger *slog.Logger) *ExportService {
+	return &ExportService{
+		DB:       db,
+		Storage:  store,
+		Users:    users,
+		Posts:    posts,
+		Comments: comments,
+		Log:      logger,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// StartExport 创建导出任务；已有未完成的任务时直接返回该任务
+func (s *ExportService) StartExport(ctx context.Context, userID uint) (*model.ExportJob, error) {
+	db := s.DB.WithContext(ctx)
+
+	var job model.ExportJob
+	err := db.Where("user_id = ? AND status IN ?", userID, []string{consts.ExportStatusPending, consts.ExportStatusRunning}).
+		Order("id desc").
+		First(&job).Error
+	if err == nil {
+		return &job, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to query export: %w", err)
+	}
+
+	downloadKey, err := newDownloadKey()
+	if err != nil {
+		return nil, err
+	}
+	job = model.ExportJob{UserID: userID, Status: consts.ExportStatusPending, DownloadKey: downloadKey}
+	if err := db.Create(&job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+
+	// 唤醒后台任务立即处理
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return &job, nil
+}
+
+// GetJob 获取当前用户的导出任务
+func (s *ExportService) GetJob(ctx context.Context, userID, jobID uint) (*model.ExportJob, error) {
+	var job model.ExportJob
+	if err := s.DB.WithContext(ctx).Where("id = ? AND user_id = ?", jobID, userID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportNotFound
+		}
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+	return &job, nil
+}
+
+// DownloadURL 为已完成的任务生成限时下载链接，链接本身携带签名，无需登录即可下载
+func (s *ExportService) DownloadURL(job *model.ExportJob) (string, time.Time) {
+	expiresAt := time.Now().Add(consts.ExportLinkTTL)
+	if job.ExpiresAt != nil && job.ExpiresAt.Before(expiresAt) {
+		expiresAt = *job.ExpiresAt
+	}
+	exp := expiresAt.Unix()
+	return fmt.Sprintf("%s/api/v1/exports/%d/download?expires=%d&signature=%s",
+		s.Users.AppURL, job.ID, exp, s.signExportLink(job, exp)), expiresAt
+}
+
+// OpenDownload 校验下载链接签名与有效期，返回归档内容，调用方负责关闭
+// 任务不存在与签名错误返回同样的错误，避免通过下载接口探测任务ID
+func (s *ExportService) OpenDownload(ctx context.Context, jobID uint, expires, signature string) (io.ReadCloser, *model.ExportJob, error) {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return nil, nil, ErrInvalidToken
+	}
+
+	var job model.ExportJob
+	if err := s.DB.WithContext(ctx).First(&job, jobID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, fmt.Errorf("failed to get export: %w", err)
+	}
+	if job.DownloadKey == "" || !hmac.Equal([]byte(signature), []byte(s.signExportLink(&job, exp))) {
+		return nil, nil, ErrInvalidToken
+	}
+	if job.Status != consts.ExportStatusCompleted || job.ExpiresAt == nil || time.Now().After(*job.ExpiresAt) {
+		return nil, nil, ErrExportNotFound
+	}
+
+	rc, err := s.Storage.Get(ctx, job.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return nil, nil, ErrExportNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	return rc, &job, nil
+}
+
+// Run 后台处理导出任务并清理过期归档，直到 ctx 取消
+// 多个实例同时运行时通过条件更新认领任务，每个任务只会被一个实例处理
+func (s *ExportService) Run(ctx context.Context) {
+	ticker := time.NewTicker(consts.ExportPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ProcessPending(ctx); err != nil {
+			s.Log.ErrorContext(ctx, "export processing failed", "error", err)
+		}
+		if _, err := s.CleanupExpired(ctx); err != nil {
+			s.Log.ErrorContext(ctx, "export cleanup failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessPending 处理所有待处理任务，返回处理的任务数
+// 处于 running 状态超过 ExportStaleAfter 的任务视为实例中断，重新排队
+func (s *ExportService) ProcessPending(ctx context.Context) (int, error) {
+	db := s.DB.WithContext(ctx)
+
+	if err := db.Model(&model.ExportJob{}).
+		Where("status = ? AND started_at < ?", consts.ExportStatusRunning, time.Now().Add(-consts.ExportStaleAfter)).
+		Update("status", consts.ExportStatusPending).Error; err != nil {
+		return 0, fmt.Errorf("failed to requeue stale exports: %w", err)
+	}
+
+	processed := 0
+	for ctx.Err() == nil {
+		var job model.ExportJob
+		err := db.Where("status = ?", consts.ExportStatusPending).Order("id").First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return processed, fmt.Errorf("failed to query exports: %w", err)
+		}
+
+		now := time.Now()
+		claim := db.Model(&model.ExportJob{}).
+			Where("id = ? AND status = ?", job.ID, consts.ExportStatusPending).
+			Updates(map[string]interface{}{"status": consts.ExportStatusRunning, "started_at": now})
+		if claim.Error != nil {
+			return processed, fmt.Errorf("failed to claim export: %w", claim.Error)
+		}
+		if claim.RowsAffected != 1 {
+			continue // 已被其它实例认领
+		}
+
+		s.process(ctx, &job)
+		processed++
+	}
+	return processed, nil
+}
+
+// CleanupExpired 删除过期归档，返回删除数量
+func (s *ExportService) CleanupExpired(ctx context.Context) (int, error) {
+	db := s.DB.WithContext(ctx)
+
+	var jobs []model.ExportJob
+	if err := db.Where("status = ? AND expires_at <= ?", consts.ExportStatusCompleted, time.Now()).
+		Find(&jobs).Error; err != nil {
+		return 0, fmt.Errorf("failed to query expired exports: %w", err)
+	}
+
+	removed := 0
+	for _, job := range jobs {
+		if err := s.Storage.Delete(ctx, job.StorageKey); err != nil {
+			return removed, fmt.Errorf("failed to delete export %d: %w", job.ID, err)
+		}
+		if err := db.Delete(&job).Error; err != nil {
+			return removed, fmt.Errorf("failed to delete export %d: %w", job.ID, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// process 生成归档并更新任务状态
+// 因进程退出被取消的任务保持 running，由下次启动后的过期重排流程重新处理；
+// 状态更新不随 ctx 取消，避免已生成的归档丢失记录
+func (s *ExportService) process(ctx context.Context, job *model.ExportJob) {
+	db := s.DB.WithContext(context.WithoutCancel(ctx))
+
+	key, size, err := s.buildArchive(ctx, job.UserID)
+	if err != nil {
+		if ctx.Err() != nil {
+			s.Log.WarnContext(ctx, "export interrupted", "export_id", job.ID, "error", err)
+			return
+		}
+		s.Log.ErrorContext(ctx, "export failed", "export_id", job.ID, "user_id", job.UserID, "error", err)
+		db.Model(job).Updates(map[string]interface{}{
+			"status": consts.ExportStatusFailed,
+			"error":  truncate(err.Error(), 255),
+		})
+		return
+	}
+
+	now := time.Now()
+	s.Log.InfoContext(ctx, "export completed", "export_id", job.ID, "user_id", job.UserID, "size", size)
+	if err := db.Model(job).Updates(map[string]interface{}{
+		"status":       consts.ExportStatusCompleted,
+		"storage_key":  key,
+		"size":         size,
+		"completed_at": now,
+		"expires_at":   now.Add(consts.ExportRetention),
+	}).Error; err != nil {
+		s.Log.ErrorContext(ctx, "failed to update export status", "export_id", job.ID, "error", err)
+		s.Storage.Delete(ctx, key)
+	}
+}
+
+// exportProfile 归档中的 profile.json
+type exportProfile struct {
+	ID              uint       `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	DisplayName     string     `json:"display_name"`
+	Bio             string     `json:"bio"`
+	AvatarURL       string     `json:"avatar_url"`
+	Role            string     `json:"role"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ExportedAt      time.Time  `json:"exported_at"`
+}
+
+// exportComment 归档中 comments.json 的单条评论
+type exportComment struct {
+	ID        uint      `json:"id"`
+	PostID    uint      `json:"post_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// postFrontMatter 文章 Markdown 文件的 YAML front matter
+type postFrontMatter struct {
+	ID          uint      `yaml:"id"`
+	Title       string    `yaml:"title"`
+	CreatedAt   time.Time `yaml:"created_at"`
+	UpdatedAt   time.Time `yaml:"updated_at"`
+	Tags        []string  `yaml:"tags,omitempty"`
+	Attachments []string  `yaml:"attachments,omitempty"`
+}
+
+// buildArchive 生成 ZIP 归档并写入存储，返回存储键与大小
+// 归档先写入临时文件，避免大量文章占用内存
+func (s *ExportService) buildArchive(ctx context.Context, userID uint) (string, int64, error) {
+	user, err := s.Users.getUser(ctx, userID)
+	if err != nil {
+		return "", 0, err
+	}
+	posts, err := s.Posts.ListByUser(ctx, userID)
+	if err != nil {
+		return "", 0, err
+	}
+	comments, err := s.Comments.ListByUser(ctx, userID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	tmp, err := os.CreateTemp("", "blog-export-*.zip")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := zip.NewWriter(tmp)
+
+	profile := exportProfile{
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		DisplayName:     user.DisplayName,
+		Bio:             user.Bio,
+		AvatarURL:       user.AvatarURL,
+		Role:            user.Role,
+		CreatedAt:       user.CreatedAt,
+		ExportedAt:      time.Now(),
+	}
+	if err := writeZipJSON(zw, "profile.json", profile); err != nil {
+		return "", 0, err
+	}
+
+	for _, post := range posts {
+		if err := writePostMarkdown(zw, post); err != nil {
+			return "", 0, err
+		}
+	}
+
+	exported := make([]exportComment, 0, len(comments))
+	for _, c := range comments {
+		exported = append(exported, exportComment{ID: c.ID, PostID: c.PostID, Content: c.Content, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt})
+	}
+	if err := writeZipJSON(zw, "comments.json", exported); err != nil {
+		return "", 0, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+
+	key, err := newObjectKey(fmt.Sprintf("exports/%d", userID), ".zip")
+	if err != nil {
+		return "", 0, err
+	}
+	if err := s.Storage.Put(ctx, key, tmp, size, "application/zip"); err != nil {
+		return "", 0, fmt.Errorf("failed to store archive: %w", err)
+	}
+	return key, size, nil
+}
+
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// writePostMarkdown 以 posts/<ID>-<slug>.md 写入文章，正文前为 YAML front matter
+func writePostMarkdown(zw *zip.Writer, post model.Post) error {
+	fm := postFrontMatter{ID: post.ID, Title: post.Title, CreatedAt: post.CreatedAt, UpdatedAt: post.UpdatedAt}
+	for _, t := range post.Tags {
+		fm.Tags = append(fm.Tags, t.Name)
+	}
+	for _, a := range post.Attachments {
+		fm.Attachments = append(fm.Attachments, a.Filename)
+	}
+	header, err := yaml.Marshal(fm)
+	if err != nil {
+		return fmt.Errorf("failed to encode front matter: %w", err)
+	}
+
+	name := fmt.Sprintf("posts/%d.md", post.ID)
+	if slug := strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(post.Title), "-"), "-"); slug != "" {
+		name = fmt.Sprintf("posts/%d-%s.md", post.ID, truncate(slug, 60))
+	}
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: post.UpdatedAt})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	_, err = fmt.Fprintf(w, "---\n%s---\n\n%s\n", header, post.Content)
+	return err
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// signExportLink 下载链接签名，复用账号令牌密钥并以用途前缀区分
+// 签名内容包含任务所属用户与任务自己的随机因子，仅凭密钥或任务ID都无法伪造链接
+func (s *ExportService) signExportLink(job *model.ExportJob, exp int64) string {
+	payload := fmt.Sprintf("export_download:%d:%d:%d:%s", job.ID, job.UserID, exp, job.DownloadKey)
+	return base64.RawURLEncoding.EncodeToString(s.Users.signAccountToken(payload))
+}
+
+// newDownloadKey 生成任务的下载链接随机因子
+func newDownloadKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate download key: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
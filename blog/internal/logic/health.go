@@ -0,0 +1,82 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/migrate"
+
+	"gorm.io/gorm"
+)
+
+// HealthService 存活与就绪检查
+type HealthService struct {
+	DB       *gorm.DB
+	Migrator *migrate.Migrator
+	draining atomic.Bool
+}
+
+// NewHealthService 构造函数
+func NewHealthService(db *gorm.DB, migrator *migrate.Migrator) *HealthService {
+	return &HealthService{DB: db, Migrator: migrator}
+}
+
+// Readiness 就绪检查结果，Checks 为各检查项的状态
+type Readiness struct {
+	Ready   bool              `json:"ready"`
+	Checks  map[string]string `json:"checks"`
+	Pending []string          `json:"pending_migrations,omitempty"`
+}
+
+// SetDraining 进入退出流程，之后就绪检查失败，负载均衡不再转发新请求
+func (s *HealthService) SetDraining() {
+	s.draining.Store(true)
+}
+
+// Ready 检查数据库连通性与迁移状态；退出流程中直接返回未就绪
+func (s *HealthService) Ready(ctx context.Context) *Readiness {
+	r := &Readiness{Ready: true, Checks: map[string]string{}}
+	fail := func(name, status string) {
+		r.Ready = false
+		r.Checks[name] = status
+	}
+
+	if s.draining.Load() {
+		fail("server", consts.CheckDraining)
+		return r
+	}
+	r.Checks["server"] = consts.CheckOK
+
+	ctx, cancel := context.WithTimeout(ctx, consts.ReadinessCheckTimeout)
+	defer cancel()
+
+	if err := s.ping(ctx); err != nil {
+		fail("database", consts.CheckFailed)
+		return r
+	}
+	r.Checks["database"] = consts.CheckOK
+
+	pending, err := s.Migrator.Pending(ctx)
+	switch {
+	case err != nil:
+		fail("migrations", consts.CheckFailed)
+	case len(pending) > 0:
+		fail("migrations", consts.CheckPending)
+		for _, m := range pending {
+			r.Pending = append(r.Pending, m.Version)
+		}
+	default:
+		r.Checks["migrations"] = consts.CheckOK
+	}
+	return r
+}
+
+func (s *HealthService) ping(ctx context.Context) error {
+	sqlDB, err := s.DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+	return sqlDB.PingContext(ctx)
+}
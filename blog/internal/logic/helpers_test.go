@@ -0,0 +1,90 @@
+package logic_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/mailer"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// errInjected 测试注入的仓储错误
+var errInjected = errors.New("injected failure")
+
+// wrapUoW 在内存事务中替换部分仓储，用于注入失败或记录写入；提交与回滚仍由内存事务完成
+type wrapUoW struct {
+	repository.UnitOfWork
+	wrap func(repos *repository.Repositories)
+}
+
+func (u wrapUoW) Do(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	return u.UnitOfWork.Do(ctx, func(repos repository.Repositories) error {
+		u.wrap(&repos)
+		return fn(repos)
+	})
+}
+
+// recordingOutbox 记录事务中写入的发件箱事件
+type recordingOutbox struct {
+	repository.OutboxRepository
+	events *[]string
+}
+
+func (o recordingOutbox) Append(ctx context.Context, event *model.OutboxEvent) error {
+	if err := o.OutboxRepository.Append(ctx, event); err != nil {
+		return err
+	}
+	*o.events = append(*o.events, event.Type)
+	return nil
+}
+
+type failingOutbox struct{}
+
+func (failingOutbox) Append(context.Context, *model.OutboxEvent) error { return errInjected }
+
+type failingNotifications struct{}
+
+func (failingNotifications) Create(context.Context, *model.Notification) error { return errInjected }
+
+// memoryMailer 保存发出的邮件
+type memoryMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (m *memoryMailer) Send(_ context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func (m *memoryMailer) last() mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.messages) == 0 {
+		return mailer.Message{}
+	}
+	return m.messages[len(m.messages)-1]
+}
+
+// createUser 直接在仓储中创建用户
+func createUser(t *testing.T, repos repository.Repositories, username, password string) *model.User {
+	t.Helper()
+
+	hash, err := consts.HashPassword(password, bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: username, Email: username + "@example.com", Password: hash}
+	if err := repos.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	return user
+}
@@ -0,0 +1,386 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/oidc"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownProvider      = errors.New("unknown identity provider")
+	ErrInvalidOAuthState    = errors.New("invalid or expired oauth state")
+	ErrIdentityLinked       = errors.New("identity already linked to another account")
+	ErrIdentityEmailInUse   = errors.New("email already registered, log in and link this provider from your account")
+	ErrIdentityEmailMissing = errors.New("identity provider did not return an email")
+	ErrIdentityNotFound     = errors.New("identity not found")
+	ErrLastLoginMethod      = errors.New("cannot unlink the only login method of an account without a verified email")
+)
+
+// IdentityService 第三方登录（OIDC / OAuth2）服务
+// 登录成功后通过 UserService 签发与密码登录相同的 JWT
+type IdentityService struct {
+	DB        *gorm.DB
+	Users     *UserService
+	Providers map[string]*oidc.Provider
+}
+
+func NewIdentityService(db *gorm.DB, users *UserService, providers map[string]*oidc.Provider) *IdentityService {
+	if providers == nil {
+		providers = make(map[string]*oidc.Provider)
+	}
+	return &IdentityService{DB: db, Users: users, Providers: providers}
+}
+
+// OAuthCallbackResult 回调处理结果
+// 登录模式返回 Login；绑定模式只返回绑定的 Identity
+type OAuthCallbackResult struct {
+	Login    *LoginResult
+	Identity *model.Identity
+	NewUser  bool
+}
+
+// ProviderNames 已启用的提供方名称
+func (s *IdentityService) ProviderNames() []string {
+	names := make([]string, 0, len(s.Providers))
+	for name := range s.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AuthorizationURL 生成提供方授权地址，并保存 state、nonce 与 PKCE verifier
+// linkUserID 非空时为绑定模式：回调后把第三方身份绑定到该用户
+// 返回的 state 需由调用方保存在发起授权的浏览器中（Cookie），回调时原样传给 Callback
+func (s *IdentityService) AuthorizationURL(ctx context.Context, providerName string, linkUserID *uint) (authURL, state string, err error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err = oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomString(48)
+	if err != nil {
+		return "", "", err
+	}
+
+	record := model.OAuthState{
+		StateHash:    hashOAuthState(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(consts.OAuthStateTTL),
+	}
+	if err := s.DB.WithContext(ctx).Create(&record).Error; err != nil {
+		return "", "", fmt.Errorf("failed to save oauth state: %w", err)
+	}
+
+	return provider.AuthCodeURL(state, nonce, verifier), state, nil
+}
+
+// Callback 处理提供方回调：校验 state，用授权码换取令牌并识别用户，然后登录或绑定
+// browserState 为发起授权的浏览器保存的 state，必须与回调的 state 一致，
+// 否则攻击者可把自己发起的授权回调地址发给受害者，让受害者登录攻击者的账号或把攻击者的身份绑定到受害者账号
+// 登录时按（提供方, subject）查找已绑定身份；没有则在双方邮箱均已验证时按邮箱关联已有账号，否则新建账号
+func (s *IdentityService) Callback(ctx context.Context, providerName, code, state, browserState, ip, userAgent string) (*OAuthCallbackResult, error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	if subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return nil, ErrInvalidOAuthState
+	}
+
+	record, err := s.consumeState(ctx, providerName, state)
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := provider.Exchange(ctx, code, record.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := provider.Identify(ctx, tok, record.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if record.LinkUserID != nil {
+		identity, err := s.link(ctx, *record.LinkUserID, providerName, claims)
+		if err != nil {
+			return nil, err
+		}
+		return &OAuthCallbackResult{Identity: identity}, nil
+	}
+
+	user, identity, created, err := s.resolveUser(ctx, providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OAuthCallbackResult{Identity: identity, NewUser: created}
+	if user.TOTPEnabledAt != nil {
+		mfaToken, err := s.Users.generateMFAChallenge(*user)
+		if err != nil {
+			return nil, errors.New("failed to generate token")
+		}
+		result.Login = &LoginResult{MFARequired: true, MFAToken: mfaToken}
+		return result, nil
+	}
+
+	token, err := s.Users.issueToken(ctx, *user, ip, userAgent)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	s.Users.recordAttempt(ctx, user.Username, &user.ID, ip, userAgent, true)
+	result.Login = &LoginResult{Token: token}
+	return result, nil
+}
+
+// ListIdentities 列出用户绑定的第三方身份
+func (s *IdentityService) ListIdentities(ctx context.Context, userID uint) ([]model.Identity, error) {
+	var identities []model.Identity
+	if err := s.DB.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&identities).Error; err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	return identities, nil
+}
+
+// Unlink 解除绑定
+// 没有已验证邮箱（无法通过找回密码设置密码）的账号不能解除最后一个第三方身份，避免账号无法登录
+func (s *IdentityService) Unlink(ctx context.Context, userID, identityID uint) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var identity model.Identity
+		if err := tx.Where("id = ? AND user_id = ?", identityID, userID).First(&identity).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrIdentityNotFound
+			}
+			return fmt.Errorf("failed to query identity: %w", err)
+		}
+
+		var user model.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return fmt.Errorf("failed to query user: %w", err)
+		}
+		var count int64
+		if err := tx.Model(&model.Identity{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count identities: %w", err)
+		}
+		if count <= 1 && user.EmailVerifiedAt == nil {
+			return ErrLastLoginMethod
+		}
+
+		// 硬删除，释放（提供方, subject）唯一索引，便于之后重新绑定
+		if err := tx.Unscoped().Delete(&identity).Error; err != nil {
+			return fmt.Errorf("failed to unlink identity: %w", err)
+		}
+		return nil
+	})
+}
+
+// consumeState 校验并删除 state，保证每个授权请求只能回调一次
+func (s *IdentityService) consumeState(ctx context.Context, providerName, state string) (*model.OAuthState, error) {
+	if state == "" {
+		return nil, ErrInvalidOAuthState
+	}
+
+	var record model.OAuthState
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state_hash = ?", hashOAuthState(state)).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidOAuthState
+			}
+			return fmt.Errorf("failed to query oauth state: %w", err)
+		}
+
+		result := tx.Unscoped().Where("id = ?", record.ID).Delete(&model.OAuthState{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to consume oauth state: %w", result.Error)
+		}
+		if result.RowsAffected != 1 {
+			return ErrInvalidOAuthState
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Provider != providerName || time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidOAuthState
+	}
+	return &record, nil
+}
+
+// resolveUser 查找或创建第三方身份对应的用户
+func (s *IdentityService) resolveUser(ctx context.Context, providerName string, claims *oidc.Claims) (*model.User, *model.Identity, bool, error) {
+	var (
+		user     model.User
+		identity model.Identity
+		created  bool
+	)
+
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("provider = ? AND subject = ?", providerName, claims.Subject).First(&identity).Error
+		if err == nil {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return fmt.Errorf("failed to query user: %w", err)
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to query identity: %w", err)
+		}
+
+		if claims.Email == "" {
+			return ErrIdentityEmailMissing
+		}
+
+		err = tx.Where("email = ?", claims.Email).First(&user).Error
+		switch {
+		case err == nil:
+			// 只有双方都确认过邮箱归属才自动关联，防止用未验证邮箱接管他人账号
+			if !claims.EmailVerified || user.EmailVerifiedAt == nil {
+				return ErrIdentityEmailInUse
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := s.Users.createIdentityUser(tx, &user, claims); err != nil {
+				return err
+			}
+			created = true
+		default:
+			return fmt.Errorf("failed to query user: %w", err)
+		}
+
+		identity = model.Identity{
+			UserID:   user.ID,
+			Provider: providerName,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}
+		if err := tx.Create(&identity).Error; err != nil {
+			return fmt.Errorf("failed to link identity: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return &user, &identity, created, nil
+}
+
+// link 把第三方身份绑定到已登录用户
+func (s *IdentityService) link(ctx context.Context, userID uint, providerName string, claims *oidc.Claims) (*model.Identity, error) {
+	db := s.DB.WithContext(ctx)
+
+	var identity model.Identity
+	err := db.Where("provider = ? AND subject = ?", providerName, claims.Subject).First(&identity).Error
+	if err == nil {
+		if identity.UserID != userID {
+			return nil, ErrIdentityLinked
+		}
+		return &identity, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to query identity: %w", err)
+	}
+
+	identity = model.Identity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := db.Create(&identity).Error; err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+	return &identity, nil
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// createIdentityUser 为第三方身份创建本地账号
+// 密码为随机值（用户可通过找回密码设置），用户名取自提供方，冲突时追加随机后缀
+func (s *UserService) createIdentityUser(tx *gorm.DB, user *model.User, claims *oidc.Claims) error {
+	randomPassword, err := oidc.RandomString(32)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := s.hashPassword(randomPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	base := claims.Username
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 15 {
+		base = base[:15]
+	}
+
+	username := base
+	for i := 0; ; i++ {
+		var count int64
+		if err := tx.Model(&model.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to query user: %w", err)
+		}
+		if count == 0 {
+			break
+		}
+		if i == 5 {
+			return errors.New("failed to allocate username")
+		}
+		suffix := make([]byte, 2)
+		if _, err := rand.Read(suffix); err != nil {
+			return err
+		}
+		username = base + "-" + hex.EncodeToString(suffix)
+	}
+
+	*user = model.User{
+		Username: username,
+		Email:    claims.Email,
+		Password: hashedPassword,
+		Role:     consts.RoleUser,
+	}
+	if claims.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := tx.Create(user).Error; err != nil {
+		return errors.New("failed to create user")
+	}
+	return nil
+}
+
+func hashOAuthState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,351 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/importer"
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrImportFailed = errors.New("import failed")
+	errDryRun       = errors.New("dry run")
+)
+
+// ImportService 批量导入外部博客的文章
+type ImportService struct {
+	DB *gorm.DB
+}
+
+// NewImportService 构造函数
+func NewImportService(db *gorm.DB) *ImportService {
+	return &ImportService{DB: db}
+}
+
+// ImportOptions 导入参数
+type ImportOptions struct {
+	Source        string            // 来源标识，与文章的来源ID共同确定唯一文章，重复导入时据此更新而不是新建
+	DefaultAuthor string            // 来源作者无法匹配本地用户时使用的用户名，为空则该文章导入失败
+	AuthorMap     map[string]string // 来源作者（登录名或邮箱）到本地用户名的映射
+	DryRun        bool              // 只生成报告，不写入
+}
+
+// ImportItem 单篇文章的处理结果
+type ImportItem struct {
+	SourceID string `json:"source_id"`
+	Title    string `json:"title"`
+	Action   string `json:"action"`
+	PostID   uint   `json:"post_id,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ImportReport 导入报告
+type ImportReport struct {
+	Source    string       `json:"source"`
+	DryRun    bool         `json:"dry_run"`
+	Created   int          `json:"created"`
+	Updated   int          `json:"updated"`
+	Unchanged int          `json:"unchanged"`
+	Skipped   int          `json:"skipped"`
+	Failed    int          `json:"failed"`
+	Items     []ImportItem `json:"items"`
+}
+
+// Import 在一个事务中导入全部文章：任一文章失败则整体回滚并返回 ErrImportFailed，报告中列出失败原因；
+// DryRun 时照常执行后回滚，报告与实际导入的结果一致
+func (s *ImportService) Import(ctx context.Context, docs []importer.Document, opts ImportOptions) (*ImportReport, error) {
+	if opts.Source == "" {
+		return nil, fmt.Errorf("%w: source is required", ErrInvalidInput)
+	}
+
+	report := &ImportReport{Source: opts.Source, DryRun: opts.DryRun, Items: make([]ImportItem, 0, len(docs))}
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		imp := &postImporter{
+			tx:      tx,
+			opts:    opts,
+			authors: map[string]*model.User{},
+			tags:    map[string]model.Tag{},
+		}
+		seen := make(map[string]bool, len(docs))
+
+		for _, doc := range docs {
+			if seen[doc.SourceID] {
+				report.add(ImportItem{SourceID: doc.SourceID, Title: doc.Title, Action: consts.ImportActionFailed, Error: "duplicate source id"})
+				continue
+			}
+			seen[doc.SourceID] = true
+
+			item, err := imp.importOne(doc)
+			if err != nil {
+				return err
+			}
+			report.add(item)
+		}
+
+		if report.Failed > 0 {
+			return ErrImportFailed
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	switch {
+	case err == nil, errors.Is(err, errDryRun):
+		return report, nil
+	case errors.Is(err, ErrImportFailed):
+		return report, fmt.Errorf("%w: %d of %d posts failed", ErrImportFailed, report.Failed, len(docs))
+	default:
+		return nil, fmt.Errorf("failed to import posts: %w", err)
+	}
+}
+
+func (r *ImportReport) add(item ImportItem) {
+	switch item.Action {
+	case consts.ImportActionCreated:
+		r.Created++
+		if r.DryRun {
+			item.PostID = 0 // 事务会回滚，ID 无意义
+		}
+	case consts.ImportActionUpdated:
+		r.Updated++
+	case consts.ImportActionUnchanged:
+		r.Unchanged++
+	case consts.ImportActionSkipped:
+		r.Skipped++
+	case consts.ImportActionFailed:
+		r.Failed++
+	}
+	r.Items = append(r.Items, item)
+}
+
+// postImporter 单次导入的事务与缓存
+type postImporter struct {
+	tx      *gorm.DB
+	opts    ImportOptions
+	authors map[string]*model.User // 来源作者 -> 本地用户，nil 表示无法匹配
+	tags    map[string]model.Tag   // 小写标签名 -> 标签
+}
+
+// importOne 导入单篇文章；文章自身的问题记入结果，返回的 error 只表示数据库故障
+func (p *postImporter) importOne(doc importer.Document) (ImportItem, error) {
+	item := ImportItem{SourceID: doc.SourceID, Title: doc.Title}
+	fail := func(msg string) (ImportItem, error) {
+		item.Action, item.Error = consts.ImportActionFailed, msg
+		return item, nil
+	}
+
+	if doc.Draft {
+		item.Action, item.Error = consts.ImportActionSkipped, "draft or unpublished"
+		return item, nil
+	}
+	switch {
+	case doc.SourceID == "":
+		return fail("missing source id")
+	case strings.TrimSpace(doc.Title) == "":
+		return fail("title is required")
+	case strings.TrimSpace(doc.Content) == "":
+		return fail("content is required")
+	}
+
+	author, err := p.resolveAuthor(doc)
+	if err != nil {
+		return item, err
+	}
+	if author == nil {
+		return fail(fmt.Sprintf("author %q does not match a local user", doc.Author))
+	}
+	item.Author = author.Username
+
+	tags, err := p.resolveTags(doc.Tags)
+	if err != nil {
+		return item, err
+	}
+
+	// 保留来源中的时间；来源未提供时视为现在发布
+	createdAt := doc.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	updatedAt := doc.UpdatedAt
+	if updatedAt.Before(createdAt) {
+		updatedAt = createdAt
+	}
+	title := truncate(strings.TrimSpace(doc.Title), 200)
+
+	var source model.PostSource
+	err = p.tx.Where("source = ? AND source_id = ?", p.opts.Source, doc.SourceID).First(&source).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return item, err
+	}
+
+	// 已导入过：有变化时更新，否则不动
+	if err == nil {
+		var post model.Post
+		if err := p.tx.Preload("Tags").First(&post, source.PostID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				item.Action, item.Error = consts.ImportActionSkipped, "post was deleted locally"
+				return item, nil
+			}
+			return item, err
+		}
+		item.PostID = post.ID
+
+		if post.Title == title && post.Content == doc.Content && post.UserID == author.ID &&
+			post.CreatedAt.Equal(createdAt) && sameTags(post.Tags, tags) {
+			item.Action = consts.ImportActionUnchanged
+			return item, nil
+		}
+
+		// UpdateColumns 不会自动改写 updated_at，保留来源中的修改时间
+		if err := p.tx.Model(&post).UpdateColumns(map[string]interface{}{
+			"title":      title,
+			"content":    doc.Content,
+			"user_id":    author.ID,
+			"created_at": createdAt,
+			"updated_at": updatedAt,
+		}).Error; err != nil {
+			return item, err
+		}
+		if err := p.replaceTags(post.ID, tags); err != nil {
+			return item, err
+		}
+		item.Action = consts.ImportActionUpdated
+		return item, nil
+	}
+
+	// CreatedAt/UpdatedAt 非零时 gorm 不会覆盖
+	post := model.Post{
+		Title:     title,
+		Content:   doc.Content,
+		UserID:    author.ID,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+	}
+	if err := p.tx.Omit("User", "Attachments", "Tags").Create(&post).Error; err != nil {
+		return item, err
+	}
+	if err := p.tx.Create(&model.PostSource{Source: p.opts.Source, SourceID: doc.SourceID, PostID: post.ID}).Error; err != nil {
+		return item, err
+	}
+	if err := p.replaceTags(post.ID, tags); err != nil {
+		return item, err
+	}
+	item.Action, item.PostID = consts.ImportActionCreated, post.ID
+	return item, nil
+}
+
+// resolveAuthor 依次尝试：映射表（按登录名、邮箱）、同名本地用户、同邮箱本地用户、默认作者
+func (p *postImporter) resolveAuthor(doc importer.Document) (*model.User, error) {
+	cacheKey := doc.Author + "\x00" + doc.AuthorEmail
+	if user, ok := p.authors[cacheKey]; ok {
+		return user, nil
+	}
+
+	type lookup struct{ column, value string }
+	var candidates []lookup
+	for _, key := range []string{doc.Author, doc.AuthorEmail} {
+		if username, ok := p.opts.AuthorMap[key]; ok && key != "" {
+			candidates = append(candidates, lookup{"username", username})
+		}
+	}
+	if doc.Author != "" {
+		candidates = append(candidates, lookup{"username", doc.Author})
+	}
+	if doc.AuthorEmail != "" {
+		candidates = append(candidates, lookup{"email", strings.ToLower(doc.AuthorEmail)})
+	}
+	if p.opts.DefaultAuthor != "" {
+		candidates = append(candidates, lookup{"username", p.opts.DefaultAuthor})
+	}
+
+	var found *model.User
+	for _, c := range candidates {
+		var user model.User
+		err := p.tx.Where(c.column+" = ?", c.value).First(&user).Error
+		if err == nil {
+			found = &user
+			break
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	p.authors[cacheKey] = found
+	return found, nil
+}
+
+// resolveTags 查找或创建标签，名称不区分大小写去重
+func (p *postImporter) resolveTags(names []string) ([]model.Tag, error) {
+	var tags []model.Tag
+	picked := map[string]bool{}
+	for _, name := range names {
+		name = truncate(strings.TrimSpace(name), consts.TagMaxLength)
+		key := strings.ToLower(name)
+		if name == "" || picked[key] {
+			continue
+		}
+		picked[key] = true
+
+		tag, ok := p.tags[key]
+		if !ok {
+			err := p.tx.Where("LOWER(name) = ?", key).First(&tag).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				tag = model.Tag{Name: name}
+				err = p.tx.Create(&tag).Error
+			}
+			if err != nil {
+				return nil, err
+			}
+			p.tags[key] = tag
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// replaceTags 直接改写关联表，避免 gorm 的关联更新刷新文章的 updated_at
+func (p *postImporter) replaceTags(postID uint, tags []model.Tag) error {
+	if err := p.tx.Table("post_tags").Where("post_id = ?", postID).Delete(nil).Error; err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	rows := make([]map[string]interface{}, 0, len(tags))
+	for _, t := range tags {
+		rows = append(rows, map[string]interface{}{"post_id": postID, "tag_id": t.ID})
+	}
+	return p.tx.Table("post_tags").Create(&rows).Error
+}
+
+func sameTags(a, b []model.Tag) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	ids := func(tags []model.Tag) []uint {
+		out := make([]uint, 0, len(tags))
+		for _, t := range tags {
+			out = append(out, t.ID)
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+		return out
+	}
+	x, y := ids(a), ids(b)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/pubsub"
+	"web-task/blog/internal/repository"
+)
+
+// ErrLiveUnavailable 未配置推送，无法订阅评论直播间
+var ErrLiveUnavailable = errors.New("live updates unavailable")
+
+// CommentEvent 文章直播间推送的评论事件；删除事件的评论只保证 id 与 post_id 有效
+type CommentEvent struct {
+	Type    string        `json:"type"`
+	Comment *EventComment `json:"comment"`
+}
+
+// CommentTopic 文章评论直播间的主题
+func CommentTopic(postID uint) string {
+	return "posts." + strconv.FormatUint(uint64(postID), 10) + ".comments"
+}
+
+// publishCommentEvent 把评论的变更推送给文章直播间，与 publishNotification 一样只是尽力而为
+func publishCommentEvent(ctx context.Context, hub pubsub.Hub, eventType string, comment *model.Comment) {
+	if hub == nil {
+		return
+	}
+	payload, err := json.Marshal(CommentEvent{
+		Type: eventType,
+		Comment: &EventComment{
+			ID:        comment.ID,
+			Content:   comment.Content,
+			UserID:    comment.UserID,
+			PostID:    comment.PostID,
+			CreatedAt: comment.CreatedAt,
+		},
+	})
+	if err != nil {
+		return
+	}
+	_ = hub.Publish(context.WithoutCancel(ctx), CommentTopic(comment.PostID), payload)
+}
+
+// Subscribe 加入文章的评论直播间，消息为 JSON 编码的 CommentEvent
+// 缓冲满时订阅被关闭（pubsub.ErrSlowConsumer），调用方应断开连接，由客户端重连后重新拉取评论
+func (s *CommentService) Subscribe(ctx context.Context, postID uint) (pubsub.Subscription, error) {
+	if s.Hub == nil {
+		return nil, ErrLiveUnavailable
+	}
+	if _, err := s.Posts.FindByID(ctx, postID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrPostNotFound
+		}
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+
+	sub, err := s.Hub.Subscribe(ctx, CommentTopic(postID), consts.LiveSendBuffer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe comments: %w", err)
+	}
+	return sub, nil
+}
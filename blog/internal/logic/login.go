@@ -1,93 +1,221 @@
 package logic
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
 	"time"
+
 	"web-task/blog/internal/consts"
+	"web-task/blog/internal/keyring"
+	"web-task/blog/internal/mailer"
+	"web-task/blog/internal/metrics"
 	"web-task/blog/internal/model"
+	"web-task/blog/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
 
-	"github.com/golang-jwt/jwt"
-	"gorm.io/gorm"
+var (
+	ErrUserNotFound = errors.New("user not found")
 )
 
 // service/user.go
 type UserService struct {
-	DB *gorm.DB
+	Users  repository.UserRepository
+	Posts  repository.PostRepository // 公开资料中的文章数
+	UoW    repository.UnitOfWork     // 需要原子性的多步操作，如修改密码、注销账号
+	Mailer mailer.Mailer
+	Keys   *keyring.KeyRing // JWT 签名密钥
+	AppURL string           // 对外访问地址，用于拼接邮件中的链接，同时作为令牌的 iss
+	Log    *slog.Logger
+	// TokenSecret 邮箱验证、找回密码等一次性令牌与导出下载链接的 HMAC 密钥，由 BLOG_ACCOUNT_TOKEN_SECRET 配置
+	TokenSecret []byte
+	// PasswordCost 新密码哈希的 bcrypt 成本因子，默认 consts.PasswordCost；已有哈希按其中记录的成本校验
+	PasswordCost int
+
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
 
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{DB: db}
+func NewUserService(repos repository.Repositories, uow repository.UnitOfWork, m mailer.Mailer, keys *keyring.KeyRing, tokenSecret []byte, appURL string, logger *slog.Logger) *UserService {
+	return &UserService{
+		Users:        repos.Users,
+		Posts:        repos.Posts,
+		UoW:          uow,
+		Mailer:       m,
+		Keys:         keys,
+		AppURL:       strings.TrimRight(appURL, "/"),
+		Log:          logger,
+		TokenSecret:  tokenSecret,
+		PasswordCost: consts.PasswordCost,
+	}
 }
 
-func (s *UserService) Register(username, email, password string) (*model.User, error) {
+func (s *UserService) Register(ctx context.Context, username, email, password string) (*model.User, error) {
 	// 检查用户名/邮箱是否已存在
-	var existingUser model.User
-	if err := s.DB.Where("username = ?", username).First(&existingUser).Error; err == nil {
+	if _, err := s.Users.FindByUsername(ctx, username); err == nil {
 		return nil, errors.New("username already exists")
 	}
-	if err := s.DB.Where("email = ?", email).First(&existingUser).Error; err == nil {
+	if _, err := s.Users.FindByEmail(ctx, email); err == nil {
 		return nil, errors.New("email already exists")
 	}
 
 	// 加密密码
-	hashedPassword, err := consts.HashPassword(password)
+	hashedPassword, err := s.hashPassword(password)
 	if err != nil {
 		return nil, errors.New("failed to hash password")
 	}
 
 	// 创建用户
-	fmt.Printf("Start to register with username: %s, password: %s, email: %s\n", username, password, email)
 	newUser := model.User{
 		Username: username,
 		Email:    email,
 		Password: hashedPassword,
+		Role:     consts.RoleUser,
 	}
 
-	if err := s.DB.Create(&newUser).Error; err != nil {
+	if err := s.Users.Create(ctx, &newUser); err != nil {
 		return nil, errors.New("failed to create user")
 	}
+	s.Log.InfoContext(ctx, "user registered", "user_id", newUser.ID, "username", newUser.Username)
+	metrics.UserRegistrations.Inc()
+
+	// 发送验证邮件；发送失败不影响注册，用户可稍后重新发送
+	if err := s.sendVerificationEmail(ctx, &newUser); err != nil {
+		s.Log.WarnContext(ctx, "failed to send verification email", "user_id", newUser.ID, "error", err)
+	}
 
 	return &newUser, nil
 }
 
-func (s *UserService) Login(username, password string) (token string, err error) {
-	// 1. 检查用户是否存在
-	var user model.User
-	if err = s.DB.Where("username = ?", username).First(&user).Error; err != nil {
-		return "", errors.New("user not found")
+// Login 校验用户名密码并签发令牌
+// 用户不存在与密码错误返回同一个 ErrInvalidCredentials；账号或 IP 被锁定时返回 *LockoutError；
+// 开启两步验证的账号只返回挑战令牌，见 LoginResult
+func (s *UserService) Login(ctx context.Context, username, password, ip, userAgent string) (*LoginResult, error) {
+	// 1. 检查账号与 IP 是否处于锁定期
+	if err := s.checkLockout(ctx, username, ip); err != nil {
+		var lockout *LockoutError
+		if errors.As(err, &lockout) {
+			metrics.Logins.WithLabelValues(metrics.LoginLocked).Inc()
+		}
+		return nil, err
+	}
+
+	// 2. 检查用户是否存在；不存在时仍做一次哈希比较，使两种失败的耗时一致
+	found, err := s.Users.FindByUsername(ctx, username)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("failed to query user: %w", err)
+		}
+		consts.CheckPassword(password, s.dummyPasswordHash())
+		s.recordAttempt(ctx, username, nil, ip, userAgent, false)
+		return nil, ErrInvalidCredentials
+	}
+	user := *found
+
+	// 3. 验证密码
+	if err := consts.CheckPassword(password, user.Password); err != nil {
+		s.recordAttempt(ctx, username, &user.ID, ip, userAgent, false)
+		return nil, ErrInvalidCredentials
+	}
+
+	// 4. 开启两步验证时先返回挑战令牌，验证码通过后才签发正式令牌
+	if user.TOTPEnabledAt != nil {
+		mfaToken, err := s.generateMFAChallenge(user)
+		if err != nil {
+			return nil, errors.New("failed to generate token")
+		}
+		metrics.Logins.WithLabelValues(metrics.LoginMFARequired).Inc()
+		return &LoginResult{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	// 5. 生成 JWT 令牌并记录登录会话
+	token, err := s.issueToken(ctx, user, ip, userAgent)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	s.recordAttempt(ctx, username, &user.ID, ip, userAgent, true)
+
+	return &LoginResult{Token: token}, nil
+}
+
+// getUser 根据ID获取用户
+func (s *UserService) getUser(ctx context.Context, id uint) (*model.User, error) {
+	user, err := s.Users.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	return user, nil
+}
 
-	// 2. 验证密码
-	if err = consts.CheckPassword(password, user.Password); err != nil {
-		return "", errors.New("invalid password")
+// issueToken 签发令牌并写入登录会话审计
+func (s *UserService) issueToken(ctx context.Context, user model.User, ip, userAgent string) (string, error) {
+	tokenID, err := randomTokenID()
+	if err != nil {
+		return "", err
 	}
+	expiresAt := time.Now().Add(consts.TokenTTL)
 
-	// 3. 生成 JWT 令牌
-	token, err = s.generateJWT(user)
+	token, err := s.generateJWT(user, tokenID, expiresAt)
 	if err != nil {
-		return "", errors.New("failed to generate token")
+		return "", err
+	}
+
+	session := model.LoginSession{
+		UserID:    user.ID,
+		TokenID:   tokenID,
+		IP:        ip,
+		UserAgent: truncate(userAgent, 255),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.Users.CreateSession(ctx, &session); err != nil {
+		return "", fmt.Errorf("failed to record session: %w", err)
 	}
 
 	return token, nil
 }
 
-func (s *UserService) generateJWT(user model.User) (string, error) {
+func (s *UserService) generateJWT(user model.User, tokenID string, expiresAt time.Time) (string, error) {
 	// 设置 JWT 的有效载荷
 	claims := jwt.MapClaims{
+		"iss":      s.AppURL,
+		"jti":      tokenID,
+		"user_id":  user.ID,
 		"username": user.Username,
-		"password": user.Password,
-		"exp":      time.Now().Add(24 * time.Hour).Unix(), // 设置令牌有效期为 24 小时
+		"role":     user.Role,
+		"exp":      expiresAt.Unix(), // 令牌有效期见 consts.TokenTTL
 	}
 
-	// 使用 HS256 算法签名
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// 使用密钥环中的当前密钥签名，头部携带 kid
+	return s.Keys.Sign(claims)
+}
 
-	// 使用密钥签名并返回令牌
-	signedToken, err := token.SignedString([]byte(consts.JWTSecret))
-	if err != nil {
-		return "", err
-	}
+// hashPassword 按服务配置的成本因子生成密码哈希
+func (s *UserService) hashPassword(password string) (string, error) {
+	return consts.HashPassword(password, s.PasswordCost)
+}
+
+// dummyPasswordHash 用户不存在时用于比较的哈希，首次使用时生成，成本与真实哈希一致以免泄露用户是否存在
+func (s *UserService) dummyPasswordHash() string {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = s.hashPassword("dummy-password-for-timing")
+	})
+	return s.dummyHash
+}
 
-	return signedToken, nil
+func randomTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
 }
@@ -0,0 +1,153 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/metrics"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/repository"
+)
+
+var (
+	// ErrInvalidCredentials 用户不存在与密码错误统一返回该错误，避免用户名枚举
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrAccountLocked      = errors.New("too many failed login attempts")
+)
+
+// LockoutError 账号或 IP 处于锁定期，RetryAfter 为剩余锁定时长
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrAccountLocked, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// checkLockout 根据历史失败记录判断账号和 IP 是否被锁定
+// 账号统计最近一次成功登录之后的连续失败，IP 统计时间窗口内的全部失败；
+// 每达到一次阈值锁定时长翻倍（LoginLockoutBase、2倍、4倍...），上限 LoginLockoutMax
+func (s *UserService) checkLockout(ctx context.Context, username, ip string) error {
+	now := time.Now()
+
+	accountFailures, accountLast, err := s.countFailures(ctx, repository.LoginFailureFilter{
+		Username: username,
+		Since:    now.Add(-consts.LoginAccountWindow),
+	})
+	if err != nil {
+		return err
+	}
+	ipFailures, ipLast, err := s.countFailures(ctx, repository.LoginFailureFilter{
+		IP:    ip,
+		Since: now.Add(-consts.LoginIPWindow),
+	})
+	if err != nil {
+		return err
+	}
+
+	retry := lockoutRemaining(accountFailures, consts.LoginMaxFailuresPerAccount, accountLast, now)
+	if r := lockoutRemaining(ipFailures, consts.LoginMaxFailuresPerIP, ipLast, now); r > retry {
+		retry = r
+	}
+	if retry > 0 {
+		return &LockoutError{RetryAfter: retry}
+	}
+	return nil
+}
+
+// countFailures 统计 since 之后未被清除的失败次数及最后一次失败时间
+// 按账号统计时只统计最近一次成功登录之后的失败
+func (s *UserService) countFailures(ctx context.Context, filter repository.LoginFailureFilter) (int64, time.Time, error) {
+	if filter.Username != "" {
+		lastSuccess, err := s.Users.LastLoginSuccess(ctx, filter.Username, filter.Since)
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("failed to query login attempts: %w", err)
+		}
+		if lastSuccess.After(filter.Since) {
+			filter.Since = lastSuccess
+		}
+	}
+
+	count, last, err := s.Users.CountLoginFailures(ctx, filter)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to count login attempts: %w", err)
+	}
+	return count, last, nil
+}
+
+// lockoutRemaining 计算剩余锁定时长，未锁定时返回 0
+func lockoutRemaining(failures int64, threshold int, last time.Time, now time.Time) time.Duration {
+	if failures < int64(threshold) {
+		return 0
+	}
+
+	lock := consts.LoginLockoutBase
+	for n := failures/int64(threshold) - 1; n > 0 && lock < consts.LoginLockoutMax; n-- {
+		lock *= 2
+	}
+	if lock > consts.LoginLockoutMax {
+		lock = consts.LoginLockoutMax
+	}
+
+	if remaining := last.Add(lock).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// recordAttempt 记录一次登录尝试；记录失败不影响登录结果
+// 写入不随请求取消，避免客户端提前断开来绕过失败计数
+func (s *UserService) recordAttempt(ctx context.Context, username string, userID *uint, ip, userAgent string, success bool) {
+	if success {
+		metrics.Logins.WithLabelValues(metrics.LoginSucceeded).Inc()
+	} else {
+		metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+	}
+
+	attempt := model.LoginAttempt{
+		Username:  username,
+		UserID:    userID,
+		IP:        ip,
+		UserAgent: truncate(userAgent, 255),
+		Success:   success,
+	}
+	if err := s.Users.CreateLoginAttempt(context.WithoutCancel(ctx), &attempt); err != nil {
+		s.Log.ErrorContext(ctx, "failed to record login attempt", "username", username, "error", err)
+	}
+}
+
+// Unlock 清除账号（及可选 IP）的失败记录，立即解除锁定（管理员操作）
+func (s *UserService) Unlock(ctx context.Context, username, ip string) error {
+	if err := s.Users.ClearLoginFailures(ctx, username, ip); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	return nil
+}
+
+// ListSessions 列出用户最近的成功登录记录
+func (s *UserService) ListSessions(ctx context.Context, userID uint, limit int) ([]model.LoginSession, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	sessions, err := s.Users.ListSessions(ctx, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,133 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/pubsub"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotificationStreamUnavailable 未配置推送，无法订阅实时通知
+var ErrNotificationStreamUnavailable = errors.New("notification stream unavailable")
+
+// NotificationTopic 用户实时通知的主题
+func NotificationTopic(userID uint) string {
+	return "notifications." + strconv.FormatUint(uint64(userID), 10)
+}
+
+// publishNotification 在通知所属事务提交后推送给在线的接收者
+// 推送只是尽力而为：失败时接收者重连或刷新列表时从数据库补齐，不影响触发通知的操作
+func publishNotification(ctx context.Context, hub pubsub.Hub, notification *model.Notification) {
+	if hub == nil {
+		return
+	}
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return
+	}
+	_ = hub.Publish(context.WithoutCancel(ctx), NotificationTopic(notification.UserID), payload)
+}
+
+// NotificationService 站内通知：列表、已读状态与实时推送的订阅
+// 通知由触发它的业务（如发表评论）在同一事务中写入
+type NotificationService struct {
+	DB  *gorm.DB
+	Hub pubsub.Hub
+}
+
+// NewNotificationService 构造函数；hub 为 nil 时不支持实时推送
+func NewNotificationService(db *gorm.DB, hub pubsub.Hub) *NotificationService {
+	return &NotificationService{DB: db, Hub: hub}
+}
+
+// List 分页获取用户的通知，按创建时间倒序；同时返回符合条件的总数与未读总数
+func (s *NotificationService) List(ctx context.Context, userID uint, unreadOnly bool, page, pageSize int) ([]model.Notification, int64, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > consts.NotificationMaxPageSize {
+		pageSize = consts.NotificationMaxPageSize
+	}
+
+	db := s.DB.WithContext(ctx).Model(&model.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		db = db.Where("read_at IS NULL")
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	var notifications []model.Notification
+	if err := db.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	unread := total
+	if !unreadOnly {
+		var err error
+		if unread, err = s.UnreadCount(ctx, userID); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+	return notifications, total, unread, nil
+}
+
+// UnreadCount 用户的未读通知数
+func (s *NotificationService) UnreadCount(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := s.DB.WithContext(ctx).Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead 把用户的通知标记为已读，ids 为空时标记全部；不属于该用户或已读的通知被忽略
+// 返回本次标记的条数
+func (s *NotificationService) MarkRead(ctx context.Context, userID uint, ids []uint) (int64, error) {
+	db := s.DB.WithContext(ctx).Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) > 0 {
+		db = db.Where("id IN ?", ids)
+	}
+	result := db.Update("read_at", time.Now())
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// Since 获取ID大于 afterID 的通知，按ID正序，用于推送连接断开重连后补发
+func (s *NotificationService) Since(ctx context.Context, userID, afterID uint, limit int) ([]model.Notification, error) {
+	var notifications []model.Notification
+	err := s.DB.WithContext(ctx).
+		Where("user_id = ? AND id > ?", userID, afterID).
+		Order("id").Limit(limit).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// Subscribe 订阅用户的实时通知，消息为 JSON 编码的 model.Notification
+// 缓冲满时订阅被关闭，调用方应结束连接，由客户端按 Last-Event-ID 重连补齐
+func (s *NotificationService) Subscribe(ctx context.Context, userID uint) (pubsub.Subscription, error) {
+	if s.Hub == nil {
+		return nil, ErrNotificationStreamUnavailable
+	}
+	sub, err := s.Hub.Subscribe(ctx, NotificationTopic(userID), consts.NotificationStreamBuffer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe notifications: %w", err)
+	}
+	return sub, nil
+}
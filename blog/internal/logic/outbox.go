@@ -0,0 +1,80 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/repository"
+)
+
+// EventPost 事件数据中的文章
+type EventPost struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	UserID    uint      `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EventComment 事件数据中的评论
+type EventComment struct {
+	ID        uint      `json:"id"`
+	Content   string    `json:"content"`
+	UserID    uint      `json:"user_id"`
+	PostID    uint      `json:"post_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EventData 事件数据，文章事件只有 post，评论事件同时包含评论及其所属文章
+type EventData struct {
+	Post    *EventPost    `json:"post"`
+	Comment *EventComment `json:"comment,omitempty"`
+}
+
+func newEventPost(p *model.Post) *EventPost {
+	return &EventPost{
+		ID:        p.ID,
+		Title:     p.Title,
+		Content:   p.Content,
+		UserID:    p.UserID,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
+
+// appendPostEvent 在发件箱中记录文章事件，由文章作者的 webhook 接收
+func appendPostEvent(ctx context.Context, outbox repository.OutboxRepository, eventType string, post *model.Post) error {
+	return appendEvent(ctx, outbox, eventType, post.UserID, EventData{Post: newEventPost(post)})
+}
+
+// appendCommentEvent 在发件箱中记录评论事件，由被评论文章作者的 webhook 接收
+func appendCommentEvent(ctx context.Context, outbox repository.OutboxRepository, post *model.Post, comment *model.Comment) error {
+	return appendEvent(ctx, outbox, consts.WebhookEventCommentCreated, post.UserID, EventData{
+		Post: newEventPost(post),
+		Comment: &EventComment{
+			ID:        comment.ID,
+			Content:   comment.Content,
+			UserID:    comment.UserID,
+			PostID:    comment.PostID,
+			CreatedAt: comment.CreatedAt,
+		},
+	})
+}
+
+// appendEvent 事件数据在写入时序列化，投递的是事件发生时的快照
+func appendEvent(ctx context.Context, outbox repository.OutboxRepository, eventType string, userID uint, data EventData) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	event := model.OutboxEvent{Type: eventType, UserID: userID, Payload: string(payload)}
+	if err := outbox.Append(ctx, &event); err != nil {
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+	return nil
+}
@@ -2,12 +2,17 @@
 package logic
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/metrics"
 	"web-task/blog/internal/model"
+	"web-task/blog/internal/repository"
+	"web-task/blog/internal/tracing"
 
-	"gorm.io/gorm"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
@@ -15,14 +20,18 @@ var (
 )
 
 type PostService struct {
-	DB *gorm.DB
+	Posts repository.PostRepository
+	UoW   repository.UnitOfWork // 写操作与发件箱事件在同一事务中提交
 }
 
-func NewPostService(db *gorm.DB) *PostService {
-	return &PostService{DB: db}
+func NewPostService(repos repository.Repositories, uow repository.UnitOfWork) *PostService {
+	return &PostService{Posts: repos.Posts, UoW: uow}
 }
 
-func (s *PostService) Create(UserID uint, title, content string) (*model.Post, error) {
+func (s *PostService) Create(ctx context.Context, UserID uint, title, content string) (*model.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.Create", attribute.Int("user.id", int(UserID)))
+	defer span.End()
+
 	if title == "" || content == "" {
 		return nil, fmt.Errorf("%w: title and content are required", ErrInvalidInput)
 	}
@@ -33,52 +42,82 @@ func (s *PostService) Create(UserID uint, title, content string) (*model.Post, e
 		UserID:  UserID,
 	}
 
-	if err := s.DB.Create(&post).Error; err != nil {
+	err := s.UoW.Do(ctx, func(repos repository.Repositories) error {
+		if err := repos.Posts.Create(ctx, &post); err != nil {
+			return err
+		}
+		return appendPostEvent(ctx, repos.Outbox, consts.WebhookEventPostCreated, &post)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create post: %w", err)
 	}
+	metrics.PostsCreated.Inc()
 
 	return &post, nil
 }
 
-func (s *PostService) GetByID(id uint) (*model.Post, error) {
-	var post model.Post
-	if err := s.DB.Preload("User").First(&post, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+func (s *PostService) GetByID(ctx context.Context, id uint) (*model.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetByID", attribute.Int("post.id", int(id)))
+	defer span.End()
+
+	post, err := s.Posts.FindDetail(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrPostNotFound
 		}
 		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
-	return &post, nil
+	return post, nil
 }
 
-func (s *PostService) List(page, pageSize int) ([]model.Post, int64, error) {
-	if page < 1 {
-		page = 1
+// GetByIDs 批量获取文章（不含关联），不存在的 ID 忽略
+func (s *PostService) GetByIDs(ctx context.Context, ids []uint) ([]model.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetByIDs", attribute.Int("post.count", len(ids)))
+	defer span.End()
+
+	posts, err := s.Posts.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
 	}
-	offset := (page - 1) * pageSize
+	return posts, nil
+}
 
-	var posts []model.Post
-	var total int64
+func (s *PostService) List(ctx context.Context, page, pageSize int) ([]model.Post, int64, error) {
+	ctx, span := tracing.Start(ctx, "PostService.List", attribute.Int("page", page), attribute.Int("page_size", pageSize))
+	defer span.End()
 
-	if err := s.DB.Model(&model.Post{}).Count(&total).Error; err != nil {
-		return nil, 0, fmt.Errorf("failed to count posts: %w", err)
+	if page < 1 {
+		page = 1
 	}
+	offset := (page - 1) * pageSize
 
-	if err := s.DB.Preload("User").
-		Limit(pageSize).
-		Offset(offset).
-		Order("created_at desc").
-		Find(&posts).Error; err != nil {
+	posts, total, err := s.Posts.List(ctx, offset, pageSize)
+	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list posts: %w", err)
 	}
 
 	return posts, total, nil
 }
 
-func (s *PostService) Update(userID uint, id uint, title, content string) (*model.Post, error) {
-	var post model.Post
-	if err := s.DB.Preload("User").First(&post, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+// ListByUser 获取用户的全部文章（含附件与标签），按创建时间正序
+func (s *PostService) ListByUser(ctx context.Context, userID uint) ([]model.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.ListByUser", attribute.Int("user.id", int(userID)))
+	defer span.End()
+
+	posts, err := s.Posts.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list posts: %w", err)
+	}
+	return posts, nil
+}
+
+func (s *PostService) Update(ctx context.Context, userID uint, id uint, title, content string) (*model.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.Update", attribute.Int("post.id", int(id)))
+	defer span.End()
+
+	post, err := s.Posts.FindDetail(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, ErrPostNotFound
 		}
 		return nil, fmt.Errorf("failed to get post: %w", err)
@@ -88,25 +127,39 @@ func (s *PostService) Update(userID uint, id uint, title, content string) (*mode
 		return nil, errors.New("permission denied")
 	}
 
-	updates := make(map[string]interface{})
+	var columns []string
 	if title != "" {
-		updates["title"] = title
+		post.Title = title
+		columns = append(columns, "title")
 	}
 	if content != "" {
-		updates["content"] = content
+		post.Content = content
+		columns = append(columns, "content")
+	}
+	if len(columns) == 0 {
+		return post, nil
 	}
 
-	if err := s.DB.Model(&post).Updates(updates).Error; err != nil {
+	err = s.UoW.Do(ctx, func(repos repository.Repositories) error {
+		if err := repos.Posts.Update(ctx, post, columns...); err != nil {
+			return err
+		}
+		return appendPostEvent(ctx, repos.Outbox, consts.WebhookEventPostUpdated, post)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to update post: %w", err)
 	}
 
-	return &post, nil
+	return post, nil
 }
 
-func (s *PostService) Delete(userID uint, id uint) error {
-	var post model.Post
-	if err := s.DB.First(&post, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+func (s *PostService) Delete(ctx context.Context, userID uint, id uint) error {
+	ctx, span := tracing.Start(ctx, "PostService.Delete", attribute.Int("post.id", int(id)))
+	defer span.End()
+
+	post, err := s.Posts.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return ErrPostNotFound
 		}
 		return fmt.Errorf("failed to get post: %w", err)
@@ -116,7 +169,13 @@ func (s *PostService) Delete(userID uint, id uint) error {
 		return errors.New("permission denied")
 	}
 
-	if err := s.DB.Delete(&post).Error; err != nil {
+	err = s.UoW.Do(ctx, func(repos repository.Repositories) error {
+		if err := repos.Posts.Delete(ctx, post); err != nil {
+			return err
+		}
+		return appendPostEvent(ctx, repos.Outbox, consts.WebhookEventPostDeleted, post)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to delete post: %w", err)
 	}
 
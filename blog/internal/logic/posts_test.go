@@ -0,0 +1,77 @@
+package logic_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/logic"
+	"web-task/blog/internal/repository"
+)
+
+func TestPostService(t *testing.T) {
+	ctx := context.Background()
+	repos, uow := repository.NewMemory()
+	var events []string
+	posts := logic.NewPostService(repos, wrapUoW{uow, func(r *repository.Repositories) {
+		r.Outbox = recordingOutbox{r.Outbox, &events}
+	}})
+	alice := createUser(t, repos, "alice", "secret123")
+	bob := createUser(t, repos, "bob", "secret123")
+
+	if _, err := posts.Create(ctx, alice.ID, "", "content"); !errors.Is(err, logic.ErrInvalidInput) {
+		t.Fatalf("Create without title error = %v, want ErrInvalidInput", err)
+	}
+	post, err := posts.Create(ctx, alice.ID, "Hello", "World")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := posts.Update(ctx, bob.ID, post.ID, "Hijacked", ""); err == nil {
+		t.Fatal("Update by another user succeeded")
+	}
+	updated, err := posts.Update(ctx, alice.ID, post.ID, "Hello again", "")
+	if err != nil || updated.Title != "Hello again" || updated.Content != "World" {
+		t.Fatalf("Update = %+v, %v; want only the title changed", updated, err)
+	}
+
+	if err := posts.Delete(ctx, bob.ID, post.ID); err == nil {
+		t.Fatal("Delete by another user succeeded")
+	}
+	if err := posts.Delete(ctx, alice.ID, post.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := posts.GetByID(ctx, post.ID); !errors.Is(err, logic.ErrPostNotFound) {
+		t.Fatalf("GetByID after delete error = %v, want ErrPostNotFound", err)
+	}
+
+	// 每次写操作都在同一事务中写入一个发件箱事件，被拒绝的操作不写
+	want := []string{consts.WebhookEventPostCreated, consts.WebhookEventPostUpdated, consts.WebhookEventPostDeleted}
+	if !slices.Equal(events, want) {
+		t.Fatalf("outbox events = %v, want %v", events, want)
+	}
+}
+
+func TestPostCreateRollsBackWhenOutboxFails(t *testing.T) {
+	ctx := context.Background()
+	repos, uow := repository.NewMemory()
+	alice := createUser(t, repos, "alice", "secret123")
+
+	failing := logic.NewPostService(repos, wrapUoW{uow, func(r *repository.Repositories) {
+		r.Outbox = failingOutbox{}
+	}})
+	if _, err := failing.Create(ctx, alice.ID, "Hello", "World"); !errors.Is(err, errInjected) {
+		t.Fatalf("Create error = %v, want the outbox failure", err)
+	}
+
+	// 文章已在事务中写入，发件箱失败后随事务回滚
+	posts := logic.NewPostService(repos, uow)
+	if list, total, err := posts.List(ctx, 1, 10); err != nil || total != 0 || len(list) != 0 {
+		t.Fatalf("List after rollback = %d posts (total %d), %v; want none", len(list), total, err)
+	}
+	if n, err := repos.Posts.CountByUser(ctx, alice.ID); err != nil || n != 0 {
+		t.Fatalf("CountByUser after rollback = %d, %v; want 0", n, err)
+	}
+}
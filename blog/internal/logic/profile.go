@@ -0,0 +1,250 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/repository"
+)
+
+var (
+	ErrIncorrectPassword = errors.New("incorrect password")
+	ErrEmailTaken        = errors.New("email already exists")
+)
+
+// PublicProfile 公开的用户资料
+type PublicProfile struct {
+	User      model.User
+	PostCount int64
+}
+
+// ProfileUpdate 资料修改项，nil 表示不修改
+type ProfileUpdate struct {
+	DisplayName *string
+	Bio         *string
+	AvatarURL   *string
+	Email       *string
+}
+
+// GetProfile 获取当前用户资料
+func (s *UserService) GetProfile(ctx context.Context, userID uint) (*model.User, error) {
+	return s.getUser(ctx, userID)
+}
+
+// GetUsers 批量获取用户，不存在或已注销的用户忽略
+func (s *UserService) GetUsers(ctx context.Context, ids []uint) ([]model.User, error) {
+	users, err := s.Users.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	return users, nil
+}
+
+// GetPublicProfile 按用户名获取公开资料及文章数
+func (s *UserService) GetPublicProfile(ctx context.Context, username string) (*PublicProfile, error) {
+	user, err := s.Users.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	count, err := s.Posts.CountByUser(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count posts: %w", err)
+	}
+	return &PublicProfile{User: *user, PostCount: count}, nil
+}
+
+// UpdateProfile 修改资料；修改邮箱后需要重新验证，并向新邮箱发送验证邮件
+func (s *UserService) UpdateProfile(ctx context.Context, userID uint, update ProfileUpdate) (*model.User, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	if update.DisplayName != nil {
+		name := strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(name) > 50 {
+			return nil, fmt.Errorf("%w: display name must be at most 50 characters", ErrInvalidInput)
+		}
+		user.DisplayName = name
+		columns = append(columns, "display_name")
+	}
+	if update.Bio != nil {
+		if utf8.RuneCountInString(*update.Bio) > 500 {
+			return nil, fmt.Errorf("%w: bio must be at most 500 characters", ErrInvalidInput)
+		}
+		user.Bio = *update.Bio
+		columns = append(columns, "bio")
+	}
+	if update.AvatarURL != nil {
+		avatar := strings.TrimSpace(*update.AvatarURL)
+		if avatar != "" {
+			u, err := url.Parse(avatar)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(avatar) > 255 {
+				return nil, fmt.Errorf("%w: avatar must be an http(s) url", ErrInvalidInput)
+			}
+		}
+		user.AvatarURL = avatar
+		columns = append(columns, "avatar_url")
+	}
+
+	emailChanged := false
+	if update.Email != nil && *update.Email != user.Email {
+		email := strings.TrimSpace(*update.Email)
+		if email == "" || len(email) > 100 || !strings.Contains(email, "@") {
+			return nil, fmt.Errorf("%w: invalid email", ErrInvalidInput)
+		}
+		taken, err := s.Users.EmailInUse(ctx, email, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query user: %w", err)
+		}
+		if taken {
+			return nil, ErrEmailTaken
+		}
+		user.Email = email
+		user.EmailVerifiedAt = nil
+		columns = append(columns, "email", "email_verified_at")
+		emailChanged = true
+	}
+
+	if len(columns) > 0 {
+		if err := s.Users.Update(ctx, user, columns...); err != nil {
+			return nil, fmt.Errorf("failed to update profile: %w", err)
+		}
+	}
+
+	if emailChanged {
+		// 旧邮箱的验证链接作废；发送失败不影响修改，用户可稍后重新发送
+		if err := s.Users.RevokeAccountTokens(ctx, user.ID, consts.TokenPurposeVerifyEmail); err != nil {
+			return nil, fmt.Errorf("failed to revoke tokens: %w", err)
+		}
+		if err := s.sendVerificationEmail(ctx, user); err != nil {
+			s.Log.WarnContext(ctx, "failed to send verification email", "user_id", user.ID, "error", err)
+		}
+	}
+
+	return s.getUser(ctx, userID)
+}
+
+// ChangePassword 校验旧密码后修改密码，吊销该用户全部登录会话，并为当前客户端签发新令牌
+func (s *UserService) ChangePassword(ctx context.Context, userID uint, oldPassword, newPassword, ip, userAgent string) (string, error) {
+	if len(newPassword) < 6 {
+		return "", fmt.Errorf("%w: password must be at least 6 characters", ErrInvalidInput)
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if err := consts.CheckPassword(oldPassword, user.Password); err != nil {
+		return "", ErrIncorrectPassword
+	}
+
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		return "", errors.New("failed to hash password")
+	}
+
+	user.Password = hashedPassword
+	err = s.UoW.Do(ctx, func(repos repository.Repositories) error {
+		if err := repos.Users.Update(ctx, user, "password"); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+		if err := revokeSessions(ctx, repos.Users, user.ID); err != nil {
+			return err
+		}
+		return repos.Users.RevokeAccountTokens(ctx, user.ID, consts.TokenPurposeResetPassword)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.issueToken(ctx, *user, ip, userAgent)
+	if err != nil {
+		return "", errors.New("failed to generate token")
+	}
+	return token, nil
+}
+
+// DeleteAccount 注销账号，需提供密码（开启两步验证时还需验证码或恢复码）
+// 用户记录被匿名化并软删除：用户名、邮箱等个人信息被清除，评论保留但不再关联到可识别的作者；
+// deletePosts 为 true 时同时删除其文章，否则文章保留并显示为已注销用户。
+// 登录会话、个人访问令牌、第三方身份、恢复码、登录记录与导出归档一并删除
+func (s *UserService) DeleteAccount(ctx context.Context, userID uint, password, code string, deletePosts bool) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := consts.CheckPassword(password, user.Password); err != nil {
+		return ErrIncorrectPassword
+	}
+	// 匿名后的用户名与邮箱使用随机后缀，不能被他人抢先注册而导致注销因唯一索引冲突失败
+	suffix, err := randomTokenID()
+	if err != nil {
+		return err
+	}
+
+	return s.UoW.Do(ctx, func(repos repository.Repositories) error {
+		if user.TOTPEnabledAt != nil {
+			if err := verifySecondFactor(ctx, repos.Users, user, code); err != nil {
+				return err
+			}
+		}
+
+		if deletePosts {
+			if err := repos.Posts.DeleteByUser(ctx, user.ID); err != nil {
+				return fmt.Errorf("failed to delete posts: %w", err)
+			}
+		}
+
+		if err := revokeSessions(ctx, repos.Users, user.ID); err != nil {
+			return err
+		}
+		if err := repos.Users.PurgeAccountData(ctx, user); err != nil {
+			return fmt.Errorf("failed to delete account data: %w", err)
+		}
+
+		// 匿名化后软删除，释放原用户名和邮箱
+		tombstone := "deleted-" + suffix
+		*user = model.User{
+			ID:       user.ID,
+			Username: tombstone,
+			Email:    tombstone + "@deleted.invalid",
+		}
+		if err := repos.Users.Update(ctx, user, "username", "email", "password", "display_name", "bio",
+			"avatar_url", "email_verified_at", "totp_secret", "totp_enabled_at"); err != nil {
+			return fmt.Errorf("failed to anonymize user: %w", err)
+		}
+		if err := repos.Users.Delete(ctx, user); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	})
+}
+
+// SessionActive 登录会话是否仍然有效（未被吊销），供 AuthMiddleware 使用
+func (s *UserService) SessionActive(ctx context.Context, tokenID string) (bool, error) {
+	active, err := s.Users.SessionActive(ctx, tokenID)
+	if err != nil {
+		return false, fmt.Errorf("failed to query session: %w", err)
+	}
+	return active, nil
+}
+
+// revokeSessions 吊销用户全部未过期的登录会话
+func revokeSessions(ctx context.Context, users repository.UserRepository, userID uint) error {
+	if err := users.RevokeSessions(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/repository"
+	"web-task/blog/internal/totp"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrTOTPNotEnabled     = errors.New("two-factor authentication not enabled")
+	ErrTOTPNotEnrolled    = errors.New("two-factor enrollment not started")
+	ErrInvalidMFACode     = errors.New("invalid verification code")
+)
+
+// LoginResult 登录结果
+// 开启两步验证的账号不直接返回 Token，而是返回短期有效的 MFAToken，需调用 LoginMFA 换取正式令牌
+type LoginResult struct {
+	Token       string
+	MFARequired bool
+	MFAToken    string
+}
+
+// TOTPEnrollment 两步验证注册信息，密钥只在注册阶段展示
+type TOTPEnrollment struct {
+	Secret     string
+	OTPAuthURL string
+	QRCodePNG  []byte
+}
+
+// EnrollTOTP 开始注册两步验证：生成新密钥（待确认状态），返回密钥、otpauth 地址和二维码
+// 重复调用会替换尚未确认的密钥
+func (s *UserService) EnrollTOTP(ctx context.Context, userID uint) (*TOTPEnrollment, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastCounter = 0
+	if err := s.Users.Update(ctx, user, "totp_secret", "totp_last_counter"); err != nil {
+		return nil, fmt.Errorf("failed to save totp secret: %w", err)
+	}
+
+	return s.totpEnrollment(user.Username, secret)
+}
+
+// TOTPQRCode 获取待确认密钥的二维码；启用后不再提供，避免密钥泄露
+func (s *UserService) TOTPQRCode(ctx context.Context, userID uint) ([]byte, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	enrollment, err := s.totpEnrollment(user.Username, user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	return enrollment.QRCodePNG, nil
+}
+
+// ConfirmTOTP 用验证器生成的验证码确认注册，启用两步验证并返回一次性恢复码（仅此一次明文展示）
+func (s *UserService) ConfirmTOTP(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	counter, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	var codes []string
+	err = s.UoW.Do(ctx, func(repos repository.Repositories) error {
+		now := time.Now()
+		user.TOTPEnabledAt = &now
+		user.TOTPLastCounter = counter
+		if err := repos.Users.Update(ctx, user, "totp_enabled_at", "totp_last_counter"); err != nil {
+			return fmt.Errorf("failed to enable totp: %w", err)
+		}
+
+		codes, err = replaceRecoveryCodes(ctx, repos.Users, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP 关闭两步验证，需同时提供密码与验证码（或恢复码）
+func (s *UserService) DisableTOTP(ctx context.Context, userID uint, password, code string) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabledAt == nil {
+		return ErrTOTPNotEnabled
+	}
+	if err := consts.CheckPassword(password, user.Password); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	return s.UoW.Do(ctx, func(repos repository.Repositories) error {
+		if err := verifySecondFactor(ctx, repos.Users, user, code); err != nil {
+			return err
+		}
+
+		user.TOTPSecret = ""
+		user.TOTPEnabledAt = nil
+		user.TOTPLastCounter = 0
+		if err := repos.Users.Update(ctx, user, "totp_secret", "totp_enabled_at", "totp_last_counter"); err != nil {
+			return fmt.Errorf("failed to disable totp: %w", err)
+		}
+		if err := repos.Users.DeleteRecoveryCodes(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+// RegenerateRecoveryCodes 使用验证码重新生成恢复码，旧恢复码全部失效
+func (s *UserService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrTOTPNotEnabled
+	}
+
+	var codes []string
+	err = s.UoW.Do(ctx, func(repos repository.Repositories) error {
+		if err := verifySecondFactor(ctx, repos.Users, user, code); err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(ctx, repos.Users, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// LoginMFA 登录第二步：用挑战令牌和验证码（或恢复码）换取正式令牌
+// 失败同样计入登录失败次数，受账号与 IP 锁定约束
+func (s *UserService) LoginMFA(ctx context.Context, mfaToken, code, ip, userAgent string) (string, error) {
+	userID, err := s.parseMFAChallenge(mfaToken)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return "", ErrInvalidToken
+		}
+		return "", err
+	}
+	if user.TOTPEnabledAt == nil {
+		return "", ErrInvalidToken
+	}
+
+	if err := s.checkLockout(ctx, user.Username, ip); err != nil {
+		return "", err
+	}
+
+	if err := verifySecondFactor(ctx, s.Users, user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.recordAttempt(ctx, user.Username, &user.ID, ip, userAgent, false)
+		}
+		return "", err
+	}
+
+	token, err := s.issueToken(ctx, *user, ip, userAgent)
+	if err != nil {
+		return "", errors.New("failed to generate token")
+	}
+	s.recordAttempt(ctx, user.Username, &user.ID, ip, userAgent, true)
+
+	return token, nil
+}
+
+func (s *UserService) totpEnrollment(username, secret string) (*TOTPEnrollment, error) {
+	uri := totp.URI(consts.TOTPIssuer, username, secret)
+	png, err := totp.QRCodePNG(uri, 256)
+	if err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollment{Secret: secret, OTPAuthURL: uri, QRCodePNG: png}, nil
+}
+
+// verifySecondFactor 校验 TOTP 验证码或恢复码，成功后立即标记为已使用
+// 6 位数字按 TOTP 校验（时间步必须大于上次使用的时间步），其它格式按恢复码校验
+func verifySecondFactor(ctx context.Context, users repository.UserRepository, user *model.User, code string) error {
+	code = strings.TrimSpace(code)
+
+	if _, err := strconv.Atoi(code); err == nil && len(code) == totp.Digits {
+		counter, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+		if !ok || counter <= user.TOTPLastCounter {
+			return ErrInvalidMFACode
+		}
+		// 条件更新防止同一验证码被并发重放
+		advanced, err := users.AdvanceTOTPCounter(ctx, user.ID, counter)
+		if err != nil {
+			return fmt.Errorf("failed to update totp counter: %w", err)
+		}
+		if !advanced {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := users.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes 删除旧恢复码并生成新的一组，返回明文
+func replaceRecoveryCodes(ctx context.Context, users repository.UserRepository, userID uint) ([]string, error) {
+	codes := make([]string, 0, consts.RecoveryCodeCount)
+	records := make([]model.RecoveryCode, 0, consts.RecoveryCodeCount)
+	for i := 0; i < consts.RecoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, model.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	if err := users.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// newRecoveryCode 生成形如 abcde-fghij 的恢复码（50 位熵）
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	s := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+	return s[:5] + "-" + s[5:], nil
+}
+
+// hashRecoveryCode 忽略大小写、空格与连字符后计算哈希
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateMFAChallenge 签发两步验证挑战令牌，typ 声明使其不能被 AuthMiddleware 当作登录令牌接受
+func (s *UserService) generateMFAChallenge(user model.User) (string, error) {
+	claims := jwt.MapClaims{
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+		"typ": consts.TokenTypeMFAChallenge,
+		"exp": time.Now().Add(consts.MFAChallengeTTL).Unix(),
+	}
+	return s.Keys.Sign(claims)
+}
+
+func (s *UserService) parseMFAChallenge(tokenString string) (uint, error) {
+	claims := jwt.MapClaims{}
+	token, err := s.Keys.Parse(tokenString, claims)
+	if err != nil || !token.Valid {
+		return 0, ErrInvalidToken
+	}
+
+	if claims["typ"] != consts.TokenTypeMFAChallenge {
+		return 0, ErrInvalidToken
+	}
+	sub, _ := claims["sub"].(string)
+	userID, err := strconv.ParseUint(sub, 10, 32)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return uint(userID), nil
+}
@@ -0,0 +1,291 @@
+// internal/service/uploads.go
+package logic
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"path"
+	"time"
+
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+	"web-task/blog/internal/storage"
+
+	"github.com/gabriel-vasile/mimetype"
+	"golang.org/x/image/draw"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAttachmentNotFound  = errors.New("attachment not found")
+	ErrFileTooLarge        = errors.New("file too large")
+	ErrUnsupportedFileType = errors.New("unsupported file type")
+)
+
+// UploadService 附件上传服务
+type UploadService struct {
+	DB      *gorm.DB
+	Storage storage.Storage
+}
+
+// NewUploadService 构造函数
+func NewUploadService(db *gorm.DB, store storage.Storage) *UploadService {
+	return &UploadService{DB: db, Storage: store}
+}
+
+// Upload 保存上传文件；postID 为空时附件暂不关联文章，超过 UnattachedUploadTTL 未关联会被回收
+func (s *UploadService) Upload(ctx context.Context, userID uint, postID *uint, filename string, r io.Reader) (*model.Attachment, error) {
+	// 多读一个字节用于判断是否超限
+	data, err := io.ReadAll(io.LimitReader(r, consts.UploadMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if len(data) > consts.UploadMaxSize {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrFileTooLarge, consts.UploadMaxSize)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidInput)
+	}
+
+	// 以内容嗅探结果为准，忽略客户端声明的 Content-Type
+	mimeType, _, err := mime.ParseMediaType(mimetype.Detect(data).String())
+	if err != nil {
+		return nil, ErrUnsupportedFileType
+	}
+	ext, ok := consts.AllowedUploadTypes[mimeType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, mimeType)
+	}
+
+	// 解码前先读取文件头中的尺寸，拒绝超大画布
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > consts.UploadMaxPixels {
+			return nil, fmt.Errorf("%w: image dimensions %dx%d exceed the limit of %d pixels", ErrInvalidInput, cfg.Width, cfg.Height, consts.UploadMaxPixels)
+		}
+	}
+
+	if postID != nil {
+		if err := s.checkPostOwner(ctx, userID, *postID); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := newObjectKey("attachments", ext)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := model.Attachment{
+		PostID:     postID,
+		UserID:     userID,
+		Filename:   path.Base(filename),
+		MimeType:   mimeType,
+		Size:       int64(len(data)),
+		StorageKey: key,
+	}
+
+	if err := s.Storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), mimeType); err != nil {
+		return nil, fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	// 图片生成缩略图；解码失败不影响原文件上传
+	if img, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+		bounds := img.Bounds()
+		attachment.Width, attachment.Height = bounds.Dx(), bounds.Dy()
+
+		thumb, thumbType, thumbExt, err := makeThumbnail(img, mimeType)
+		if err == nil {
+			thumbKey, err := newObjectKey("thumbnails", thumbExt)
+			if err == nil && s.Storage.Put(ctx, thumbKey, bytes.NewReader(thumb), int64(len(thumb)), thumbType) == nil {
+				attachment.ThumbnailKey = thumbKey
+			}
+		}
+	}
+
+	if err := s.DB.WithContext(ctx).Create(&attachment).Error; err != nil {
+		s.removeObjects(ctx, &attachment)
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// GetByID 根据ID获取附件元数据
+func (s *UploadService) GetByID(ctx context.Context, id uint) (*model.Attachment, error) {
+	var attachment model.Attachment
+	if err := s.DB.WithContext(ctx).First(&attachment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return &attachment, nil
+}
+
+// Open 打开附件内容；thumbnail 为 true 时返回缩略图
+// 返回的 contentType 为实际读取对象的类型
+func (s *UploadService) Open(ctx context.Context, id uint, thumbnail bool) (io.ReadCloser, string, *model.Attachment, error) {
+	attachment, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	key, contentType := attachment.StorageKey, attachment.MimeType
+	if thumbnail {
+		if attachment.ThumbnailKey == "" {
+			return nil, "", nil, ErrAttachmentNotFound
+		}
+		key = attachment.ThumbnailKey
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+
+	rc, err := s.Storage.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return nil, "", nil, ErrAttachmentNotFound
+		}
+		return nil, "", nil, err
+	}
+	return rc, contentType, attachment, nil
+}
+
+// Delete 删除附件及其存储对象（仅上传者可删除）
+func (s *UploadService) Delete(ctx context.Context, userID uint, id uint) error {
+	attachment, err := s.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if attachment.UserID != userID {
+		return errors.New("permission denied")
+	}
+
+	if err := s.DB.WithContext(ctx).Unscoped().Delete(attachment).Error; err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	s.removeObjects(ctx, attachment)
+
+	return nil
+}
+
+// CollectGarbage 清理孤立附件：所属文章已删除，或上传后超过 UnattachedUploadTTL 仍未关联文章
+// 返回清理的附件数量
+func (s *UploadService) CollectGarbage(ctx context.Context) (int, error) {
+	db := s.DB.WithContext(ctx)
+
+	deletedPosts := db.Unscoped().Model(&model.Post{}).
+		Select("id").
+		Where("deleted_at IS NOT NULL")
+
+	var orphans []model.Attachment
+	if err := db.Unscoped().
+		Where("post_id IN (?)", deletedPosts).
+		Or("post_id IS NULL AND created_at < ?", time.Now().Add(-consts.UnattachedUploadTTL)).
+		Find(&orphans).Error; err != nil {
+		return 0, fmt.Errorf("failed to find orphaned attachments: %w", err)
+	}
+
+	collected := 0
+	for i := range orphans {
+		if err := ctx.Err(); err != nil {
+			return collected, err
+		}
+		// 先删对象再删记录，对象删除失败时保留记录以便下次重试
+		if err := s.removeObjects(ctx, &orphans[i]); err != nil {
+			continue
+		}
+		if err := db.Unscoped().Delete(&orphans[i]).Error; err != nil {
+			return collected, fmt.Errorf("failed to delete attachment: %w", err)
+		}
+		collected++
+	}
+
+	return collected, nil
+}
+
+func (s *UploadService) checkPostOwner(ctx context.Context, userID uint, postID uint) error {
+	var post model.Post
+	if err := s.DB.WithContext(ctx).Select("id", "user_id").First(&post, postID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPostNotFound
+		}
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+	if post.UserID != userID {
+		return errors.New("permission denied")
+	}
+	return nil
+}
+
+func (s *UploadService) removeObjects(ctx context.Context, attachment *model.Attachment) error {
+	if attachment.ThumbnailKey != "" {
+		if err := s.Storage.Delete(ctx, attachment.ThumbnailKey); err != nil {
+			return err
+		}
+	}
+	return s.Storage.Delete(ctx, attachment.StorageKey)
+}
+
+// newObjectKey 生成形如 attachments/2006/01/02/<随机串>.jpg 的对象键
+func newObjectKey(prefix, ext string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate object key: %w", err)
+	}
+	return path.Join(prefix, time.Now().Format("2006/01/02"), hex.EncodeToString(buf)+ext), nil
+}
+
+// makeThumbnail 按最长边 ThumbnailMaxDimension 等比缩放
+// PNG/GIF 输出 PNG 以保留透明度，其余输出 JPEG
+func makeThumbnail(img image.Image, mimeType string) ([]byte, string, string, error) {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return nil, "", "", errors.New("empty image")
+	}
+
+	limit := consts.ThumbnailMaxDimension
+	if w > limit || h > limit {
+		if w >= h {
+			h = h * limit / w
+			w = limit
+		} else {
+			w = w * limit / h
+			h = limit
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if mimeType == "image/png" || mimeType == "image/gif" {
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/png", ".png", nil
+	}
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", "", err
+	}
+	return buf.Bytes(), "image/jpeg", ".jpg", nil
+}
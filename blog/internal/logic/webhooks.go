@@ -0,0 +1,578 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrTooManyWebhooks  = errors.New("too many webhooks")
+	ErrInvalidEvent     = errors.New("invalid event type")
+	// ErrWebhookTargetForbidden webhook 地址指向回环、内网、链路本地等非公网地址
+	ErrWebhookTargetForbidden = errors.New("webhook target address is not allowed")
+)
+
+// WebhookPayload webhook 请求体
+type WebhookPayload struct {
+	ID        uint            `json:"id"` // 事件ID，重试与重新投递时不变，接收方可据此去重
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"` // EventData
+}
+
+// WebhookUpdate 修改 webhook 的字段，nil 表示不修改
+type WebhookUpdate struct {
+	URL         *string
+	Events      []string
+	Active      *bool
+	Description *string
+}
+
+// WebhookService 出站 webhook：管理用户注册的接收地址，并把发件箱中的事件签名后投递出去
+// 投递失败按指数退避重试，达到 WebhookMaxAttempts 次后进入死信，可通过接口手动重新投递
+type WebhookService struct {
+	DB     *gorm.DB
+	Client *http.Client
+	Log    *slog.Logger
+	// AllowPrivateTargets 允许投递到回环与内网地址，仅供测试连接本机的接收端，生产环境不得开启
+	AllowPrivateTargets bool
+
+	wake chan struct{}
+}
+
+// NewWebhookService 构造函数；投递不跟随重定向，3xx 视为失败，
+// 不使用环境变量中的代理，连接前检查 DNS 解析后的目标 IP，拒绝非公网地址（含 DNS 重绑定）
+func NewWebhookService(db *gorm.DB, logger *slog.Logger) *WebhookService {
+	s := &WebhookService{
+		DB:   db,
+		Log:  logger,
+		wake: make(chan struct{}, 1),
+	}
+	dialer := &net.Dialer{
+		Timeout: consts.WebhookTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			if s.AllowPrivateTargets {
+				return nil
+			}
+			return checkWebhookTarget(address)
+		},
+	}
+	s.Client = &http.Client{
+		Timeout:   consts.WebhookTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, ForceAttemptHTTP2: true, TLSHandshakeTimeout: consts.WebhookTimeout},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return s
+}
+
+// Create 注册 webhook，返回的签名密钥只在创建时展示一次
+func (s *WebhookService) Create(ctx context.Context, userID uint, rawURL string, events []string, description string) (*model.Webhook, string, error) {
+	target, err := normalizeWebhookURL(rawURL, s.AllowPrivateTargets)
+	if err != nil {
+		return nil, "", err
+	}
+	if events, err = normalizeEvents(events); err != nil {
+		return nil, "", err
+	}
+
+	db := s.DB.WithContext(ctx)
+	var count int64
+	if err := db.Model(&model.Webhook{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	if count >= consts.WebhookMaxPerUser {
+		return nil, "", ErrTooManyWebhooks
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	secret := "whsec_" + base64.RawURLEncoding.EncodeToString(raw)
+
+	hook := model.Webhook{
+		UserID:      userID,
+		URL:         target,
+		Secret:      secret,
+		Events:      strings.Join(events, ","),
+		Active:      true,
+		Description: strings.TrimSpace(description),
+	}
+	if err := db.Create(&hook).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return &hook, secret, nil
+}
+
+// List 列出用户的 webhook，按创建时间正序
+func (s *WebhookService) List(ctx context.Context, userID uint) ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	if err := s.DB.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&hooks).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// Get 获取当前用户的 webhook
+func (s *WebhookService) Get(ctx context.Context, userID, id uint) (*model.Webhook, error) {
+	var hook model.Webhook
+	if err := s.DB.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&hook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return &hook, nil
+}
+
+// Update 修改接收地址、订阅的事件、启用状态或备注
+func (s *WebhookService) Update(ctx context.Context, userID, id uint, update WebhookUpdate) (*model.Webhook, error) {
+	hook, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]interface{})
+	if update.URL != nil {
+		if hook.URL, err = normalizeWebhookURL(*update.URL, s.AllowPrivateTargets); err != nil {
+			return nil, err
+		}
+		changes["url"] = hook.URL
+	}
+	if update.Events != nil {
+		events, err := normalizeEvents(update.Events)
+		if err != nil {
+			return nil, err
+		}
+		hook.Events = strings.Join(events, ",")
+		changes["events"] = hook.Events
+	}
+	if update.Active != nil {
+		hook.Active = *update.Active
+		changes["active"] = hook.Active
+	}
+	if update.Description != nil {
+		hook.Description = strings.TrimSpace(*update.Description)
+		changes["description"] = hook.Description
+	}
+	if len(changes) == 0 {
+		return hook, nil
+	}
+
+	if err := s.DB.WithContext(ctx).Model(hook).Updates(changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return hook, nil
+}
+
+// Delete 删除 webhook，尚未完成的投递在下次尝试时进入死信
+func (s *WebhookService) Delete(ctx context.Context, userID, id uint) error {
+	hook, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.DB.WithContext(ctx).Delete(hook).Error; err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries 列出 webhook 最近的投递记录，按创建时间倒序；status 为空时不过滤
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID, webhookID uint, status string, limit int) ([]model.WebhookDelivery, error) {
+	if _, err := s.Get(ctx, userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	db := s.DB.WithContext(ctx).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	var deliveries []model.WebhookDelivery
+	if err := db.Order("id desc").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Redeliver 重新投递已完成（成功或死信）的投递，尝试次数清零；仍在重试中的投递原样返回
+func (s *WebhookService) Redeliver(ctx context.Context, userID, webhookID, deliveryID uint) (*model.WebhookDelivery, error) {
+	if _, err := s.Get(ctx, userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	db := s.DB.WithContext(ctx)
+	var delivery model.WebhookDelivery
+	if err := db.Where("id = ? AND webhook_id = ?", deliveryID, webhookID).First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	if delivery.Status == consts.WebhookDeliveryPending {
+		return &delivery, nil
+	}
+
+	delivery.Status = consts.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := db.Model(&delivery).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to requeue webhook delivery: %w", err)
+	}
+
+	// 唤醒后台任务立即投递
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return &delivery, nil
+}
+
+// Run 后台分发发件箱事件、投递到期的请求并清理过期记录，直到 ctx 取消
+// 多个实例同时运行时通过条件更新认领事件与投递，每次尝试只会由一个实例执行
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(consts.WebhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.FanOut(ctx); err != nil {
+			s.Log.ErrorContext(ctx, "webhook fan-out failed", "error", err)
+		}
+		if _, err := s.DeliverDue(ctx); err != nil {
+			s.Log.ErrorContext(ctx, "webhook delivery failed", "error", err)
+		}
+		if _, err := s.Cleanup(ctx); err != nil {
+			s.Log.ErrorContext(ctx, "webhook cleanup failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// FanOut 为待分发的事件创建投递记录（每个订阅该事件的启用中 webhook 一条），返回分发的事件数
+// 标记事件与创建投递在同一事务中完成，事件不会重复分发也不会丢失
+func (s *WebhookService) FanOut(ctx context.Context) (int, error) {
+	db := s.DB.WithContext(ctx)
+
+	var events []model.OutboxEvent
+	if err := db.Where("dispatched_at IS NULL").Order("id").Limit(consts.WebhookBatchSize).Find(&events).Error; err != nil {
+		return 0, fmt.Errorf("failed to query outbox: %w", err)
+	}
+
+	dispatched := 0
+	for _, event := range events {
+		claimed := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			now := time.Now()
+			claim := tx.Model(&model.OutboxEvent{}).
+				Where("id = ? AND dispatched_at IS NULL", event.ID).
+				Update("dispatched_at", now)
+			if claim.Error != nil || claim.RowsAffected != 1 {
+				return claim.Error // 已被其它实例分发
+			}
+			claimed = true
+
+			var hooks []model.Webhook
+			if err := tx.Where("user_id = ? AND active = ?", event.UserID, true).Order("id").Find(&hooks).Error; err != nil {
+				return err
+			}
+			var deliveries []model.WebhookDelivery
+			for _, hook := range hooks {
+				if hook.Subscribed(event.Type) {
+					deliveries = append(deliveries, model.WebhookDelivery{
+						WebhookID:     hook.ID,
+						EventID:       event.ID,
+						EventType:     event.Type,
+						Status:        consts.WebhookDeliveryPending,
+						NextAttemptAt: now,
+					})
+				}
+			}
+			if len(deliveries) == 0 {
+				return nil
+			}
+			return tx.Create(&deliveries).Error
+		})
+		if err != nil {
+			return dispatched, fmt.Errorf("failed to dispatch event %d: %w", event.ID, err)
+		}
+		if claimed {
+			dispatched++
+		}
+	}
+	return dispatched, nil
+}
+
+// DeliverDue 依次投递到期的请求，返回尝试的次数；单次投递受 WebhookTimeout 限制
+// 认领时即计入尝试次数并把下次尝试时间推迟 WebhookClaimTTL，实例中断后由其它实例重新投递
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	db := s.DB.WithContext(ctx)
+
+	var due []model.WebhookDelivery
+	if err := db.Where("status = ? AND next_attempt_at <= ?", consts.WebhookDeliveryPending, time.Now()).
+		Order("next_attempt_at, id").
+		Limit(consts.WebhookBatchSize).
+		Find(&due).Error; err != nil {
+		return 0, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+
+	attempted := 0
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			break
+		}
+
+		now := time.Now()
+		claim := db.Model(&model.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND attempts = ?", delivery.ID, consts.WebhookDeliveryPending, delivery.Attempts).
+			Updates(map[string]interface{}{
+				"attempts":        delivery.Attempts + 1,
+				"last_attempt_at": now,
+				"next_attempt_at": now.Add(consts.WebhookClaimTTL),
+			})
+		if claim.Error != nil {
+			return attempted, fmt.Errorf("failed to claim webhook delivery: %w", claim.Error)
+		}
+		if claim.RowsAffected != 1 {
+			continue // 已被其它实例认领
+		}
+		delivery.Attempts++
+
+		s.deliver(ctx, &delivery)
+		attempted++
+	}
+	return attempted, nil
+}
+
+// Cleanup 删除超过保留期的已完成投递记录与已分发事件，返回删除的投递记录数
+func (s *WebhookService) Cleanup(ctx context.Context) (int, error) {
+	db := s.DB.WithContext(ctx).Unscoped()
+	cutoff := time.Now().Add(-consts.WebhookRetention)
+
+	deleted := db.Where("status <> ? AND updated_at < ?", consts.WebhookDeliveryPending, cutoff).
+		Delete(&model.WebhookDelivery{})
+	if deleted.Error != nil {
+		return 0, fmt.Errorf("failed to delete webhook deliveries: %w", deleted.Error)
+	}
+	if err := db.Where("dispatched_at < ?", cutoff).Delete(&model.OutboxEvent{}).Error; err != nil {
+		return int(deleted.RowsAffected), fmt.Errorf("failed to delete outbox events: %w", err)
+	}
+	return int(deleted.RowsAffected), nil
+}
+
+// deliver 发送一次请求并记录结果；因进程退出被取消的投递保持认领状态，认领过期后重新投递
+func (s *WebhookService) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	db := s.DB.WithContext(context.WithoutCancel(ctx))
+
+	var hook model.Webhook
+	if err := db.First(&hook, delivery.WebhookID).Error; err != nil {
+		s.finish(ctx, delivery, 0, fmt.Errorf("webhook is unavailable: %w", err), true)
+		return
+	}
+	if !hook.Active {
+		s.finish(ctx, delivery, 0, errors.New("webhook is disabled"), true)
+		return
+	}
+	var event model.OutboxEvent
+	if err := db.First(&event, delivery.EventID).Error; err != nil {
+		s.finish(ctx, delivery, 0, fmt.Errorf("event is unavailable: %w", err), true)
+		return
+	}
+
+	status, err := s.send(ctx, &hook, &event, delivery.ID)
+	if err != nil && ctx.Err() != nil {
+		s.Log.WarnContext(ctx, "webhook delivery interrupted", "delivery_id", delivery.ID, "error", err)
+		return
+	}
+	// 地址解析到非公网地址时重试没有意义，直接进入死信
+	s.finish(ctx, delivery, status, err, errors.Is(err, ErrWebhookTargetForbidden))
+}
+
+// send 签名并发送事件，2xx 视为成功
+func (s *WebhookService) send(ctx context.Context, hook *model.Webhook, event *model.OutboxEvent, deliveryID uint) (int, error) {
+	body, err := json.Marshal(WebhookPayload{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "blog-webhooks/1.0")
+	req.Header.Set(consts.WebhookEventHeader, event.Type)
+	req.Header.Set(consts.WebhookDeliveryHeader, strconv.FormatUint(uint64(deliveryID), 10))
+	req.Header.Set(consts.WebhookTimestampHeader, timestamp)
+	req.Header.Set(consts.WebhookSignatureHeader, signWebhook(hook.Secret, timestamp, body))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// 读完（有限的）响应体以复用连接，内容不保存
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// finish 记录投递结果：成功、按退避时间重试，或在重试耗尽（以及 webhook 或事件已不存在）时进入死信
+func (s *WebhookService) finish(ctx context.Context, delivery *model.WebhookDelivery, status int, deliveryErr error, dead bool) {
+	now := time.Now()
+	changes := map[string]interface{}{"response_status": status, "error": ""}
+	switch {
+	case deliveryErr == nil:
+		changes["status"] = consts.WebhookDeliverySucceeded
+		changes["delivered_at"] = now
+	case dead || delivery.Attempts >= consts.WebhookMaxAttempts:
+		changes["status"] = consts.WebhookDeliveryDead
+		changes["error"] = truncate(deliveryErr.Error(), 255)
+		s.Log.WarnContext(ctx, "webhook delivery dead-lettered", "delivery_id", delivery.ID, "webhook_id", delivery.WebhookID, "attempts", delivery.Attempts, "error", deliveryErr)
+	default:
+		changes["next_attempt_at"] = now.Add(webhookBackoff(delivery.Attempts))
+		changes["error"] = truncate(deliveryErr.Error(), 255)
+	}
+
+	if err := s.DB.WithContext(context.WithoutCancel(ctx)).Model(delivery).Updates(changes).Error; err != nil {
+		s.Log.ErrorContext(ctx, "failed to update webhook delivery", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// webhookBackoff 第 attempts 次失败后的等待时间：WebhookRetryBase 起每次翻倍，不超过 WebhookRetryMax
+func webhookBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 30 {
+		return consts.WebhookRetryMax
+	}
+	return min(consts.WebhookRetryBase<<(attempts-1), consts.WebhookRetryMax)
+}
+
+// signWebhook 计算签名头：sha256=hex(HMAC-SHA256(secret, "<timestamp>.<body>"))
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// normalizeWebhookURL 只接受带主机名的 http/https 地址；主机为 IP 时不能是非公网地址，
+// 域名在投递时解析后由拨号检查
+func normalizeWebhookURL(raw string, allowPrivate bool) (string, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return "", fmt.Errorf("%w: url must be an absolute http or https url", ErrInvalidInput)
+	}
+	if len(raw) > 2048 {
+		return "", fmt.Errorf("%w: url must be at most 2048 characters", ErrInvalidInput)
+	}
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil && !allowPrivate && !publicAddr(ip) {
+		return "", fmt.Errorf("%w: %w", ErrInvalidInput, ErrWebhookTargetForbidden)
+	}
+	return raw, nil
+}
+
+// checkWebhookTarget 拨号前检查 DNS 解析后的地址（host:port）
+func checkWebhookTarget(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrWebhookTargetForbidden, address)
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !publicAddr(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookTargetForbidden, host)
+	}
+	return nil
+}
+
+// nonPublicPrefixes netip 未覆盖的保留网段：本网络、运营商级 NAT、IETF 协议分配、基准测试与保留地址，以及映射到 IPv4 的 NAT64 前缀
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// publicAddr 是否为可投递的公网单播地址：排除回环、内网（RFC 1918 与 fc00::/7）、
+// 链路本地（含 169.254.169.254 云元数据地址）、未指定、组播与其它保留地址
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeEvents(events []string) ([]string, error) {
+	result := make([]string, 0, len(events))
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !slices.Contains(consts.WebhookEvents, event) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidEvent, event)
+		}
+		if !slices.Contains(result, event) {
+			result = append(result, event)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: at least one event is required", ErrInvalidEvent)
+	}
+	slices.Sort(result)
+	return result, nil
+}
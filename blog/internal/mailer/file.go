@@ -0,0 +1,44 @@
+// internal/mailer/file.go
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// FileMailer 本地开发用的邮件实现：不真正发送，而是把邮件写成 .eml 文件并打印日志
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// NewFileMailer 构造函数，目录不存在时自动创建
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail dir: %w", err)
+	}
+	return &FileMailer{Dir: dir, From: from}, nil
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	body, err := buildMIME(m.From, msg)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000"), unsafeFilenameChars.ReplaceAllString(msg.To, "_"))
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, body, 0o600); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+
+	slog.Info("mail written to file", "to", msg.To, "subject", msg.Subject, "path", path)
+	return nil
+}
@@ -0,0 +1,72 @@
+// internal/mailer/mailer.go
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"strings"
+	"time"
+)
+
+// Message 待发送的邮件，Text 与 HTML 会组成 multipart/alternative
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer 邮件发送抽象，生产环境使用 SMTPMailer，本地开发使用 FileMailer
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// buildMIME 将邮件编码为 RFC 5322 格式
+func buildMIME(from string, msg Message) ([]byte, error) {
+	boundaryBytes := make([]byte, 12)
+	if _, err := rand.Read(boundaryBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate boundary: %w", err)
+	}
+	boundary := "blog-" + hex.EncodeToString(boundaryBytes)
+
+	var buf bytes.Buffer
+	writeHeader := func(k, v string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+	}
+	writeHeader("From", from)
+	writeHeader("To", msg.To)
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", boundary))
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		if part.body == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		writeHeader("Content-Type", part.contentType)
+		writeHeader("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(strings.ReplaceAll(part.body, "\n", "\r\n"))); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,36 @@
+// internal/mailer/templates.go
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var (
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html"))
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt"))
+)
+
+// 模板名称，对应 templates 目录下同名的 .html 与 .txt 文件
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplateResetPassword = "reset_password"
+)
+
+// Render 渲染指定模板的 HTML 与纯文本版本，生成发往 to 的邮件
+func Render(name, to, subject string, data any) (Message, error) {
+	var html, text bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s.html: %w", name, err)
+	}
+	if err := textTemplates.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s.txt: %w", name, err)
+	}
+	return Message{To: to, Subject: subject, HTML: html.String(), Text: text.String()}, nil
+}
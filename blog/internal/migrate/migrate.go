@@ -0,0 +1,108 @@
+// Package migrate 版本化的数据库迁移：已执行的版本记录在 schema_migrations 表中，
+// 启动时（或通过 cmd/migrate）执行未应用的迁移，就绪检查据此判断数据库结构是否为最新
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var ErrDuplicateVersion = errors.New("duplicate migration version")
+
+// Migration 一次数据库结构变更
+// 已发布的迁移不可修改，结构变更应追加新版本
+type Migration struct {
+	Version     string // 按字典序执行，如 0002_add_post_slug
+	Description string
+	Up          func(tx *gorm.DB) error
+}
+
+// Migrator 执行并查询迁移
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+}
+
+// New 构造函数，迁移按版本排序；版本重复属于编码错误，直接 panic
+func New(db *gorm.DB, migrations []Migration) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			panic(fmt.Errorf("%w: %s", ErrDuplicateVersion, sorted[i].Version))
+		}
+	}
+	return &Migrator{DB: db, Migrations: sorted}
+}
+
+// Applied 已执行的迁移，尚未建立记录表时返回空
+func (m *Migrator) Applied(ctx context.Context) ([]model.SchemaMigration, error) {
+	db := m.DB.WithContext(ctx)
+
+	if !db.Migrator().HasTable(&model.SchemaMigration{}) {
+		return nil, nil
+	}
+	var applied []model.SchemaMigration
+	if err := db.Order("version").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to query schema migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// Pending 尚未执行的迁移
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[string]bool, len(applied))
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+
+	var pending []Migration
+	for _, mig := range m.Migrations {
+		if !done[mig.Version] {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Up 依次执行未应用的迁移，返回本次执行的迁移
+// 每个迁移与其记录在同一事务中提交；MySQL 的 DDL 会隐式提交，因此迁移本身应可重复执行
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	db := m.DB.WithContext(ctx)
+
+	if err := db.AutoMigrate(&model.SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, mig := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := mig.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&model.SchemaMigration{
+				Version:     mig.Version,
+				Description: mig.Description,
+				AppliedAt:   time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %s failed: %w", mig.Version, err)
+		}
+	}
+	return pending, nil
+}
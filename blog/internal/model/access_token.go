@@ -0,0 +1,32 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PersonalAccessToken 个人访问令牌表，供脚本和命令行客户端调用接口，只保存令牌哈希
+type PersonalAccessToken struct {
+	gorm.Model
+	ID         uint       `gorm:"primary_key;auto_increment;comment:令牌ID" json:"id"`
+	UserID     uint       `gorm:"type:int;not_null;index;comment:用户ID" json:"user_id"`
+	Name       string     `gorm:"type:varchar(100);not_null;comment:令牌名称" json:"name"`
+	Prefix     string     `gorm:"type:varchar(32);not_null;comment:令牌可见前缀，用于识别" json:"prefix"`
+	TokenHash  string     `gorm:"type:char(64);not_null;unique;comment:令牌SHA-256哈希" json:"-"`
+	Scopes     string     `gorm:"type:varchar(255);not_null;comment:权限范围，逗号分隔" json:"-"`
+	ExpiresAt  time.Time  `gorm:"type:timestamp;not_null;comment:过期时间" json:"expires_at"`
+	LastUsedAt *time.Time `gorm:"type:timestamp;default:null;comment:最近使用时间" json:"last_used_at"`
+	CreatedAt  time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt  *time.Time `gorm:"type:timestamp;default:null;comment:撤销时间" json:"deleted_at"`
+}
+
+// ScopeList 权限范围列表
+func (t PersonalAccessToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return nil
+	}
+	return strings.Split(t.Scopes, ",")
+}
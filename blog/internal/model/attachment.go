@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Attachment 附件信息表（图片及其它上传文件）
+type Attachment struct {
+	gorm.Model
+	ID           uint       `gorm:"primary_key;auto_increment;comment:附件ID" json:"id"`
+	PostID       *uint      `gorm:"type:int;index;comment:所属文章ID，为空表示尚未关联" json:"post_id"`
+	UserID       uint       `gorm:"type:int;not_null;index;comment:上传用户ID" json:"user_id"`
+	Filename     string     `gorm:"type:varchar(255);not_null;comment:原始文件名" json:"filename"`
+	MimeType     string     `gorm:"type:varchar(100);not_null;comment:嗅探得到的MIME类型" json:"mime_type"`
+	Size         int64      `gorm:"type:bigint;not_null;comment:文件大小（字节）" json:"size"`
+	StorageKey   string     `gorm:"type:varchar(255);not_null;unique;comment:存储对象键" json:"-"`
+	ThumbnailKey string     `gorm:"type:varchar(255);comment:缩略图对象键" json:"-"`
+	Width        int        `gorm:"type:int;comment:图片宽度" json:"width,omitempty"`
+	Height       int        `gorm:"type:int;comment:图片高度" json:"height,omitempty"`
+	CreatedAt    time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt    *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
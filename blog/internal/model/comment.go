@@ -15,6 +15,7 @@ type Comment struct {
 	User      User       `gorm:"foreignKey:UserID;references:ID;comment:评论用户"` // 外键关联
 	PostID    uint       `gorm:"type:int;not_null;comment:文章ID" json:"post_id"`
 	Post      Post       `gorm:"foreignKey:PostID;references:ID;comment:评论文章"` // 外键关联
+	ParentID  *uint      `gorm:"type:int;index;comment:父评论ID，为空表示顶级评论" json:"parent_id"`
 	CreatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
 	UpdatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
 	DeletedAt *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
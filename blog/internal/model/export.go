@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ExportJob 用户数据导出任务表
+type ExportJob struct {
+	gorm.Model
+	ID          uint       `gorm:"primary_key;auto_increment;comment:任务ID" json:"id"`
+	UserID      uint       `gorm:"type:int;not_null;index;comment:用户ID" json:"user_id"`
+	Status      string     `gorm:"type:varchar(16);not_null;index;comment:状态（pending/running/completed/failed）" json:"status"`
+	StorageKey  string     `gorm:"type:varchar(255);comment:归档文件存储键" json:"-"`
+	DownloadKey string     `gorm:"type:varchar(64);comment:下载链接签名的随机因子，链接只对本任务有效" json:"-"`
+	Size        int64      `gorm:"type:bigint;not_null;default:0;comment:归档大小（字节）" json:"size"`
+	Error       string     `gorm:"type:varchar(255);comment:失败原因" json:"error,omitempty"`
+	StartedAt   *time.Time `gorm:"type:timestamp;default:null;comment:开始时间" json:"started_at"`
+	CompletedAt *time.Time `gorm:"type:timestamp;default:null;comment:完成时间" json:"completed_at"`
+	ExpiresAt   *time.Time `gorm:"type:timestamp;default:null;index;comment:归档过期时间，之后删除" json:"expires_at"`
+	CreatedAt   time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt   *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
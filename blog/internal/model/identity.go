@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Identity 第三方登录身份表，一个用户可绑定多个提供方账号
+type Identity struct {
+	gorm.Model
+	ID        uint       `gorm:"primary_key;auto_increment;comment:身份ID" json:"id"`
+	UserID    uint       `gorm:"type:int;not_null;index;comment:用户ID" json:"user_id"`
+	Provider  string     `gorm:"type:varchar(32);not_null;uniqueIndex:idx_identity_provider_subject;comment:提供方（google/github）" json:"provider"`
+	Subject   string     `gorm:"type:varchar(255);not_null;uniqueIndex:idx_identity_provider_subject;comment:提供方用户唯一标识" json:"-"`
+	Email     string     `gorm:"type:varchar(100);comment:提供方返回的邮箱" json:"email"`
+	CreatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:绑定时间" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
+
+// OAuthState 第三方登录授权请求表，保存 state 对应的 nonce 与 PKCE verifier，回调时一次性消费
+type OAuthState struct {
+	gorm.Model
+	ID           uint       `gorm:"primary_key;auto_increment;comment:记录ID" json:"id"`
+	StateHash    string     `gorm:"type:char(64);not_null;unique;comment:state的SHA-256哈希" json:"-"`
+	Provider     string     `gorm:"type:varchar(32);not_null;comment:提供方" json:"provider"`
+	Nonce        string     `gorm:"type:varchar(64);not_null;comment:OIDC nonce" json:"-"`
+	CodeVerifier string     `gorm:"type:varchar(128);not_null;comment:PKCE verifier" json:"-"`
+	LinkUserID   *uint      `gorm:"type:int;comment:绑定模式下发起绑定的用户ID" json:"link_user_id"`
+	ExpiresAt    time.Time  `gorm:"type:timestamp;not_null;index;comment:过期时间" json:"expires_at"`
+	CreatedAt    time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt    *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
+
+// TableName 默认命名会得到 o_auth_states
+func (OAuthState) TableName() string {
+	return "oauth_states"
+}
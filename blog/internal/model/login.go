@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginAttempt 登录尝试记录表，用于按账号和 IP 计算锁定状态
+type LoginAttempt struct {
+	gorm.Model
+	ID        uint       `gorm:"primary_key;auto_increment;comment:记录ID" json:"id"`
+	Username  string     `gorm:"type:varchar(50);not_null;index;comment:尝试登录的用户名（可能不存在）" json:"username"`
+	UserID    *uint      `gorm:"type:int;index;comment:匹配到的用户ID" json:"user_id"`
+	IP        string     `gorm:"type:varchar(64);not_null;index;comment:客户端IP" json:"ip"`
+	UserAgent string     `gorm:"type:varchar(255);comment:客户端UA" json:"user_agent"`
+	Success   bool       `gorm:"not_null;default:false;comment:是否登录成功" json:"success"`
+	Cleared   bool       `gorm:"not_null;default:false;comment:是否已被管理员解锁清除" json:"cleared"`
+	CreatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;index;comment:尝试时间" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
+
+// LoginSession 成功登录审计表，每次签发令牌记录一条
+type LoginSession struct {
+	gorm.Model
+	ID        uint       `gorm:"primary_key;auto_increment;comment:会话ID" json:"id"`
+	UserID    uint       `gorm:"type:int;not_null;index;comment:用户ID" json:"user_id"`
+	TokenID   string     `gorm:"type:varchar(64);not_null;unique;comment:令牌jti" json:"-"`
+	IP        string     `gorm:"type:varchar(64);not_null;comment:登录IP" json:"ip"`
+	UserAgent string     `gorm:"type:varchar(255);comment:登录UA" json:"user_agent"`
+	ExpiresAt time.Time  `gorm:"type:timestamp;not_null;comment:令牌过期时间" json:"expires_at"`
+	RevokedAt *time.Time `gorm:"type:timestamp;default:null;comment:吊销时间（修改密码等）" json:"revoked_at"`
+	CreatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:登录时间" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
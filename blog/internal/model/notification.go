@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification 站内通知表，也是通知推送（SSE）的消息格式
+type Notification struct {
+	gorm.Model
+	ID        uint       `gorm:"primary_key;auto_increment;comment:通知ID" json:"id"`
+	UserID    uint       `gorm:"type:int;not_null;index:idx_notification_user_read,priority:1;comment:接收通知的用户ID" json:"user_id"`
+	Type      string     `gorm:"type:varchar(32);not_null;comment:通知类型" json:"type"`
+	ActorID   uint       `gorm:"type:int;not_null;comment:触发通知的用户ID" json:"actor_id"`
+	PostID    uint       `gorm:"type:int;not_null;default:0;comment:相关文章ID" json:"post_id,omitempty"`
+	CommentID uint       `gorm:"type:int;not_null;default:0;comment:相关评论ID" json:"comment_id,omitempty"`
+	Excerpt   string     `gorm:"type:varchar(255);comment:内容摘要" json:"excerpt"`
+	ReadAt    *time.Time `gorm:"type:timestamp;default:null;index:idx_notification_user_read,priority:2;comment:已读时间，为空表示未读" json:"read_at"`
+	CreatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
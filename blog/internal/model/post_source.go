@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostSource 导入文章的来源记录，(来源, 来源ID) 唯一，用于重复导入时定位已有文章
+type PostSource struct {
+	gorm.Model
+	ID        uint       `gorm:"primary_key;auto_increment;comment:记录ID" json:"id"`
+	Source    string     `gorm:"type:varchar(64);not_null;uniqueIndex:idx_post_source;comment:来源（如 wordpress、hugo）" json:"source"`
+	SourceID  string     `gorm:"type:varchar(255);not_null;uniqueIndex:idx_post_source;comment:来源中的文章ID或路径" json:"source_id"`
+	PostID    uint       `gorm:"type:int;not_null;index;comment:文章ID" json:"post_id"`
+	CreatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode 两步验证恢复码表，只保存哈希，每个恢复码只能使用一次
+type RecoveryCode struct {
+	gorm.Model
+	ID        uint       `gorm:"primary_key;auto_increment;comment:恢复码ID" json:"id"`
+	UserID    uint       `gorm:"type:int;not_null;index;comment:用户ID" json:"user_id"`
+	CodeHash  string     `gorm:"type:char(64);not_null;unique;comment:恢复码SHA-256哈希" json:"-"`
+	UsedAt    *time.Time `gorm:"type:timestamp;default:null;comment:使用时间" json:"used_at"`
+	CreatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
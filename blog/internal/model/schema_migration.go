@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// SchemaMigration 已执行的数据库迁移记录，见 internal/migrate
+type SchemaMigration struct {
+	Version     string    `gorm:"primaryKey;type:varchar(100);comment:迁移版本" json:"version"`
+	Description string    `gorm:"type:varchar(255);comment:迁移说明" json:"description"`
+	AppliedAt   time.Time `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:执行时间" json:"applied_at"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SigningKey JWT 签名密钥表，多实例共享同一组密钥
+// 未退役的最新密钥用于签名；退役密钥在宽限期内仍用于验证，之后删除
+type SigningKey struct {
+	gorm.Model
+	ID         uint       `gorm:"primary_key;auto_increment;comment:密钥ID" json:"id"`
+	Kid        string     `gorm:"type:varchar(64);not_null;unique;comment:JWT头中的kid" json:"kid"`
+	Algorithm  string     `gorm:"type:varchar(16);not_null;comment:签名算法（RS256/ES256/EdDSA）" json:"algorithm"`
+	PrivateKey string     `gorm:"type:text;not_null;comment:AES-GCM加密的PKCS#8 PEM私钥，密钥不落库" json:"-"`
+	RetiredAt  *time.Time `gorm:"type:timestamp;default:null;index;comment:退役时间，之后只用于验证" json:"retired_at"`
+	CreatedAt  time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt  *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
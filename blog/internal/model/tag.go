@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tag 文章标签表，与文章通过 post_tags 多对多关联
+type Tag struct {
+	gorm.Model
+	ID        uint       `gorm:"primary_key;auto_increment;comment:标签ID" json:"id"`
+	Name      string     `gorm:"type:varchar(64);not_null;unique;comment:标签名" json:"name"`
+	CreatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
@@ -3,17 +3,39 @@ package model
 import (
 	"time"
 
+	"web-task/blog/dto"
+
 	"gorm.io/gorm"
 )
 
 // User 用户信息表
 type User struct {
 	gorm.Model
-	ID        uint       `gorm:"primary_key;auto_increment;comment:用户ID" json:"id"`
-	Username  string     `gorm:"type:varchar(50);not_null;unique;comment:用户名" json:"username"`
-	Password  string     `gorm:"type:varchar(255);not_null;comment:密码" json:"password"`
-	Email     string     `gorm:"type:varchar(100);not_null;unique;comment:电子邮箱" json:"email"`
-	CreatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:记录创建时间" json:"created_at"`
-	UpdatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:记录更新时间" json:"updated_at"`
-	DeletedAt *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+	ID              uint       `gorm:"primary_key;auto_increment;comment:用户ID" json:"id"`
+	Username        string     `gorm:"type:varchar(50);not_null;unique;comment:用户名" json:"username"`
+	Password        string     `gorm:"type:varchar(255);not_null;comment:密码" json:"-"`
+	Email           string     `gorm:"type:varchar(100);not_null;unique;comment:电子邮箱" json:"email"`
+	DisplayName     string     `gorm:"type:varchar(50);comment:显示名称" json:"display_name"`
+	Bio             string     `gorm:"type:varchar(500);comment:个人简介" json:"bio"`
+	AvatarURL       string     `gorm:"type:varchar(255);comment:头像地址" json:"avatar_url"`
+	Role            string     `gorm:"type:varchar(20);not_null;default:user;comment:角色（user/admin）" json:"role"`
+	EmailVerifiedAt *time.Time `gorm:"type:timestamp;default:null;comment:邮箱验证时间" json:"email_verified_at"`
+	TOTPSecret      string     `gorm:"type:varchar(64);comment:TOTP密钥（Base32）" json:"-"`
+	TOTPEnabledAt   *time.Time `gorm:"type:timestamp;default:null;comment:两步验证启用时间" json:"totp_enabled_at"`
+	TOTPLastCounter int64      `gorm:"type:bigint;not_null;default:0;comment:最近一次使用的TOTP时间步，防重放" json:"-"`
+	CreatedAt       time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:记录创建时间" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:记录更新时间" json:"updated_at"`
+	DeletedAt       *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
+
+// Public 用户的公开资料；文章与评论的作者在 REST 与 gRPC 接口中都按此返回，不包含邮箱、角色等字段
+func (u User) Public() dto.PublicUser {
+	return dto.PublicUser{
+		ID:          u.ID,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		AvatarURL:   u.AvatarURL,
+		CreatedAt:   u.CreatedAt,
+	}
 }
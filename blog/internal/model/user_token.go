@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserToken 一次性账号令牌表（邮箱验证、找回密码），只保存令牌哈希
+type UserToken struct {
+	gorm.Model
+	ID        uint       `gorm:"primary_key;auto_increment;comment:令牌ID" json:"id"`
+	UserID    uint       `gorm:"type:int;not_null;index;comment:用户ID" json:"user_id"`
+	Purpose   string     `gorm:"type:varchar(32);not_null;comment:用途（verify_email/reset_password）" json:"purpose"`
+	TokenHash string     `gorm:"type:char(64);not_null;unique;comment:令牌SHA-256哈希" json:"-"`
+	ExpiresAt time.Time  `gorm:"type:timestamp;not_null;comment:过期时间" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"type:timestamp;default:null;comment:使用时间" json:"used_at"`
+	CreatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
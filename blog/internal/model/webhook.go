@@ -0,0 +1,69 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook 用户注册的出站 webhook，接收其文章及文章下评论的事件
+type Webhook struct {
+	gorm.Model
+	ID          uint       `gorm:"primary_key;auto_increment;comment:webhook ID" json:"id"`
+	UserID      uint       `gorm:"type:int;not_null;index;comment:用户ID" json:"user_id"`
+	URL         string     `gorm:"type:varchar(2048);not_null;comment:接收地址" json:"url"`
+	Secret      string     `gorm:"type:varchar(64);not_null;comment:签名密钥" json:"-"`
+	Events      string     `gorm:"type:varchar(255);not_null;comment:订阅的事件类型，逗号分隔" json:"-"`
+	Active      bool       `gorm:"not_null;default:true;comment:是否启用" json:"active"`
+	Description string     `gorm:"type:varchar(255);comment:备注" json:"description"`
+	CreatedAt   time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt   *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
+
+// EventList 订阅的事件类型列表
+func (w Webhook) EventList() []string {
+	if w.Events == "" {
+		return nil
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribed 是否订阅了事件类型
+func (w Webhook) Subscribed(eventType string) bool {
+	return slices.Contains(w.EventList(), eventType)
+}
+
+// OutboxEvent 事件发件箱表，与产生事件的写操作在同一事务中写入，由后台任务分发给 webhook
+type OutboxEvent struct {
+	gorm.Model
+	ID           uint       `gorm:"primary_key;auto_increment;comment:事件ID" json:"id"`
+	Type         string     `gorm:"type:varchar(32);not_null;comment:事件类型" json:"type"`
+	UserID       uint       `gorm:"type:int;not_null;comment:事件所属用户（文章作者）" json:"user_id"`
+	Payload      string     `gorm:"type:text;not_null;comment:事件数据（JSON）" json:"-"`
+	DispatchedAt *time.Time `gorm:"type:timestamp;default:null;index;comment:分发时间，为空表示待分发" json:"dispatched_at"`
+	CreatedAt    time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt    *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
+
+// WebhookDelivery webhook 投递记录表，每个事件对每个订阅的 webhook 一条
+type WebhookDelivery struct {
+	gorm.Model
+	ID             uint       `gorm:"primary_key;auto_increment;comment:投递ID" json:"id"`
+	WebhookID      uint       `gorm:"type:int;not_null;index;comment:webhook ID" json:"webhook_id"`
+	EventID        uint       `gorm:"type:int;not_null;index;comment:事件ID" json:"event_id"`
+	EventType      string     `gorm:"type:varchar(32);not_null;comment:事件类型" json:"event_type"`
+	Status         string     `gorm:"type:varchar(16);not_null;index:idx_webhook_delivery_due,priority:1;comment:状态（pending/succeeded/dead）" json:"status"`
+	Attempts       int        `gorm:"type:int;not_null;default:0;comment:已尝试次数" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"type:timestamp;not_null;index:idx_webhook_delivery_due,priority:2;comment:下次尝试时间" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `gorm:"type:timestamp;default:null;comment:最近尝试时间" json:"last_attempt_at"`
+	ResponseStatus int        `gorm:"type:int;not_null;default:0;comment:最近一次响应状态码，0 表示未收到响应" json:"response_status"`
+	Error          string     `gorm:"type:varchar(255);comment:最近一次失败原因" json:"error,omitempty"`
+	DeliveredAt    *time.Time `gorm:"type:timestamp;default:null;comment:投递成功时间" json:"delivered_at"`
+	CreatedAt      time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"type:timestamp;not_null;default:CURRENT_TIMESTAMP;on_update:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	DeletedAt      *time.Time `gorm:"type:timestamp;default:null;comment:删除时间" json:"deleted_at"`
+}
@@ -0,0 +1,66 @@
+// internal/oidc/idtoken.go
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// idTokenClaims ID Token 中用到的声明
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     any    `json:"email_verified"` // 部分提供方返回字符串 "true"
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+}
+
+// VerifyIDToken 校验 ID Token 的签名（按 kid 从 JWKS 选择公钥）、iss、aud、exp 与 nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	if p.keys == nil {
+		return nil, fmt.Errorf("%w: provider has no jwks", ErrInvalidIDToken)
+	}
+
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.key(ctx, kid, t.Method.Alg())
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "PS256", "EdDSA"}),
+		jwt.WithIssuer(p.Metadata.Issuer),
+		jwt.WithAudience(p.Config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 || nonce == "" {
+		return nil, ErrNonceMismatch
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+
+	return &Claims{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: verified,
+		Username:      claims.PreferredUsername,
+		Name:          claims.Name,
+	}, nil
+}
@@ -0,0 +1,258 @@
+// internal/oidc/oidctest/provider.go
+// Package oidctest 提供内存中的模拟 OIDC 提供方，供测试和本地联调使用
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"web-task/blog/internal/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	ClientID     = "test-client"
+	ClientSecret = "test-secret"
+	keyID        = "oidctest-key"
+)
+
+// User 模拟登录的用户，授权端点会直接以该用户身份同意授权
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Name          string
+}
+
+type authRequest struct {
+	user          User
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// Provider 模拟提供方，实现发现文档、授权、令牌、用户信息和 JWKS 端点
+type Provider struct {
+	Server *httptest.Server
+
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	user  User
+	codes map[string]authRequest
+	// OverrideNonce 非空时 ID Token 使用该 nonce 而不是授权请求中的值，用于测试 nonce 校验
+	OverrideNonce *string
+}
+
+// NewProvider 启动模拟提供方，使用完毕后调用 Close
+func NewProvider() *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	p := &Provider{
+		key:   key,
+		codes: make(map[string]authRequest),
+		user:  User{Subject: "1001", Email: "alice@example.com", EmailVerified: true, Username: "alice", Name: "Alice"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/userinfo", p.userinfo)
+	mux.HandleFunc("/jwks", p.jwks)
+	p.Server = httptest.NewServer(mux)
+
+	return p
+}
+
+// Close 关闭模拟服务
+func (p *Provider) Close() {
+	p.Server.Close()
+}
+
+// Issuer 提供方标识
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+// DiscoveryURL 发现文档地址
+func (p *Provider) DiscoveryURL() string {
+	return p.Server.URL + "/.well-known/openid-configuration"
+}
+
+// Config 生成指向本模拟提供方的客户端配置
+func (p *Provider) Config(name, redirectURL string) oidc.Config {
+	return oidc.Config{
+		Name:         name,
+		ClientID:     ClientID,
+		ClientSecret: ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+		DiscoveryURL: p.DiscoveryURL(),
+	}
+}
+
+// SetUser 设置下一次授权使用的用户
+func (p *Provider) SetUser(u User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.user = u
+}
+
+// Authorize 模拟浏览器访问授权地址并同意授权，返回回调地址中的 code 和 state
+func (p *Provider) Authorize(authURL string) (code, state string, err error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", "", err
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state"), nil
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.Metadata{
+		Issuer:                p.Issuer(),
+		AuthorizationEndpoint: p.Server.URL + "/authorize",
+		TokenEndpoint:         p.Server.URL + "/token",
+		UserinfoEndpoint:      p.Server.URL + "/userinfo",
+		JWKSURI:               p.Server.URL + "/jwks",
+	})
+}
+
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != ClientID || q.Get("response_type") != "code" ||
+		q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code, _ := oidc.RandomString(16)
+	p.mu.Lock()
+	p.codes[code] = authRequest{
+		user:          p.user,
+		redirectURI:   q.Get("redirect_uri"),
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+	}
+	p.mu.Unlock()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	v := redirect.Query()
+	v.Set("code", code)
+	v.Set("state", q.Get("state"))
+	redirect.RawQuery = v.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	p.mu.Lock()
+	req, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code")) // 授权码只能使用一次
+	p.mu.Unlock()
+
+	switch {
+	case !ok || req.redirectURI != r.PostForm.Get("redirect_uri"):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case r.PostForm.Get("client_id") != ClientID || r.PostForm.Get("client_secret") != ClientSecret:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	case oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != req.codeChallenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "pkce verification failed"})
+		return
+	}
+
+	nonce := req.nonce
+	if p.OverrideNonce != nil {
+		nonce = *p.OverrideNonce
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                p.Issuer(),
+		"sub":                req.user.Subject,
+		"aud":                ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"nonce":              nonce,
+		"email":              req.user.Email,
+		"email_verified":     req.user.EmailVerified,
+		"preferred_username": req.user.Username,
+		"name":               req.user.Name,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, oidc.TokenResponse{
+		AccessToken: "access-" + req.user.Subject,
+		TokenType:   "Bearer",
+		IDToken:     idToken,
+		ExpiresIn:   3600,
+	})
+}
+
+func (p *Provider) userinfo(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	u := p.user
+	p.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer access-"+u.Subject {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sub":                u.Subject,
+		"email":              u.Email,
+		"email_verified":     u.EmailVerified,
+		"preferred_username": u.Username,
+		"name":               u.Name,
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	jwk, err := oidc.NewJWK(keyID, "RS256", &p.key.PublicKey)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, oidc.JWKS{Keys: []oidc.JWK{jwk}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
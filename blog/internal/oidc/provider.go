@@ -0,0 +1,283 @@
+// internal/oidc/provider.go
+// Package oidc 实现 OpenID Connect 依赖方（授权码 + PKCE），
+// 同时兼容 GitHub 这类只支持 OAuth2、通过用户信息接口识别用户的提供方
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidIDToken = errors.New("invalid id token")
+	ErrNonceMismatch  = errors.New("nonce mismatch")
+)
+
+// Config 提供方配置
+// 设置 DiscoveryURL 时从发现文档读取各端点；否则（如 GitHub）需显式配置 AuthURL、TokenURL、UserInfoURL
+type Config struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	DiscoveryURL string
+
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	// EmailsURL GitHub 风格的邮箱列表接口，用于获取私密邮箱及其验证状态
+	EmailsURL string
+	// UserInfo 字段映射，默认按 OIDC 标准声明 sub/email/preferred_username/name 读取
+	SubjectField  string
+	EmailField    string
+	UsernameField string
+}
+
+// Metadata 发现文档中用到的字段
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims 登录用户的身份信息
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Name          string
+}
+
+// TokenResponse 令牌端点响应
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	Error       string `json:"error"`
+	ErrorDesc   string `json:"error_description"`
+}
+
+// Provider 一个已初始化的身份提供方
+type Provider struct {
+	Config   Config
+	Metadata Metadata
+
+	client *http.Client
+	keys   *keySet
+}
+
+// NewProvider 构造函数；配置了 DiscoveryURL 时会请求发现文档
+func NewProvider(ctx context.Context, cfg Config, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	p := &Provider{Config: cfg, client: client}
+
+	if cfg.DiscoveryURL != "" {
+		if err := p.getJSON(ctx, cfg.DiscoveryURL, "", &p.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to fetch discovery document: %w", err)
+		}
+		if p.Metadata.AuthorizationEndpoint == "" || p.Metadata.TokenEndpoint == "" {
+			return nil, fmt.Errorf("discovery document of %s is missing endpoints", cfg.Name)
+		}
+	}
+	if cfg.AuthURL != "" {
+		p.Metadata.AuthorizationEndpoint = cfg.AuthURL
+	}
+	if cfg.TokenURL != "" {
+		p.Metadata.TokenEndpoint = cfg.TokenURL
+	}
+	if cfg.UserInfoURL != "" {
+		p.Metadata.UserinfoEndpoint = cfg.UserInfoURL
+	}
+	if p.Metadata.AuthorizationEndpoint == "" || p.Metadata.TokenEndpoint == "" {
+		return nil, fmt.Errorf("provider %s needs a discovery url or explicit endpoints", cfg.Name)
+	}
+	if p.Metadata.JWKSURI != "" {
+		p.keys = newKeySet(p.Metadata.JWKSURI, client)
+	}
+
+	return p, nil
+}
+
+// IsOIDC 是否支持 ID Token；不支持时通过用户信息接口识别用户
+func (p *Provider) IsOIDC() bool {
+	return p.keys != nil
+}
+
+// AuthCodeURL 生成授权地址，使用 S256 PKCE；nonce 仅对 OIDC 提供方有意义
+func (p *Provider) AuthCodeURL(state, nonce, codeVerifier string) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.Config.ClientID)
+	v.Set("redirect_uri", p.Config.RedirectURL)
+	v.Set("scope", strings.Join(p.Config.Scopes, " "))
+	v.Set("state", state)
+	v.Set("code_challenge", CodeChallenge(codeVerifier))
+	v.Set("code_challenge_method", "S256")
+	if p.IsOIDC() {
+		v.Set("nonce", nonce)
+	}
+
+	sep := "?"
+	if strings.Contains(p.Metadata.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.Metadata.AuthorizationEndpoint + sep + v.Encode()
+}
+
+// Exchange 用授权码和 PKCE verifier 换取令牌
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (*TokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.Config.RedirectURL)
+	form.Set("client_id", p.Config.ClientID)
+	form.Set("client_secret", p.Config.ClientSecret)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tok TokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tok.Error != "" {
+		return nil, fmt.Errorf("token endpoint returned %d: %s %s", resp.StatusCode, tok.Error, tok.ErrorDesc)
+	}
+	if tok.AccessToken == "" {
+		return nil, errors.New("token endpoint returned no access token")
+	}
+	return &tok, nil
+}
+
+// Identify 根据令牌响应确定用户身份
+// OIDC 提供方校验 ID Token（签名、iss、aud、exp、nonce）；其它提供方请求用户信息接口
+func (p *Provider) Identify(ctx context.Context, tok *TokenResponse, nonce string) (*Claims, error) {
+	if p.IsOIDC() {
+		if tok.IDToken == "" {
+			return nil, fmt.Errorf("%w: missing id_token", ErrInvalidIDToken)
+		}
+		claims, err := p.VerifyIDToken(ctx, tok.IDToken, nonce)
+		if err != nil {
+			return nil, err
+		}
+		// ID Token 不一定包含邮箱等资料，按需补充用户信息
+		if claims.Email == "" && p.Metadata.UserinfoEndpoint != "" {
+			if info, err := p.UserInfo(ctx, tok.AccessToken); err == nil && info.Subject == claims.Subject {
+				claims.Email, claims.EmailVerified = info.Email, info.EmailVerified
+				if claims.Username == "" {
+					claims.Username = info.Username
+				}
+			}
+		}
+		return claims, nil
+	}
+	return p.UserInfo(ctx, tok.AccessToken)
+}
+
+// UserInfo 请求用户信息接口
+func (p *Provider) UserInfo(ctx context.Context, accessToken string) (*Claims, error) {
+	if p.Metadata.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("provider %s has no userinfo endpoint", p.Config.Name)
+	}
+
+	var raw map[string]any
+	if err := p.getJSON(ctx, p.Metadata.UserinfoEndpoint, accessToken, &raw); err != nil {
+		return nil, fmt.Errorf("failed to fetch userinfo: %w", err)
+	}
+
+	claims := &Claims{
+		Subject:  stringClaim(raw, fieldOr(p.Config.SubjectField, "sub")),
+		Email:    stringClaim(raw, fieldOr(p.Config.EmailField, "email")),
+		Username: stringClaim(raw, fieldOr(p.Config.UsernameField, "preferred_username")),
+		Name:     stringClaim(raw, "name"),
+	}
+	claims.EmailVerified, _ = raw["email_verified"].(bool)
+	if claims.Subject == "" {
+		return nil, errors.New("userinfo response has no subject")
+	}
+
+	// 用户信息接口不提供验证状态时，以邮箱列表中的主邮箱为准
+	if p.Config.EmailsURL != "" {
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		if err := p.getJSON(ctx, p.Config.EmailsURL, accessToken, &emails); err != nil {
+			return nil, fmt.Errorf("failed to fetch emails: %w", err)
+		}
+		for _, e := range emails {
+			if e.Primary {
+				claims.Email, claims.EmailVerified = e.Email, e.Verified
+				break
+			}
+		}
+	}
+	return claims, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint, bearer string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// stringClaim 读取字符串声明，数字 ID（如 GitHub 的 id）转为十进制字符串
+func stringClaim(raw map[string]any, key string) string {
+	switch v := raw[key].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return ""
+	}
+}
+
+func fieldOr(field, fallback string) string {
+	if field == "" {
+		return fallback
+	}
+	return field
+}
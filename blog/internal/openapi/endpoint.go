@@ -0,0 +1,117 @@
+package openapi
+
+import "net/http"
+
+// Auth 接口的认证方式，决定文档中的 security 以及认证中间件可能返回的 401/403
+type Auth int
+
+const (
+	AuthNone     Auth = iota // 无需登录
+	AuthRequired             // 必须携带有效令牌（AuthMiddleware）
+	AuthOptional             // 可匿名访问，携带令牌时必须有效（OptionalAuthMiddleware）
+	AuthAdmin                // 需要管理员身份（AuthMiddleware + AdminMiddleware）
+)
+
+// Endpoint 一个处理器的接口描述，与路由注册一起生成文档
+type Endpoint struct {
+	// Handler 处理器的方法表达式，如 (*PostHandler).CreatePost，按函数名与注册的路由匹配
+	Handler any
+	// Method 同一处理器注册在多个方法上时用于区分，为空时匹配所有方法
+	Method string
+	// Path 处理器不是具名方法（如 gin.WrapH 包装的 http.Handler）时按路由路径匹配
+	Path string
+	// OperationID 默认为处理器方法名的小驼峰形式
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+
+	Auth Auth
+	// Scopes 个人访问令牌所需的权限范围，为空时不接受个人访问令牌
+	Scopes []string
+	// RateLimited 路由挂了限流中间件，可能返回 429
+	RateLimited bool
+
+	Query   []Param
+	Headers []Param
+	// Body JSON 请求体，取值同 Response.Body
+	Body any
+	// OptionalBody 请求体可以为空
+	OptionalBody bool
+	// Form multipart/form-data 请求体的字段
+	Form []Param
+
+	Responses []Response
+}
+
+// Param 查询参数、请求头或表单字段
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+	// Schema 为空时按字符串处理
+	Schema *Schema
+}
+
+// Response 一种响应
+type Response struct {
+	Status      int
+	Description string
+	// Body 响应体：某个类型的零值、*Schema，或 Envelope、AnyOf 的返回值；为空表示没有响应体
+	Body any
+	// ContentType 默认为 application/json
+	ContentType string
+	// Headers 响应头及其说明
+	Headers map[string]string
+}
+
+// MiddlewareError 中间件（认证、限流、按文档校验等）返回的错误
+type MiddlewareError struct {
+	Error          string   `json:"error"`
+	RequiredScopes []string `json:"required_scopes,omitempty"`
+}
+
+type envelope struct {
+	data any
+}
+
+// Envelope 描述 {code, msg, data} 格式的响应，data 为 nil 时表示只有 code 与 msg 的响应（错误或无数据的成功响应）
+func Envelope(data any) any {
+	return envelope{data: data}
+}
+
+type anyOf []any
+
+// AnyOf 描述可能是几种结构之一的响应，如登录成功返回令牌、需要两步验证时返回挑战令牌
+func AnyOf(values ...any) any {
+	return anyOf(values)
+}
+
+// Errors 按状态码批量描述错误响应，说明取 HTTP 状态码的标准文本
+func Errors(body any, statuses ...int) []Response {
+	responses := make([]Response, 0, len(statuses))
+	for _, status := range statuses {
+		responses = append(responses, Response{Status: status, Description: http.StatusText(status), Body: body})
+	}
+	return responses
+}
+
+// String 字符串
+func String() *Schema {
+	return &Schema{Type: Types{"string"}}
+}
+
+// Integer 整数
+func Integer() *Schema {
+	return &Schema{Type: Types{"integer"}}
+}
+
+// Boolean 布尔值
+func Boolean() *Schema {
+	return &Schema{Type: Types{"boolean"}}
+}
+
+// Binary 文件内容
+func Binary() *Schema {
+	return &Schema{Type: Types{"string"}, ContentMediaType: "application/octet-stream"}
+}
@@ -0,0 +1,456 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// schemaMode 同一类型用作请求体与响应时，必填字段的规则不同
+type schemaMode int
+
+const (
+	// modeResponse encoding/json 总会输出未标 omitempty 的字段，这些字段都视为必有
+	modeResponse schemaMode = iota
+	// modeRequest 必填字段以 binding:"required" 为准，其余 binding 规则转换为对应的约束
+	modeRequest
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	deletedAtType  = reflect.TypeOf(gorm.DeletedAt{})
+	fileHeaderType = reflect.TypeOf(multipart.FileHeader{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	marshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+type schemaKey struct {
+	t    reflect.Type
+	mode schemaMode
+}
+
+// generator 把 Go 类型按 encoding/json 的规则转换为 JSON Schema，具名结构体放入 components 并以 $ref 引用
+type generator struct {
+	schemas map[string]*Schema
+	names   map[schemaKey]string
+	err     error
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: map[string]*Schema{},
+		names:   map[schemaKey]string{},
+	}
+}
+
+// schemaOf 返回描述值 v 的结构：v 可以是某个类型的零值、*Schema，或 Envelope、AnyOf 的返回值
+func (g *generator) schemaOf(v any, mode schemaMode) *Schema {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case *Schema:
+		return v
+	case envelope:
+		return g.envelope(v.data, mode)
+	case anyOf:
+		s := &Schema{}
+		for _, item := range v {
+			s.AnyOf = append(s.AnyOf, g.schemaOf(item, mode))
+		}
+		return s
+	}
+	return g.typeSchema(reflect.TypeOf(v), mode, true)
+}
+
+// envelope 用户、身份等接口统一的 {code, msg, data} 响应；data 为 nil 时只有 code 与 msg
+func (g *generator) envelope(data any, mode schemaMode) *Schema {
+	if data == nil {
+		return g.component("Envelope", func() *Schema {
+			return &Schema{
+				Type:     Types{"object"},
+				Required: []string{"code", "msg"},
+				Properties: map[string]*Schema{
+					"code": Integer(),
+					"msg":  String(),
+				},
+			}
+		})
+	}
+	return &Schema{
+		Type:     Types{"object"},
+		Required: []string{"code", "msg", "data"},
+		Properties: map[string]*Schema{
+			"code": Integer(),
+			"msg":  String(),
+			"data": g.schemaOf(data, mode),
+		},
+	}
+}
+
+// component 注册不对应 Go 类型的公共结构
+func (g *generator) component(name string, build func() *Schema) *Schema {
+	if _, ok := g.schemas[name]; !ok {
+		g.schemas[name] = build()
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// typeSchema 返回类型 t 的结构；nullable 为 true 时指针、切片与 map 可为 null（nil 编码为 null）
+func (g *generator) typeSchema(t reflect.Type, mode schemaMode, nullable bool) *Schema {
+	if t.Kind() == reflect.Pointer {
+		s := g.typeSchema(t.Elem(), mode, false)
+		if nullable {
+			return orNull(s)
+		}
+		return s
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	case deletedAtType:
+		return orNull(&Schema{Type: Types{"string"}, Format: "date-time"})
+	case fileHeaderType:
+		return Binary()
+	case rawMessageType:
+		return &Schema{}
+	}
+	// 自定义编码的类型无法从结构推断
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		return &Schema{}
+	}
+
+	var s *Schema
+	switch t.Kind() {
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Integer()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = Integer()
+		s.Minimum = ptr(0.0)
+		return s
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.String:
+		return String()
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		return g.structRef(t, mode)
+	case reflect.Array:
+		return &Schema{Type: Types{"array"}, Items: g.typeSchema(t.Elem(), mode, true)}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			s = &Schema{Type: Types{"string"}, ContentEncoding: "base64"}
+		} else {
+			s = &Schema{Type: Types{"array"}, Items: g.typeSchema(t.Elem(), mode, true)}
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			g.fail(fmt.Errorf("unsupported map key type %s", t))
+		}
+		s = &Schema{Type: Types{"object"}, AdditionalProperties: g.typeSchema(t.Elem(), mode, true)}
+	default:
+		g.fail(fmt.Errorf("unsupported type %s", t))
+		return &Schema{}
+	}
+	if nullable {
+		return orNull(s)
+	}
+	return s
+}
+
+// structRef 具名结构体放入 components，匿名结构体直接内联
+func (g *generator) structRef(t reflect.Type, mode schemaMode) *Schema {
+	if t.Name() == "" {
+		return g.structSchema(t, mode)
+	}
+
+	key := schemaKey{t: t, mode: mode}
+	name, ok := g.names[key]
+	if !ok {
+		name = g.componentName(t, mode)
+		g.names[key] = name
+		// 先占位再生成，自引用的结构体（如评论引用文章、文章引用用户）得到同一个 $ref
+		s := &Schema{}
+		g.schemas[name] = s
+		*s = *g.structSchema(t, mode)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName 默认使用类型名，重名时加上包名，同一类型同时用于请求与响应时请求版本加 Input 后缀
+func (g *generator) componentName(t reflect.Type, mode schemaMode) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	candidates := []string{t.Name(), upperFirst(pkg) + t.Name()}
+	if mode == modeRequest {
+		candidates = append(candidates, t.Name()+"Input", upperFirst(pkg)+t.Name()+"Input")
+	}
+	for _, name := range candidates {
+		if _, taken := g.schemas[name]; !taken {
+			return name
+		}
+	}
+	g.fail(fmt.Errorf("schema name %s is used by several types", t.Name()))
+	return t.String()
+}
+
+func (g *generator) structSchema(t reflect.Type, mode schemaMode) *Schema {
+	s := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{}}
+	for _, f := range jsonFields(t) {
+		fs := g.typeSchema(f.typ, mode, !f.omitEmpty)
+		required := g.applyBinding(fs, f)
+		if mode == modeResponse {
+			required = !f.omitEmpty
+		}
+		if required {
+			s.Required = append(s.Required, f.name)
+		}
+		s.Properties[f.name] = fs
+	}
+	return s
+}
+
+// applyBinding 把 gin binding 规则转换为 JSON Schema 约束，返回字段是否必填
+func (g *generator) applyBinding(s *Schema, f field) (required bool) {
+	if f.binding == "" {
+		return false
+	}
+
+	kind := f.typ.Kind()
+	pointer := kind == reflect.Pointer
+	if pointer {
+		kind = f.typ.Elem().Kind()
+	}
+	rules := strings.Split(f.binding, ",")
+	// 非指针字段带 omitempty 时零值跳过校验，约束只能作用于非零值，这里不再声明
+	if !pointer && containsRule(rules, "omitempty") {
+		return containsRule(rules, "required")
+	}
+
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "url", "uri":
+			s.Format = "uri"
+		case "uuid":
+			s.Format = "uuid"
+		case "min", "gte":
+			setBound(s, kind, arg, true)
+		case "max", "lte":
+			setBound(s, kind, arg, false)
+		case "len":
+			setBound(s, kind, arg, true)
+			setBound(s, kind, arg, false)
+		case "oneof":
+			for _, v := range strings.Fields(arg) {
+				if kind == reflect.String {
+					s.Enum = append(s.Enum, v)
+				} else if n, err := strconv.ParseFloat(v, 64); err == nil {
+					s.Enum = append(s.Enum, n)
+				}
+			}
+			if pointer {
+				s.Enum = append(s.Enum, nil)
+			}
+		}
+	}
+	return required
+}
+
+func setBound(s *Schema, kind reflect.Kind, arg string, lower bool) {
+	n, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return
+	}
+	switch kind {
+	case reflect.String:
+		if lower {
+			s.MinLength = ptr(int(n))
+		} else {
+			s.MaxLength = ptr(int(n))
+		}
+	case reflect.Slice, reflect.Array:
+		if lower {
+			s.MinItems = ptr(int(n))
+		} else {
+			s.MaxItems = ptr(int(n))
+		}
+	case reflect.Map:
+	default:
+		if lower {
+			s.Minimum = ptr(n)
+		} else {
+			s.Maximum = ptr(n)
+		}
+	}
+}
+
+func containsRule(rules []string, name string) bool {
+	for _, r := range rules {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+// field 结构体按 encoding/json 规则展开后的一个 JSON 字段
+type field struct {
+	name      string
+	typ       reflect.Type
+	tagged    bool
+	depth     int
+	omitEmpty bool
+	binding   string
+}
+
+// jsonFields 按 encoding/json 的规则列出结构体编码后的字段：跳过 json:"-" 与未导出字段，
+// 内嵌结构体的字段提升到外层，同名字段取层级最浅的一个，同层级时取带标签的一个，仍无法区分时都忽略
+func jsonFields(t reflect.Type) []field {
+	var all []field
+	var walk func(t reflect.Type, depth int, seen map[reflect.Type]bool)
+	walk = func(t reflect.Type, depth int, seen map[reflect.Type]bool) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			ft := sf.Type
+			if sf.Anonymous && ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if sf.Anonymous {
+				if !sf.IsExported() && ft.Kind() != reflect.Struct {
+					continue
+				}
+			} else if !sf.IsExported() {
+				continue
+			}
+
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft, depth+1, seen)
+				continue
+			}
+
+			f := field{
+				name:      name,
+				typ:       sf.Type,
+				tagged:    name != "",
+				depth:     depth,
+				omitEmpty: containsRule(strings.Split(opts, ","), "omitempty"),
+				binding:   sf.Tag.Get("binding"),
+			}
+			if f.name == "" {
+				f.name = sf.Name
+			}
+			all = append(all, f)
+		}
+	}
+	walk(t, 0, map[reflect.Type]bool{})
+
+	byName := map[string][]field{}
+	var order []string
+	for _, f := range all {
+		if _, ok := byName[f.name]; !ok {
+			order = append(order, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+
+	fields := make([]field, 0, len(order))
+	for _, name := range order {
+		if f, ok := dominantField(byName[name]); ok {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func dominantField(candidates []field) (field, bool) {
+	depth := candidates[0].depth
+	for _, f := range candidates {
+		depth = min(depth, f.depth)
+	}
+	var shallow, tagged []field
+	for _, f := range candidates {
+		if f.depth != depth {
+			continue
+		}
+		shallow = append(shallow, f)
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	switch {
+	case len(shallow) == 1:
+		return shallow[0], true
+	case len(tagged) == 1:
+		return tagged[0], true
+	}
+	return field{}, false
+}
+
+// orNull 允许结构为 null
+func orNull(s *Schema) *Schema {
+	switch {
+	case s.Ref != "" || len(s.AnyOf) > 0:
+		return &Schema{AnyOf: []*Schema{s, {Type: Types{"null"}}}}
+	case len(s.Type) == 0:
+		return s
+	}
+	n := *s
+	n.Type = append(append(Types{}, s.Type...), "null")
+	return &n
+}
+
+func upperFirst(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
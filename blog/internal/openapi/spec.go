@@ -0,0 +1,341 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrNotDocumented   = errors.New("route is not documented")
+	ErrUnusedEndpoint  = errors.New("endpoint does not match any route")
+	ErrInvalidRequest  = errors.New("request does not match the API specification")
+	ErrInvalidResponse = errors.New("response does not match the API specification")
+)
+
+// 认证方式名称
+const (
+	securityBearer      = "bearerAuth"
+	securityAccessToken = "personalAccessToken"
+)
+
+var securitySchemes = map[string]SecurityScheme{
+	securityBearer: {
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description:  "登录或第三方登录返回的令牌",
+	},
+	securityAccessToken: {
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "个人访问令牌（blogpat_ 前缀），权限范围见各接口的 security",
+	},
+}
+
+// Spec 由路由注册与接口描述生成的文档及其校验器
+type Spec struct {
+	routes    func() gin.RoutesInfo
+	info      Info
+	tags      []Tag
+	endpoints []Endpoint
+
+	once       sync.Once
+	err        error
+	doc        *Document
+	raw        []byte
+	operations map[string]*compiledOperation
+}
+
+// NewSpec 创建文档；路由在之后注册，首次使用时（或调用 Build）才按 r 上的路由生成
+func NewSpec(r *gin.Engine, info Info, tags []Tag, endpoints []Endpoint) *Spec {
+	return &Spec{
+		routes:    r.Routes,
+		info:      info,
+		tags:      tags,
+		endpoints: endpoints,
+	}
+}
+
+// Build 按已注册的路由生成文档并编译校验用的结构，应在注册完所有路由后调用；
+// 有路由缺少描述或有描述没有对应的路由时返回错误。重复调用返回首次的结果
+func (s *Spec) Build() error {
+	s.once.Do(func() {
+		s.err = s.build()
+	})
+	return s.err
+}
+
+// Document 返回生成的文档
+func (s *Spec) Document() (*Document, error) {
+	if err := s.Build(); err != nil {
+		return nil, err
+	}
+	return s.doc, nil
+}
+
+// JSON 返回文档的 JSON 编码
+func (s *Spec) JSON() ([]byte, error) {
+	if err := s.Build(); err != nil {
+		return nil, err
+	}
+	return s.raw, nil
+}
+
+func (s *Spec) build() error {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    s.info,
+		Tags:    s.tags,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			SecuritySchemes: securitySchemes,
+		},
+	}
+
+	var errs []error
+	used := make([]bool, len(s.endpoints))
+	operationIDs := map[string]string{}
+	refs := map[string]operationRef{}
+	for _, route := range s.routes() {
+		i := s.match(route)
+		if i < 0 {
+			errs = append(errs, fmt.Errorf("%w: %s %s", ErrNotDocumented, route.Method, route.Path))
+			continue
+		}
+		used[i] = true
+
+		op := g.operation(route, s.endpoints[i])
+		if prev, ok := operationIDs[op.OperationID]; ok {
+			errs = append(errs, fmt.Errorf("duplicate operationId %q for %s and %s %s", op.OperationID, prev, route.Method, route.Path))
+		}
+		operationIDs[op.OperationID] = route.Method + " " + route.Path
+
+		path := openAPIPath(route.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(route.Method)] = op
+		refs[route.Method+" "+route.Path] = operationRef{path: path, method: strings.ToLower(route.Method)}
+	}
+	for i, e := range s.endpoints {
+		if !used[i] {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUnusedEndpoint, e.name()))
+		}
+	}
+	if g.err != nil {
+		errs = append(errs, g.err)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	doc.Components.Schemas = g.schemas
+
+	raw, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode openapi document: %w", err)
+	}
+	operations, err := compile(raw, doc, refs)
+	if err != nil {
+		return err
+	}
+
+	s.doc, s.raw, s.operations = doc, raw, operations
+	return nil
+}
+
+// match 找到与路由对应的接口描述，返回其下标，没有时返回 -1
+func (s *Spec) match(route gin.RouteInfo) int {
+	handler := strings.TrimSuffix(route.Handler, "-fm")
+	for i, e := range s.endpoints {
+		if e.Method != "" && e.Method != route.Method {
+			continue
+		}
+		if e.Handler != nil && funcName(e.Handler) == handler {
+			return i
+		}
+		if e.Handler == nil && e.Path == route.Path {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e Endpoint) name() string {
+	if e.Handler != nil {
+		return funcName(e.Handler)
+	}
+	return e.Method + " " + e.Path
+}
+
+// funcName 方法表达式的完整函数名，与 gin 记录的处理器名（方法值带 -fm 后缀）一致
+func funcName(f any) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// operation 生成单个接口，补上由中间件产生的响应
+func (g *generator) operation(route gin.RouteInfo, e Endpoint) *Operation {
+	op := &Operation{
+		OperationID: e.OperationID,
+		Summary:     e.Summary,
+		Description: e.Description,
+		Tags:        e.Tags,
+		Responses:   map[string]*ResponseObject{},
+	}
+	if op.OperationID == "" {
+		name := funcName(e.Handler)
+		op.OperationID = lowerFirst(name[strings.LastIndex(name, ".")+1:])
+	}
+
+	for _, seg := range strings.Split(route.Path, "/") {
+		if seg == "" || (seg[0] != ':' && seg[0] != '*') {
+			continue
+		}
+		name := seg[1:]
+		schema := String()
+		if name == "id" || strings.HasSuffix(name, "ID") {
+			schema = Integer()
+		}
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	for _, p := range e.Query {
+		op.Parameters = append(op.Parameters, p.parameter("query"))
+	}
+	for _, p := range e.Headers {
+		op.Parameters = append(op.Parameters, p.parameter("header"))
+	}
+
+	switch {
+	case e.Body != nil:
+		op.RequestBody = &RequestBody{
+			Required: !e.OptionalBody,
+			Content:  map[string]MediaType{"application/json": {Schema: g.schemaOf(e.Body, modeRequest)}},
+		}
+	case len(e.Form) > 0:
+		form := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{}}
+		for _, p := range e.Form {
+			form.Properties[p.Name] = p.schema()
+			if p.Required {
+				form.Required = append(form.Required, p.Name)
+			}
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"multipart/form-data": {Schema: form}},
+		}
+	}
+
+	for _, r := range e.Responses {
+		g.addResponse(op, r)
+	}
+
+	// 中间件产生的响应
+	middlewareError := func(status int, description string) {
+		g.addResponse(op, Response{Status: status, Description: description, Body: MiddlewareError{}})
+	}
+	if e.Body != nil {
+		middlewareError(http.StatusBadRequest, "请求体不符合接口文档")
+	}
+	if e.Auth != AuthNone {
+		bearer := SecurityRequirement{securityBearer: {}}
+		op.Security = []SecurityRequirement{bearer}
+		if len(e.Scopes) > 0 {
+			op.Security = append(op.Security, SecurityRequirement{securityAccessToken: e.Scopes})
+		}
+		if e.Auth == AuthOptional {
+			op.Security = append(op.Security, SecurityRequirement{})
+		}
+		middlewareError(http.StatusUnauthorized, "未登录或令牌无效")
+		middlewareError(http.StatusForbidden, "令牌权限不足")
+	}
+	if e.RateLimited {
+		middlewareError(http.StatusTooManyRequests, "请求过于频繁")
+	}
+	middlewareError(http.StatusInternalServerError, "服务端错误")
+	return op
+}
+
+// addResponse 添加响应；同一状态码已有响应时（如处理器与中间件都会返回 401）合并为 anyOf
+func (g *generator) addResponse(op *Operation, r Response) {
+	key := strconv.Itoa(r.Status)
+	resp, ok := op.Responses[key]
+	if !ok {
+		resp = &ResponseObject{Description: r.Description}
+		op.Responses[key] = resp
+	}
+
+	for name, description := range r.Headers {
+		if resp.Headers == nil {
+			resp.Headers = map[string]Header{}
+		}
+		resp.Headers[name] = Header{Description: description, Schema: String()}
+	}
+
+	if r.Body == nil {
+		return
+	}
+	contentType := r.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	schema := g.schemaOf(r.Body, modeResponse)
+	if resp.Content == nil {
+		resp.Content = map[string]MediaType{}
+	}
+	if existing, ok := resp.Content[contentType]; ok {
+		schema = mergeAnyOf(existing.Schema, schema)
+	}
+	resp.Content[contentType] = MediaType{Schema: schema}
+}
+
+// mergeAnyOf 合并两种结构，anyOf 可以直接展开
+func mergeAnyOf(a, b *Schema) *Schema {
+	merged := &Schema{}
+	for _, s := range []*Schema{a, b} {
+		if reflect.DeepEqual(*s, Schema{AnyOf: s.AnyOf}) && len(s.AnyOf) > 0 {
+			merged.AnyOf = append(merged.AnyOf, s.AnyOf...)
+		} else {
+			merged.AnyOf = append(merged.AnyOf, s)
+		}
+	}
+	return merged
+}
+
+func (p Param) parameter(in string) Parameter {
+	return Parameter{Name: p.Name, In: in, Description: p.Description, Required: p.Required, Schema: p.schema()}
+}
+
+func (p Param) schema() *Schema {
+	s := p.Schema
+	if s == nil {
+		s = String()
+	}
+	if p.Description != "" && s.Description == "" {
+		c := *s
+		c.Description = p.Description
+		s = &c
+	}
+	return s
+}
+
+// openAPIPath 把 gin 的 :id、*path 参数转换为 {id}、{path}
+func openAPIPath(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if seg != "" && (seg[0] == ':' || seg[0] == '*') {
+			segs[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
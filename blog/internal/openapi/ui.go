@@ -0,0 +1,41 @@
+package openapi
+
+import (
+	_ "embed"
+	"io/fs"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// 文档页面从 /openapi.json 读取文档；Swagger UI 的脚本与样式随程序一起编译（版本由 go.sum 锁定），
+// 不从第三方 CDN 加载，页面不会执行服务之外的代码
+var (
+	//go:embed ui/swagger.html
+	swaggerUI []byte
+)
+
+// uiAssets 页面引用的 Swagger UI 静态文件，只发布这些文件
+var uiAssets = map[string]string{
+	"swagger-ui-bundle.js": "text/javascript; charset=utf-8",
+	"swagger-ui.css":       "text/css; charset=utf-8",
+	"favicon-32x32.png":    "image/png",
+	"favicon-16x16.png":    "image/png",
+}
+
+// SwaggerUI 返回 Swagger UI 页面
+func SwaggerUI() []byte {
+	return swaggerUI
+}
+
+// UIAsset 返回文档页面引用的静态文件及其 Content-Type，不存在时 ok 为 false
+func UIAsset(name string) (data []byte, contentType string, ok bool) {
+	contentType, ok = uiAssets[name]
+	if !ok {
+		return nil, "", false
+	}
+	data, err := fs.ReadFile(swaggerFiles.FS, name)
+	if err != nil {
+		return nil, "", false
+	}
+	return data, contentType, true
+}
@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// documentURL 编译时文档的资源地址，仅用于解析 $ref
+const documentURL = "urn:blog:openapi"
+
+// compiledOperation 单个接口编译后的请求体与各状态码 JSON 响应的结构
+type compiledOperation struct {
+	body         *jsonschema.Schema
+	bodyRequired bool
+	responses    map[int]*jsonschema.Schema
+}
+
+// operationRef 接口在文档中的位置
+type operationRef struct {
+	path   string
+	method string
+}
+
+// compile 按文档中的 JSON 指针编译各接口的结构，refs 与返回值的键为 gin 的方法与路径，如 "GET /api/v1/posts/:id"
+func compile(raw []byte, doc *Document, refs map[string]operationRef) (map[string]*compiledOperation, error) {
+	resource, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode openapi document: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	if err := c.AddResource(documentURL, resource); err != nil {
+		return nil, fmt.Errorf("failed to load openapi document: %w", err)
+	}
+
+	operations := map[string]*compiledOperation{}
+	for key, ref := range refs {
+		path, method := ref.path, ref.method
+		op := (*doc.Paths[path])[method]
+		pointer := documentURL + "#/paths/" + escapePointer(path) + "/" + method
+		compiled := &compiledOperation{responses: map[int]*jsonschema.Schema{}}
+
+		if op.RequestBody != nil {
+			if _, ok := op.RequestBody.Content["application/json"]; ok {
+				compiled.body, err = c.Compile(pointer + "/requestBody/content/application~1json/schema")
+				if err != nil {
+					return nil, fmt.Errorf("failed to compile request body of %s %s: %w", method, path, err)
+				}
+				compiled.bodyRequired = op.RequestBody.Required
+			}
+		}
+
+		for status, resp := range op.Responses {
+			code, _ := strconv.Atoi(status)
+			compiled.responses[code] = nil
+			if _, ok := resp.Content["application/json"]; !ok {
+				continue
+			}
+			compiled.responses[code], err = c.Compile(pointer + "/responses/" + status + "/content/application~1json/schema")
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile %s response of %s %s: %w", status, method, path, err)
+			}
+		}
+
+		operations[key] = compiled
+	}
+	return operations, nil
+}
+
+// ValidateRequest 按文档校验 JSON 请求体；route 为 gin 注册的路径（c.FullPath()），未收录的路由不校验
+func (s *Spec) ValidateRequest(method, route string, body []byte) error {
+	if err := s.Build(); err != nil {
+		return err
+	}
+	op, ok := s.operations[method+" "+route]
+	if !ok || op.body == nil {
+		return nil
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.bodyRequired {
+			return fmt.Errorf("%w: request body is required", ErrInvalidRequest)
+		}
+		return nil
+	}
+	if err := validate(op.body, body); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRequest, err)
+	}
+	return nil
+}
+
+// ValidateResponse 校验响应状态码是否在文档中列出，JSON 响应体是否符合对应的结构
+func (s *Spec) ValidateResponse(method, route string, status int, contentType string, body []byte) error {
+	if err := s.Build(); err != nil {
+		return err
+	}
+	op, ok := s.operations[method+" "+route]
+	if !ok {
+		return nil
+	}
+
+	schema, ok := op.responses[status]
+	if !ok {
+		return fmt.Errorf("%w: status %d is not documented", ErrInvalidResponse, status)
+	}
+	if !IsJSON(contentType) || len(body) == 0 {
+		return nil
+	}
+	if schema == nil {
+		return fmt.Errorf("%w: status %d has no JSON body", ErrInvalidResponse, status)
+	}
+	if err := validate(schema, body); err != nil {
+		return fmt.Errorf("%w: status %d: %s", ErrInvalidResponse, status, err)
+	}
+	return nil
+}
+
+// IsJSON 判断内容类型是否为 JSON
+func IsJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// validate 校验 JSON 文档，返回的错误只包含各处不符合的位置与原因
+func validate(schema *jsonschema.Schema, body []byte) error {
+	inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	err = schema.Validate(inst)
+	if err == nil {
+		return nil
+	}
+	// 首行是 "jsonschema validation failed with '<schema 地址>'"，对调用方没有意义
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) > 1 {
+		lines = lines[1:]
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimSpace(line), "- ")
+	}
+	return fmt.Errorf("%s", strings.Join(lines, "; "))
+}
+
+// escapePointer 按 JSON 指针规则转义路径中的 ~ 与 /
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
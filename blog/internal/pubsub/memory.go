@@ -0,0 +1,111 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryHub 进程内的 Hub，只能推送给本实例上的订阅者
+type MemoryHub struct {
+	mu     sync.Mutex // 保护 topics 与各订阅的 channel，发送与关闭都在锁内进行
+	topics map[string]map[*memorySubscription]struct{}
+	closed bool
+}
+
+// NewMemoryHub 构造函数
+func NewMemoryHub() *MemoryHub {
+	return &MemoryHub{topics: make(map[string]map[*memorySubscription]struct{})}
+}
+
+func (h *MemoryHub) Publish(ctx context.Context, topic string, payload []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return ErrClosed
+	}
+
+	for sub := range h.topics[topic] {
+		select {
+		case sub.ch <- payload:
+		default:
+			h.removeLocked(sub, ErrSlowConsumer)
+		}
+	}
+	return nil
+}
+
+func (h *MemoryHub) Subscribe(ctx context.Context, topic string, buffer int) (Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	sub := &memorySubscription{hub: h, topic: topic, ch: make(chan []byte, max(buffer, 1))}
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*memorySubscription]struct{})
+	}
+	h.topics[topic][sub] = struct{}{}
+	return sub, nil
+}
+
+func (h *MemoryHub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	for _, subs := range h.topics {
+		for sub := range subs {
+			h.removeLocked(sub, ErrClosed)
+		}
+	}
+	return nil
+}
+
+// removeLocked 移除订阅并关闭其 channel，调用方持有 h.mu
+func (h *MemoryHub) removeLocked(sub *memorySubscription, err error) {
+	subs, ok := h.topics[sub.topic]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.topics, sub.topic)
+	}
+	sub.err = err
+	close(sub.ch)
+}
+
+type memorySubscription struct {
+	hub   *MemoryHub
+	topic string
+	ch    chan []byte
+	err   error // 在 hub.mu 内写入，channel 关闭后读取
+}
+
+func (s *memorySubscription) C() <-chan []byte {
+	return s.ch
+}
+
+func (s *memorySubscription) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.err
+}
+
+func (s *memorySubscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.removeLocked(s, nil)
+}
@@ -0,0 +1,37 @@
+// Package pubsub 发布订阅：把通知、评论等事件推送给 SSE、WebSocket 等长连接
+// Hub 只承诺至多一次投递，订阅者断开期间的消息不会补发，需要完整数据的一方应从数据库补齐；
+// 默认使用进程内实现，多实例部署时可替换为基于消息代理（如 Redis Pub/Sub、NATS）的实现
+package pubsub
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrClosed Hub 已关闭
+	ErrClosed = errors.New("pubsub: hub closed")
+	// ErrSlowConsumer 订阅者处理过慢、缓冲已满，订阅被关闭
+	ErrSlowConsumer = errors.New("pubsub: slow consumer")
+)
+
+// Hub 按主题发布与订阅消息
+type Hub interface {
+	// Publish 向主题的全部订阅者发布消息，不等待订阅者处理；订阅者不得修改 payload
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe 订阅主题，buffer 为缓冲的消息数；缓冲已满时订阅被关闭（ErrSlowConsumer），
+	// 由订阅者重新订阅并自行补齐，避免一个慢订阅者拖慢发布方或占用无限内存
+	Subscribe(ctx context.Context, topic string, buffer int) (Subscription, error)
+	// Close 关闭全部订阅（Err 返回 ErrClosed），之后的发布与订阅返回 ErrClosed
+	Close() error
+}
+
+// Subscription 一个订阅
+type Subscription interface {
+	// C 接收消息，订阅结束后关闭
+	C() <-chan []byte
+	// Err C 关闭后返回结束原因：调用 Close 结束时为 nil
+	Err() error
+	// Close 取消订阅，可重复调用
+	Close()
+}
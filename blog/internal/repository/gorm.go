@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// NewGorm 基于 gorm 的仓储与事务
+func NewGorm(db *gorm.DB) (Repositories, UnitOfWork) {
+	return newGormRepositories(db), &gormUnitOfWork{db: db}
+}
+
+func newGormRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		Users:    &gormUserRepository{db: db},
+		Posts:    &gormPostRepository{db: db},
+		Comments: &gormCommentRepository{db: db},
+		Outbox:   &gormOutboxRepository{db: db},
+
+		Notifications: &gormNotificationRepository{db: db},
+	}
+}
+
+type gormUnitOfWork struct {
+	db *gorm.DB
+}
+
+func (u *gormUnitOfWork) Do(ctx context.Context, fn func(repos Repositories) error) error {
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(newGormRepositories(tx))
+	})
+}
+
+// gormError 将 gorm.ErrRecordNotFound 转换为 ErrNotFound
+func gormError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// updateColumns 只更新指定列，同时刷新 updated_at
+func updateColumns(db *gorm.DB, value interface{}, columns []string) error {
+	return db.Model(value).Select(append(columns, "updated_at")).Updates(value).Error
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type gormCommentRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormCommentRepository) Create(ctx context.Context, comment *model.Comment) error {
+	return r.db.WithContext(ctx).Create(comment).Error
+}
+
+func (r *gormCommentRepository) FindByID(ctx context.Context, id uint, unscoped bool) (*model.Comment, error) {
+	db := r.db.WithContext(ctx)
+	if unscoped {
+		db = db.Unscoped()
+	}
+
+	var comment model.Comment
+	if err := db.First(&comment, id).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &comment, nil
+}
+
+func (r *gormCommentRepository) FindDetail(ctx context.Context, id uint) (*model.Comment, error) {
+	var comment model.Comment
+	if err := r.db.WithContext(ctx).Preload("User").Preload("Post").First(&comment, id).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &comment, nil
+}
+
+func (r *gormCommentRepository) ListByUser(ctx context.Context, userID uint) ([]model.Comment, error) {
+	var comments []model.Comment
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("created_at asc, id asc").
+		Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// ListByPosts 用窗口函数在数据库中按文章截断，评论很多的文章也只读取 perPost 行
+func (r *gormCommentRepository) ListByPosts(ctx context.Context, postIDs []uint, perPost int) ([]model.Comment, error) {
+	db := r.db.WithContext(ctx)
+	ranked := db.Model(&model.Comment{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY post_id ORDER BY created_at ASC, id ASC) AS post_rank").
+		Where("post_id IN ?", postIDs)
+
+	var comments []model.Comment
+	if err := db.Table("(?) AS ranked", ranked).
+		Where("post_rank <= ?", perPost).
+		Order("created_at asc, id asc").
+		Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *gormCommentRepository) Update(ctx context.Context, comment *model.Comment, columns ...string) error {
+	return updateColumns(r.db.WithContext(ctx), comment, columns)
+}
+
+func (r *gormCommentRepository) Delete(ctx context.Context, comment *model.Comment, withChildren bool) error {
+	db := r.db.WithContext(ctx)
+	if !withChildren {
+		return db.Delete(comment).Error
+	}
+
+	// 回复只有一层，子评论即 parent_id 指向该评论的回复
+	return db.Model(&model.Comment{}).Where("id = ? OR parent_id = ?", comment.ID, comment.ID).
+		UpdateColumn("deleted_at", gorm.Expr("CURRENT_TIMESTAMP")).Error
+}
+
+func (r *gormCommentRepository) Restore(ctx context.Context, comment *model.Comment, withChildren bool) error {
+	db := r.db.WithContext(ctx)
+	if !withChildren {
+		return db.Unscoped().Model(comment).UpdateColumn("deleted_at", nil).Error
+	}
+
+	return db.Unscoped().Model(&model.Comment{}).Where("id = ? OR parent_id = ?", comment.ID, comment.ID).
+		UpdateColumn("deleted_at", nil).Error
+}
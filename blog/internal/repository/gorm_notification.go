@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type gormNotificationRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormNotificationRepository) Create(ctx context.Context, notification *model.Notification) error {
+	return r.db.WithContext(ctx).Create(notification).Error
+}
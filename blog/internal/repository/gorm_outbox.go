@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type gormOutboxRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormOutboxRepository) Append(ctx context.Context, event *model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
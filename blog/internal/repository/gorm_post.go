@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type gormPostRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormPostRepository) Create(ctx context.Context, post *model.Post) error {
+	return r.db.WithContext(ctx).Create(post).Error
+}
+
+func (r *gormPostRepository) FindByID(ctx context.Context, id uint) (*model.Post, error) {
+	var post model.Post
+	if err := r.db.WithContext(ctx).First(&post, id).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &post, nil
+}
+
+func (r *gormPostRepository) FindByIDs(ctx context.Context, ids []uint) ([]model.Post, error) {
+	var posts []model.Post
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (r *gormPostRepository) FindDetail(ctx context.Context, id uint) (*model.Post, error) {
+	var post model.Post
+	if err := r.db.WithContext(ctx).Preload("User").Preload("Attachments").Preload("Tags").First(&post, id).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &post, nil
+}
+
+func (r *gormPostRepository) List(ctx context.Context, offset, limit int) ([]model.Post, int64, error) {
+	db := r.db.WithContext(ctx)
+
+	var total int64
+	if err := db.Model(&model.Post{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var posts []model.Post
+	if err := db.Preload("User").
+		Limit(limit).
+		Offset(offset).
+		Order("created_at desc").
+		Find(&posts).Error; err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+func (r *gormPostRepository) ListByUser(ctx context.Context, userID uint) ([]model.Post, error) {
+	var posts []model.Post
+	if err := r.db.WithContext(ctx).Preload("Attachments").Preload("Tags").
+		Where("user_id = ?", userID).
+		Order("created_at asc, id asc").
+		Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (r *gormPostRepository) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Post{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *gormPostRepository) Update(ctx context.Context, post *model.Post, columns ...string) error {
+	return updateColumns(r.db.WithContext(ctx), post, columns)
+}
+
+func (r *gormPostRepository) Delete(ctx context.Context, post *model.Post) error {
+	return r.db.WithContext(ctx).Delete(post).Error
+}
+
+func (r *gormPostRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.Post{}).Error
+}
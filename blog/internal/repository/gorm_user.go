@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) FindByID(ctx context.Context, id uint) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) FindByIDs(ctx context.Context, ids []uint) ([]model.User, error) {
+	var users []model.User
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *gormUserRepository) FindByUsername(ctx context.Context, username string) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) EmailInUse(ctx context.Context, email string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.User{}).Where("email = ? AND id <> ?", email, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormUserRepository) Create(ctx context.Context, user *model.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *gormUserRepository) Update(ctx context.Context, user *model.User, columns ...string) error {
+	return updateColumns(r.db.WithContext(ctx), user, columns)
+}
+
+func (r *gormUserRepository) AdvanceTOTPCounter(ctx context.Context, userID uint, counter int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.User{}).
+		Where("id = ? AND totp_last_counter < ?", userID, counter).
+		Update("totp_last_counter", counter)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormUserRepository) Delete(ctx context.Context, user *model.User) error {
+	return r.db.WithContext(ctx).Delete(user).Error
+}
+
+func (r *gormUserRepository) PurgeAccountData(ctx context.Context, user *model.User) error {
+	db := r.db.WithContext(ctx)
+
+	// 先删除 webhook 的投递记录，之后不再向用户注册的地址发送任何事件
+	hooks := db.Unscoped().Model(&model.Webhook{}).Select("id").Where("user_id = ?", user.ID)
+	if err := db.Unscoped().Where("webhook_id IN (?)", hooks).Delete(&model.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	for _, m := range []interface{}{&model.PersonalAccessToken{}, &model.Identity{}, &model.RecoveryCode{}, &model.UserToken{},
+		&model.Webhook{}, &model.Notification{}} {
+		if err := db.Unscoped().Where("user_id = ?", user.ID).Delete(m).Error; err != nil {
+			return err
+		}
+	}
+	// 已生成的导出归档立即过期，由导出任务的清理流程删除
+	if err := db.Model(&model.ExportJob{}).Where("user_id = ? AND status = ?", user.ID, consts.ExportStatusCompleted).
+		Update("expires_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return db.Unscoped().Where("user_id = ? OR username = ?", user.ID, user.Username).
+		Delete(&model.LoginAttempt{}).Error
+}
+
+func (r *gormUserRepository) CreateLoginAttempt(ctx context.Context, attempt *model.LoginAttempt) error {
+	return r.db.WithContext(ctx).Create(attempt).Error
+}
+
+func (r *gormUserRepository) LastLoginSuccess(ctx context.Context, username string, since time.Time) (time.Time, error) {
+	var last model.LoginAttempt
+	err := r.db.WithContext(ctx).Select("created_at").
+		Where("username = ?", username).
+		Where("success = ? AND created_at > ?", true, since).
+		Order("created_at desc").
+		Limit(1).
+		Find(&last).Error
+	return last.CreatedAt, err
+}
+
+func (r *gormUserRepository) CountLoginFailures(ctx context.Context, filter LoginFailureFilter) (int64, time.Time, error) {
+	failures := r.db.WithContext(ctx).Model(&model.LoginAttempt{}).
+		Where("success = ? AND cleared = ? AND created_at > ?", false, false, filter.Since)
+	if filter.Username != "" {
+		failures = failures.Where("username = ?", filter.Username)
+	} else {
+		failures = failures.Where("ip = ?", filter.IP)
+	}
+	failures = failures.Session(&gorm.Session{})
+
+	var count int64
+	if err := failures.Count(&count).Error; err != nil {
+		return 0, time.Time{}, err
+	}
+	if count == 0 {
+		return 0, time.Time{}, nil
+	}
+
+	var last model.LoginAttempt
+	if err := failures.Select("created_at").
+		Order("created_at desc").
+		Limit(1).
+		Find(&last).Error; err != nil {
+		return 0, time.Time{}, err
+	}
+	return count, last.CreatedAt, nil
+}
+
+func (r *gormUserRepository) ClearLoginFailures(ctx context.Context, username, ip string) error {
+	db := r.db.WithContext(ctx).Model(&model.LoginAttempt{}).Where("success = ? AND cleared = ?", false, false)
+	if ip != "" {
+		db = db.Where("username = ? OR ip = ?", username, ip)
+	} else {
+		db = db.Where("username = ?", username)
+	}
+	return db.Update("cleared", true).Error
+}
+
+func (r *gormUserRepository) CreateSession(ctx context.Context, session *model.LoginSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *gormUserRepository) ListSessions(ctx context.Context, userID uint, limit int) ([]model.LoginSession, error) {
+	var sessions []model.LoginSession
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *gormUserRepository) RevokeSessions(ctx context.Context, userID uint) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&model.LoginSession{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Update("revoked_at", now).Error
+}
+
+func (r *gormUserRepository) SessionActive(ctx context.Context, tokenID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.LoginSession{}).
+		Where("token_id = ? AND revoked_at IS NULL", tokenID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormUserRepository) CreateAccountToken(ctx context.Context, token *model.UserToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *gormUserRepository) ConsumeAccountToken(ctx context.Context, tokenHash string, userID uint, purpose string) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.UserToken{}).
+		Where("token_hash = ? AND user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?",
+			tokenHash, userID, purpose, now).
+		Update("used_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormUserRepository) RevokeAccountTokens(ctx context.Context, userID uint, purpose string) error {
+	return r.db.WithContext(ctx).Model(&model.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+func (r *gormUserRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []model.RecoveryCode) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Unscoped().Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return db.Create(&codes).Error
+}
+
+func (r *gormUserRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormUserRepository) DeleteRecoveryCodes(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+}
+
+func (r *gormUserRepository) CountActiveAccessTokens(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.PersonalAccessToken{}).
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *gormUserRepository) CreateAccessToken(ctx context.Context, token *model.PersonalAccessToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *gormUserRepository) ListAccessTokens(ctx context.Context, userID uint) ([]model.PersonalAccessToken, error) {
+	var tokens []model.PersonalAccessToken
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at desc").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *gormUserRepository) RevokeAccessToken(ctx context.Context, userID, tokenID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", tokenID, userID).Delete(&model.PersonalAccessToken{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormUserRepository) FindAccessTokenByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	var token model.PersonalAccessToken
+	if err := r.db.WithContext(ctx).
+		Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).
+		First(&token).Error; err != nil {
+		return nil, gormError(err)
+	}
+	return &token, nil
+}
+
+func (r *gormUserRepository) TouchAccessToken(ctx context.Context, tokenID uint, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.PersonalAccessToken{}).
+		Where("id = ?", tokenID).
+		UpdateColumn("last_used_at", usedAt).Error
+}
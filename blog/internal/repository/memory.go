@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm/schema"
+)
+
+// NewMemory 基于内存的仓储与事务，用于不依赖数据库的单元测试
+// 事务之间串行执行，fn 返回错误时恢复到事务开始前的快照（事务外的并发写入不隔离）；
+// 只保存用户、文章、评论、发件箱事件、通知及用户的认证数据，文章的附件与标签原样保存、不做关联查询
+func NewMemory() (Repositories, UnitOfWork) {
+	s := &memoryStore{
+		users:         newTable[model.User](),
+		posts:         newTable[model.Post](),
+		comments:      newTable[model.Comment](),
+		attempts:      newTable[model.LoginAttempt](),
+		sessions:      newTable[model.LoginSession](),
+		accountTokens: newTable[model.UserToken](),
+		recoveryCodes: newTable[model.RecoveryCode](),
+		accessTokens:  newTable[model.PersonalAccessToken](),
+		outbox:        newTable[model.OutboxEvent](),
+		notifications: newTable[model.Notification](),
+	}
+	repos := Repositories{
+		Users:    &memoryUserRepository{s: s},
+		Posts:    &memoryPostRepository{s: s},
+		Comments: &memoryCommentRepository{s: s},
+		Outbox:   &memoryOutboxRepository{s: s},
+
+		Notifications: &memoryNotificationRepository{s: s},
+	}
+	return repos, &memoryUnitOfWork{s: s, repos: repos}
+}
+
+type memoryStore struct {
+	txMu sync.Mutex // 串行化事务
+	mu   sync.Mutex // 保护下面的表
+
+	users         *table[model.User]
+	posts         *table[model.Post]
+	comments      *table[model.Comment]
+	attempts      *table[model.LoginAttempt]
+	sessions      *table[model.LoginSession]
+	accountTokens *table[model.UserToken]
+	recoveryCodes *table[model.RecoveryCode]
+	accessTokens  *table[model.PersonalAccessToken]
+	outbox        *table[model.OutboxEvent]
+	notifications *table[model.Notification]
+}
+
+// lock 检查 ctx 后加锁，使内存实现与数据库一样响应取消
+func (s *memoryStore) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	return nil
+}
+
+type memorySnapshot struct {
+	users         table[model.User]
+	posts         table[model.Post]
+	comments      table[model.Comment]
+	attempts      table[model.LoginAttempt]
+	sessions      table[model.LoginSession]
+	accountTokens table[model.UserToken]
+	recoveryCodes table[model.RecoveryCode]
+	accessTokens  table[model.PersonalAccessToken]
+	outbox        table[model.OutboxEvent]
+	notifications table[model.Notification]
+}
+
+func (s *memoryStore) snapshot() memorySnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return memorySnapshot{
+		users:         s.users.clone(),
+		posts:         s.posts.clone(),
+		comments:      s.comments.clone(),
+		attempts:      s.attempts.clone(),
+		sessions:      s.sessions.clone(),
+		accountTokens: s.accountTokens.clone(),
+		recoveryCodes: s.recoveryCodes.clone(),
+		accessTokens:  s.accessTokens.clone(),
+		outbox:        s.outbox.clone(),
+		notifications: s.notifications.clone(),
+	}
+}
+
+func (s *memoryStore) restore(snap memorySnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*s.users = snap.users
+	*s.posts = snap.posts
+	*s.comments = snap.comments
+	*s.attempts = snap.attempts
+	*s.sessions = snap.sessions
+	*s.accountTokens = snap.accountTokens
+	*s.recoveryCodes = snap.recoveryCodes
+	*s.accessTokens = snap.accessTokens
+	*s.outbox = snap.outbox
+	*s.notifications = snap.notifications
+}
+
+type memoryUnitOfWork struct {
+	s     *memoryStore
+	repos Repositories
+}
+
+func (u *memoryUnitOfWork) Do(ctx context.Context, fn func(repos Repositories) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	u.s.txMu.Lock()
+	defer u.s.txMu.Unlock()
+
+	snap := u.s.snapshot()
+	if err := fn(u.repos); err != nil {
+		u.s.restore(snap)
+		return err
+	}
+	return nil
+}
+
+// table 一张内存表，行按值保存，读写都复制，调用方修改返回值不影响存储
+type table[T any] struct {
+	rows   map[uint]T
+	nextID uint
+}
+
+func newTable[T any]() *table[T] {
+	return &table[T]{rows: make(map[uint]T)}
+}
+
+func (t *table[T]) clone() table[T] {
+	return table[T]{rows: maps.Clone(t.rows), nextID: t.nextID}
+}
+
+// insert 分配自增ID并设置创建、更新时间（未设置时），写回 row
+func (t *table[T]) insert(row *T) {
+	t.nextID++
+	v := reflect.ValueOf(row).Elem()
+	v.FieldByName("ID").SetUint(uint64(t.nextID))
+	now := time.Now()
+	for _, name := range []string{"CreatedAt", "UpdatedAt"} {
+		if f := v.FieldByName(name); f.Interface().(time.Time).IsZero() {
+			f.Set(reflect.ValueOf(now))
+		}
+	}
+	t.rows[uint(t.nextID)] = *row
+}
+
+// get 按ID获取未软删除的行
+func (t *table[T]) get(id uint, unscoped bool) (T, bool) {
+	row, ok := t.rows[id]
+	if !ok || (!unscoped && deleted(&row)) {
+		var zero T
+		return zero, false
+	}
+	return row, true
+}
+
+// find 按ID顺序返回满足条件的未软删除行
+func (t *table[T]) find(match func(*T) bool) []T {
+	ids := slices.Sorted(maps.Keys(t.rows))
+	var result []T
+	for _, id := range ids {
+		row := t.rows[id]
+		if !deleted(&row) && match(&row) {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+// update 修改满足条件的未软删除行，返回修改的行数
+func (t *table[T]) update(match func(*T) bool, apply func(*T)) int {
+	n := 0
+	for id, row := range t.rows {
+		if !deleted(&row) && match(&row) {
+			apply(&row)
+			t.rows[id] = row
+			n++
+		}
+	}
+	return n
+}
+
+// softDelete 软删除满足条件的行，返回删除的行数
+func (t *table[T]) softDelete(match func(*T) bool) int {
+	now := time.Now()
+	return t.update(match, func(row *T) {
+		reflect.ValueOf(row).Elem().FieldByName("DeletedAt").Set(reflect.ValueOf(&now))
+	})
+}
+
+// purge 物理删除满足条件的行（包括已软删除的行）
+func (t *table[T]) purge(match func(*T) bool) {
+	for id, row := range t.rows {
+		if match(&row) {
+			delete(t.rows, id)
+		}
+	}
+}
+
+// saveColumns 把 src 中 columns 指定的列及 updated_at 写入ID相同的存储行，并回写 src 的更新时间
+func (t *table[T]) saveColumns(src *T, columns []string) error {
+	v := reflect.ValueOf(src).Elem()
+	id := uint(v.FieldByName("ID").Uint())
+	row, ok := t.get(id, false)
+	if !ok {
+		return nil // 与数据库一致：更新不存在的行不报错
+	}
+
+	sch, err := schema.Parse(src, &schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	dst := reflect.ValueOf(&row).Elem()
+	for _, column := range columns {
+		field := sch.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("unknown column %q", column)
+		}
+		dst.FieldByName(field.Name).Set(v.FieldByName(field.Name))
+	}
+	now := reflect.ValueOf(time.Now())
+	dst.FieldByName("UpdatedAt").Set(now)
+	v.FieldByName("UpdatedAt").Set(now)
+	t.rows[id] = row
+	return nil
+}
+
+var schemaCache sync.Map
+
+func deleted[T any](row *T) bool {
+	return !reflect.ValueOf(row).Elem().FieldByName("DeletedAt").IsNil()
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"slices"
+
+	"web-task/blog/internal/model"
+)
+
+type memoryCommentRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryCommentRepository) Create(ctx context.Context, comment *model.Comment) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.comments.insert(comment)
+	return nil
+}
+
+func (r *memoryCommentRepository) FindByID(ctx context.Context, id uint, unscoped bool) (*model.Comment, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	comment, ok := r.s.comments.get(id, unscoped)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &comment, nil
+}
+
+func (r *memoryCommentRepository) FindDetail(ctx context.Context, id uint) (*model.Comment, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	comment, ok := r.s.comments.get(id, false)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	comment.User, _ = r.s.users.get(comment.UserID, false)
+	comment.Post, _ = r.s.posts.get(comment.PostID, false)
+	return &comment, nil
+}
+
+func (r *memoryCommentRepository) ListByUser(ctx context.Context, userID uint) ([]model.Comment, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	comments := r.s.comments.find(func(c *model.Comment) bool { return c.UserID == userID })
+	slices.SortStableFunc(comments, func(a, b model.Comment) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return comments, nil
+}
+
+func (r *memoryCommentRepository) ListByPosts(ctx context.Context, postIDs []uint, perPost int) ([]model.Comment, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	comments := r.s.comments.find(func(c *model.Comment) bool { return slices.Contains(postIDs, c.PostID) })
+	slices.SortStableFunc(comments, func(a, b model.Comment) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	counts := make(map[uint]int, len(postIDs))
+	return slices.DeleteFunc(comments, func(c model.Comment) bool {
+		counts[c.PostID]++
+		return counts[c.PostID] > perPost
+	}), nil
+}
+
+func (r *memoryCommentRepository) Update(ctx context.Context, comment *model.Comment, columns ...string) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	return r.s.comments.saveColumns(comment, columns)
+}
+
+func (r *memoryCommentRepository) Delete(ctx context.Context, comment *model.Comment, withChildren bool) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.comments.softDelete(func(c *model.Comment) bool { return withFamily(c, comment.ID, withChildren) })
+	return nil
+}
+
+func (r *memoryCommentRepository) Restore(ctx context.Context, comment *model.Comment, withChildren bool) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	for id, row := range r.s.comments.rows {
+		if withFamily(&row, comment.ID, withChildren) {
+			row.DeletedAt = nil
+			r.s.comments.rows[id] = row
+		}
+	}
+	return nil
+}
+
+// withFamily 是否为该评论本身，或 withChildren 时为其回复
+func withFamily(c *model.Comment, id uint, withChildren bool) bool {
+	return c.ID == id || withChildren && c.ParentID != nil && *c.ParentID == id
+}
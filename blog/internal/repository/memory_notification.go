@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"web-task/blog/internal/model"
+)
+
+type memoryNotificationRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryNotificationRepository) Create(ctx context.Context, notification *model.Notification) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.notifications.insert(notification)
+	return nil
+}
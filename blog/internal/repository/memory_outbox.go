@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"web-task/blog/internal/model"
+)
+
+type memoryOutboxRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryOutboxRepository) Append(ctx context.Context, event *model.OutboxEvent) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.outbox.insert(event)
+	return nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"slices"
+
+	"web-task/blog/internal/model"
+)
+
+type memoryPostRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryPostRepository) Create(ctx context.Context, post *model.Post) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.posts.insert(post)
+	return nil
+}
+
+func (r *memoryPostRepository) FindByID(ctx context.Context, id uint) (*model.Post, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	post, ok := r.s.posts.get(id, false)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &post, nil
+}
+
+func (r *memoryPostRepository) FindByIDs(ctx context.Context, ids []uint) ([]model.Post, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	return r.s.posts.find(func(p *model.Post) bool { return slices.Contains(ids, p.ID) }), nil
+}
+
+func (r *memoryPostRepository) FindDetail(ctx context.Context, id uint) (*model.Post, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	post, ok := r.s.posts.get(id, false)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	r.s.withAuthor(&post)
+	return &post, nil
+}
+
+func (r *memoryPostRepository) List(ctx context.Context, offset, limit int) ([]model.Post, int64, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, 0, err
+	}
+	defer r.s.mu.Unlock()
+
+	posts := r.s.posts.find(func(*model.Post) bool { return true })
+	total := int64(len(posts))
+	slices.SortStableFunc(posts, func(a, b model.Post) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	posts = page(posts, offset, limit)
+	for i := range posts {
+		r.s.withAuthor(&posts[i])
+	}
+	return posts, total, nil
+}
+
+func (r *memoryPostRepository) ListByUser(ctx context.Context, userID uint) ([]model.Post, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	posts := r.s.posts.find(func(p *model.Post) bool { return p.UserID == userID })
+	slices.SortStableFunc(posts, func(a, b model.Post) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return posts, nil
+}
+
+func (r *memoryPostRepository) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.s.mu.Unlock()
+
+	return int64(len(r.s.posts.find(func(p *model.Post) bool { return p.UserID == userID }))), nil
+}
+
+func (r *memoryPostRepository) Update(ctx context.Context, post *model.Post, columns ...string) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	return r.s.posts.saveColumns(post, columns)
+}
+
+func (r *memoryPostRepository) Delete(ctx context.Context, post *model.Post) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.posts.softDelete(func(p *model.Post) bool { return p.ID == post.ID })
+	return nil
+}
+
+func (r *memoryPostRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.posts.softDelete(func(p *model.Post) bool { return p.UserID == userID })
+	return nil
+}
+
+// withAuthor 关联文章作者，调用方持有锁
+func (s *memoryStore) withAuthor(post *model.Post) {
+	post.User, _ = s.users.get(post.UserID, false)
+}
+
+// page 按 offset、limit 截取，limit < 0 表示不限制
+func page[T any](rows []T, offset, limit int) []T {
+	if offset >= len(rows) {
+		return nil
+	}
+	rows = rows[offset:]
+	if limit >= 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
@@ -0,0 +1,406 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"web-task/blog/internal/model"
+)
+
+type memoryUserRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, id uint) (*model.User, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	user, ok := r.s.users.get(id, false)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) FindByIDs(ctx context.Context, ids []uint) ([]model.User, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	return r.s.users.find(func(u *model.User) bool { return slices.Contains(ids, u.ID) }), nil
+}
+
+func (r *memoryUserRepository) FindByUsername(ctx context.Context, username string) (*model.User, error) {
+	return r.findOne(ctx, func(u *model.User) bool { return u.Username == username })
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.findOne(ctx, func(u *model.User) bool { return u.Email == email })
+}
+
+func (r *memoryUserRepository) findOne(ctx context.Context, match func(*model.User) bool) (*model.User, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	users := r.s.users.find(match)
+	if len(users) == 0 {
+		return nil, ErrNotFound
+	}
+	return &users[0], nil
+}
+
+func (r *memoryUserRepository) EmailInUse(ctx context.Context, email string, excludeID uint) (bool, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer r.s.mu.Unlock()
+
+	return len(r.s.users.find(func(u *model.User) bool { return u.Email == email && u.ID != excludeID })) > 0, nil
+}
+
+// Create 与数据库的唯一索引一致，用户名、邮箱与已删除的用户也不能重复
+func (r *memoryUserRepository) Create(ctx context.Context, user *model.User) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	for _, u := range r.s.users.rows {
+		if u.Username == user.Username || u.Email == user.Email {
+			return fmt.Errorf("duplicate user %q", user.Username)
+		}
+	}
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	r.s.users.insert(user)
+	return nil
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, user *model.User, columns ...string) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	return r.s.users.saveColumns(user, columns)
+}
+
+func (r *memoryUserRepository) AdvanceTOTPCounter(ctx context.Context, userID uint, counter int64) (bool, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer r.s.mu.Unlock()
+
+	n := r.s.users.update(func(u *model.User) bool {
+		return u.ID == userID && u.TOTPLastCounter < counter
+	}, func(u *model.User) {
+		u.TOTPLastCounter = counter
+	})
+	return n == 1, nil
+}
+
+func (r *memoryUserRepository) Delete(ctx context.Context, user *model.User) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.users.softDelete(func(u *model.User) bool { return u.ID == user.ID })
+	return nil
+}
+
+// PurgeAccountData 内存实现不保存第三方身份、webhook 与导出任务，只删除其余数据
+func (r *memoryUserRepository) PurgeAccountData(ctx context.Context, user *model.User) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.accessTokens.purge(func(t *model.PersonalAccessToken) bool { return t.UserID == user.ID })
+	r.s.recoveryCodes.purge(func(c *model.RecoveryCode) bool { return c.UserID == user.ID })
+	r.s.accountTokens.purge(func(t *model.UserToken) bool { return t.UserID == user.ID })
+	r.s.notifications.purge(func(n *model.Notification) bool { return n.UserID == user.ID })
+	r.s.attempts.purge(func(a *model.LoginAttempt) bool {
+		return (a.UserID != nil && *a.UserID == user.ID) || a.Username == user.Username
+	})
+	return nil
+}
+
+func (r *memoryUserRepository) CreateLoginAttempt(ctx context.Context, attempt *model.LoginAttempt) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.attempts.insert(attempt)
+	return nil
+}
+
+func (r *memoryUserRepository) LastLoginSuccess(ctx context.Context, username string, since time.Time) (time.Time, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return time.Time{}, err
+	}
+	defer r.s.mu.Unlock()
+
+	var last time.Time
+	for _, a := range r.s.attempts.find(func(a *model.LoginAttempt) bool {
+		return a.Username == username && a.Success && a.CreatedAt.After(since)
+	}) {
+		if a.CreatedAt.After(last) {
+			last = a.CreatedAt
+		}
+	}
+	return last, nil
+}
+
+func (r *memoryUserRepository) CountLoginFailures(ctx context.Context, filter LoginFailureFilter) (int64, time.Time, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return 0, time.Time{}, err
+	}
+	defer r.s.mu.Unlock()
+
+	failures := r.s.attempts.find(func(a *model.LoginAttempt) bool {
+		if a.Success || a.Cleared || !a.CreatedAt.After(filter.Since) {
+			return false
+		}
+		if filter.Username != "" {
+			return a.Username == filter.Username
+		}
+		return a.IP == filter.IP
+	})
+
+	var last time.Time
+	for _, a := range failures {
+		if a.CreatedAt.After(last) {
+			last = a.CreatedAt
+		}
+	}
+	return int64(len(failures)), last, nil
+}
+
+func (r *memoryUserRepository) ClearLoginFailures(ctx context.Context, username, ip string) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.attempts.update(func(a *model.LoginAttempt) bool {
+		return !a.Success && !a.Cleared && (a.Username == username || (ip != "" && a.IP == ip))
+	}, func(a *model.LoginAttempt) {
+		a.Cleared = true
+	})
+	return nil
+}
+
+func (r *memoryUserRepository) CreateSession(ctx context.Context, session *model.LoginSession) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.sessions.insert(session)
+	return nil
+}
+
+func (r *memoryUserRepository) ListSessions(ctx context.Context, userID uint, limit int) ([]model.LoginSession, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	sessions := r.s.sessions.find(func(s *model.LoginSession) bool { return s.UserID == userID })
+	slices.Reverse(sessions)
+	slices.SortStableFunc(sessions, func(a, b model.LoginSession) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return page(sessions, 0, limit), nil
+}
+
+func (r *memoryUserRepository) RevokeSessions(ctx context.Context, userID uint) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	r.s.sessions.update(func(s *model.LoginSession) bool {
+		return s.UserID == userID && s.RevokedAt == nil && s.ExpiresAt.After(now)
+	}, func(s *model.LoginSession) {
+		s.RevokedAt = &now
+	})
+	return nil
+}
+
+func (r *memoryUserRepository) SessionActive(ctx context.Context, tokenID string) (bool, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer r.s.mu.Unlock()
+
+	return len(r.s.sessions.find(func(s *model.LoginSession) bool {
+		return s.TokenID == tokenID && s.RevokedAt == nil
+	})) > 0, nil
+}
+
+func (r *memoryUserRepository) CreateAccountToken(ctx context.Context, token *model.UserToken) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.accountTokens.insert(token)
+	return nil
+}
+
+func (r *memoryUserRepository) ConsumeAccountToken(ctx context.Context, tokenHash string, userID uint, purpose string) (bool, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	n := r.s.accountTokens.update(func(t *model.UserToken) bool {
+		return t.TokenHash == tokenHash && t.UserID == userID && t.Purpose == purpose &&
+			t.UsedAt == nil && t.ExpiresAt.After(now)
+	}, func(t *model.UserToken) {
+		t.UsedAt = &now
+	})
+	return n == 1, nil
+}
+
+func (r *memoryUserRepository) RevokeAccountTokens(ctx context.Context, userID uint, purpose string) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	r.s.accountTokens.update(func(t *model.UserToken) bool {
+		return t.UserID == userID && t.Purpose == purpose && t.UsedAt == nil
+	}, func(t *model.UserToken) {
+		t.UsedAt = &now
+	})
+	return nil
+}
+
+func (r *memoryUserRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []model.RecoveryCode) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.recoveryCodes.purge(func(c *model.RecoveryCode) bool { return c.UserID == userID })
+	for i := range codes {
+		r.s.recoveryCodes.insert(&codes[i])
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	n := r.s.recoveryCodes.update(func(c *model.RecoveryCode) bool {
+		return c.UserID == userID && c.CodeHash == codeHash && c.UsedAt == nil
+	}, func(c *model.RecoveryCode) {
+		c.UsedAt = &now
+	})
+	return n == 1, nil
+}
+
+func (r *memoryUserRepository) DeleteRecoveryCodes(ctx context.Context, userID uint) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.recoveryCodes.softDelete(func(c *model.RecoveryCode) bool { return c.UserID == userID })
+	return nil
+}
+
+func (r *memoryUserRepository) CountActiveAccessTokens(ctx context.Context, userID uint) (int64, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	return int64(len(r.s.accessTokens.find(func(t *model.PersonalAccessToken) bool {
+		return t.UserID == userID && t.ExpiresAt.After(now)
+	}))), nil
+}
+
+func (r *memoryUserRepository) CreateAccessToken(ctx context.Context, token *model.PersonalAccessToken) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.accessTokens.insert(token)
+	return nil
+}
+
+func (r *memoryUserRepository) ListAccessTokens(ctx context.Context, userID uint) ([]model.PersonalAccessToken, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	tokens := r.s.accessTokens.find(func(t *model.PersonalAccessToken) bool { return t.UserID == userID })
+	slices.Reverse(tokens)
+	slices.SortStableFunc(tokens, func(a, b model.PersonalAccessToken) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return tokens, nil
+}
+
+func (r *memoryUserRepository) RevokeAccessToken(ctx context.Context, userID, tokenID uint) (bool, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer r.s.mu.Unlock()
+
+	n := r.s.accessTokens.softDelete(func(t *model.PersonalAccessToken) bool {
+		return t.ID == tokenID && t.UserID == userID
+	})
+	return n > 0, nil
+}
+
+func (r *memoryUserRepository) FindAccessTokenByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	tokens := r.s.accessTokens.find(func(t *model.PersonalAccessToken) bool {
+		return t.TokenHash == tokenHash && t.ExpiresAt.After(now)
+	})
+	if len(tokens) == 0 {
+		return nil, ErrNotFound
+	}
+	return &tokens[0], nil
+}
+
+func (r *memoryUserRepository) TouchAccessToken(ctx context.Context, tokenID uint, usedAt time.Time) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.accessTokens.update(func(t *model.PersonalAccessToken) bool { return t.ID == tokenID }, func(t *model.PersonalAccessToken) {
+		t.LastUsedAt = &usedAt
+	})
+	return nil
+}
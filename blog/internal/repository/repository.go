@@ -0,0 +1,143 @@
+// Package repository 数据访问层：logic 服务只依赖这里的接口，不直接构造 SQL
+// gorm 实现用于生产环境，内存实现用于不依赖数据库的单元测试；
+// 需要原子性的多步操作通过 UnitOfWork 在同一事务中执行
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"web-task/blog/internal/model"
+)
+
+// ErrNotFound 记录不存在（或已软删除）
+var ErrNotFound = errors.New("record not found")
+
+// Repositories 一组仓储；在 UnitOfWork.Do 中拿到的仓储共享同一个事务
+type Repositories struct {
+	Users    UserRepository
+	Posts    PostRepository
+	Comments CommentRepository
+	Outbox   OutboxRepository
+	// Notifications 站内通知，与触发通知的写操作在同一事务中写入
+	Notifications NotificationRepository
+}
+
+// UnitOfWork 在同一事务中执行多个仓储操作，fn 返回错误时全部回滚
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+// PostRepository 文章仓储
+type PostRepository interface {
+	Create(ctx context.Context, post *model.Post) error
+	// FindByID 只加载文章本身
+	FindByID(ctx context.Context, id uint) (*model.Post, error)
+	// FindByIDs 批量加载文章本身，不存在的 ID 忽略
+	FindByIDs(ctx context.Context, ids []uint) ([]model.Post, error)
+	// FindDetail 加载文章及其作者、附件与标签
+	FindDetail(ctx context.Context, id uint) (*model.Post, error)
+	// List 分页列出文章（含作者），按创建时间倒序，同时返回总数
+	List(ctx context.Context, offset, limit int) ([]model.Post, int64, error)
+	// ListByUser 列出用户的全部文章（含附件与标签），按创建时间正序
+	ListByUser(ctx context.Context, userID uint) ([]model.Post, error)
+	CountByUser(ctx context.Context, userID uint) (int64, error)
+	// Update 只保存 columns 指定的列
+	Update(ctx context.Context, post *model.Post, columns ...string) error
+	Delete(ctx context.Context, post *model.Post) error
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+// CommentRepository 评论仓储
+type CommentRepository interface {
+	Create(ctx context.Context, comment *model.Comment) error
+	// FindByID 只加载评论本身；unscoped 为 true 时包括已软删除的评论
+	FindByID(ctx context.Context, id uint, unscoped bool) (*model.Comment, error)
+	// FindDetail 加载评论及其作者与所属文章
+	FindDetail(ctx context.Context, id uint) (*model.Comment, error)
+	// ListByUser 列出用户的全部评论，按创建时间正序
+	ListByUser(ctx context.Context, userID uint) ([]model.Comment, error)
+	// ListByPosts 列出多篇文章下的评论（不含关联），按创建时间正序；每篇文章最多返回最早的 perPost 条
+	ListByPosts(ctx context.Context, postIDs []uint, perPost int) ([]model.Comment, error)
+	Update(ctx context.Context, comment *model.Comment, columns ...string) error
+	// Delete 软删除评论，withChildren 为 true 时同时删除其子评论
+	Delete(ctx context.Context, comment *model.Comment, withChildren bool) error
+	// Restore 恢复软删除的评论，withChildren 为 true 时同时恢复其子评论
+	Restore(ctx context.Context, comment *model.Comment, withChildren bool) error
+}
+
+// OutboxRepository 事件发件箱，事件与产生它的写操作在同一事务中写入，由 webhook 后台任务分发
+type OutboxRepository interface {
+	Append(ctx context.Context, event *model.OutboxEvent) error
+}
+
+// NotificationRepository 站内通知仓储；列表与已读状态由 NotificationService 直接查询
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *model.Notification) error
+}
+
+// LoginFailureFilter 统计登录失败的条件，Username 与 IP 二选一
+type LoginFailureFilter struct {
+	Username string
+	IP       string
+	Since    time.Time
+}
+
+// UserRepository 用户及其认证数据：登录记录、登录会话、一次性账号令牌、恢复码与个人访问令牌
+type UserRepository interface {
+	FindByID(ctx context.Context, id uint) (*model.User, error)
+	// FindByIDs 批量加载用户，不存在的 ID 忽略
+	FindByIDs(ctx context.Context, ids []uint) ([]model.User, error)
+	FindByUsername(ctx context.Context, username string) (*model.User, error)
+	FindByEmail(ctx context.Context, email string) (*model.User, error)
+	// EmailInUse 邮箱是否已被 excludeID 以外的用户使用
+	EmailInUse(ctx context.Context, email string, excludeID uint) (bool, error)
+	Create(ctx context.Context, user *model.User) error
+	// Update 只保存 columns 指定的列
+	Update(ctx context.Context, user *model.User, columns ...string) error
+	// AdvanceTOTPCounter 仅当新时间步大于已使用的时间步时更新，返回是否更新成功（防止验证码重放）
+	AdvanceTOTPCounter(ctx context.Context, userID uint, counter int64) (bool, error)
+	// Delete 软删除用户
+	Delete(ctx context.Context, user *model.User) error
+	// PurgeAccountData 注销账号时删除个人访问令牌、第三方身份、恢复码、账号令牌、webhook 及其投递记录、通知与登录记录，
+	// 并使导出归档立即过期
+	PurgeAccountData(ctx context.Context, user *model.User) error
+
+	CreateLoginAttempt(ctx context.Context, attempt *model.LoginAttempt) error
+	// LastLoginSuccess 用户名在 since 之后最近一次成功登录的时间，没有时返回零值
+	LastLoginSuccess(ctx context.Context, username string, since time.Time) (time.Time, error)
+	// CountLoginFailures 统计未被清除的失败次数及最后一次失败时间
+	CountLoginFailures(ctx context.Context, filter LoginFailureFilter) (int64, time.Time, error)
+	// ClearLoginFailures 清除账号的失败记录，ip 非空时同时清除该 IP 的失败记录
+	ClearLoginFailures(ctx context.Context, username, ip string) error
+
+	CreateSession(ctx context.Context, session *model.LoginSession) error
+	ListSessions(ctx context.Context, userID uint, limit int) ([]model.LoginSession, error)
+	// RevokeSessions 吊销用户全部未过期的登录会话
+	RevokeSessions(ctx context.Context, userID uint) error
+	SessionActive(ctx context.Context, tokenID string) (bool, error)
+
+	CreateAccountToken(ctx context.Context, token *model.UserToken) error
+	// ConsumeAccountToken 将未使用且未过期的令牌标记为已使用，返回是否成功（并发时只有一个成功）
+	ConsumeAccountToken(ctx context.Context, tokenHash string, userID uint, purpose string) (bool, error)
+	// RevokeAccountTokens 作废用户某种用途的全部未使用令牌
+	RevokeAccountTokens(ctx context.Context, userID uint, purpose string) error
+
+	// ReplaceRecoveryCodes 删除旧恢复码并保存新的一组
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []model.RecoveryCode) error
+	// UseRecoveryCode 将未使用的恢复码标记为已使用，返回是否成功
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error)
+	DeleteRecoveryCodes(ctx context.Context, userID uint) error
+
+	// CountActiveAccessTokens 统计未过期、未撤销的个人访问令牌
+	CountActiveAccessTokens(ctx context.Context, userID uint) (int64, error)
+	CreateAccessToken(ctx context.Context, token *model.PersonalAccessToken) error
+	// ListAccessTokens 列出用户未撤销的令牌（含已过期），按创建时间倒序
+	ListAccessTokens(ctx context.Context, userID uint) ([]model.PersonalAccessToken, error)
+	// RevokeAccessToken 撤销令牌（软删除），返回是否存在
+	RevokeAccessToken(ctx context.Context, userID, tokenID uint) (bool, error)
+	// FindAccessTokenByHash 查找未过期、未撤销的令牌
+	FindAccessTokenByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error)
+	TouchAccessToken(ctx context.Context, tokenID uint, usedAt time.Time) error
+}
@@ -0,0 +1,23 @@
+// internal/storage/storage.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	ErrObjectNotFound = errors.New("object not found")
+)
+
+// Storage 对象存储抽象，上传的附件与缩略图均通过它读写
+// 本地磁盘与 S3 兼容存储各有一个实现，由 utility.InitStorage 按配置选择
+type Storage interface {
+	// Put 写入对象；size 为 -1 表示长度未知
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，调用方负责关闭返回的 ReadCloser；对象不存在时返回 ErrObjectNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象；对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,75 @@
+// Package tracing 基于 OpenTelemetry 的链路追踪：gin 中间件创建服务端 span，
+// 经 context.Context 传入 logic 服务与 gorm，跨服务使用 W3C trace-context 传播
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName 博客自身创建的 span 使用的 tracer 名称
+const InstrumentationName = "web-task/blog"
+
+// 导出方式
+const (
+	ExporterNone   = "none"   // 不导出，仍然传播上游的 trace-context
+	ExporterStdout = "stdout" // 输出到标准输出，本地调试用
+	ExporterOTLP   = "otlp"   // OTLP/HTTP，地址等由 OTEL_EXPORTER_OTLP_* 环境变量配置
+)
+
+// Config 追踪配置
+type Config struct {
+	ServiceName string
+	Exporter    string
+	SampleRatio float64   // 根 span 的采样率，上游已采样的请求始终跟随上游
+	Stdout      io.Writer // ExporterStdout 的输出位置
+}
+
+// Init 设置全局 TracerProvider 与 W3C 传播器，返回的函数用于退出时刷新并关闭导出器
+func Init(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(cfg.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Start 创建子 span；未初始化 TracerProvider 时为空操作
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(InstrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
@@ -1,35 +1,231 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"web-task/blog/api"
+	"web-task/blog/internal/consts"
 
 	"web-task/blog/internal/controller"
+	"web-task/blog/internal/graph"
+	"web-task/blog/internal/grpcapi"
+	"web-task/blog/internal/keyring"
+	"web-task/blog/internal/lifecycle"
 	"web-task/blog/internal/logic"
+	"web-task/blog/internal/pubsub"
+	"web-task/blog/internal/repository"
+	"web-task/blog/middleware"
 	"web-task/blog/utility"
 
 	"github.com/gin-gonic/gin"
 )
 
+// 修改 proto 后重新生成 gen 下的 gRPC 与 grpc-gateway 代码，需安装 buf 与 buf.gen.yaml 中的插件
+//go:generate buf generate
+
 func main() {
-	// 初始化数据库连接
+	// 初始化日志、数据库连接与附件存储
+	utility.InitLogger()
+	utility.InitDB()
+	utility.InitMetrics()
+	utility.InitTracing()
+	utility.InitKeyRing()
+	utility.InitStorage()
+	utility.InitRateLimiter()
+	utility.InitMailer()
+	utility.InitOIDC()
+	utility.InitTimeouts()
+	utility.InitOpenAPI()
 	db := utility.DB
+	logger := utility.Logger
 
-	// 初始化控制器
-	userService := logic.NewUserService(db)
+	// 后台任务与退出时需要释放的资源
+	lc := lifecycle.New(logger)
+	lc.OnStop("database", func(context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+	lc.OnStop("tracing", utility.ShutdownTracing)
+
+	// 初始化仓储与控制器
+	repos, uow := repository.NewGorm(db)
+	userService := logic.NewUserService(repos, uow, utility.Mailer, utility.KeyRing, utility.AccountTokenSecret, utility.AppURL(), logger)
 	userCtl := controller.NewUserController(userService)
+	// 个人访问令牌与登录会话吊销由用户服务校验
+	middleware.UseAccessTokens(userService)
+	middleware.UseSessions(userService)
 
-	postService := logic.NewPostService(db)
+	postService := logic.NewPostService(repos, uow)
 	postCtl := controller.NewPostHandler(postService)
 
-	commentService := logic.NewCommentService(db)
+	// 实时推送使用进程内的发布订阅，多实例部署时替换为基于消息代理的实现
+	hub := pubsub.NewMemoryHub()
+
+	commentService := logic.NewCommentService(repos, uow, hub)
 	commentCtl := controller.NewCommentHandler(commentService)
 
-	// 初始化gin引擎
-	r := gin.Default()
+	uploadService := logic.NewUploadService(db, utility.Storage)
+	uploadCtl := controller.NewUploadHandler(uploadService)
+
+	identityService := logic.NewIdentityService(db, userService, utility.OIDCProviders)
+	identityCtl := controller.NewIdentityController(identityService)
+
+	jwksCtl := controller.NewJWKSHandler(utility.KeyRing)
+
+	exportService := logic.NewExportService(db, utility.Storage, userService, postService, commentService, logger)
+	exportCtl := controller.NewExportHandler(exportService)
+
+	webhookService := logic.NewWebhookService(db, logger)
+	webhookCtl := controller.NewWebhookHandler(webhookService)
+
+	notificationCtl := controller.NewNotificationHandler(logic.NewNotificationService(db, hub))
+
+	importCtl := controller.NewImportHandler(logic.NewImportService(db))
+
+	graphServer, err := graph.NewServer(userService, postService, commentService, utility.RateLimiter, graph.Limits{
+		MaxDepth:          consts.GraphQLMaxDepth,
+		MaxComplexity:     consts.GraphQLMaxComplexity,
+		MaxMutationFields: consts.GraphQLMaxMutationFields,
+	})
+	if err != nil {
+		log.Fatalf("Failed to build GraphQL schema: %v", err)
+	}
+	graphqlCtl := controller.NewGraphQLHandler(graphServer)
+
+	rpcServer, err := grpcapi.NewServer(userService, postService, commentService, utility.RateLimiter, logger)
+	if err != nil {
+		log.Fatalf("Failed to create gRPC server: %v", err)
+	}
+
+	healthService := logic.NewHealthService(db, utility.Migrator)
+	healthCtl := controller.NewHealthHandler(healthService)
+
+	// 定期回收已删除文章或长期未关联的附件
+	lc.Go("upload-gc", func(ctx context.Context) { collectUploadGarbage(ctx, uploadService, logger) })
+	// 定期轮换签名密钥并同步其它实例生成的密钥
+	lc.Go("signing-keys", func(ctx context.Context) { maintainSigningKeys(ctx, utility.KeyRing, logger) })
+	// 处理数据导出任务并清理过期归档
+	lc.Go("exports", exportService.Run)
+	// 分发文章与评论事件并投递 webhook
+	lc.Go("webhooks", webhookService.Run)
+
+	// 初始化gin引擎，使用结构化日志替代 gin 默认的日志与恢复中间件
+	r := gin.New()
+	// gin 默认信任所有代理的转发头，改为只信任配置的反向代理
+	if err := r.SetTrustedProxies(utility.TrustedProxies()); err != nil {
+		log.Fatalf("Invalid BLOG_TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(middleware.RequestID(), middleware.Tracing(), middleware.AccessLog(logger), middleware.Metrics(), middleware.Recovery(logger), middleware.Timeout())
+	// 接口文档在所有路由注册后生成；开发与测试环境按文档校验请求与响应
+	spec := controller.NewSpec(r)
+	if utility.OpenAPIValidation {
+		r.Use(middleware.ValidateOpenAPI(spec))
+	}
+	// multipart 表单超过该大小的部分落盘，避免大文件占用内存
+	r.MaxMultipartMemory = 8 << 20
 
 	// 注册所有路由
-	api.SetupRouter(r, userCtl, postCtl, commentCtl)
+	api.SetupRouter(r, userCtl, postCtl, commentCtl, uploadCtl, identityCtl, jwksCtl, exportCtl, importCtl, healthCtl, controller.NewOpenAPIHandler(spec), graphqlCtl, webhookCtl, notificationCtl, utility.RateLimiter)
+	if err := spec.Build(); err != nil {
+		log.Fatalf("Failed to build OpenAPI document: %v", err)
+	}
+
+	// 启动服务，收到 SIGINT/SIGTERM 后停止接受新请求并等待进行中的请求完成
+	srv := utility.NewServer(r)
+	// Shutdown 不会中断 SSE 与 WebSocket 长连接，关闭 hub 使其结束
+	srv.RegisterOnShutdown(func() { hub.Close() })
+	serveErr := make(chan error, 2)
+	go func() {
+		logger.Info("server listening", "addr", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+	// gRPC 与 grpc-gateway 使用单独的端口
+	rpcSrv := utility.NewGRPCServer(rpcServer.Handler())
+	go func() {
+		logger.Info("grpc server listening", "addr", rpcSrv.Addr)
+		serveErr <- rpcSrv.ListenAndServe()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	case <-ctx.Done():
+	}
+	stop()
+
+	logger.Info("shutting down", "timeout", utility.ShutdownTimeout)
+	healthService.SetDraining()
+	time.Sleep(utility.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), utility.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("server shutdown incomplete", "error", err)
+	}
+	if err := rpcSrv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("grpc server shutdown incomplete", "error", err)
+	}
+	rpcServer.Close()
+	if err := lc.Shutdown(shutdownCtx); err != nil {
+		logger.Error("background shutdown incomplete", "error", err)
+	}
+	logger.Info("server stopped")
+}
+
+func collectUploadGarbage(ctx context.Context, uploadService *logic.UploadService, logger *slog.Logger) {
+	ticker := time.NewTicker(consts.UploadGCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := uploadService.CollectGarbage(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "upload gc failed", "error", err)
+			continue
+		}
+		if n > 0 {
+			logger.InfoContext(ctx, "upload gc removed orphaned attachments", "count", n)
+		}
+	}
+}
+
+func maintainSigningKeys(ctx context.Context, keys *keyring.KeyRing, logger *slog.Logger) {
+	ticker := time.NewTicker(consts.JWTKeyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 
-	// 启动服务
-	r.Run(":8080")
+		rotated, err := keys.RotateIfDue(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "signing key maintenance failed", "error", err)
+			continue
+		}
+		if rotated {
+			logger.InfoContext(ctx, "signing key rotated")
+		}
+	}
 }
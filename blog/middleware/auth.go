@@ -1,67 +1,254 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
 	"errors"
 	"web-task/blog/internal/consts"
+	"web-task/blog/internal/keyring"
 	"web-task/blog/internal/model"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
+	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
-// AuthMiddleware 是一个中间件函数，用于验证 JWT 令牌
-func AuthMiddleware() gin.HandlerFunc {
+// tokenKeys 验证令牌使用的密钥环，启动时通过 UseKeyRing 设置
+var tokenKeys *keyring.KeyRing
+
+// UseKeyRing 设置 AuthMiddleware 验证令牌使用的密钥环
+func UseKeyRing(kr *keyring.KeyRing) {
+	tokenKeys = kr
+}
+
+// AccessTokenAuthenticator 校验个人访问令牌，返回所属用户与权限范围
+type AccessTokenAuthenticator interface {
+	AuthenticateAccessToken(ctx context.Context, token string) (*model.User, []string, error)
+}
+
+// accessTokens 个人访问令牌校验器，启动时通过 UseAccessTokens 设置
+var accessTokens AccessTokenAuthenticator
+
+// UseAccessTokens 设置 AuthMiddleware 校验个人访问令牌使用的校验器
+func UseAccessTokens(a AccessTokenAuthenticator) {
+	accessTokens = a
+}
+
+// SessionValidator 检查登录令牌对应的会话是否已被吊销（修改密码、注销账号等）
+type SessionValidator interface {
+	SessionActive(ctx context.Context, tokenID string) (bool, error)
+}
+
+// sessions 登录会话校验器，启动时通过 UseSessions 设置；未设置时不检查吊销
+var sessions SessionValidator
+
+// UseSessions 设置 AuthMiddleware 检查会话吊销使用的校验器
+func UseSessions(v SessionValidator) {
+	sessions = v
+}
+
+// AuthMiddleware 是一个中间件函数，用于验证 JWT 令牌或个人访问令牌
+// 不传 scopes 时只接受登录令牌（账号安全、令牌管理等接口）；
+// 传入 scopes 时同时接受持有全部这些权限范围的个人访问令牌，登录令牌不受权限范围限制
+func AuthMiddleware(scopes ...string) gin.HandlerFunc {
+	return authenticate(scopes, true)
+}
+
+// OptionalAuthMiddleware 与 AuthMiddleware 相同，但未携带 Authorization 时按匿名请求放行，
+// 用于公开的读接口：携带了令牌就必须有效且具备权限范围
+func OptionalAuthMiddleware(scopes ...string) gin.HandlerFunc {
+	return authenticate(scopes, false)
+}
+
+// WebSocketAuthMiddleware 与 AuthMiddleware 相同，但浏览器的 WebSocket API 不能设置请求头，
+// 未携带 Authorization 时从子协议中读取令牌：Sec-WebSocket-Protocol: bearer, <token>；
+// 令牌不放在查询参数中，避免写入访问日志与代理日志
+func WebSocketAuthMiddleware(scopes ...string) gin.HandlerFunc {
+	auth := authenticate(scopes, true)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token, ok := subprotocolToken(c.Request); ok {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		auth(c)
+	}
+}
+
+// subprotocolToken 取出子协议列表中紧跟在 bearer 之后的令牌
+func subprotocolToken(r *http.Request) (string, bool) {
+	var protocols []string
+	for _, h := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(h, ",") {
+			protocols = append(protocols, strings.TrimSpace(p))
+		}
+	}
+	for i, p := range protocols {
+		if p == consts.LiveSubprotocol && i+1 < len(protocols) && protocols[i+1] != "" {
+			return protocols[i+1], true
+		}
+	}
+	return "", false
+}
+
+func authenticate(scopes []string, required bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从请求头中获取 Authorization 字段
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
+			if !required {
+				c.Next()
+				return
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
 			c.Abort()
 			return
 		}
 
 		// 解析 Bearer 令牌
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		tokenString, ok := BearerToken(authHeader)
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
 			c.Abort()
 			return
 		}
-		tokenString := parts[1]
-
-		// 解析 JWT 令牌
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// 确保令牌的签名方法是 HS256
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			return []byte(consts.JWTSecret), nil
-		})
 
+		principal, err := VerifyToken(c.Request.Context(), tokenString, scopes)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+			abortAuth(c, err, scopes)
 			return
 		}
 
-		// 检查令牌是否有效
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// 从 Claims 中获取用户名
-			username := claims["username"].(string)
+		// 将用户信息存储到上下文中，供后续使用；旧版登录令牌不携带用户 ID 与角色
+		c.Set("username", principal.Username)
+		if principal.UserID != 0 {
+			c.Set("user_id", principal.UserID)
+		}
+		if principal.Role != "" {
+			c.Set("role", principal.Role)
+		}
+		if principal.AccessToken {
+			c.Set("scopes", principal.Scopes)
+		}
+		c.Next()
+	}
+}
+
+// abortAuth 按 VerifyToken 的错误返回对应的状态码
+func abortAuth(c *gin.Context, err error, scopes []string) {
+	switch {
+	case errors.Is(err, ErrInvalidToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	case errors.Is(err, ErrTokenRevoked):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+	case errors.Is(err, ErrAccessTokenNotAccepted):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Personal access tokens are not accepted for this endpoint"})
+	case errors.Is(err, ErrInsufficientScope):
+		c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient scope", "required_scopes": scopes})
+	case errors.Is(err, ErrAuthNotConfigured):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token verification is not configured"})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+	c.Abort()
+}
+
+var (
+	ErrInvalidToken           = errors.New("invalid token")
+	ErrTokenRevoked           = errors.New("token has been revoked")
+	ErrAccessTokenNotAccepted = errors.New("personal access tokens are not accepted")
+	ErrInsufficientScope      = errors.New("insufficient scope")
+	ErrAuthNotConfigured      = errors.New("token verification is not configured")
+)
+
+// Principal 令牌对应的调用者
+type Principal struct {
+	UserID   uint // 旧版登录令牌不携带用户 ID，为 0
+	Username string
+	Role     string
+	// AccessToken 为个人访问令牌，Scopes 为其授予的权限范围；登录令牌不受权限范围限制
+	AccessToken bool
+	Scopes      []string
+}
+
+// BearerToken 从 Authorization 头取出 Bearer 令牌
+func BearerToken(header string) (string, bool) {
+	parts := strings.Split(header, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// VerifyToken 校验登录令牌或个人访问令牌，供 HTTP 中间件与 gRPC 拦截器共用
+// scopes 语义与 AuthMiddleware 相同：为空时拒绝个人访问令牌，否则要求个人访问令牌持有全部权限范围
+func VerifyToken(ctx context.Context, tokenString string, scopes []string) (*Principal, error) {
+	if strings.HasPrefix(tokenString, consts.AccessTokenPrefix) {
+		return verifyAccessToken(ctx, tokenString, scopes)
+	}
+
+	if tokenKeys == nil {
+		return nil, ErrAuthNotConfigured
+	}
+
+	// 解析 JWT 令牌，按头部 kid 选择公钥验证签名
+	claims := jwt.MapClaims{}
+	token, err := tokenKeys.Parse(tokenString, claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	// 检查令牌是否有效；带 typ 声明的是两步验证挑战等专用令牌，不能用于访问接口
+	if !token.Valid || claims["typ"] != nil {
+		return nil, ErrInvalidToken
+	}
+	if sessions != nil {
+		jti, _ := claims["jti"].(string)
+		active, err := sessions.SessionActive(ctx, jti)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check session: %w", err)
+		}
+		if !active {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	p := &Principal{}
+	p.Username, _ = claims["username"].(string)
+	if userID, ok := claims["user_id"].(float64); ok {
+		p.UserID = uint(userID)
+	}
+	p.Role, _ = claims["role"].(string)
+	return p, nil
+}
+
+// verifyAccessToken 校验个人访问令牌及其权限范围
+func verifyAccessToken(ctx context.Context, tokenString string, scopes []string) (*Principal, error) {
+	if len(scopes) == 0 {
+		return nil, ErrAccessTokenNotAccepted
+	}
+	if accessTokens == nil {
+		return nil, ErrAuthNotConfigured
+	}
 
-			// 将用户名存储到上下文中，供后续使用
-			c.Set("username", username)
-			c.Next()
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+	user, granted, err := accessTokens.AuthenticateAccessToken(ctx, tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return nil, fmt.Errorf("%w: %s required", ErrInsufficientScope, strings.Join(scopes, " "))
 		}
 	}
+	return &Principal{UserID: user.ID, Username: user.Username, Role: user.Role, AccessToken: true, Scopes: granted}, nil
 }
 
 // CheckBlogOwnerMiddleware 是一个中间件函数，用于检查请求者是否是博客的主人
@@ -147,3 +334,15 @@ func CheckBlogOwnerMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminMiddleware 是一个中间件函数，要求请求者为管理员，需在 AuthMiddleware 之后使用
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if role, _ := c.Get("role"); role != consts.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin privileges required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
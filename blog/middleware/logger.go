@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"web-task/blog/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quietLogKey 标记成功时只在 debug 级别记录的请求，见 QuietLog
+const quietLogKey = "quiet_log"
+
+// QuietLog 用于探针等高频路由：请求成功时访问日志降为 debug 级别
+func QuietLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(quietLogKey, true)
+	}
+}
+
+// AccessLog 替代 gin 默认日志，每个请求记录一条结构化日志，并把记录器放入 request context；
+// 只记录路径不记录查询串（验证邮件、下载链接等把令牌放在查询串中），处理器通过 c.Error 附加的错误一并输出
+func AccessLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), logger))
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			attrs = append(attrs, slog.Any("user_id", userID))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case c.GetBool(quietLogKey):
+			level = slog.LevelDebug
+		}
+		logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery 捕获处理器 panic，记录堆栈后返回 500
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.ErrorContext(c.Request.Context(), "panic recovered",
+					slog.String("panic", fmt.Sprint(r)),
+					slog.String("stack", string(debug.Stack())),
+				)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-task/blog/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricsToken 抓取 /metrics 需要携带的令牌，为空表示不校验
+var metricsToken string
+
+// UseMetricsToken 设置 /metrics 的访问令牌，启动时调用
+func UseMetricsToken(token string) {
+	metricsToken = token
+}
+
+// Metrics 记录每个请求的次数与耗时；route 使用路由模板，避免路径参数导致标签基数膨胀
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		metrics.HTTPInFlight.Inc()
+		defer metrics.HTTPInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsAuth 配置了令牌时要求 Authorization: Bearer <token>
+func MetricsAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if metricsToken == "" {
+			c.Next()
+			return
+		}
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(metricsToken)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"web-task/blog/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateOpenAPI 按接口文档校验请求体与响应，用于开发与测试环境：
+// 请求体不符合文档时返回 400；响应状态码未在文档中列出或 JSON 响应体不符合文档时改为返回 500，
+// 使处理器与文档不一致的问题在测试中暴露出来。multipart 请求体与非 JSON 响应（文件下载等）不缓存，只校验状态码
+func ValidateOpenAPI(spec *openapi.Spec) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			c.Next()
+			return
+		}
+		method := c.Request.Method
+
+		if c.Request.Body != nil && !strings.HasPrefix(c.ContentType(), "multipart/") {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+			if err := spec.ValidateRequest(method, route, body); err != nil {
+				c.Error(err)
+				if errors.Is(err, openapi.ErrInvalidRequest) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				} else {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				}
+				c.Abort()
+				return
+			}
+		}
+
+		w := &specWriter{
+			ResponseWriter: c.Writer,
+			status:         http.StatusOK,
+			check: func(status int, contentType string, body []byte) error {
+				return spec.ValidateResponse(method, route, status, contentType, body)
+			},
+			onError: func(err error) { c.Error(err) },
+		}
+		c.Writer = w
+		// 处理器 panic 时恢复原始 Writer，由 Recovery 直接写出 500
+		defer func() { c.Writer = w.ResponseWriter }()
+
+		c.Next()
+		w.finish()
+	}
+}
+
+type specWriterState int
+
+const (
+	statePending   specWriterState = iota // 尚未写出
+	stateBuffering                        // JSON 响应，缓存到处理器返回后校验
+	stateStreaming                        // 其它响应，状态码校验通过后直接转发
+	stateRejected                         // 不符合文档，已改为返回 500
+	stateHijacked                         // 连接已被接管（WebSocket 等协议升级），不再校验或写出
+)
+
+// specWriter 缓存 JSON 响应，处理器返回后按文档校验再写出
+type specWriter struct {
+	gin.ResponseWriter
+	status  int
+	state   specWriterState
+	body    bytes.Buffer
+	check   func(status int, contentType string, body []byte) error
+	onError func(err error)
+}
+
+func (w *specWriter) WriteHeader(code int) {
+	switch w.state {
+	case statePending:
+		if code > 0 {
+			w.status = code
+		}
+	case stateStreaming:
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *specWriter) WriteHeaderNow() {
+	w.start()
+}
+
+func (w *specWriter) Write(data []byte) (int, error) {
+	w.start()
+	switch w.state {
+	case stateBuffering:
+		return w.body.Write(data)
+	case stateStreaming:
+		return w.ResponseWriter.Write(data)
+	}
+	return len(data), nil
+}
+
+func (w *specWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *specWriter) Status() int {
+	switch w.state {
+	case stateHijacked:
+		return http.StatusSwitchingProtocols
+	case stateStreaming, stateRejected:
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *specWriter) Size() int {
+	if w.state == stateBuffering {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *specWriter) Written() bool {
+	return w.state != statePending
+}
+
+func (w *specWriter) Flush() {
+	if w.state == stateStreaming {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// Hijack 协议升级后由处理器直接读写连接；升级前的错误响应仍按文档校验
+func (w *specWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.Hijack()
+	if err == nil {
+		w.state = stateHijacked
+	}
+	return conn, rw, err
+}
+
+// start 首次写出时按内容类型决定缓存还是直接转发
+func (w *specWriter) start() {
+	if w.state != statePending {
+		return
+	}
+	contentType := w.Header().Get("Content-Type")
+	if openapi.IsJSON(contentType) {
+		w.state = stateBuffering
+		return
+	}
+
+	w.state = stateStreaming
+	if err := w.check(w.status, contentType, nil); err != nil {
+		w.reject(err)
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// finish 处理器返回后校验并写出缓存的 JSON 响应
+func (w *specWriter) finish() {
+	w.start()
+	if w.state != stateBuffering {
+		return
+	}
+	if err := w.check(w.status, w.Header().Get("Content-Type"), w.body.Bytes()); err != nil {
+		w.reject(err)
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	w.ResponseWriter.Write(w.body.Bytes())
+}
+
+func (w *specWriter) reject(err error) {
+	w.state = stateRejected
+	w.onError(err)
+
+	header := w.Header()
+	header.Del("Content-Disposition")
+	header.Del("Content-Length")
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	w.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+	w.ResponseWriter.WriteHeaderNow()
+	body, _ := json.Marshal(gin.H{"error": err.Error()})
+	w.ResponseWriter.Write(body)
+}
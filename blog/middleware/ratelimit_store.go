@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryRateLimitStore 进程内令牌桶存储
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	idle   time.Duration // 桶回满所需时间，超过后可回收
+}
+
+// NewMemoryRateLimitStore 构造函数
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket), lastSweep: time.Now()}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{
+			tokens: float64(policy.Burst),
+			last:   now,
+			idle:   time.Duration(float64(policy.Burst) / policy.Rate * float64(time.Second)),
+		}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(policy.Burst), b.tokens+now.Sub(b.last).Seconds()*policy.Rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return tokenBucketResult(allowed, b.tokens, policy), nil
+}
+
+// sweep 每分钟清理一次已回满的桶，避免按 IP 限流时内存无限增长
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > b.idle {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// tokenBucketScript 在 Redis 中原子地补充并消耗令牌，时间取自 Redis 服务器以保证多实例一致
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+  tokens = burst
+  ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisRateLimitStore 基于 Redis 兼容服务（Redis、Valkey、KeyDB 等）的令牌桶存储，可在多实例间共享
+// client 可以是 *redis.Client、*redis.ClusterClient 或任何实现 redis.Scripter 的对象
+type RedisRateLimitStore struct {
+	client redis.Scripter
+}
+
+// NewRedisRateLimitStore 构造函数
+func NewRedisRateLimitStore(client redis.Scripter) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client}
+}
+
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	vals, err := tokenBucketScript.Run(ctx, s.client, []string{key}, policy.Rate, policy.Burst).Slice()
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("failed to run token bucket script: %w", err)
+	}
+	if len(vals) != 2 {
+		return RateLimitResult{}, fmt.Errorf("unexpected token bucket reply: %v", vals)
+	}
+
+	allowed, _ := vals[0].(int64)
+	raw, _ := vals[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("unexpected token bucket reply: %v", vals)
+	}
+
+	return tokenBucketResult(allowed == 1, tokens, policy), nil
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"web-task/blog/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 请求ID的请求头与响应头
+const RequestIDHeader = "X-Request-ID"
+
+// 上游传入的请求ID只接受常见字符与长度，避免日志注入
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID 沿用上游网关传入的 X-Request-ID，没有或不合法时生成新的；
+// 请求ID写入响应头、gin 上下文与 request context，之后的日志都会带上
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求截止时间，<= 0 表示不限制
+var (
+	requestTimeout  time.Duration
+	transferTimeout time.Duration
+)
+
+// timeoutBaseKey 保存设置截止时间前的 context，路由级的 TransferTimeout 以它为基础重新设置
+const timeoutBaseKey = "timeout_base_ctx"
+
+// UseTimeouts 设置普通请求与上传下载类请求的截止时间，启动时调用
+func UseTimeouts(request, transfer time.Duration) {
+	requestTimeout = request
+	transferTimeout = transfer
+}
+
+// RequestTimeout 普通请求的截止时间，供 gRPC 等 gin 之外的入口使用
+func RequestTimeout() time.Duration {
+	return requestTimeout
+}
+
+// Timeout 为每个请求设置默认截止时间；到期后 request context 被取消，
+// 经 logic 服务传入 gorm 的查询随之中断，处理器将 context.DeadlineExceeded 映射为 504
+func Timeout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(timeoutBaseKey, c.Request.Context())
+		withTimeout(c, requestTimeout)
+	}
+}
+
+// TransferTimeout 上传、下载与导入等耗时较长的路由改用更长的截止时间
+func TransferTimeout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withTimeout(c, transferTimeout)
+	}
+}
+
+// NoTimeout SSE 等长连接不设截止时间，由处理器自行限制连接时长，
+// 仍受 HTTP 服务的 WriteTimeout 约束
+func NoTimeout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withTimeout(c, 0)
+	}
+}
+
+func withTimeout(c *gin.Context, d time.Duration) {
+	ctx := c.Request.Context()
+	if base, ok := c.Get(timeoutBaseKey); ok {
+		ctx = base.(context.Context)
+	}
+	if d <= 0 {
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
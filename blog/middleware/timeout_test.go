@@ -0,0 +1,111 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"web-task/blog/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutResult 处理器观察到的 context 状态
+type timeoutResult struct {
+	err      error
+	deadline time.Duration // 进入处理器时距截止时间的剩余时长，0 表示没有截止时间
+	elapsed  time.Duration
+}
+
+// newTimeoutEngine 注册 /slow 与 /transfer/slow 两个路由，处理器像数据库驱动一样等待 context 结束，最多等 5s
+func newTimeoutEngine(t *testing.T, request, transfer time.Duration) (*gin.Engine, chan timeoutResult) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	middleware.UseTimeouts(request, transfer)
+	t.Cleanup(func() { middleware.UseTimeouts(0, 0) })
+
+	results := make(chan timeoutResult, 1)
+	slow := func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var res timeoutResult
+		if deadline, ok := ctx.Deadline(); ok {
+			res.deadline = time.Until(deadline)
+		}
+		start := time.Now()
+		select {
+		case <-ctx.Done():
+			res.err = ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+		res.elapsed = time.Since(start)
+		results <- res
+		if errors.Is(res.err, context.DeadlineExceeded) {
+			c.Status(http.StatusGatewayTimeout)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+
+	r := gin.New()
+	r.Use(middleware.Timeout())
+	r.GET("/slow", slow)
+	r.GET("/transfer/slow", middleware.TransferTimeout(), slow)
+	return r, results
+}
+
+func TestTimeoutInterruptsHandler(t *testing.T) {
+	r, results := newTimeoutEngine(t, 50*time.Millisecond, time.Minute)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusGatewayTimeout)
+	}
+	res := <-results
+	if !errors.Is(res.err, context.DeadlineExceeded) {
+		t.Fatalf("handler context error = %v, want context.DeadlineExceeded", res.err)
+	}
+	if res.elapsed > 2*time.Second {
+		t.Fatalf("handler waited %s after a 50ms deadline", res.elapsed)
+	}
+}
+
+func TestTransferTimeoutReplacesRequestDeadline(t *testing.T) {
+	// 传输类路由的截止时间以请求原始 context 为基础，不受更短的普通截止时间限制
+	r, results := newTimeoutEngine(t, 50*time.Millisecond, 200*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transfer/slow", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusGatewayTimeout)
+	}
+	res := <-results
+	if res.deadline <= 100*time.Millisecond || res.deadline > 200*time.Millisecond {
+		t.Fatalf("transfer deadline in %s, want about 200ms", res.deadline)
+	}
+	if res.elapsed < 100*time.Millisecond {
+		t.Fatalf("transfer handler stopped after %s, before the transfer deadline", res.elapsed)
+	}
+}
+
+func TestTimeoutDisabled(t *testing.T) {
+	r, results := newTimeoutEngine(t, 0, 0)
+
+	// 不设置截止时间时客户端断开仍会取消 context
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil).WithContext(ctx)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	res := <-results
+	if res.deadline != 0 {
+		t.Fatalf("deadline set in %s, want none", res.deadline)
+	}
+	if !errors.Is(res.err, context.Canceled) {
+		t.Fatalf("handler context error = %v, want context.Canceled", res.err)
+	}
+}
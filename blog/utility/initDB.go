@@ -1,25 +1,58 @@
 package utility
 
 import (
-	"fmt"
+	"context"
 	"log"
+	"log/slog"
+
+	"web-task/blog/internal/logging"
+	"web-task/blog/internal/migrate"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
+var (
+	DB       *gorm.DB
+	Migrator *migrate.Migrator
+)
 
+// InitDB 连接数据库并执行未应用的迁移
+// BLOG_AUTO_MIGRATE=false 时不在启动时迁移（由 cmd/migrate 单独执行），存在未执行的迁移时 /readyz 返回 503
 func InitDB() {
+	ConnectDB()
+
+	if getEnv("BLOG_AUTO_MIGRATE", "true") != "true" {
+		pending, err := Migrator.Pending(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to check migrations: %v", err)
+		}
+		if len(pending) > 0 {
+			slog.Warn("database has pending migrations", "count", len(pending))
+		}
+		return
+	}
+
+	applied, err := Migrator.Up(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	for _, m := range applied {
+		slog.Info("migration applied", "version", m.Version)
+	}
+}
+
+// ConnectDB 只连接数据库，不执行迁移
+func ConnectDB() {
 	var err error
 	// 替换为你的数据库配置
 	dsn := "root:MyPass123!@tcp(172.21.224.1:3306)/blog?charset=utf8mb4&parseTime=True&loc=Local"
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logging.NewGormLogger(slog.Default())})
 
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	Migrator = NewMigrator(DB)
 
-	fmt.Println("Database connection successfully opened")
-
+	slog.Info("Database connection successfully opened")
 }
@@ -0,0 +1,67 @@
+package utility
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/internal/keyring"
+	"web-task/blog/middleware"
+)
+
+var KeyRing *keyring.KeyRing
+
+// AccountTokenSecret 邮箱验证、找回密码与导出下载链接的签名密钥
+var AccountTokenSecret []byte
+
+// InitKeyRing 初始化 JWT 签名密钥环，需在 InitDB 之后调用
+// BLOG_JWT_ALGORITHM 选择新密钥的算法（RS256/ES256/EdDSA），修改后启动时立即轮换；
+// BLOG_JWT_ROTATION 与 BLOG_JWT_GRACE 分别设置密钥使用期限和退役后的验证宽限期；
+// 必填的 BLOG_JWT_KEY_SECRET 用于加密数据库中的签名私钥，
+// 必填的 BLOG_ACCOUNT_TOKEN_SECRET 作为一次性令牌的签名密钥，未配置时拒绝启动
+func InitKeyRing() {
+	rotation := parseDurationEnv("BLOG_JWT_ROTATION", consts.JWTKeyRotationInterval)
+	grace := parseDurationEnv("BLOG_JWT_GRACE", consts.JWTKeyGrace)
+	// 退役密钥在新密钥的发布等待期内仍在签名，宽限期需覆盖这段时间再加上令牌有效期
+	if minGrace := consts.TokenTTL + consts.JWTKeyPublishDelay; grace < minGrace {
+		log.Fatalf("BLOG_JWT_GRACE (%s) must not be shorter than the token lifetime plus the key publish delay (%s)", grace, minGrace)
+	}
+
+	// 私钥加密密钥与一次性令牌密钥一样不落库，数据库泄露时签名私钥无法直接使用；
+	// 多实例部署时所有实例需配置相同的值，更换后已有密钥无法解密
+	keySecret := getEnv("BLOG_JWT_KEY_SECRET", "")
+	if len(keySecret) < consts.JWTKeySecretMinLength {
+		log.Fatalf("BLOG_JWT_KEY_SECRET must be set to at least %d random bytes, e.g. `openssl rand -base64 32`", consts.JWTKeySecretMinLength)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var err error
+	KeyRing, err = keyring.New(ctx, DB, getEnv("BLOG_JWT_ALGORITHM", consts.JWTAlgorithm), []byte(keySecret), rotation, grace, consts.JWTKeyPublishDelay)
+	if err != nil {
+		log.Fatalf("Failed to init signing keys: %v", err)
+	}
+	middleware.UseKeyRing(KeyRing)
+
+	// 一次性令牌的签名密钥不落库也不写进代码，泄露后可伪造任意用户的重置密码链接，
+	// 多实例部署时所有实例需配置相同的值
+	secret := getEnv("BLOG_ACCOUNT_TOKEN_SECRET", "")
+	if len(secret) < consts.AccountTokenSecretMinLength {
+		log.Fatalf("BLOG_ACCOUNT_TOKEN_SECRET must be set to at least %d random bytes, e.g. `openssl rand -base64 32`", consts.AccountTokenSecretMinLength)
+	}
+	AccountTokenSecret = []byte(secret)
+}
+
+func parseDurationEnv(key string, fallback time.Duration) time.Duration {
+	raw := getEnv(key, "")
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		log.Fatalf("Invalid %s=%q: %v", key, raw, err)
+	}
+	return d
+}
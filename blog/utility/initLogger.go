@@ -0,0 +1,25 @@
+package utility
+
+import (
+	"log/slog"
+	"os"
+
+	"web-task/blog/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+var Logger *slog.Logger
+
+// InitLogger 初始化结构化日志，需最先调用
+// BLOG_ENV=production 时输出 JSON 并关闭 gin 的调试输出；BLOG_LOG_LEVEL 可选 debug/info/warn/error。
+// 同时设为 slog 默认记录器，标准库 log 的输出也会经过脱敏与格式化
+func InitLogger() {
+	production := getEnv("BLOG_ENV", "development") == "production"
+	Logger = logging.New(os.Stderr, logging.ParseLevel(getEnv("BLOG_LOG_LEVEL", "info")), production)
+	slog.SetDefault(Logger)
+
+	if production {
+		gin.SetMode(gin.ReleaseMode)
+	}
+}
@@ -0,0 +1,48 @@
+package utility
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"web-task/blog/internal/mailer"
+)
+
+var Mailer mailer.Mailer
+
+// InitMailer 根据环境变量初始化邮件发送
+// BLOG_MAILER=file（默认）时邮件写入 BLOG_MAIL_DIR 供本地查看；=smtp 时使用 BLOG_SMTP_* 配置发送
+func InitMailer() {
+	var err error
+	from := getEnv("BLOG_MAIL_FROM", "Blog <no-reply@localhost>")
+
+	switch driver := getEnv("BLOG_MAILER", "file"); driver {
+	case "file":
+		Mailer, err = mailer.NewFileMailer(getEnv("BLOG_MAIL_DIR", "./mail"), from)
+	case "smtp":
+		port, perr := strconv.Atoi(getEnv("BLOG_SMTP_PORT", "587"))
+		if perr != nil {
+			err = fmt.Errorf("invalid BLOG_SMTP_PORT: %w", perr)
+			break
+		}
+		Mailer = mailer.NewSMTPMailer(
+			os.Getenv("BLOG_SMTP_HOST"),
+			port,
+			os.Getenv("BLOG_SMTP_USERNAME"),
+			os.Getenv("BLOG_SMTP_PASSWORD"),
+			from,
+		)
+	default:
+		err = fmt.Errorf("unknown mailer %q", driver)
+	}
+
+	if err != nil {
+		log.Fatalf("Failed to init mailer: %v", err)
+	}
+}
+
+// AppURL 对外访问地址，用于拼接邮件等场景中的链接
+func AppURL() string {
+	return getEnv("BLOG_APP_URL", "http://localhost:8080")
+}
@@ -0,0 +1,25 @@
+package utility
+
+import (
+	"log"
+
+	"web-task/blog/internal/metrics"
+	"web-task/blog/middleware"
+)
+
+// InitMetrics 为数据库注册查询耗时插件与连接池指标，需在 InitDB 之后调用
+// BLOG_METRICS_TOKEN 非空时抓取 /metrics 需携带该令牌
+func InitMetrics() {
+	if err := DB.Use(metrics.GormPlugin{}); err != nil {
+		log.Fatalf("Failed to init database metrics: %v", err)
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		log.Fatalf("Failed to init database metrics: %v", err)
+	}
+	if err := metrics.RegisterDB(sqlDB, "blog"); err != nil {
+		log.Fatalf("Failed to init database metrics: %v", err)
+	}
+
+	middleware.UseMetricsToken(getEnv("BLOG_METRICS_TOKEN", ""))
+}
@@ -0,0 +1,71 @@
+package utility
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"web-task/blog/internal/oidc"
+)
+
+var OIDCProviders = make(map[string]*oidc.Provider)
+
+// 内置提供方配置；Google 使用 OIDC 发现文档，GitHub 只支持 OAuth2，通过用户信息接口识别用户
+var builtinOIDCProviders = map[string]oidc.Config{
+	"google": {
+		DiscoveryURL: "https://accounts.google.com/.well-known/openid-configuration",
+		Scopes:       []string{"openid", "email", "profile"},
+	},
+	"github": {
+		AuthURL:       "https://github.com/login/oauth/authorize",
+		TokenURL:      "https://github.com/login/oauth/access_token",
+		UserInfoURL:   "https://api.github.com/user",
+		EmailsURL:     "https://api.github.com/user/emails",
+		SubjectField:  "id",
+		UsernameField: "login",
+		Scopes:        []string{"read:user", "user:email"},
+	},
+}
+
+// InitOIDC 根据环境变量初始化第三方登录提供方
+// 设置 BLOG_OIDC_<NAME>_CLIENT_ID / _CLIENT_SECRET 即启用对应提供方（google、github）；
+// 其它 OIDC 提供方在 BLOG_OIDC_PROVIDERS 中列出名称，并设置 BLOG_OIDC_<NAME>_DISCOVERY_URL；
+// 回调地址默认为 BLOG_APP_URL/api/v1/auth/<name>/callback，可用 BLOG_OIDC_<NAME>_REDIRECT_URL 覆盖
+func InitOIDC() {
+	configs := make(map[string]oidc.Config)
+	for name, cfg := range builtinOIDCProviders {
+		configs[name] = cfg
+	}
+	for _, name := range strings.Split(os.Getenv("BLOG_OIDC_PROVIDERS"), ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			if _, ok := configs[name]; !ok {
+				configs[name] = oidc.Config{Scopes: []string{"openid", "email", "profile"}}
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	for name, cfg := range configs {
+		prefix := "BLOG_OIDC_" + strings.ToUpper(name) + "_"
+		cfg.Name = name
+		cfg.ClientID = os.Getenv(prefix + "CLIENT_ID")
+		cfg.ClientSecret = os.Getenv(prefix + "CLIENT_SECRET")
+		if cfg.ClientID == "" {
+			continue
+		}
+		cfg.DiscoveryURL = getEnv(prefix+"DISCOVERY_URL", cfg.DiscoveryURL)
+		cfg.RedirectURL = getEnv(prefix+"REDIRECT_URL", strings.TrimRight(AppURL(), "/")+"/api/v1/auth/"+name+"/callback")
+
+		// 提供方暂时不可用时跳过，不影响其它登录方式
+		provider, err := oidc.NewProvider(ctx, cfg, nil)
+		if err != nil {
+			slog.Error("Failed to init identity provider", "provider", name, "error", err)
+			continue
+		}
+		OIDCProviders[name] = provider
+	}
+}
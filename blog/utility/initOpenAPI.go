@@ -0,0 +1,21 @@
+package utility
+
+import (
+	"log"
+	"strconv"
+)
+
+// OpenAPIValidation 是否按接口文档校验请求与响应
+var OpenAPIValidation bool
+
+// InitOpenAPI BLOG_OPENAPI_VALIDATE=true/false 控制是否按接口文档校验请求体与响应，
+// 默认在 BLOG_ENV=production 时关闭、其它环境开启；校验需要缓存整个 JSON 响应，不建议在生产环境开启
+func InitOpenAPI() {
+	fallback := strconv.FormatBool(getEnv("BLOG_ENV", "development") != "production")
+	value := getEnv("BLOG_OPENAPI_VALIDATE", fallback)
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Invalid BLOG_OPENAPI_VALIDATE=%q: must be true or false", value)
+	}
+	OpenAPIValidation = enabled
+}
@@ -0,0 +1,76 @@
+package utility
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-task/blog/internal/consts"
+	"web-task/blog/middleware"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var RateLimiter *middleware.RateLimiter
+
+// InitRateLimiter 初始化各路由组的限流策略
+// 设置 BLOG_REDIS_ADDR 时使用 Redis 兼容存储以便多实例共享，否则使用进程内存储
+// 策略可通过 BLOG_RATELIMIT_<NAME>=次数/时间窗 覆盖，如 BLOG_RATELIMIT_LOGIN=5/1m
+func InitRateLimiter() {
+	var store middleware.RateLimitStore
+	if addr := os.Getenv("BLOG_REDIS_ADDR"); addr != "" {
+		store = middleware.NewRedisRateLimitStore(redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: os.Getenv("BLOG_REDIS_PASSWORD"),
+		}))
+	} else {
+		store = middleware.NewMemoryRateLimitStore()
+	}
+
+	policies := []middleware.RateLimitPolicy{
+		ratePolicy(consts.RateLimitLogin, "5/1m", middleware.KeyByIP),
+		ratePolicy(consts.RateLimitCommentCreate, "10/1m", middleware.KeyByUser),
+		ratePolicy(consts.RateLimitReads, "300/1m", middleware.KeyByIP),
+		ratePolicy(consts.RateLimitEmail, "5/1h", middleware.KeyByIP),
+		ratePolicy(consts.RateLimitExport, "3/1h", middleware.KeyByUser),
+	}
+
+	RateLimiter = middleware.NewRateLimiter(store, policies...)
+}
+
+// ratePolicy 解析 "次数/时间窗" 格式的策略，环境变量优先于默认值
+// 时间窗内允许突发全部次数，之后按平均速率恢复
+func ratePolicy(name, fallback, keyBy string) middleware.RateLimitPolicy {
+	spec := getEnv("BLOG_RATELIMIT_"+strings.ToUpper(name), fallback)
+
+	count, window, err := parseRateSpec(spec)
+	if err != nil {
+		log.Fatalf("Invalid rate limit policy %s=%q: %v", name, spec, err)
+	}
+
+	return middleware.RateLimitPolicy{
+		Name:  name,
+		Rate:  float64(count) / window.Seconds(),
+		Burst: count,
+		KeyBy: keyBy,
+	}
+}
+
+func parseRateSpec(spec string) (int, time.Duration, error) {
+	countStr, windowStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected <count>/<window>")
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count <= 0 {
+		return 0, 0, fmt.Errorf("invalid count %q", countStr)
+	}
+	window, err := time.ParseDuration(windowStr)
+	if err != nil || window <= 0 {
+		return 0, 0, fmt.Errorf("invalid window %q", windowStr)
+	}
+	return count, window, nil
+}
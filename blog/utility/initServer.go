@@ -0,0 +1,54 @@
+package utility
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ShutdownTimeout 收到退出信号后等待进行中请求与后台任务结束的最长时间
+var ShutdownTimeout time.Duration
+
+// ShutdownDelay 进入退出流程后、停止接受新连接前的等待时间，留给负载均衡感知 /readyz 失败
+var ShutdownDelay time.Duration
+
+// NewServer 按环境变量创建 HTTP 服务
+// BLOG_ADDR 监听地址，默认 :8080；超时格式为 Go 时长，0 表示不限制：
+// BLOG_READ_HEADER_TIMEOUT 默认 10s，BLOG_READ_TIMEOUT 与 BLOG_WRITE_TIMEOUT 默认 15m（需长于 BLOG_TRANSFER_TIMEOUT），
+// BLOG_IDLE_TIMEOUT 默认 2m，BLOG_SHUTDOWN_TIMEOUT 默认 30s，BLOG_SHUTDOWN_DELAY 默认 0s
+func NewServer(handler http.Handler) *http.Server {
+	ShutdownTimeout = parseTimeout("BLOG_SHUTDOWN_TIMEOUT", "30s")
+	ShutdownDelay = parseTimeout("BLOG_SHUTDOWN_DELAY", "0s")
+
+	return newHTTPServer(getEnv("BLOG_ADDR", ":8080"), handler)
+}
+
+// newHTTPServer 创建使用 BLOG_*_TIMEOUT 超时设置的 HTTP 服务，REST 与 gRPC 服务共用同一组设置
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: parseTimeout("BLOG_READ_HEADER_TIMEOUT", "10s"),
+		ReadTimeout:       parseTimeout("BLOG_READ_TIMEOUT", "15m"),
+		WriteTimeout:      parseTimeout("BLOG_WRITE_TIMEOUT", "15m"),
+		IdleTimeout:       parseTimeout("BLOG_IDLE_TIMEOUT", "2m"),
+	}
+}
+
+// TrustedProxies 允许设置 X-Forwarded-For 等转发头的反向代理，来自 BLOG_TRUSTED_PROXIES（逗号分隔的 IP 或 CIDR）
+// 默认不信任任何代理，客户端 IP 取连接的对端地址，否则客户端可伪造转发头绕过按 IP 的限流与登录锁定
+func TrustedProxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(getEnv("BLOG_TRUSTED_PROXIES", ""), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
+// NewGRPCServer 创建 gRPC 与 grpc-gateway 共用的 HTTP 服务
+// BLOG_GRPC_ADDR 监听地址，默认 :9090；读写与空闲超时与 HTTP 服务相同，防止慢速连接长期占用
+func NewGRPCServer(handler http.Handler) *http.Server {
+	return newHTTPServer(getEnv("BLOG_GRPC_ADDR", ":9090"), handler)
+}
@@ -0,0 +1,53 @@
+package utility
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"web-task/blog/internal/storage"
+)
+
+var Storage storage.Storage
+
+// InitStorage 根据环境变量初始化附件存储
+// BLOG_STORAGE_DRIVER=local（默认）时写入 BLOG_UPLOAD_DIR；=s3 时使用 BLOG_S3_* 配置连接 S3 兼容服务
+func InitStorage() {
+	var err error
+
+	switch driver := getEnv("BLOG_STORAGE_DRIVER", "local"); driver {
+	case "local":
+		Storage, err = storage.NewLocalStorage(getEnv("BLOG_UPLOAD_DIR", "./uploads"))
+	case "s3":
+		useSSL, _ := strconv.ParseBool(getEnv("BLOG_S3_USE_SSL", "true"))
+		pathStyle, _ := strconv.ParseBool(getEnv("BLOG_S3_PATH_STYLE", "false"))
+		Storage, err = storage.NewS3Storage(context.Background(), storage.S3Config{
+			Endpoint:  os.Getenv("BLOG_S3_ENDPOINT"),
+			Region:    os.Getenv("BLOG_S3_REGION"),
+			Bucket:    getEnv("BLOG_S3_BUCKET", "blog-uploads"),
+			AccessKey: os.Getenv("BLOG_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("BLOG_S3_SECRET_KEY"),
+			UseSSL:    useSSL,
+			PathStyle: pathStyle,
+		})
+	default:
+		err = fmt.Errorf("unknown storage driver %q", driver)
+	}
+
+	if err != nil {
+		log.Fatalf("Failed to init storage: %v", err)
+	}
+
+	slog.Info("Storage successfully initialized")
+}
+
+// getEnv 读取环境变量，未设置时返回默认值
+func getEnv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,27 @@
+package utility
+
+import (
+	"log"
+	"time"
+
+	"web-task/blog/middleware"
+)
+
+// InitTimeouts 配置请求截止时间，格式为 Go 时长如 15s、2m，0 表示不限制
+// BLOG_REQUEST_TIMEOUT 为普通请求的截止时间，默认 15s；
+// BLOG_TRANSFER_TIMEOUT 用于附件上传下载、导出下载与批量导入，默认 10m
+func InitTimeouts() {
+	middleware.UseTimeouts(
+		parseTimeout("BLOG_REQUEST_TIMEOUT", "15s"),
+		parseTimeout("BLOG_TRANSFER_TIMEOUT", "10m"),
+	)
+}
+
+func parseTimeout(key, fallback string) time.Duration {
+	value := getEnv(key, fallback)
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Fatalf("Invalid %s=%q: must be a duration such as 15s", key, value)
+	}
+	return d
+}
@@ -0,0 +1,38 @@
+package utility
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+
+	"web-task/blog/internal/tracing"
+)
+
+// ShutdownTracing 退出前调用，刷新尚未导出的 span
+var ShutdownTracing = func(context.Context) error { return nil }
+
+// InitTracing 初始化链路追踪，需在 InitDB 之后调用
+// BLOG_TRACING_EXPORTER 可选 none（默认）、stdout、otlp；otlp 的地址与认证由标准的 OTEL_EXPORTER_OTLP_* 环境变量配置；
+// BLOG_TRACING_SAMPLE_RATIO 为根 span 的采样率，默认 1
+func InitTracing() {
+	ratio, err := strconv.ParseFloat(getEnv("BLOG_TRACING_SAMPLE_RATIO", "1"), 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		log.Fatalf("Invalid BLOG_TRACING_SAMPLE_RATIO: must be between 0 and 1")
+	}
+
+	shutdown, err := tracing.Init(context.Background(), tracing.Config{
+		ServiceName: getEnv("OTEL_SERVICE_NAME", "blog"),
+		Exporter:    getEnv("BLOG_TRACING_EXPORTER", tracing.ExporterNone),
+		SampleRatio: ratio,
+		Stdout:      os.Stdout,
+	})
+	if err != nil {
+		log.Fatalf("Failed to init tracing: %v", err)
+	}
+	ShutdownTracing = shutdown
+
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatalf("Failed to init database tracing: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package utility
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"web-task/blog/internal/migrate"
+	"web-task/blog/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// Migrations 全部数据库迁移，结构变更在末尾追加新版本，已发布的迁移不可修改
+var Migrations = []migrate.Migration{
+	{
+		Version:     "0001_initial_schema",
+		Description: "create all tables that existed before versioned migrations",
+		Up:          AutoMigrate,
+	},
+	{
+		Version:     "0002_webhooks",
+		Description: "create webhook, outbox event and webhook delivery tables",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&model.Webhook{}, &model.OutboxEvent{}, &model.WebhookDelivery{})
+		},
+	},
+	{
+		Version:     "0003_notifications",
+		Description: "create notification table",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&model.Notification{})
+		},
+	},
+	{
+		Version:     "0004_export_download_keys",
+		Description: "add a per-job download key to export jobs and backfill existing jobs",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&model.ExportJob{}); err != nil {
+				return err
+			}
+			var ids []uint
+			if err := tx.Model(&model.ExportJob{}).Where("download_key IS NULL OR download_key = ''").Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			for _, id := range ids {
+				key := make([]byte, 32)
+				if _, err := rand.Read(key); err != nil {
+					return err
+				}
+				if err := tx.Model(&model.ExportJob{}).Where("id = ?", id).Update("download_key", hex.EncodeToString(key)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     "0005_comment_replies",
+		Description: "add parent_id to comments for replies",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&model.Comment{})
+		},
+	},
+}
+
+// NewMigrator 创建包含全部迁移的 Migrator
+func NewMigrator(db *gorm.DB) *migrate.Migrator {
+	return migrate.New(db, Migrations)
+}
+
+// AutoMigrate 同步所有模型的表结构
+// 作为第一个迁移执行；对已有数据库可重复执行，升级到版本化迁移的部署会直接记为已执行
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&model.User{},
+		&model.Post{},
+		&model.Comment{},
+		&model.Attachment{},
+		&model.LoginAttempt{},
+		&model.LoginSession{},
+		&model.UserToken{},
+		&model.RecoveryCode{},
+		&model.Identity{},
+		&model.OAuthState{},
+		&model.SigningKey{},
+		&model.PersonalAccessToken{},
+		&model.ExportJob{},
+		&model.Tag{},
+		&model.PostSource{},
+	)
+}